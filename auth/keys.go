@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// AlgorithmHS256 signs tokens with a shared secret (HMAC SHA-256)
+	AlgorithmHS256 = "HS256"
+	// AlgorithmRS256 signs tokens with a RSA private key (RSASSA-PKCS1-v1_5 SHA-256)
+	AlgorithmRS256 = "RS256"
+	// AlgorithmES256 signs tokens with an ECDSA P-256 private key
+	AlgorithmES256 = "ES256"
+)
+
+var (
+	keySet      *KeySet
+	keySetMutex sync.RWMutex
+)
+
+// Key defines a key used to sign and/or verify tokens, identified by the 'kid' header
+type Key struct {
+	ID        string
+	Algorithm string
+	// signKey is nil for keys which are only used for verification
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet defines the key used to sign new tokens and every key accepted to verify them,
+// which allows rotating keys without invalidating tokens signed by previous ones
+type KeySet struct {
+	signing      *Key
+	verification map[string]*Key
+}
+
+// JWK defines a public JSON Web Key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS defines a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKey will return a HS256 key based on a shared secret
+func NewHMACKey(id string, secret []byte) (Key, error) {
+	if len(secret) == 0 {
+		return Key{}, errors.New("empty HMAC secret")
+	}
+
+	return Key{ID: id, Algorithm: AlgorithmHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// ParseKey will return a key from its algorithm and material: a shared secret for HS256 or a
+// PEM encoded private (to sign and verify) or public (to verify only) key for RS256 and ES256
+func ParseKey(id string, algorithm string, material []byte) (Key, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		return NewHMACKey(id, bytes.TrimSpace(material))
+	case AlgorithmRS256:
+		if private, err := jwt.ParseRSAPrivateKeyFromPEM(material); err == nil {
+			return Key{ID: id, Algorithm: algorithm, signKey: private, verifyKey: &private.PublicKey}, nil
+		}
+
+		public, err := jwt.ParseRSAPublicKeyFromPEM(material)
+		if err != nil {
+			return Key{}, fmt.Errorf("could not parse RSA key '%s': %w", id, err)
+		}
+		return Key{ID: id, Algorithm: algorithm, verifyKey: public}, nil
+	case AlgorithmES256:
+		if private, err := jwt.ParseECPrivateKeyFromPEM(material); err == nil {
+			return Key{ID: id, Algorithm: algorithm, signKey: private, verifyKey: &private.PublicKey}, nil
+		}
+
+		public, err := jwt.ParseECPublicKeyFromPEM(material)
+		if err != nil {
+			return Key{}, fmt.Errorf("could not parse ECDSA key '%s': %w", id, err)
+		}
+		return Key{ID: id, Algorithm: algorithm, verifyKey: public}, nil
+	}
+
+	return Key{}, fmt.Errorf("unsupported signing algorithm '%s'", algorithm)
+}
+
+// CanSign will return if a key holds a private key or secret
+func (k Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// NewKeySet will return a key set signing with a given key and verifying with it plus all given keys
+func NewKeySet(signing Key, verification ...Key) (*KeySet, error) {
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("key '%s' can not be used to sign tokens", signing.ID)
+	}
+
+	k := &KeySet{
+		signing:      &signing,
+		verification: map[string]*Key{signing.ID: &signing},
+	}
+
+	for i := range verification {
+		key := verification[i]
+		if _, ok := k.verification[key.ID]; ok {
+			return nil, fmt.Errorf("duplicated key id '%s'", key.ID)
+		}
+		k.verification[key.ID] = &key
+	}
+
+	return k, nil
+}
+
+// Sign will sign a set of claims with the signing key, adding its 'kid' header
+func (k *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.signing.Algorithm), claims)
+	token.Header["kid"] = k.signing.ID
+
+	return token.SignedString(k.signing.signKey)
+}
+
+// Parse will parse and verify a token with the key identified by its 'kid' header. Tokens
+// without a 'kid' are verified with the signing key
+func (k *KeySet) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		key := k.signing
+		if kid, ok := token.Header["kid"].(string); ok {
+			key, ok = k.verification[kid]
+			if !ok {
+				return nil, fmt.Errorf("unknown key id '%s'", kid)
+			}
+		}
+
+		// the algorithm is always enforced by the key, never by the token itself
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("could not decode token")
+		}
+
+		return key.verifyKey, nil
+	})
+}
+
+// JWKS will return all public verification keys. Shared secrets are never published
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, key := range k.verification {
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "RSA",
+				Use:       "sig",
+				KeyID:     key.ID,
+				Algorithm: key.Algorithm,
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "EC",
+				Use:       "sig",
+				KeyID:     key.ID,
+				Algorithm: key.Algorithm,
+				Curve:     public.Curve.Params().Name,
+				X:         base64.RawURLEncoding.EncodeToString(padBytes(public.X.Bytes(), size)),
+				Y:         base64.RawURLEncoding.EncodeToString(padBytes(public.Y.Bytes(), size)),
+			})
+		}
+	}
+
+	return jwks
+}
+
+// padBytes will left pad a big-endian integer to a fixed size, as required by JWK coordinates
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+// UseKeySet will define the key set used across the application
+func UseKeySet(k *KeySet) {
+	keySetMutex.Lock()
+	defer keySetMutex.Unlock()
+
+	keySet = k
+}
+
+// Keys will return the key set used across the application. When no key set was defined
+// an ephemeral HMAC secret is generated, so tokens will not survive restarts
+func Keys() *KeySet {
+	keySetMutex.RLock()
+	k := keySet
+	keySetMutex.RUnlock()
+
+	if k != nil {
+		return k
+	}
+
+	keySetMutex.Lock()
+	defer keySetMutex.Unlock()
+
+	if keySet == nil {
+		log.Warnln("no JWT signing key configured, using an ephemeral one")
+
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Panicln("could not generate ephemeral JWT signing key", err)
+		}
+
+		key, _ := NewHMACKey("ephemeral", secret)
+		keySet, _ = NewKeySet(key)
+	}
+
+	return keySet
+}
+
+// LoadKeySet will load a key set from a signing key, given either as a secret or a file, and
+// verification keys given as "kid:algorithm:file". It returns a nil key set when no signing key is given
+func LoadKeySet(id string, algorithm string, secret string, file string, verification []string) (*KeySet, error) {
+	var material []byte
+	switch {
+	case secret != "" && algorithm != AlgorithmHS256:
+		return nil, fmt.Errorf("a plain signing key is only supported by %s, use a key file instead", AlgorithmHS256)
+	case secret != "":
+		material = []byte(secret)
+	case file != "":
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read signing key file: %w", err)
+		}
+		material = b
+	default:
+		return nil, nil
+	}
+
+	signing, err := ParseKey(id, algorithm, material)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []Key
+	for _, v := range verification {
+		parts := strings.SplitN(strings.TrimSpace(v), ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("verification key '%s' must be defined as 'kid:algorithm:file'", v)
+		}
+
+		b, err := ioutil.ReadFile(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("could not read verification key file: %w", err)
+		}
+
+		key, err := ParseKey(parts[0], parts[1], b)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(signing, keys...)
+}
+
+// GenerateECDSAKey will return a new ES256 key, mostly useful for development and tests
+func GenerateECDSAKey(id string) (Key, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Key{}, err
+	}
+
+	return Key{ID: id, Algorithm: AlgorithmES256, signKey: private, verifyKey: &private.PublicKey}, nil
+}
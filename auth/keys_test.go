@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+func TestKeySetVerifiesRotatedKeys(t *testing.T) {
+	previous, err := GenerateECDSAKey("previous")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := GenerateECDSAKey("current")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousSet, err := NewKeySet(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := previousSet.Sign(jwt.MapClaims{"sub": "5143afc66d44e1ceb372121e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotatedSet, err := NewKeySet(current, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rotatedSet.Parse(token); err != nil {
+		t.Errorf("token signed by a previous key was not verified: %v", err)
+	}
+
+	currentOnlySet, err := NewKeySet(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := currentOnlySet.Parse(token); err == nil {
+		t.Errorf("token signed by an unknown key was verified")
+	}
+}
+
+func TestKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	key, err := NewHMACKey("default", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := NewKeySet(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a token claiming the same key id with another algorithm must never be accepted
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{"sub": "5143afc66d44e1ceb372121e"})
+	token.Header["kid"] = "default"
+	signed, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := k.Parse(signed); err == nil {
+		t.Errorf("token with a mismatching algorithm was verified")
+	}
+}
+
+func TestKeySetJWKSPublishesOnlyPublicKeys(t *testing.T) {
+	hmac, err := NewHMACKey("shared", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsa, err := GenerateECDSAKey("asymmetric")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := NewKeySet(hmac, ecdsa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := k.JWKS()
+	if len(jwks.Keys) != 1 {
+		t.Fatalf("unexpected number of published keys: got %v want 1", len(jwks.Keys))
+	}
+
+	if jwks.Keys[0].KeyID != "asymmetric" || jwks.Keys[0].Curve != "P-256" || len(jwks.Keys[0].X) != 43 {
+		t.Errorf("unexpected published key: %+v", jwks.Keys[0])
+	}
+}
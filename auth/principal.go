@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// RoleAdmin allows platform wide operations such as managing users and listing every resource
+	RoleAdmin = "admin"
+)
+
+const (
+	// TokenTypeAccess defines tokens used to authenticate requests
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh defines tokens used only to issue new access tokens
+	TokenTypeRefresh = "refresh"
+)
+
+// Roles defines all roles which can be granted to users
+var Roles = []string{RoleAdmin}
+
+// ValidRole will validate if a role is a known one
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// contextKey defines the key type used to store values at request contexts
+type contextKey struct{}
+
+// Principal defines the authenticated user performing a request
+type Principal struct {
+	// Subject is the user ID taken from the token 'sub' claim
+	Subject string
+	// Login is the user login taken from the token 'name' claim
+	Login string
+	// Roles are taken from the token 'roles' claim
+	Roles []string
+	// TokenID is the token 'jti' claim, used to revoke it
+	TokenID string
+	// IssuedAt and ExpiresAt are taken from the token 'iat' and 'exp' claims
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// WithPrincipal will return a copy of a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// PrincipalFromContext will return the authenticated principal from a context, if any
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
+
+// Owns will return if a principal is the owner of a given owner ID
+func (p Principal) Owns(ownerID string) bool {
+	return p.Subject != "" && p.Subject == ownerID
+}
+
+// HasRole will return if a principal was granted a given role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccess will return if a principal can access resources from a given owner ID,
+// either by owning them or by being an admin
+func (p Principal) CanAccess(ownerID string) bool {
+	return p.Owns(ownerID) || p.HasRole(RoleAdmin)
+}
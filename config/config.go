@@ -0,0 +1,400 @@
+package config
+
+import (
+	"budget-tracker-api/auth"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// TracingExporterJaeger will export traces to a jaeger collector
+	TracingExporterJaeger = "jaeger"
+	// TracingExporterZipkin will export traces to a zipkin collector
+	TracingExporterZipkin = "zipkin"
+	// TracingExporterStdout will print traces to stdout
+	TracingExporterStdout = "stdout"
+	// TracingExporterNone will disable traces
+	TracingExporterNone = "none"
+
+	// StorageBackendMongoDB will store data on mongoDB
+	StorageBackendMongoDB = "mongodb"
+	// StorageBackendMemory will store data in memory, lost on restarts
+	StorageBackendMemory = "memory"
+	// StorageBackendSQLite will store data on a SQLite database file
+	StorageBackendSQLite = "sqlite"
+	// StorageBackendPostgres will store data on PostgreSQL
+	StorageBackendPostgres = "postgres"
+)
+
+// Config defines the whole application configuration
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Storage   StorageConfig   `yaml:"storage"`
+	MongoDB   MongoDBConfig   `yaml:"mongodb"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+}
+
+// ServerConfig defines the HTTP server configuration
+type ServerConfig struct {
+	Port            string        `yaml:"port"`
+	TLS             bool          `yaml:"tls"`
+	CertFile        string        `yaml:"cert_file"`
+	KeyFile         string        `yaml:"key_file"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// StorageConfig defines which backend stores data
+type StorageConfig struct {
+	Backend string `yaml:"backend"`
+	// DSN is the data source of SQL backends: a file path for sqlite or a connection URL for postgres
+	DSN string `yaml:"dsn"`
+}
+
+// MongoDBConfig defines the mongoDB client configuration
+type MongoDBConfig struct {
+	URI         string            `yaml:"uri"`
+	Database    string            `yaml:"database"`
+	Timeout     time.Duration     `yaml:"timeout"`
+	Collections MongoDBCollection `yaml:"collections"`
+}
+
+// MongoDBCollection defines the name of every mongoDB collection
+type MongoDBCollection struct {
+	Users             string `yaml:"users"`
+	Cards             string `yaml:"cards"`
+	Balances          string `yaml:"balances"`
+	Spends            string `yaml:"spends"`
+	RefreshTokens     string `yaml:"refresh_tokens"`
+	Revocations       string `yaml:"revocations"`
+	LoginAttempts     string `yaml:"login_attempts"`
+	ExchangeRates     string `yaml:"exchange_rates"`
+	Recurrences       string `yaml:"recurrences"`
+	StatementPayments string `yaml:"statement_payments"`
+	BudgetAlerts      string `yaml:"budget_alerts"`
+	Categories        string `yaml:"categories"`
+	CategoryRules     string `yaml:"category_rules"`
+}
+
+// TracingConfig defines which exporter traces are sent to
+type TracingConfig struct {
+	ServiceName string `yaml:"service_name"`
+	Exporter    string `yaml:"exporter"`
+	JaegerURL   string `yaml:"jaeger_url"`
+	ZipkinURL   string `yaml:"zipkin_url"`
+}
+
+// JWTConfig defines how tokens are signed and for how long they are valid
+type JWTConfig struct {
+	Algorithm string `yaml:"algorithm"`
+	KeyID     string `yaml:"key_id"`
+	Key       string `yaml:"key"`
+	KeyFile   string `yaml:"key_file"`
+	// VerificationKeys are previous keys still accepted, as "kid:algorithm:file"
+	VerificationKeys []string      `yaml:"verification_keys"`
+	AccessTokenTTL   time.Duration `yaml:"access_token_ttl"`
+	RefreshTokenTTL  time.Duration `yaml:"refresh_token_ttl"`
+}
+
+// SchedulerConfig defines how often recurring spends are materialized
+type SchedulerConfig struct {
+	// Interval between runs, where zero disables the scheduler (e.g. on extra replicas)
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Default will return the configuration used when nothing else is defined
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:            ":5000",
+			CertFile:        "config/tls/server.crt",
+			KeyFile:         "config/tls/server.key",
+			ReadTimeout:     1 * time.Second,
+			WriteTimeout:    1 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend: StorageBackendMongoDB,
+		},
+		MongoDB: MongoDBConfig{
+			URI:      "mongodb://localhost:27017/",
+			Database: "budget-tracker",
+			Timeout:  5 * time.Second,
+			Collections: MongoDBCollection{
+				Users:             "users",
+				Cards:             "cards",
+				Balances:          "balance",
+				Spends:            "spends",
+				RefreshTokens:     "refresh_tokens",
+				Revocations:       "revocations",
+				LoginAttempts:     "login_attempts",
+				ExchangeRates:     "exchange_rates",
+				Recurrences:       "recurrences",
+				StatementPayments: "statement_payments",
+				BudgetAlerts:      "budget_alerts",
+				Categories:        "categories",
+				CategoryRules:     "category_rules",
+			},
+		},
+		Tracing: TracingConfig{
+			ServiceName: "budget-tracker-api",
+			Exporter:    TracingExporterJaeger,
+			JaegerURL:   "http://localhost:14268/api/traces",
+			ZipkinURL:   "http://localhost:9411/api/v2/spans",
+		},
+		JWT: JWTConfig{
+			Algorithm:       auth.AlgorithmHS256,
+			KeyID:           "default",
+			AccessTokenTTL:  5 * time.Minute,
+			RefreshTokenTTL: 24 * time.Hour,
+		},
+		Scheduler: SchedulerConfig{
+			Interval: 1 * time.Hour,
+		},
+	}
+}
+
+// Load will return the default configuration overridden by an optional YAML file, defined by
+// the CONFIG_FILE environment variable, and then by environment variables
+func Load() (Config, error) {
+	c := Default()
+
+	if file := os.Getenv("CONFIG_FILE"); file != "" {
+		err := c.LoadFile(file)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	err := c.LoadEnv(os.LookupEnv)
+	if err != nil {
+		return Config{}, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return c, nil
+}
+
+// LoadFile will override the configuration with the attributes defined in a YAML file
+func (c *Config) LoadFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	defer f.Close()
+
+	d := yaml.NewDecoder(f)
+	d.KnownFields(true)
+
+	// an empty file keeps the configuration untouched
+	err = d.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("could not parse config file '%s': %w", file, err)
+	}
+
+	return nil
+}
+
+// LoadEnv will override the configuration with the environment variables found by a lookup function
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	e := envLoader{lookup: lookup}
+
+	e.string("SERVER_PORT", &c.Server.Port)
+	e.bool("SERVER_TLS", &c.Server.TLS)
+	e.string("SERVER_TLS_CERT_FILE", &c.Server.CertFile)
+	e.string("SERVER_TLS_KEY_FILE", &c.Server.KeyFile)
+	e.duration("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout)
+	e.duration("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	e.duration("SERVER_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+
+	e.string("STORAGE_BACKEND", &c.Storage.Backend)
+	e.string("STORAGE_DSN", &c.Storage.DSN)
+
+	e.string("MONGODB_URI", &c.MongoDB.URI)
+	e.string("MONGODB_DATABASE", &c.MongoDB.Database)
+	e.duration("MONGODB_TIMEOUT", &c.MongoDB.Timeout)
+
+	e.string("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	e.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	e.string("TRACING_JAEGER_URL", &c.Tracing.JaegerURL)
+	e.string("TRACING_ZIPKIN_URL", &c.Tracing.ZipkinURL)
+
+	e.string("JWT_SIGNING_ALGORITHM", &c.JWT.Algorithm)
+	e.string("JWT_SIGNING_KEY_ID", &c.JWT.KeyID)
+	e.string("JWT_SIGNING_KEY", &c.JWT.Key)
+	e.string("JWT_SIGNING_KEY_FILE", &c.JWT.KeyFile)
+	e.list("JWT_VERIFICATION_KEYS", &c.JWT.VerificationKeys)
+	e.duration("JWT_ACCESS_TOKEN_TTL", &c.JWT.AccessTokenTTL)
+	e.duration("JWT_REFRESH_TOKEN_TTL", &c.JWT.RefreshTokenTTL)
+
+	e.duration("SCHEDULER_INTERVAL", &c.Scheduler.Interval)
+
+	if len(e.errs) > 0 {
+		return fmt.Errorf("invalid environment variables: %s", strings.Join(e.errs, "; "))
+	}
+
+	return nil
+}
+
+// Validate will return an error describing every invalid attribute
+func (c Config) Validate() error {
+	var errs []string
+
+	if c.Server.Port == "" {
+		errs = append(errs, "server port must not be empty")
+	}
+
+	if c.Server.TLS && (c.Server.CertFile == "" || c.Server.KeyFile == "") {
+		errs = append(errs, "server TLS requires both cert and key files")
+	}
+
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, "server timeouts must be positive")
+	}
+
+	switch c.Storage.Backend {
+	case StorageBackendMongoDB, StorageBackendMemory:
+	case StorageBackendSQLite, StorageBackendPostgres:
+		if c.Storage.DSN == "" {
+			errs = append(errs, fmt.Sprintf("storage backend '%s' requires a dsn", c.Storage.Backend))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported storage backend '%s'", c.Storage.Backend))
+	}
+
+	if !strings.HasPrefix(c.MongoDB.URI, "mongodb://") && !strings.HasPrefix(c.MongoDB.URI, "mongodb+srv://") {
+		errs = append(errs, fmt.Sprintf("invalid mongodb uri '%s'", c.MongoDB.URI))
+	}
+
+	if c.MongoDB.Database == "" {
+		errs = append(errs, "mongodb database must not be empty")
+	}
+
+	if c.MongoDB.Timeout <= 0 {
+		errs = append(errs, "mongodb timeout must be positive")
+	}
+
+	collections := c.MongoDB.Collections
+	for _, name := range []string{
+		collections.Users,
+		collections.Cards,
+		collections.Balances,
+		collections.Spends,
+		collections.RefreshTokens,
+		collections.Revocations,
+		collections.LoginAttempts,
+		collections.ExchangeRates,
+		collections.Recurrences,
+		collections.StatementPayments,
+		collections.BudgetAlerts,
+		collections.Categories,
+		collections.CategoryRules,
+	} {
+		if name == "" {
+			errs = append(errs, "mongodb collection names must not be empty")
+			break
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingExporterJaeger:
+		if c.Tracing.JaegerURL == "" {
+			errs = append(errs, "jaeger tracing exporter requires a jaeger url")
+		}
+	case TracingExporterZipkin:
+		if c.Tracing.ZipkinURL == "" {
+			errs = append(errs, "zipkin tracing exporter requires a zipkin url")
+		}
+	case TracingExporterStdout, TracingExporterNone:
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported tracing exporter '%s'", c.Tracing.Exporter))
+	}
+
+	switch c.JWT.Algorithm {
+	case auth.AlgorithmHS256, auth.AlgorithmRS256, auth.AlgorithmES256:
+	default:
+		errs = append(errs, fmt.Sprintf("unsupported JWT signing algorithm '%s'", c.JWT.Algorithm))
+	}
+
+	if c.JWT.AccessTokenTTL <= 0 || c.JWT.RefreshTokenTTL <= 0 {
+		errs = append(errs, "JWT token TTLs must be positive")
+	}
+
+	if c.Scheduler.Interval < 0 {
+		errs = append(errs, "scheduler interval must not be negative")
+	}
+
+	if len(errs) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// envLoader will parse environment variables, collecting every invalid one
+type envLoader struct {
+	lookup func(string) (string, bool)
+	errs   []string
+}
+
+func (e *envLoader) string(name string, value *string) {
+	if v, ok := e.lookup(name); ok {
+		*value = v
+	}
+}
+
+func (e *envLoader) list(name string, value *[]string) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	*value = nil
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*value = append(*value, item)
+		}
+	}
+}
+
+func (e *envLoader) bool(name string, value *bool) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Sprintf("%s must be a boolean", name))
+		return
+	}
+	*value = b
+}
+
+func (e *envLoader) duration(name string, value *time.Duration) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Sprintf("%s must be a duration such as '5s'", name))
+		return
+	}
+	*value = d
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestDefaultConfigIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("default config is not valid: %v", err)
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(file, []byte("mongodb:\n  uri: mongodb://file:27017/\n  timeout: 3s\ntracing:\n  exporter: stdout\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Default()
+	if err := c.LoadFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.LoadEnv(lookupFrom(map[string]string{
+		"MONGODB_URI":           "mongodb://env:27017/",
+		"JWT_VERIFICATION_KEYS": "old:ES256:/keys/old.pem, older:RS256:/keys/older.pem",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.MongoDB.URI != "mongodb://env:27017/" {
+		t.Errorf("unexpected mongodb uri: got %v want %v", c.MongoDB.URI, "mongodb://env:27017/")
+	}
+
+	if c.MongoDB.Timeout != 3*time.Second {
+		t.Errorf("unexpected mongodb timeout: got %v want %v", c.MongoDB.Timeout, 3*time.Second)
+	}
+
+	if c.Tracing.Exporter != TracingExporterStdout {
+		t.Errorf("unexpected tracing exporter: got %v want %v", c.Tracing.Exporter, TracingExporterStdout)
+	}
+
+	if c.MongoDB.Database != "budget-tracker" {
+		t.Errorf("default mongodb database was not kept: got %v", c.MongoDB.Database)
+	}
+
+	if len(c.JWT.VerificationKeys) != 2 || c.JWT.VerificationKeys[1] != "older:RS256:/keys/older.pem" {
+		t.Errorf("unexpected verification keys: %v", c.JWT.VerificationKeys)
+	}
+}
+
+func TestLoadFileRejectsUnknownAttributes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(file, []byte("mongodb:\n  url: mongodb://file:27017/\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Default()
+	if err := c.LoadFile(file); err == nil {
+		t.Errorf("unknown attribute was accepted")
+	}
+}
+
+func TestInvalidConfigIsRejected(t *testing.T) {
+	c := Default()
+
+	err := c.LoadEnv(lookupFrom(map[string]string{"SERVER_READ_TIMEOUT": "soon"}))
+	if err == nil || !strings.Contains(err.Error(), "SERVER_READ_TIMEOUT") {
+		t.Errorf("invalid duration was not reported: %v", err)
+	}
+
+	c = Default()
+	c.MongoDB.URI = "localhost:27017"
+	c.Tracing.Exporter = "datadog"
+
+	err = c.Validate()
+	if err == nil {
+		t.Fatal("invalid config was accepted")
+	}
+
+	for _, expected := range []string{"invalid mongodb uri", "unsupported tracing exporter"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("error %q does not report %q", err, expected)
+		}
+	}
+}
@@ -1,46 +1,84 @@
 package controllers
 
 import (
+	"budget-tracker-api/auth"
 	"budget-tracker-api/crypt"
 	"budget-tracker-api/models"
 	"budget-tracker-api/repository"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var mySigninKey = []byte("myhellokey")
+var (
+	// AccessTokenTTL will define for how long access tokens are valid
+	AccessTokenTTL = 5 * time.Minute
+	// RefreshTokenTTL will define for how long refresh tokens are valid
+	RefreshTokenTTL = 24 * time.Hour
+)
+
+const (
+	// unknownLoginPasswordHash is a bcrypt hash checked against when a login does not exist
+	unknownLoginPasswordHash = "$2a$10$qsqGDJvLWNniNv1AhslI1uo/U8sUKEiZF5TugFlV/.D6oSkBGI5J."
+)
+
+// sessionLifetime will return for how long the longest lived token issued to a session is valid, which is
+// how long revoking every session must be kept
+func sessionLifetime() time.Duration {
+	if AccessTokenTTL > RefreshTokenTTL {
+		return AccessTokenTTL
+	}
+	return RefreshTokenTTL
+}
 
 // GenerateJWTAccessToken will generate a JWT access token
-func GenerateJWTAccessToken(sub string, login string) (string, error) {
-	accessToken := jwt.New(jwt.SigningMethodHS256)
-	claims := accessToken.Claims.(jwt.MapClaims)
+func GenerateJWTAccessToken(sub string, login string, roles []string) (string, error) {
+	if roles == nil {
+		roles = []string{}
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{}
 	claims["authorized"] = true
+	claims["typ"] = auth.TokenTypeAccess
 	claims["sub"] = sub
 	claims["name"] = login
-	claims["exp"] = time.Now().Add(5 * time.Minute).Unix()
-	claims["iat"] = time.Now().Unix()
+	claims["roles"] = roles
+	claims["jti"] = primitive.NewObjectID().Hex()
+	claims["exp"] = now.Add(AccessTokenTTL).Unix()
+	claims["iat"] = now.Unix()
+	// 'iat' only has seconds, so revoking sessions compares the issue time in milliseconds instead
+	claims["iat_ms"] = now.UnixNano() / int64(time.Millisecond)
 
-	at, err := accessToken.SignedString(mySigninKey)
+	at, err := auth.Keys().Sign(claims)
 	if err != nil {
 		return "", err
 	}
 	return at, nil
 }
 
-// GenerateJWTRefreshToken will generate a new refresh token
-func GenerateJWTRefreshToken(sub string) (string, error) {
-	refreshToken := jwt.New(jwt.SigningMethodHS256)
-	rtClaims := refreshToken.Claims.(jwt.MapClaims)
-	rtClaims["sub"] = 1
-	rtClaims["exp"] = time.Now().Add(time.Hour * 24).Unix()
+// GenerateJWTRefreshToken will generate a new refresh token identified by a given 'jti'
+func GenerateJWTRefreshToken(sub string, jti string, exp time.Time) (string, error) {
+	rtClaims := jwt.MapClaims{}
+	rtClaims["typ"] = auth.TokenTypeRefresh
+	rtClaims["sub"] = sub
+	rtClaims["jti"] = jti
+	rtClaims["exp"] = exp.Unix()
+	rtClaims["iat"] = time.Now().Unix()
 
-	rt, err := refreshToken.SignedString(mySigninKey)
+	rt, err := auth.Keys().Sign(rtClaims)
 	if err != nil {
 		return "", err
 	}
@@ -48,7 +86,56 @@ func GenerateJWTRefreshToken(sub string) (string, error) {
 	return rt, nil
 }
 
-// CreateJWTTokenEndpoint creates a token based on user credentials
+// parseJWTRefreshToken will validate a refresh token returning its claims
+func parseJWTRefreshToken(refreshToken string) (jwt.MapClaims, error) {
+	token, err := auth.Keys().Parse(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("token not valid")
+	}
+
+	if typ, _ := claims["typ"].(string); typ != auth.TokenTypeRefresh {
+		return nil, errors.New("token is not a refresh token")
+	}
+
+	return claims, nil
+}
+
+// issueJWTTokens will issue both access and refresh tokens to a user. The refresh token
+// continues a given family or starts a new one when it's empty
+func issueJWTTokens(ctx context.Context, user repository.SanitizedUser, family string) (repository.JWTResponse, error) {
+	var jwtResponse repository.JWTResponse
+
+	accessToken, err := GenerateJWTAccessToken(user.ID.Hex(), user.Login, user.Roles)
+	if err != nil {
+		return jwtResponse, fmt.Errorf("could not create access token: %w", err)
+	}
+
+	rt, err := models.CreateRefreshToken(ctx, user.ID, family, RefreshTokenTTL)
+	if err != nil {
+		return jwtResponse, fmt.Errorf("could not create refresh token: %w", err)
+	}
+
+	refreshToken, err := GenerateJWTRefreshToken(user.ID.Hex(), rt.ID, rt.ExpiresAt.Time())
+	if err != nil {
+		return jwtResponse, fmt.Errorf("could not create refresh token: %w", err)
+	}
+
+	jwtResponse.Type = "bearer"
+	jwtResponse.RefreshToken = refreshToken
+	jwtResponse.AccessToken = accessToken
+	jwtResponse.Details = user
+
+	return jwtResponse, nil
+}
+
+// CreateJWTTokenEndpoint creates a token based on user credentials. Repeated failures lock the
+// login for an exponentially growing period, and every failure answers the same message so
+// it's never revealed if a login exists
 func CreateJWTTokenEndpoint(response http.ResponseWriter, request *http.Request) {
 	response.Header().Add("content-type", "application/json")
 	response.Header().Set("Access-Control-Allow-Origin", "*")
@@ -63,55 +150,229 @@ func CreateJWTTokenEndpoint(response http.ResponseWriter, request *http.Request)
 		return
 	}
 
-	dbUser, err := models.GetUserByFilter(request.Context(), "login", jwtUser.Login)
+	attempt, err := models.GetLoginAttempt(request.Context(), jwtUser.Login)
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not create tokens", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	if attempt.IsLocked(time.Now()) {
+		writeLoginLocked(response, attempt.LockedUntil.Time())
+		return
+	}
+
+	dbUser, err := models.GetUserByLogin(request.Context(), jwtUser.Login)
+	if err != nil {
+		// the password is still checked so unknown logins take as long as existing ones
+		crypt.CheckPasswordHash(jwtUser.Password, unknownLoginPasswordHash)
+		rejectLogin(response, request, jwtUser.Login)
+		return
+	}
+
+	if dbUser.Login != jwtUser.Login || !crypt.CheckPasswordHash(jwtUser.Password, dbUser.SaltedPassword) {
+		rejectLogin(response, request, jwtUser.Login)
+		return
+	}
+
+	if attempt.Failures > 0 {
+		err = models.ResetLoginFailures(request.Context(), jwtUser.Login)
+		if err != nil {
+			log.Errorf("could not reset failed logins for user '%s': %v", jwtUser.Login, err)
+		}
+	}
+
+	jwtResponse, err := issueJWTTokens(request.Context(), repository.SanitizedUser{
+		ID:        dbUser.ID,
+		Login:     dbUser.Login,
+		Firstname: dbUser.Firstname,
+		Lastname:  dbUser.Lastname,
+		Email:     dbUser.Email,
+		Roles:     dbUser.Roles,
+	}, "")
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not create tokens", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	log.Infof("created token for user '%s'", jwtUser.Login)
+	writeJWTResponse(response, jwtResponse)
+}
+
+// rejectLogin will account a failed login and answer it, either as invalid credentials or as
+// locked when this failure reached the lockout threshold
+func rejectLogin(response http.ResponseWriter, request *http.Request, login string) {
+	attempt, err := models.RegisterLoginFailure(request.Context(), login)
+	if err != nil {
+		log.Errorf("could not register failed login for user '%s': %v", login, err)
+	}
+
+	if err == nil && attempt.IsLocked(time.Now()) {
+		writeLoginLocked(response, attempt.LockedUntil.Time())
+		return
+	}
+
+	response.WriteHeader(http.StatusUnauthorized)
+	response.Write([]byte(`{"message": "invalid credentials"}`))
+}
+
+// writeLoginLocked will answer a login attempt while it's locked
+func writeLoginLocked(response http.ResponseWriter, until time.Time) {
+	retryAfter := int64(math.Ceil(time.Until(until).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	response.Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	response.WriteHeader(http.StatusTooManyRequests)
+	response.Write([]byte(`{"message": "too many failed login attempts, try again later"}`))
+}
+
+// RefreshJWTTokenEndpoint exchanges a refresh token by a new access token and a rotated refresh token
+func RefreshJWTTokenEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var jwtRefresh repository.JWTRefresh
+
+	_ = json.NewDecoder(request.Body).Decode(&jwtRefresh)
+
+	if jwtRefresh.RefreshToken == "" {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "empty required payload attributes"}`))
+		return
+	}
+
+	claims, err := parseJWTRefreshToken(jwtRefresh.RefreshToken)
+	if err != nil {
+		response.WriteHeader(http.StatusUnauthorized)
+		response.Write([]byte(`{"message": "could not refresh token", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	sub, _ := claims["sub"].(string)
+	jti, _ := claims["jti"].(string)
+	if sub == "" || jti == "" {
+		response.WriteHeader(http.StatusUnauthorized)
+		response.Write([]byte(`{"message": "could not refresh token", "details": "malformed token claims"}`))
+		return
+	}
+
+	rt, err := models.ConsumeRefreshToken(request.Context(), jti)
 	if err != nil {
+		if strings.Contains(err.Error(), "refresh token") {
+			response.WriteHeader(http.StatusUnauthorized)
+			response.Write([]byte(`{"message": "could not refresh token", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not refresh token", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	if rt.OwnerID.Hex() != sub {
 		response.WriteHeader(http.StatusUnauthorized)
-		response.Write([]byte(`{"message": "invalid credentials for user '` + jwtUser.Login + `'"}`))
-		return
-	}
-
-	if dbUser.Login == jwtUser.Login {
-		// validates password
-		match := crypt.CheckPasswordHash(jwtUser.Password, dbUser.SaltedPassword)
-		if match {
-			AccessToken, err := GenerateJWTAccessToken(dbUser.ID.Hex(), dbUser.Login)
-			if err != nil {
-				response.WriteHeader(http.StatusInternalServerError)
-				response.Write([]byte(`{"message": "could not create access token", "details": "` + err.Error() + `"}`))
-				return
-			}
-
-			RefreshToken, err := GenerateJWTRefreshToken(dbUser.ID.Hex())
-			if err != nil {
-				response.WriteHeader(http.StatusInternalServerError)
-				response.Write([]byte(`{"message": "could not create refresh token", "details": "` + err.Error() + `"}`))
-				return
-			}
-
-			log.Infof("created token for user '%s'", jwtUser.Login)
-			response.WriteHeader(http.StatusCreated)
-
-			var jwtResponse repository.JWTResponse
-			jwtResponse.Type = "bearer"
-			jwtResponse.RefreshToken = RefreshToken
-			jwtResponse.AccessToken = AccessToken
-			jwtResponse.Details.ID = dbUser.ID
-			jwtResponse.Details.Login = dbUser.Login
-			jwtResponse.Details.Firstname = dbUser.Firstname
-			jwtResponse.Details.Lastname = dbUser.Lastname
-			jwtResponse.Details.Email = dbUser.Email
-
-			jwtResponseJSON, err := json.Marshal(jwtResponse)
-			response.Write(jwtResponseJSON)
-			if err != nil {
-				log.Errorf("could not marshal JWT response for user '%s'", jwtUser.Login)
-				response.Write([]byte(`{"message": "could not create access token", "details": "could not marshal JWT response"}`))
-			}
+		response.Write([]byte(`{"message": "could not refresh token", "details": "token subject mismatch"}`))
+		return
+	}
+
+	user, err := models.GetUser(request.Context(), sub)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find user") {
+			response.WriteHeader(http.StatusUnauthorized)
+			response.Write([]byte(`{"message": "could not refresh token", "details": "user does not exist anymore"}`))
 			return
 		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not refresh token", "details": "` + err.Error() + `"}`))
+		return
 	}
 
-	response.WriteHeader(http.StatusUnauthorized)
-	response.Write([]byte(`{"message": "invalid credentials for user '` + dbUser.Login + `'"}`))
-	return
+	jwtResponse, err := issueJWTTokens(request.Context(), *user, rt.Family)
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not create tokens", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	log.Infof("refreshed token for user '%s'", user.Login)
+	writeJWTResponse(response, jwtResponse)
+}
+
+// RevokeJWTTokenEndpoint revokes the access token used at the request (logout). When a refresh
+// token is given at the payload, every refresh token from its session is revoked as well
+func RevokeJWTTokenEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Set("Access-Control-Allow-Origin", "*")
+
+	principal, ok := auth.PrincipalFromContext(request.Context())
+	if !ok {
+		response.WriteHeader(http.StatusUnauthorized)
+		response.Write([]byte(`{"message": "could not revoke token", "details": "missing authenticated user"}`))
+		return
+	}
+
+	var jwtRefresh repository.JWTRefresh
+
+	_ = json.NewDecoder(request.Body).Decode(&jwtRefresh)
+
+	if jwtRefresh.RefreshToken != "" {
+		claims, err := parseJWTRefreshToken(jwtRefresh.RefreshToken)
+		if err != nil {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not revoke token", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if sub, _ := claims["sub"].(string); sub != principal.Subject {
+			response.WriteHeader(http.StatusForbidden)
+			response.Write([]byte(`{"message": "could not revoke token", "details": "refresh token does not belong to the authenticated user"}`))
+			return
+		}
+
+		jti, _ := claims["jti"].(string)
+		err = models.RevokeRefreshToken(request.Context(), jti)
+		if err != nil && !strings.Contains(err.Error(), "refresh token") {
+			response.WriteHeader(http.StatusInternalServerError)
+			response.Write([]byte(`{"message": "could not revoke token", "details": "` + err.Error() + `"}`))
+			return
+		}
+	}
+
+	if principal.TokenID != "" {
+		ownerID, err := primitive.ObjectIDFromHex(principal.Subject)
+		if err != nil {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not revoke token", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		err = models.RevokeToken(request.Context(), principal.TokenID, ownerID, principal.ExpiresAt)
+		if err != nil {
+			response.WriteHeader(http.StatusInternalServerError)
+			response.Write([]byte(`{"message": "could not revoke token", "details": "` + err.Error() + `"}`))
+			return
+		}
+	}
+
+	log.Infof("revoked token for user '%s'", principal.Login)
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "revoked token"}`))
+}
+
+// writeJWTResponse will write a created JWT response
+func writeJWTResponse(response http.ResponseWriter, jwtResponse repository.JWTResponse) {
+	jwtResponseJSON, err := json.Marshal(jwtResponse)
+	if err != nil {
+		log.Errorf("could not marshal JWT response for user '%s'", jwtResponse.Details.Login)
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not create access token", "details": "could not marshal JWT response"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusCreated)
+	response.Write(jwtResponseJSON)
 }
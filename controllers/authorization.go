@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"budget-tracker-api/auth"
+	"net/http"
+)
+
+// authorizeOwner will validate if the authenticated user owns a given owner ID (or is an admin),
+// writing a forbidden response otherwise. Handlers must stop processing a request when it returns false
+func authorizeOwner(response http.ResponseWriter, request *http.Request, ownerID string) bool {
+	principal, ok := auth.PrincipalFromContext(request.Context())
+	if !ok {
+		response.WriteHeader(http.StatusUnauthorized)
+		response.Write([]byte(`{"message": "could not authorize", "details": "missing authenticated user"}`))
+		return false
+	}
+
+	if !principal.CanAccess(ownerID) {
+		response.WriteHeader(http.StatusForbidden)
+		response.Write([]byte(`{"message": "could not authorize", "details": "resource does not belong to the authenticated user"}`))
+		return false
+	}
+
+	return true
+}
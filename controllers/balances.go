@@ -4,8 +4,12 @@ import (
 	"budget-tracker-api/models"
 	"budget-tracker-api/repository"
 	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -18,7 +22,13 @@ func CreateBalanceEndpoint(response http.ResponseWriter, request *http.Request)
 
 	var balance repository.Balance
 
-	_ = json.NewDecoder(request.Body).Decode(&balance)
+	// amounts are validated while decoding, so only an empty payload is tolerated here
+	err := json.NewDecoder(request.Body).Decode(&balance)
+	if err != nil && err != io.EOF {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not create balance", "details": "malformed payload"}`))
+		return
+	}
 
 	if balance.OwnerID.Hex() == "000000000000000000000000" || balance.OwnerID.Hex() == "" {
 		response.WriteHeader(http.StatusBadRequest)
@@ -26,8 +36,18 @@ func CreateBalanceEndpoint(response http.ResponseWriter, request *http.Request)
 		return
 	}
 
+	if !authorizeOwner(response, request, balance.OwnerID.Hex()) {
+		return
+	}
+
 	result, err := models.CreateBalance(request.Context(), balance)
 	if err != nil {
+		if isInvalidAmount(err) || strings.Contains(err.Error(), "invalid budget") || isInvalidCategory(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not create balance", "details": "` + err.Error() + `"}`))
+			return
+		}
+
 		if strings.Contains(err.Error(), "balance already exists") {
 			response.WriteHeader(http.StatusConflict)
 			response.Write([]byte(`{"message": "could not create balance", "details": "` + err.Error() + `"}`))
@@ -43,7 +63,54 @@ func CreateBalanceEndpoint(response http.ResponseWriter, request *http.Request)
 	response.Write([]byte(`{"message": "created balance", "id": "` + result + `"}`))
 }
 
-// GetBalanceEndpoint will return a balance from a given user
+// parseBalancePeriod will parse a "YYYY-MM" period
+func parseBalancePeriod(value string) (repository.BalancePeriod, error) {
+	t, err := time.Parse("2006-01", value)
+	if err != nil {
+		return repository.BalancePeriod{}, err
+	}
+
+	return repository.BalancePeriod{Month: int64(t.Month()), Year: int64(t.Year())}, nil
+}
+
+// parseBalanceFilter will build a balance filter from the 'year' or 'from'/'to' query params
+func parseBalanceFilter(ownerID string, v url.Values) (f repository.BalanceFilter, err error) {
+	f.OwnerID = ownerID
+
+	if year := v.Get("year"); year != "" {
+		iyear, err := strconv.ParseInt(year, 10, 64)
+		if err != nil {
+			return f, errors.New("'year' must be a number")
+		}
+		f.From = repository.BalancePeriod{Month: 1, Year: iyear}
+		f.To = repository.BalancePeriod{Month: 12, Year: iyear}
+	}
+
+	if from := v.Get("from"); from != "" {
+		f.From, err = parseBalancePeriod(from)
+		if err != nil {
+			return f, errors.New("'from' must be a period as YYYY-MM")
+		}
+	}
+
+	if to := v.Get("to"); to != "" {
+		f.To, err = parseBalancePeriod(to)
+		if err != nil {
+			return f, errors.New("'to' must be a period as YYYY-MM")
+		}
+	}
+
+	if !f.From.IsZero() && !f.To.IsZero() {
+		if f.From.Year > f.To.Year || (f.From.Year == f.To.Year && f.From.Month > f.To.Month) {
+			return f, errors.New("'from' must not be after 'to'")
+		}
+	}
+
+	return f, nil
+}
+
+// GetBalanceEndpoint will return a balance from a given user given a month and year, or
+// a chronological list of balances from a 'year' or a 'from'/'to' period
 func GetBalanceEndpoint(response http.ResponseWriter, request *http.Request) {
 	response.Header().Add("content-type", "application/json")
 
@@ -53,42 +120,300 @@ func GetBalanceEndpoint(response http.ResponseWriter, request *http.Request) {
 	month := v.Get("month")
 	year := v.Get("year")
 
-	// in case of a non-existent URL parameters
-	if month == "" || year == "" {
-		balances, err := models.GetAllBalances(request.Context(), params["owner_id"])
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	if month != "" && year == "" {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not get balance", "details": "'month' must be given along with 'year'"}`))
+		return
+	}
+
+	// in case of a existent URL parameters
+	if month != "" && year != "" {
+		imonth, err := strconv.ParseInt(month, 10, 64)
+		if err != nil || imonth < 1 || imonth > 12 {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not get balance", "details": "'month' must be a number between 1 and 12"}`))
+			return
+		}
+
+		iyear, err := strconv.ParseInt(year, 10, 64)
 		if err != nil {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not get balance", "details": "'year' must be a number"}`))
+			return
+		}
+
+		balance, err := models.GetBalance(request.Context(), params["owner_id"], imonth, iyear)
+		if err != nil {
+			if strings.Contains(err.Error(), "could not find balance") {
+				response.WriteHeader(http.StatusNotFound)
+				response.Write([]byte(`{}`))
+				return
+			}
+
 			response.WriteHeader(http.StatusInternalServerError)
 			response.Write([]byte(`{"message": "` + err.Error() + `"}`))
 			return
 		}
 
-		if len(balances) == 0 {
+		json.NewEncoder(response).Encode(balance)
+		return
+	}
+
+	filter, err := parseBalanceFilter(params["owner_id"], v)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not list balances", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	balances, err := models.GetAllBalances(request.Context(), filter)
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+		return
+	}
+
+	if len(balances) == 0 {
+		response.WriteHeader(http.StatusNotFound)
+		response.Write([]byte(`[]`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(balances)
+}
+
+// validateBalanceIncome will validate if a balance income is a consistent one
+func validateBalanceIncome(income repository.Income) error {
+	if income.GrossIncome < 0 || income.NetIncome < 0 {
+		return errors.New("income can not be negative")
+	}
+
+	if income.NetIncome > income.GrossIncome {
+		return errors.New("net income can not be greater than gross income")
+	}
+
+	return nil
+}
+
+// UpdateBalanceEndpoint will replace the income, currency and budgets of a balance from a given user
+func UpdateBalanceEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	var balance repository.Balance
+
+	err := json.NewDecoder(request.Body).Decode(&balance)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update balance", "details": "malformed payload"}`))
+		return
+	}
+
+	updateBalance(response, request, params["owner_id"], params["id"], balance)
+}
+
+// PatchBalanceEndpoint will partially update the income, currency and budgets of a balance from a given user
+func PatchBalanceEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	balance, err := models.GetBalanceByID(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find balance") {
 			response.WriteHeader(http.StatusNotFound)
-			response.Write([]byte(`[]`))
+			response.Write([]byte(`{"message": "could not update balance", "details": "` + err.Error() + `"}`))
 			return
 		}
 
-		json.NewEncoder(response).Encode(balances)
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update balance", "details": "` + err.Error() + `"}`))
+		return
 	}
 
-	// in case of a existent URL parameters
-	if month != "" && year != "" {
-		imonth, _ := strconv.ParseInt(month, 10, 64)
-		iyear, _ := strconv.ParseInt(year, 10, 64)
+	// only attributes present at the payload will override the current balance
+	err = json.NewDecoder(request.Body).Decode(balance)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update balance", "details": "malformed payload"}`))
+		return
+	}
 
-		balance, err := models.GetBalance(request.Context(), params["owner_id"], imonth, iyear)
-		if err != nil {
-			response.WriteHeader(http.StatusInternalServerError)
-			response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+	updateBalance(response, request, params["owner_id"], params["id"], *balance)
+}
+
+// updateBalance will persist an updated balance and write its response
+func updateBalance(response http.ResponseWriter, request *http.Request, ownerID string, id string, balance repository.Balance) {
+	err := validateBalanceIncome(balance.Income)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update balance", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	result, err := models.UpdateBalance(request.Context(), ownerID, id, balance)
+	if err != nil {
+		if isInvalidAmount(err) || strings.Contains(err.Error(), "balance has spends in") || strings.Contains(err.Error(), "invalid budget") || isInvalidCategory(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not update balance", "details": "` + err.Error() + `"}`))
 			return
 		}
 
-		if balance.ID.Hex() == "" {
+		if strings.Contains(err.Error(), "could not find balance") {
 			response.WriteHeader(http.StatusNotFound)
-			response.Write([]byte(`{}`))
+			response.Write([]byte(`{"message": "could not update balance", "details": "` + err.Error() + `"}`))
 			return
 		}
 
-		json.NewEncoder(response).Encode(balance)
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update balance", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(result)
+}
+
+// DeleteBalanceEndpoint will delete a balance from a given user
+func DeleteBalanceEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	err := models.DeleteBalance(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find balance") || strings.Contains(err.Error(), "non existent balance") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not delete balance", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not delete balance", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "deleted balance '` + params["id"] + `'"}`))
+}
+
+// ReconcileBalanceEndpoint will make a balance from a given user account exactly the spends of its month
+func ReconcileBalanceEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	balance, err := models.ReconcileBalance(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find balance") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not reconcile balance", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not reconcile balance", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(balance)
+}
+
+// GetCommitmentsEndpoint will return the outcome future months are already committed to, from the
+// next month over a year unless a 'from'/'to' period or a 'year' is given
+func GetCommitmentsEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	filter, err := parseBalanceFilter(params["owner_id"], request.URL.Query())
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not list commitments", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	if filter.From.IsZero() {
+		now := time.Now().UTC()
+		next := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		filter.From = repository.BalancePeriod{Month: int64(next.Month()), Year: int64(next.Year())}
+	}
+
+	if filter.To.IsZero() {
+		last := time.Date(int(filter.From.Year), time.Month(filter.From.Month)+11, 1, 0, 0, 0, 0, time.UTC)
+		filter.To = repository.BalancePeriod{Month: int64(last.Month()), Year: int64(last.Year())}
+	}
+
+	commitments, err := models.GetCommitments(request.Context(), filter)
+	if err != nil {
+		if strings.Contains(err.Error(), "commitments period") {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not list commitments", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(commitments)
+}
+
+// GetBudgetsEndpoint will return how much was spent on each budgeted category of a balance at a given
+// period, as YYYY-MM, along with the budget thresholds reached by its spends
+func GetBudgetsEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	period, err := parseBalancePeriod(params["period"])
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not get budgets", "details": "period must be given as YYYY-MM"}`))
+		return
 	}
+
+	report, err := models.GetBudgetReport(request.Context(), params["owner_id"], period.Month, period.Year)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find balance") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not get budgets", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not get budgets", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(report)
 }
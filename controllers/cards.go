@@ -30,6 +30,10 @@ func CreateCardEndpoint(response http.ResponseWriter, request *http.Request) {
 
 	_ = json.NewDecoder(request.Body).Decode(&card)
 
+	if !authorizeOwner(response, request, card.OwnerID.Hex()) {
+		return
+	}
+
 	validNetwork := validateCardNetwork(card.Network)
 	if !validNetwork {
 		response.WriteHeader(http.StatusBadRequest)
@@ -39,6 +43,12 @@ func CreateCardEndpoint(response http.ResponseWriter, request *http.Request) {
 
 	result, err := models.CreateCard(request.Context(), card)
 	if err != nil {
+		if strings.Contains(err.Error(), "invalid card") {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not create card", "details": "` + err.Error() + `"}`))
+			return
+		}
+
 		if strings.Contains(err.Error(), "card already exists") {
 			response.WriteHeader(http.StatusConflict)
 			response.Write([]byte(`{"message": "could not create card", "details": "` + err.Error() + `"}`))
@@ -74,14 +84,21 @@ func GetAllCardsEndpoint(response http.ResponseWriter, request *http.Request) {
 	json.NewEncoder(response).Encode(cards)
 }
 
-// GetCardsEndpoint will return all cards from a given user
+// GetCardsEndpoint will return all cards from a given user, archived ones only when asked to
 func GetCardsEndpoint(response http.ResponseWriter, request *http.Request) {
 	response.Header().Add("content-type", "application/json")
 	response.Header().Set("Access-Control-Allow-Origin", "*")
 
 	params := mux.Vars(request)
 
-	cards, err := models.GetCards(request.Context(), params["owner_id"])
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	// archived cards are only listed when asked to
+	archived := request.URL.Query().Get("archived") == "true"
+
+	cards, err := models.GetCards(request.Context(), params["owner_id"], archived)
 	if err != nil {
 		if strings.Contains(err.Error(), "could not find any cards") {
 			response.WriteHeader(http.StatusNotFound)
@@ -102,15 +119,142 @@ func GetCardsEndpoint(response http.ResponseWriter, request *http.Request) {
 	json.NewEncoder(response).Encode(cards)
 }
 
-// DeleteCardEndpoint deletes a card given an ID
+// GetCardEndpoint will return a single card from a given user, even when archived
+func GetCardEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Set("Access-Control-Allow-Origin", "*")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	card, err := models.GetCard(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find card") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "` + err.Error() + `", "id": "` + params["id"] + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(card)
+}
+
+// PatchCardEndpoint will partially update a card from an user, archiving it when asked to
+func PatchCardEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Set("Access-Control-Allow-Origin", "*")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	card, err := models.GetCard(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find card") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not update card", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update card", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	// only attributes present at the payload will override the current card
+	err = json.NewDecoder(request.Body).Decode(card)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update card", "details": "malformed payload"}`))
+		return
+	}
+
+	if !validateCardNetwork(card.Network) {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update card", "details": "given network '` + card.Network + `' is not a valid one"}`))
+		return
+	}
+
+	result, err := models.UpdateCard(request.Context(), params["owner_id"], params["id"], *card)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid card") {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not update card", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "could not find card") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not update card", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "used by recurrences") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not update card", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update card", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(result)
+}
+
+// DeleteCardEndpoint deletes a card given an ID, refusing cards used by spends or recurrences
 func DeleteCardEndpoint(response http.ResponseWriter, request *http.Request) {
 	response.Header().Add("content-type", "application/json")
 	response.Header().Set("Access-Control-Allow-Origin", "*")
 
 	params := mux.Vars(request)
 
-	err := models.DeleteCard(request.Context(), params["id"])
+	// the legacy route is not scoped by owner, so the card owner is looked up before authorizing it
+	ownerID, ok := params["owner_id"]
+	if !ok {
+		var err error
+		ownerID, err = models.GetCardOwner(request.Context(), params["id"])
+		if err != nil {
+			if strings.Contains(err.Error(), "could not find card") {
+				response.WriteHeader(http.StatusNotFound)
+				response.Write([]byte(`{"message": "could not delete card", "details": "` + err.Error() + `"}`))
+				return
+			}
+
+			response.WriteHeader(http.StatusInternalServerError)
+			response.Write([]byte(`{"message": "could not delete card", "details": "` + err.Error() + `"}`))
+			return
+		}
+	}
+
+	if !authorizeOwner(response, request, ownerID) {
+		return
+	}
+
+	err := models.DeleteCard(request.Context(), ownerID, params["id"])
 	if err != nil {
+		if strings.Contains(err.Error(), "could not find card") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not delete card", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "can only be archived") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not delete card", "details": "` + err.Error() + `"}`))
+			return
+		}
+
 		response.WriteHeader(http.StatusInternalServerError)
 		response.Write([]byte(`{"message": "could not delete card", "details": "` + err.Error() + `"}`))
 		return
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"budget-tracker-api/models"
+	"budget-tracker-api/repository"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// isInvalidCategory will return if an error was caused by a category which is invalid or unknown to its owner
+func isInvalidCategory(err error) bool {
+	return strings.Contains(err.Error(), "invalid category")
+}
+
+// CreateCategoryEndpoint will create a single category to an user
+func CreateCategoryEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	var category repository.Category
+
+	err := json.NewDecoder(request.Body).Decode(&category)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not create category", "details": "malformed payload"}`))
+		return
+	}
+
+	if !authorizeOwner(response, request, category.OwnerID.Hex()) {
+		return
+	}
+
+	result, err := models.CreateCategory(request.Context(), category)
+	if err != nil {
+		if isInvalidCategory(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not create category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "category already exists") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not create category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not create category", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusCreated)
+	response.Write([]byte(`{"message": "created category '` + category.Name + `'", "id": "` + result + `"}`))
+}
+
+// GetCategoriesEndpoint will return every category from a given user, sorted by name
+func GetCategoriesEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	categories, err := models.GetCategories(request.Context(), params["owner_id"])
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(categories)
+}
+
+// GetCategoryEndpoint will return a single category from a given user
+func GetCategoryEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	category, err := models.GetCategory(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find category") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not get category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not get category", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(category)
+}
+
+// PatchCategoryEndpoint will partially update a category from a given user. Renaming it rewrites every
+// spend, recurrence, budget and rule using it
+func PatchCategoryEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	category, err := models.GetCategory(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find category") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not update category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update category", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	// only attributes present at the payload will override the current category
+	err = json.NewDecoder(request.Body).Decode(category)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update category", "details": "malformed payload"}`))
+		return
+	}
+
+	result, err := models.UpdateCategory(request.Context(), params["owner_id"], params["id"], *category)
+	if err != nil {
+		if isInvalidCategory(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not update category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "could not find category") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not update category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "category already exists") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not update category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update category", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(result)
+}
+
+// MergeCategoryEndpoint will move every spend, recurrence, budget, rule and subcategory of a category
+// from a given user into the one given by 'into', deleting it
+func MergeCategoryEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	var merge struct {
+		Into string `json:"into"`
+	}
+
+	err := json.NewDecoder(request.Body).Decode(&merge)
+	if err != nil || merge.Into == "" {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not merge category", "details": "the category to merge into must be given as 'into'"}`))
+		return
+	}
+
+	result, err := models.MergeCategory(request.Context(), params["owner_id"], params["id"], merge.Into)
+	if err != nil {
+		if isInvalidCategory(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not merge category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "could not find category") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not merge category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not merge category", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(result)
+}
+
+// DeleteCategoryEndpoint will delete a category from a given user, refusing categories still in use
+func DeleteCategoryEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	err := models.DeleteCategory(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find category") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not delete category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "can only be merged") || strings.Contains(err.Error(), "has subcategories") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not delete category", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not delete category", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "deleted category '` + params["id"] + `'"}`))
+}
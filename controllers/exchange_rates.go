@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"budget-tracker-api/models"
+	"budget-tracker-api/repository"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// isInvalidExchangeRate will return if an error was caused by an invalid exchange rate
+func isInvalidExchangeRate(err error) bool {
+	return strings.HasPrefix(err.Error(), "exchange rate ")
+}
+
+// parseExchangeRatesCSV will read exchange rates from CSV lines formatted as 'date,base,quote,rate',
+// with an optional header line
+func parseExchangeRatesCSV(r io.Reader) ([]repository.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	rates := []repository.ExchangeRate{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rates, err
+		}
+
+		if line == 1 && strings.EqualFold(record[0], "date") {
+			continue
+		}
+
+		date, err := parseSpendDate(record[0], false)
+		if err != nil {
+			return rates, fmt.Errorf("line %d: date must be YYYY-MM-DD or RFC3339", line)
+		}
+
+		rate, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return rates, fmt.Errorf("line %d: rate must be a decimal number", line)
+		}
+
+		rates = append(rates, repository.ExchangeRate{
+			Date:  primitive.NewDateTimeFromTime(date),
+			Base:  record[1],
+			Quote: record[2],
+			Rate:  rate,
+		})
+	}
+
+	if len(rates) == 0 {
+		return rates, errors.New("no exchange rates to import")
+	}
+
+	return rates, nil
+}
+
+// storeExchangeRates will store a list of exchange rates and write the endpoint response
+func storeExchangeRates(response http.ResponseWriter, request *http.Request, rates []repository.ExchangeRate) {
+	err := models.UpsertExchangeRates(request.Context(), rates)
+	if err != nil {
+		if isInvalidExchangeRate(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not store exchange rates", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not store exchange rates", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "stored exchange rates", "count": ` + strconv.Itoa(len(rates)) + `}`))
+}
+
+// UpsertExchangeRatesEndpoint will create or replace a list of daily exchange rates
+func UpsertExchangeRatesEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	var rates []repository.ExchangeRate
+	err := json.NewDecoder(request.Body).Decode(&rates)
+	if err != nil || len(rates) == 0 {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "malformed payload", "details": "expected a non empty list of exchange rates"}`))
+		return
+	}
+
+	storeExchangeRates(response, request, rates)
+}
+
+// ImportExchangeRatesEndpoint will create or replace daily exchange rates from a CSV body
+func ImportExchangeRatesEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	rates, err := parseExchangeRatesCSV(request.Body)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "malformed payload", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	storeExchangeRates(response, request, rates)
+}
+
+// GetExchangeRatesEndpoint will return the exchange rates filtered by query params
+func GetExchangeRatesEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	v := request.URL.Query()
+	filter := repository.ExchangeRateFilter{Base: v.Get("base"), Quote: v.Get("quote")}
+
+	var err error
+	if from := v.Get("from"); from != "" {
+		filter.From, err = parseSpendDate(from, false)
+		if err != nil {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not list exchange rates", "details": "'from' must be a date as YYYY-MM-DD or RFC3339"}`))
+			return
+		}
+	}
+
+	if to := v.Get("to"); to != "" {
+		filter.To, err = parseSpendDate(to, true)
+		if err != nil {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not list exchange rates", "details": "'to' must be a date as YYYY-MM-DD or RFC3339"}`))
+			return
+		}
+	}
+
+	rates, err := models.GetExchangeRates(request.Context(), filter)
+	if err != nil {
+		if strings.Contains(err.Error(), "ISO-4217") {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not list exchange rates", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(rates)
+}
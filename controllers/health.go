@@ -1,8 +1,7 @@
 package controllers
 
 import (
-	"budget-tracker-api/repository"
-	"budget-tracker-api/services"
+	"budget-tracker-api/models"
 
 	"net/http"
 )
@@ -11,15 +10,7 @@ import (
 func HealthCheck(response http.ResponseWriter, request *http.Request) {
 	response.Header().Add("content-type", "application/json")
 
-	repo := repository.NewDatabaseManagerRepository(&repository.DatabaseRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:      services.MongodbURI,
-			Database: services.MongodbDatabase,
-		},
-	})
-
-	err := repo.Health()
+	err := models.Health()
 	if err != nil {
 		response.WriteHeader(http.StatusInternalServerError)
 		response.Write([]byte(`{"database": "unhealthy", "details": "` + err.Error() + `"}`))
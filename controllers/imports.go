@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"budget-tracker-api/models"
+	"budget-tracker-api/repository"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// isInvalidImport will return if an error was caused by the statement, mapping or payment method of an import
+func isInvalidImport(err error) bool {
+	return strings.Contains(err.Error(), "invalid import") || isInvalidPaymentMethod(err)
+}
+
+// importSpends will read a bank statement for a given user, creating its new spends when committing it
+func importSpends(response http.ResponseWriter, request *http.Request, commit bool) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	var imp repository.SpendImport
+
+	err := json.NewDecoder(request.Body).Decode(&imp)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not import spends", "details": "malformed payload"}`))
+		return
+	}
+
+	result, err := models.ImportSpends(request.Context(), params["owner_id"], imp, commit)
+	if err != nil {
+		// errors may quote column names of the statement, so they are encoded rather than concatenated
+		details, _ := json.Marshal(err.Error())
+
+		if isInvalidImport(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not import spends", "details": ` + string(details) + `}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not import spends", "details": ` + string(details) + `}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(result)
+}
+
+// PreviewImportEndpoint will return which transactions of a bank statement would be imported as spends of
+// a given user, and which ones are duplicates of existing spends, without creating them
+func PreviewImportEndpoint(response http.ResponseWriter, request *http.Request) {
+	importSpends(response, request, false)
+}
+
+// CommitImportEndpoint will create the transactions of a bank statement which are not duplicates of existing
+// spends as spends of a given user
+func CommitImportEndpoint(response http.ResponseWriter, request *http.Request) {
+	importSpends(response, request, true)
+}
@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"budget-tracker-api/auth"
+	"encoding/json"
+	"net/http"
+)
+
+// JWKSEndpoint will return the public keys used to verify issued tokens, so other services can validate them
+func JWKSEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Set("Access-Control-Allow-Origin", "*")
+	response.Header().Set("Cache-Control", "public, max-age=300")
+
+	json.NewEncoder(response).Encode(auth.Keys().JWKS())
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"budget-tracker-api/models"
+	"budget-tracker-api/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateRecurrenceEndpoint will create a recurring spend, materializing the spends already due. How many
+// were created and how many are left to the scheduler are returned along with it
+func CreateRecurrenceEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	var recurrence repository.Recurrence
+
+	err := json.NewDecoder(request.Body).Decode(&recurrence)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not create recurrence", "details": "malformed payload"}`))
+		return
+	}
+
+	if recurrence.OwnerID.IsZero() {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not create recurrence", "details": "missing owner ID"}`))
+		return
+	}
+
+	if !authorizeOwner(response, request, recurrence.OwnerID.Hex()) {
+		return
+	}
+
+	result, created, pending, err := models.CreateRecurrence(request.Context(), recurrence)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid recurrence") || isInvalidAmount(err) || isInvalidPaymentMethod(err) || isInvalidCategory(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not create recurrence", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not create recurrence", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusCreated)
+	response.Write([]byte(`{"message": "created recurrence", "owner_id": "` + recurrence.OwnerID.Hex() + `", "id": "` + result +
+		`", "created": ` + strconv.Itoa(created) + `, "pending": ` + strconv.Itoa(pending) + `}`))
+}
+
+// GetRecurrencesEndpoint will return every recurring spend from an user
+func GetRecurrencesEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	recurrences, err := models.GetRecurrences(request.Context(), params["owner_id"])
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(recurrences)
+}
+
+// updateRecurrenceStatus will change the status of a recurring spend from an user and write the endpoint response
+func updateRecurrenceStatus(response http.ResponseWriter, request *http.Request, action string, update func(ctx context.Context, ownerID string, id string) (*repository.Recurrence, error)) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	recurrence, err := update(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find recurrence") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not ` + action + ` recurrence", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "can not be") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not ` + action + ` recurrence", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not ` + action + ` recurrence", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(recurrence)
+}
+
+// PauseRecurrenceEndpoint will stop materializing spends from a recurrence until resumed
+func PauseRecurrenceEndpoint(response http.ResponseWriter, request *http.Request) {
+	updateRecurrenceStatus(response, request, "pause", models.PauseRecurrence)
+}
+
+// ResumeRecurrenceEndpoint will materialize spends from a paused recurrence again, skipping the paused period
+func ResumeRecurrenceEndpoint(response http.ResponseWriter, request *http.Request) {
+	updateRecurrenceStatus(response, request, "resume", models.ResumeRecurrence)
+}
+
+// CancelRecurrenceEndpoint will stop a recurrence for good, keeping the spends it already materialized
+func CancelRecurrenceEndpoint(response http.ResponseWriter, request *http.Request) {
+	updateRecurrenceStatus(response, request, "cancel", models.CancelRecurrence)
+}
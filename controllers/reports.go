@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"budget-tracker-api/models"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetMonthlyReportEndpoint will return how much a given user spent within a month, given as YYYY-MM,
+// compared against the previous one
+func GetMonthlyReportEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	period, err := parseBalancePeriod(params["period"])
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not get report", "details": "period must be given as YYYY-MM"}`))
+		return
+	}
+
+	report, err := models.GetMonthlyReport(request.Context(), params["owner_id"], period.Month, period.Year)
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not get report", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(report)
+}
+
+// GetYearlyReportEndpoint will return how much a given user spent within a year, compared against the
+// previous one, along with the totals of each of its months
+func GetYearlyReportEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	year, err := strconv.ParseInt(params["year"], 10, 64)
+	if err != nil || year < 1 {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not get report", "details": "year must be given as YYYY"}`))
+		return
+	}
+
+	report, err := models.GetYearlyReport(request.Context(), params["owner_id"], year)
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not get report", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(report)
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"budget-tracker-api/models"
+	"budget-tracker-api/repository"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// isInvalidCategoryRule will return if an error was caused by the conditions, categories or type of a rule
+func isInvalidCategoryRule(err error) bool {
+	return isInvalidCategory(err) || isInvalidCard(err)
+}
+
+// CreateCategoryRuleEndpoint will create a single category rule to an user
+func CreateCategoryRuleEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	var rule repository.CategoryRule
+
+	err := json.NewDecoder(request.Body).Decode(&rule)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not create category rule", "details": "malformed payload"}`))
+		return
+	}
+
+	if !authorizeOwner(response, request, rule.OwnerID.Hex()) {
+		return
+	}
+
+	result, err := models.CreateCategoryRule(request.Context(), rule)
+	if err != nil {
+		if isInvalidCategoryRule(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not create category rule", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not create category rule", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusCreated)
+	response.Write([]byte(`{"message": "created category rule", "id": "` + result + `"}`))
+}
+
+// GetCategoryRulesEndpoint will return every category rule from a given user, in the order they are evaluated
+func GetCategoryRulesEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	rules, err := models.GetCategoryRules(request.Context(), params["owner_id"])
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(rules)
+}
+
+// GetCategoryRuleEndpoint will return a single category rule from a given user
+func GetCategoryRuleEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	rule, err := models.GetCategoryRule(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find category rule") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not get category rule", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not get category rule", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(rule)
+}
+
+// PatchCategoryRuleEndpoint will partially update a category rule from a given user
+func PatchCategoryRuleEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	rule, err := models.GetCategoryRule(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find category rule") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not update category rule", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update category rule", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	// only attributes present at the payload will override the current rule
+	err = json.NewDecoder(request.Body).Decode(rule)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update category rule", "details": "malformed payload"}`))
+		return
+	}
+
+	result, err := models.UpdateCategoryRule(request.Context(), params["owner_id"], params["id"], *rule)
+	if err != nil {
+		if isInvalidCategoryRule(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not update category rule", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "could not find category rule") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not update category rule", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update category rule", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(result)
+}
+
+// DeleteCategoryRuleEndpoint will delete a category rule from a given user
+func DeleteCategoryRuleEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	err := models.DeleteCategoryRule(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find category rule") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not delete category rule", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not delete category rule", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "deleted category rule '` + params["id"] + `'"}`))
+}
+
+// DryRunCategoryRulesEndpoint will return which of the latest spends from a given user would be matched by
+// its category rules, or by the single rule given at the payload, without changing them
+func DryRunCategoryRulesEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	last := int64(repository.DefaultSpendsLimit)
+	if value := request.URL.Query().Get("last"); value != "" {
+		l, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not dry-run category rules", "details": "last must be a number"}`))
+			return
+		}
+		last = l
+	}
+
+	// an empty payload evaluates the stored rules
+	var rule *repository.CategoryRule
+	var payload repository.CategoryRule
+
+	err := json.NewDecoder(request.Body).Decode(&payload)
+	if err != nil && err != io.EOF {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not dry-run category rules", "details": "malformed payload"}`))
+		return
+	}
+	if err == nil {
+		rule = &payload
+	}
+
+	result, err := models.DryRunCategoryRules(request.Context(), params["owner_id"], last, rule)
+	if err != nil {
+		if isInvalidCategoryRule(err) || strings.Contains(err.Error(), "last must be") {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not dry-run category rules", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not dry-run category rules", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(result)
+}
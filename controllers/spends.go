@@ -4,11 +4,36 @@ import (
 	"budget-tracker-api/models"
 	"budget-tracker-api/repository"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// isInvalidAmount will return if an error was caused by an invalid currency or amount
+func isInvalidAmount(err error) bool {
+	return strings.Contains(err.Error(), "ISO-4217") ||
+		strings.Contains(err.Error(), "decimal places") ||
+		strings.Contains(err.Error(), "does not match balance currency") ||
+		strings.Contains(err.Error(), "could not find exchange rate")
+}
+
+// isInvalidCard will return if an error was caused by a payment method card which can not be used
+func isInvalidCard(err error) bool {
+	return strings.Contains(err.Error(), "could not find card") ||
+		strings.Contains(err.Error(), "card is archived")
+}
+
+// isInvalidPaymentMethod will return if an error was caused by a payment method which can not be used
+func isInvalidPaymentMethod(err error) bool {
+	return strings.Contains(err.Error(), "invalid payment method") || isInvalidCard(err)
+}
+
 // CreateSpendEndpoint will create a spend and add to the current month balance
 func CreateSpendEndpoint(response http.ResponseWriter, request *http.Request) {
 	response.Header().Add("content-type", "application/json")
@@ -16,7 +41,13 @@ func CreateSpendEndpoint(response http.ResponseWriter, request *http.Request) {
 
 	var spend repository.Spend
 
-	_ = json.NewDecoder(request.Body).Decode(&spend)
+	// amounts are validated while decoding, so only an empty payload is tolerated here
+	err := json.NewDecoder(request.Body).Decode(&spend)
+	if err != nil && err != io.EOF {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not create spend", "details": "malformed payload"}`))
+		return
+	}
 
 	if spend.OwnerID.Hex() == "000000000000000000000000" {
 		response.WriteHeader(http.StatusBadRequest)
@@ -24,37 +55,325 @@ func CreateSpendEndpoint(response http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	if !authorizeOwner(response, request, spend.OwnerID.Hex()) {
+		return
+	}
+
 	result, err := models.CreateSpend(request.Context(), spend)
 	if err != nil {
+		if isInvalidAmount(err) || strings.Contains(err.Error(), "installments") || isInvalidPaymentMethod(err) ||
+			isInvalidCategory(err) || strings.Contains(err.Error(), "payment spends") {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not create spend", "details": "` + err.Error() + `"}`))
+			return
+		}
+
 		response.WriteHeader(http.StatusInternalServerError)
 		response.Write([]byte(`{"message": "could not create spend", "details": "` + err.Error() + `"}`))
 		return
 	}
 
-	// add spend to balance
-
 	response.WriteHeader(http.StatusCreated)
 	response.Write([]byte(`{"message": "created spend", "owner_id": "` + spend.OwnerID.Hex() + `", "id": "` + result + `"}`))
 }
 
-// GetSpendsEndpoint will return all spends from an user
+// parseSpendDate will parse a date query param either as "YYYY-MM-DD" or RFC3339.
+// Plain dates used as an upper boundary include the whole day
+func parseSpendDate(value string, upperBoundary bool) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		if upperBoundary {
+			return t.Add(24*time.Hour - time.Millisecond), nil
+		}
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseSpendFilter will build a spend filter from the request query params
+func parseSpendFilter(ownerID string, v url.Values) (f repository.SpendFilter, err error) {
+	f.OwnerID = ownerID
+	f.Type = v.Get("type")
+	f.Category = v.Get("category")
+	f.PaymentMethod = v.Get("payment_method")
+	f.CardID = v.Get("card_id")
+	f.Description = v.Get("q")
+	f.Cursor = v.Get("cursor")
+
+	if f.Type != "" && f.Type != repository.SpendTypeFixed && f.Type != repository.SpendTypeDynamic {
+		return f, errors.New("type must be either 'fixed' or 'dynamic'")
+	}
+
+	switch f.PaymentMethod {
+	case "", repository.PaymentMethodDebit, repository.PaymentMethodCredit, repository.PaymentMethodPaymentSlip:
+	default:
+		return f, errors.New("payment_method must be one of 'debit', 'credit' or 'payment_slip'")
+	}
+
+	if from := v.Get("from"); from != "" {
+		f.From, err = parseSpendDate(from, false)
+		if err != nil {
+			return f, errors.New("'from' must be a date as YYYY-MM-DD or RFC3339")
+		}
+	}
+
+	if to := v.Get("to"); to != "" {
+		f.To, err = parseSpendDate(to, true)
+		if err != nil {
+			return f, errors.New("'to' must be a date as YYYY-MM-DD or RFC3339")
+		}
+	}
+
+	if minCost := v.Get("min_cost"); minCost != "" {
+		c, err := repository.ParseMoney(minCost)
+		if err != nil {
+			return f, errors.New("'min_cost' must be a decimal number")
+		}
+		f.MinCost = &c
+	}
+
+	if maxCost := v.Get("max_cost"); maxCost != "" {
+		c, err := repository.ParseMoney(maxCost)
+		if err != nil {
+			return f, errors.New("'max_cost' must be a decimal number")
+		}
+		f.MaxCost = &c
+	}
+
+	if limit := v.Get("limit"); limit != "" {
+		f.Limit, err = strconv.ParseInt(limit, 10, 64)
+		if err != nil || f.Limit <= 0 || f.Limit > repository.MaxSpendsLimit {
+			return f, errors.New("'limit' must be a number between 1 and " + strconv.Itoa(repository.MaxSpendsLimit))
+		}
+	}
+
+	switch v.Get("sort") {
+	case "", "desc":
+	case "asc":
+		f.Ascending = true
+	default:
+		return f, errors.New("sort must be either 'asc' or 'desc'")
+	}
+
+	if f.Cursor != "" {
+		if _, _, err := repository.DecodeSpendCursor(f.Cursor); err != nil {
+			return f, err
+		}
+	}
+
+	return f, nil
+}
+
+// GetSpendsEndpoint will return a page of spends from an user, filtered by query params.
+// The cursor of the next page, if any, is returned at the 'X-Next-Cursor' header
 func GetSpendsEndpoint(response http.ResponseWriter, request *http.Request) {
 	response.Header().Add("content-type", "application/json")
 	response.Header().Add("backend", "budget-tracker")
 
 	params := mux.Vars(request)
 
-	spends, err := models.GetSpends(request.Context(), params["owner_id"])
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	filter, err := parseSpendFilter(params["owner_id"], request.URL.Query())
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not list spends", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	page, err := models.GetSpends(request.Context(), filter)
 	if err != nil {
 		response.WriteHeader(http.StatusInternalServerError)
 		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
 		return
 	}
 
-	if len(spends) == 0 {
+	if page.NextCursor != "" {
+		response.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+
+	if len(page.Spends) == 0 {
 		response.Write([]byte(`[]`))
 		return
 	}
 
-	json.NewEncoder(response).Encode(spends)
+	json.NewEncoder(response).Encode(page.Spends)
+}
+
+// GetSpendEndpoint will return a single spend from an user
+func GetSpendEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	spend, err := models.GetSpend(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find spend") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not find spend", "id": "` + params["id"] + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(spend)
+}
+
+// UpdateSpendEndpoint will fully replace a spend from an user and its balance
+func UpdateSpendEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	var spend repository.Spend
+
+	err := json.NewDecoder(request.Body).Decode(&spend)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update spend", "details": "malformed payload"}`))
+		return
+	}
+
+	updateSpend(response, request, params["owner_id"], params["id"], spend)
+}
+
+// PatchSpendEndpoint will partially update a spend from an user and its balance
+func PatchSpendEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	spend, err := models.GetSpend(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find spend") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not update spend", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "payment spends") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not update spend", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update spend", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update spend", "details": "malformed payload"}`))
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(body, &fields)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update spend", "details": "malformed payload"}`))
+		return
+	}
+
+	// a payment method replaces the current one instead of being merged into it
+	if _, ok := fields["payment_method"]; ok {
+		spend.PaymentMethod = repository.PaymentMethod{}
+	}
+
+	// only attributes present at the payload will override the current spend
+	err = json.Unmarshal(body, spend)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not update spend", "details": "malformed payload"}`))
+		return
+	}
+
+	updateSpend(response, request, params["owner_id"], params["id"], *spend)
+}
+
+// updateSpend will persist an updated spend and write its response
+func updateSpend(response http.ResponseWriter, request *http.Request, ownerID string, id string, spend repository.Spend) {
+	result, err := models.UpdateSpend(request.Context(), ownerID, id, spend)
+	if err != nil {
+		if isInvalidAmount(err) || isInvalidPaymentMethod(err) || isInvalidCategory(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not update spend", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "could not find spend") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not update spend", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "payment spends") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not update spend", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not update spend", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(result)
+}
+
+// DeleteSpendEndpoint will delete a spend from an user and remove it from its balance
+func DeleteSpendEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	err := models.DeleteSpend(request.Context(), params["owner_id"], params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find spend") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not delete spend", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if strings.Contains(err.Error(), "payment spends") {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not delete spend", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not delete spend", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "deleted spend '` + params["id"] + `'"}`))
 }
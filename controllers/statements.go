@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"budget-tracker-api/models"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// isStatementConflict will return if an error was caused by the card or statement state
+func isStatementConflict(err error) bool {
+	return strings.Contains(err.Error(), "billing cycle") ||
+		strings.Contains(err.Error(), "still open") ||
+		strings.Contains(err.Error(), "already paid") ||
+		strings.Contains(err.Error(), "nothing to be paid")
+}
+
+// GetStatementEndpoint will return the statement of a card closing at a given period, as YYYY-MM
+func GetStatementEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	period, err := parseBalancePeriod(params["period"])
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not get statement", "details": "period must be given as YYYY-MM"}`))
+		return
+	}
+
+	statement, err := models.GetStatement(request.Context(), params["owner_id"], params["id"], period.Month, period.Year)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find card") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not get statement", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if isStatementConflict(err) {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not get statement", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not get statement", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	json.NewEncoder(response).Encode(statement)
+}
+
+// PayStatementEndpoint will mark a closed card statement as paid, posting a payment to the current balance
+func PayStatementEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	period, err := parseBalancePeriod(params["period"])
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not pay statement", "details": "period must be given as YYYY-MM"}`))
+		return
+	}
+
+	payment, err := models.PayStatement(request.Context(), params["owner_id"], params["id"], period.Month, period.Year, time.Now())
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find card") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not pay statement", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if isStatementConflict(err) {
+			response.WriteHeader(http.StatusConflict)
+			response.Write([]byte(`{"message": "could not pay statement", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		if isInvalidAmount(err) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not pay statement", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not pay statement", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusCreated)
+	json.NewEncoder(response).Encode(payment)
+}
+
+// RevertStatementPaymentEndpoint will mark a card statement as unpaid, deleting its payment from the balance
+func RevertStatementPaymentEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+	response.Header().Add("backend", "budget-tracker")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["owner_id"]) {
+		return
+	}
+
+	period, err := parseBalancePeriod(params["period"])
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(`{"message": "could not revert statement payment", "details": "period must be given as YYYY-MM"}`))
+		return
+	}
+
+	err = models.RevertStatementPayment(request.Context(), params["owner_id"], params["id"], period.Month, period.Year)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find statement payment") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not revert statement payment", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not revert statement payment", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "reverted payment of statement '` + params["period"] + `'"}`))
+}
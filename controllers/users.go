@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"budget-tracker-api/auth"
 	"budget-tracker-api/models"
 	"budget-tracker-api/repository"
 	"encoding/json"
@@ -18,6 +19,14 @@ func CreateUserEndpoint(response http.ResponseWriter, request *http.Request) {
 
 	_ = json.NewDecoder(request.Body).Decode(&user)
 
+	for _, role := range user.Roles {
+		if !auth.ValidRole(role) {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(`{"message": "could not create user", "details": "given role '` + role + `' is not a valid one"}`))
+			return
+		}
+	}
+
 	result, err := models.CreateUser(request.Context(), user)
 	if err != nil {
 		if strings.Contains(err.Error(), "user already exists") {
@@ -57,8 +66,13 @@ func GetUsersEndpoint(response http.ResponseWriter, request *http.Request) {
 // GetUserEndpoint an unique user
 func GetUserEndpoint(response http.ResponseWriter, request *http.Request) {
 	response.Header().Add("content-type", "application/json")
+
 	params := mux.Vars(request)
 
+	if !authorizeOwner(response, request, params["id"]) {
+		return
+	}
+
 	user, err := models.GetUser(request.Context(), params["id"])
 	if err != nil {
 		if strings.Contains(err.Error(), "could not find user") {
@@ -80,7 +94,19 @@ func DeleteUserEndpoint(response http.ResponseWriter, request *http.Request) {
 
 	params := mux.Vars(request)
 
-	err := models.DeleteUser(request.Context(), params["id"])
+	if !authorizeOwner(response, request, params["id"]) {
+		return
+	}
+
+	// sessions are revoked first so a deleted user is never left with a valid token
+	err := models.RevokeUserSessions(request.Context(), params["id"], sessionLifetime())
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not delete user", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	err = models.DeleteUser(request.Context(), params["id"])
 	if err != nil {
 		if strings.Contains(err.Error(), "non existent user") {
 			response.WriteHeader(http.StatusNotFound)
@@ -96,3 +122,47 @@ func DeleteUserEndpoint(response http.ResponseWriter, request *http.Request) {
 	response.WriteHeader(http.StatusCreated)
 	response.Write([]byte(`{"message": "deleted user '` + params["id"] + `'"}`))
 }
+
+// RevokeUserSessionsEndpoint revokes every token issued to an user until now
+func RevokeUserSessionsEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	if !authorizeOwner(response, request, params["id"]) {
+		return
+	}
+
+	err := models.RevokeUserSessions(request.Context(), params["id"], sessionLifetime())
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not revoke sessions", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "revoked all sessions from user '` + params["id"] + `'"}`))
+}
+
+// UnlockUserEndpoint unlocks an user locked after repeated failed logins
+func UnlockUserEndpoint(response http.ResponseWriter, request *http.Request) {
+	response.Header().Add("content-type", "application/json")
+
+	params := mux.Vars(request)
+
+	err := models.UnlockUser(request.Context(), params["id"])
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find user") {
+			response.WriteHeader(http.StatusNotFound)
+			response.Write([]byte(`{"message": "could not unlock user", "details": "` + err.Error() + `"}`))
+			return
+		}
+
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(`{"message": "could not unlock user", "details": "` + err.Error() + `"}`))
+		return
+	}
+
+	response.WriteHeader(http.StatusOK)
+	response.Write([]byte(`{"message": "unlocked user '` + params["id"] + `'"}`))
+}
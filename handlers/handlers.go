@@ -9,53 +9,154 @@ import (
 type Handlers struct {
 	HealthCheckHandler http.Handler
 	SwaggerHandler     http.Handler
+	JWKSHandler        http.Handler
 
 	OptionsJWTTokenHandler http.Handler
 	CreateJWTTokenHandler  http.Handler
 	RefreshJWTTokenHandler http.Handler
+	RevokeJWTTokenHandler  http.Handler
 
 	GetUsersHandler   http.Handler
 	CreateUserHandler http.Handler
 	GetUserHandler    http.Handler
 	DeleteUserHandler http.Handler
 
+	RevokeUserSessionsHandler http.Handler
+	UnlockUserHandler         http.Handler
+
 	OptionsCardsHandler http.Handler
 	CreateCardHandler   http.Handler
 	GetAllCardsHandler  http.Handler
 	DeleteCardHandler   http.Handler
 	GetCardsHandler     http.Handler
+	GetCardHandler      http.Handler
+	PatchCardHandler    http.Handler
+
+	GetStatementHandler           http.Handler
+	PayStatementHandler           http.Handler
+	RevertStatementPaymentHandler http.Handler
+
+	CreateCategoryHandler http.Handler
+	GetCategoriesHandler  http.Handler
+	GetCategoryHandler    http.Handler
+	PatchCategoryHandler  http.Handler
+	MergeCategoryHandler  http.Handler
+	DeleteCategoryHandler http.Handler
+
+	CreateCategoryRuleHandler  http.Handler
+	GetCategoryRulesHandler    http.Handler
+	GetCategoryRuleHandler     http.Handler
+	PatchCategoryRuleHandler   http.Handler
+	DeleteCategoryRuleHandler  http.Handler
+	DryRunCategoryRulesHandler http.Handler
 
-	CreateBalanceHandler http.Handler
-	GetBalanceHandler    http.Handler
+	CreateBalanceHandler    http.Handler
+	GetBalanceHandler       http.Handler
+	UpdateBalanceHandler    http.Handler
+	PatchBalanceHandler     http.Handler
+	DeleteBalanceHandler    http.Handler
+	ReconcileBalanceHandler http.Handler
+	GetCommitmentsHandler   http.Handler
+	GetBudgetsHandler       http.Handler
+
+	GetMonthlyReportHandler http.Handler
+	GetYearlyReportHandler  http.Handler
+
+	PreviewImportHandler http.Handler
+	CommitImportHandler  http.Handler
 
 	GetSpendsHandler   http.Handler
 	CreateSpendHandler http.Handler
+	GetSpendHandler    http.Handler
+	UpdateSpendHandler http.Handler
+	PatchSpendHandler  http.Handler
+	DeleteSpendHandler http.Handler
+
+	UpsertExchangeRatesHandler http.Handler
+	ImportExchangeRatesHandler http.Handler
+	GetExchangeRatesHandler    http.Handler
+
+	CreateRecurrenceHandler http.Handler
+	GetRecurrencesHandler   http.Handler
+	PauseRecurrenceHandler  http.Handler
+	ResumeRecurrenceHandler http.Handler
+	CancelRecurrenceHandler http.Handler
 }
 
 // GetHandlers will return all backend handlers initialized
 func GetHandlers() (h Handlers) {
 	h.HealthCheckHandler = http.HandlerFunc(controllers.HealthCheck)
 	h.SwaggerHandler = http.HandlerFunc(controllers.Swagger)
+	h.JWKSHandler = http.HandlerFunc(controllers.JWKSEndpoint)
 
 	h.OptionsJWTTokenHandler = http.HandlerFunc(controllers.JWTTokenOptionsEndpoint)
 	h.CreateJWTTokenHandler = http.HandlerFunc(controllers.CreateJWTTokenEndpoint)
-	h.RefreshJWTTokenHandler = http.HandlerFunc(controllers.CreateJWTTokenEndpoint)
+	h.RefreshJWTTokenHandler = http.HandlerFunc(controllers.RefreshJWTTokenEndpoint)
+	h.RevokeJWTTokenHandler = http.HandlerFunc(controllers.RevokeJWTTokenEndpoint)
 
 	h.CreateUserHandler = http.HandlerFunc(controllers.CreateUserEndpoint)
 	h.GetUsersHandler = http.HandlerFunc(controllers.GetUsersEndpoint)
 	h.GetUserHandler = http.HandlerFunc(controllers.GetUserEndpoint)
 	h.DeleteUserHandler = http.HandlerFunc(controllers.DeleteUserEndpoint)
+	h.RevokeUserSessionsHandler = http.HandlerFunc(controllers.RevokeUserSessionsEndpoint)
+	h.UnlockUserHandler = http.HandlerFunc(controllers.UnlockUserEndpoint)
 
 	h.OptionsCardsHandler = http.HandlerFunc(controllers.CardsOptionsEndpoint)
 	h.CreateCardHandler = http.HandlerFunc(controllers.CreateCardEndpoint)
 	h.GetAllCardsHandler = http.HandlerFunc(controllers.GetAllCardsEndpoint)
 	h.DeleteCardHandler = http.HandlerFunc(controllers.DeleteCardEndpoint)
 	h.GetCardsHandler = http.HandlerFunc(controllers.GetCardsEndpoint)
+	h.GetCardHandler = http.HandlerFunc(controllers.GetCardEndpoint)
+	h.PatchCardHandler = http.HandlerFunc(controllers.PatchCardEndpoint)
+
+	h.GetStatementHandler = http.HandlerFunc(controllers.GetStatementEndpoint)
+	h.PayStatementHandler = http.HandlerFunc(controllers.PayStatementEndpoint)
+	h.RevertStatementPaymentHandler = http.HandlerFunc(controllers.RevertStatementPaymentEndpoint)
+
+	h.CreateCategoryHandler = http.HandlerFunc(controllers.CreateCategoryEndpoint)
+	h.GetCategoriesHandler = http.HandlerFunc(controllers.GetCategoriesEndpoint)
+	h.GetCategoryHandler = http.HandlerFunc(controllers.GetCategoryEndpoint)
+	h.PatchCategoryHandler = http.HandlerFunc(controllers.PatchCategoryEndpoint)
+	h.MergeCategoryHandler = http.HandlerFunc(controllers.MergeCategoryEndpoint)
+	h.DeleteCategoryHandler = http.HandlerFunc(controllers.DeleteCategoryEndpoint)
+
+	h.CreateCategoryRuleHandler = http.HandlerFunc(controllers.CreateCategoryRuleEndpoint)
+	h.GetCategoryRulesHandler = http.HandlerFunc(controllers.GetCategoryRulesEndpoint)
+	h.GetCategoryRuleHandler = http.HandlerFunc(controllers.GetCategoryRuleEndpoint)
+	h.PatchCategoryRuleHandler = http.HandlerFunc(controllers.PatchCategoryRuleEndpoint)
+	h.DeleteCategoryRuleHandler = http.HandlerFunc(controllers.DeleteCategoryRuleEndpoint)
+	h.DryRunCategoryRulesHandler = http.HandlerFunc(controllers.DryRunCategoryRulesEndpoint)
 
 	h.CreateBalanceHandler = http.HandlerFunc(controllers.CreateBalanceEndpoint)
 	h.GetBalanceHandler = http.HandlerFunc(controllers.GetBalanceEndpoint)
+	h.UpdateBalanceHandler = http.HandlerFunc(controllers.UpdateBalanceEndpoint)
+	h.PatchBalanceHandler = http.HandlerFunc(controllers.PatchBalanceEndpoint)
+	h.DeleteBalanceHandler = http.HandlerFunc(controllers.DeleteBalanceEndpoint)
+	h.ReconcileBalanceHandler = http.HandlerFunc(controllers.ReconcileBalanceEndpoint)
+	h.GetCommitmentsHandler = http.HandlerFunc(controllers.GetCommitmentsEndpoint)
+	h.GetBudgetsHandler = http.HandlerFunc(controllers.GetBudgetsEndpoint)
+
+	h.GetMonthlyReportHandler = http.HandlerFunc(controllers.GetMonthlyReportEndpoint)
+	h.GetYearlyReportHandler = http.HandlerFunc(controllers.GetYearlyReportEndpoint)
+
+	h.PreviewImportHandler = http.HandlerFunc(controllers.PreviewImportEndpoint)
+	h.CommitImportHandler = http.HandlerFunc(controllers.CommitImportEndpoint)
 
 	h.GetSpendsHandler = http.HandlerFunc(controllers.GetSpendsEndpoint)
 	h.CreateSpendHandler = http.HandlerFunc(controllers.CreateSpendEndpoint)
+	h.GetSpendHandler = http.HandlerFunc(controllers.GetSpendEndpoint)
+	h.UpdateSpendHandler = http.HandlerFunc(controllers.UpdateSpendEndpoint)
+	h.PatchSpendHandler = http.HandlerFunc(controllers.PatchSpendEndpoint)
+	h.DeleteSpendHandler = http.HandlerFunc(controllers.DeleteSpendEndpoint)
+
+	h.UpsertExchangeRatesHandler = http.HandlerFunc(controllers.UpsertExchangeRatesEndpoint)
+	h.ImportExchangeRatesHandler = http.HandlerFunc(controllers.ImportExchangeRatesEndpoint)
+	h.GetExchangeRatesHandler = http.HandlerFunc(controllers.GetExchangeRatesEndpoint)
+
+	h.CreateRecurrenceHandler = http.HandlerFunc(controllers.CreateRecurrenceEndpoint)
+	h.GetRecurrencesHandler = http.HandlerFunc(controllers.GetRecurrencesEndpoint)
+	h.PauseRecurrenceHandler = http.HandlerFunc(controllers.PauseRecurrenceEndpoint)
+	h.ResumeRecurrenceHandler = http.HandlerFunc(controllers.ResumeRecurrenceEndpoint)
+	h.CancelRecurrenceHandler = http.HandlerFunc(controllers.CancelRecurrenceEndpoint)
 	return h
 }
@@ -1,26 +1,28 @@
 package handlers
 
 import (
-	"fmt"
+	"budget-tracker-api/auth"
+	"budget-tracker-api/models"
 	"mime"
 	"net/http"
 	"strings"
+	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
-var mySigninKey = []byte("myhellokey")
-
 // Middlewares defines middlewares to intercept handlers
 type Middlewares struct {
-	Auth func(http.Handler) http.Handler
-	JSON func(http.Handler) http.Handler
+	Auth  func(http.Handler) http.Handler
+	Admin func(http.Handler) http.Handler
+	JSON  func(http.Handler) http.Handler
 }
 
 // GetMiddlewares will return all middlewares handlers initialized
 func GetMiddlewares() (m Middlewares) {
 	m.JSON = RequireContentTypeJSON
 	m.Auth = RequireTokenAuthentication
+	m.Admin = RequireRole(auth.RoleAdmin)
 	return m
 }
 
@@ -74,12 +76,7 @@ func RequireTokenAuthentication(h http.Handler) http.Handler {
 				return
 			}
 
-			token, err := jwt.Parse(jwtString[1], func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("could not decode token")
-				}
-				return mySigninKey, nil
-			})
+			token, err := auth.Keys().Parse(jwtString[1])
 
 			if err != nil {
 				response.WriteHeader(http.StatusUnauthorized)
@@ -90,9 +87,100 @@ func RequireTokenAuthentication(h http.Handler) http.Handler {
 			if !token.Valid {
 				response.WriteHeader(http.StatusInternalServerError)
 				response.Write([]byte(`{"message": "token not valid"}`))
+				return
+			}
+
+			claims, ok := token.Claims.(jwt.MapClaims)
+			if !ok {
+				response.WriteHeader(http.StatusUnauthorized)
+				response.Write([]byte(`{"message": "could not authenticate", "details": "malformed token claims"}`))
+				return
+			}
+
+			if typ, _ := claims["typ"].(string); typ == auth.TokenTypeRefresh {
+				response.WriteHeader(http.StatusUnauthorized)
+				response.Write([]byte(`{"message": "could not authenticate", "details": "refresh tokens can not be used as access tokens"}`))
+				return
 			}
+
+			sub, _ := claims["sub"].(string)
+			if sub == "" {
+				response.WriteHeader(http.StatusUnauthorized)
+				response.Write([]byte(`{"message": "could not authenticate", "details": "token has no subject"}`))
+				return
+			}
+
+			login, _ := claims["name"].(string)
+			jti, _ := claims["jti"].(string)
+
+			var issuedAt, expiresAt time.Time
+			if iat, ok := claims["iat_ms"].(float64); ok {
+				issuedAt = time.Unix(0, int64(iat)*int64(time.Millisecond))
+			} else if iat, ok := claims["iat"].(float64); ok {
+				issuedAt = time.Unix(int64(iat), 0)
+			}
+			if exp, ok := claims["exp"].(float64); ok {
+				expiresAt = time.Unix(int64(exp), 0)
+			}
+
+			revoked, err := models.IsTokenRevoked(request.Context(), jti, sub, issuedAt)
+			if err != nil {
+				response.WriteHeader(http.StatusInternalServerError)
+				response.Write([]byte(`{"message": "could not authenticate", "details": "` + err.Error() + `"}`))
+				return
+			}
+
+			if revoked {
+				response.WriteHeader(http.StatusUnauthorized)
+				response.Write([]byte(`{"message": "could not authenticate", "details": "token has been revoked"}`))
+				return
+			}
+
+			var roles []string
+			if claimedRoles, ok := claims["roles"].([]interface{}); ok {
+				for _, r := range claimedRoles {
+					if role, ok := r.(string); ok {
+						roles = append(roles, role)
+					}
+				}
+			}
+
+			// propagates the authenticated user so handlers can enforce ownership
+			request = request.WithContext(auth.WithPrincipal(request.Context(), auth.Principal{
+				Subject:   sub,
+				Login:     login,
+				Roles:     roles,
+				TokenID:   jti,
+				IssuedAt:  issuedAt,
+				ExpiresAt: expiresAt,
+			}))
 		}
 
 		h.ServeHTTP(response, request)
 	})
 }
+
+// RequireRole enforces the authenticated user to have a given role. It must be
+// chained after RequireTokenAuthentication
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			response.Header().Set("Access-Control-Allow-Origin", "*")
+
+			principal, ok := auth.PrincipalFromContext(request.Context())
+			if !ok {
+				response.WriteHeader(http.StatusUnauthorized)
+				response.Write([]byte(`{"message": "could not authorize", "details": "missing authenticated user"}`))
+				return
+			}
+
+			if !principal.HasRole(role) {
+				response.WriteHeader(http.StatusForbidden)
+				response.Write([]byte(`{"message": "could not authorize", "details": "missing required role '` + role + `'"}`))
+				return
+			}
+
+			h.ServeHTTP(response, request)
+		})
+	}
+}
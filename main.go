@@ -27,21 +27,21 @@
 package main
 
 import (
+	"budget-tracker-api/auth"
+	"budget-tracker-api/config"
+	"budget-tracker-api/controllers"
+	"budget-tracker-api/models"
 	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
 	"budget-tracker-api/routes"
 	"budget-tracker-api/server"
 	"budget-tracker-api/services"
+	"context"
 	"crypto/tls"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
-)
-
-const (
-	port      = ":5000"
-	service   = "budget-tracker-api"
-	jaegerURL = "http://localhost:14268/api/traces"
-	zipkinURL = "http://jaeger:9411/api/v2/spans"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func init() {
@@ -49,55 +49,127 @@ func init() {
 }
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
 	c := observability.ProvidersConfig{
-		ServiceName: "budget-tracker-api",
-		JaegerURL:   jaegerURL,
-		ZipkinURL:   zipkinURL,
+		ServiceName: cfg.Tracing.ServiceName,
+		JaegerURL:   cfg.Tracing.JaegerURL,
+		ZipkinURL:   cfg.Tracing.ZipkinURL,
 	}
 
-	p, err := c.InitTracerProviders()
+	// traces are disabled with the "none" exporter or when providers could not be initialized
+	if cfg.Tracing.Exporter != config.TracingExporterNone {
+		p, err := c.InitTracerProviders()
+		if err != nil {
+			log.Errorln(err)
+		} else {
+			tp, err := p.Provider(cfg.Tracing.Exporter)
+			if err != nil {
+				log.Fatalln(err)
+			}
+
+			observability.InitGlobalTrace(tp)
+		}
+	}
+
+	keys, err := auth.LoadKeySet(cfg.JWT.KeyID, cfg.JWT.Algorithm, cfg.JWT.Key, cfg.JWT.KeyFile, cfg.JWT.VerificationKeys)
 	if err != nil {
-		log.Errorln(err)
+		log.Fatalln(err)
 	}
 
-	// Change provider exporter if needed. Ex: `p.Stdout`
-	observability.InitGlobalTrace(p.Jaeger)
+	if keys != nil {
+		auth.UseKeySet(keys)
+	}
+
+	controllers.AccessTokenTTL = cfg.JWT.AccessTokenTTL
+	controllers.RefreshTokenTTL = cfg.JWT.RefreshTokenTTL
+
 	observability.InitMetrics()
 
 	router := mux.NewRouter()
-	routes.InitRoutes(service, router)
+	routes.InitRoutes(cfg.Tracing.ServiceName, router)
 
 	hc := server.HTTPConfig{
-		Port:   port,
+		Port:   cfg.Server.Port,
 		Router: router,
 		TLSConfig: &tls.Config{
 			// In the absence of `NextProtos`, HTTP/1.1 protocol will be enabled
 			NextProtos: []string{"h2"},
 		},
-		CertFile: "config/tls/server.crt",
-		KeyFile:  "config/tls/server.key",
+		CertFile:        cfg.Server.CertFile,
+		KeyFile:         cfg.Server.KeyFile,
+		ReadTimeout:     cfg.Server.ReadTimeout,
+		WriteTimeout:    cfg.Server.WriteTimeout,
+		ShutdownTimeout: cfg.Server.ShutdownTimeout,
 	}
 
-	services.MongoClient, err = services.InitDatabaseWithURI(services.MongodbURI)
-	if err != nil {
-		log.Fatalln(err)
-	}
+	switch cfg.Storage.Backend {
+	case config.StorageBackendMemory:
+		log.Warnln("using in-memory storage, data will be lost on restarts")
+
+		repositories, err := memoryRepositories()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		models.UseRepositories(repositories)
+	case config.StorageBackendSQLite, config.StorageBackendPostgres:
+		dialect := repository.SQLDialectPostgres
+		if cfg.Storage.Backend == config.StorageBackendSQLite {
+			dialect = repository.SQLDialectSQLite
+		}
 
-	// repo := repository.NewUserRepository(&repository.UserRepositoryMongoDB{
-	// 	Client: services.MongoClient,
-	// 	Config: services.MongoCfg{
-	// 		URI:       services.MongodbURI,
-	// 		Database:  "budget-tracker",
-	// 		Colletion: "users",
-	// 	},
-	// })
+		services.SQLClient, err = services.InitSQLDatabase(dialect, cfg.Storage.DSN)
+		if err != nil {
+			log.Fatalln(err)
+		}
 
-	// r, _ := repo.GetAll(context.TODO())
-	// fmt.Println(r)
+		err = repository.MigrateSQL(context.Background(), services.SQLClient, dialect)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		models.UseRepositories(repository.NewSQLRepositories(services.SQLClient, dialect))
+	default:
+		services.ConfigureMongoDB(cfg.MongoDB)
+		services.MongoClient, err = services.InitDatabaseWithURI(services.MongodbURI)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		models.UseRepositories(repository.NewMongoDBRepositories(services.MongoClient))
+	}
+
+	// recurring spends are materialized in background, unless disabled (e.g. on extra replicas)
+	if cfg.Scheduler.Interval > 0 {
+		go models.RunRecurrenceScheduler(context.Background(), cfg.Scheduler.Interval)
+	}
 
 	// In case of 'h2' (HTTP/2) the serverTLS must be set as `true`
-	err = hc.InitHTTPServer(false)
+	err = hc.InitHTTPServer(cfg.Server.TLS)
 	if err != nil {
 		log.Fatalln(err)
 	}
 }
+
+// memoryRepositories will return in-memory repositories seeded with the same admin user
+// created by 'docker/mongo-seed', so the API can be used without any database
+func memoryRepositories() (repository.Repositories, error) {
+	r := repository.NewMemoryRepositories()
+
+	id, _ := primitive.ObjectIDFromHex("5143afc66d44e1ceb372121e")
+	_, err := r.Users.Create(context.Background(), repository.User{
+		ID:             id,
+		Login:          "admin",
+		Firstname:      "Temporary",
+		Lastname:       "Seeded User",
+		Email:          "admin@domain.com",
+		SaltedPassword: "$2a$10$IjESPXqnHFuxh35mwRLglukiKO90SCrNbJ/kgrVe5YZO.FtAzFM2W",
+		Roles:          []string{auth.RoleAdmin},
+	})
+	if err != nil {
+		return repository.Repositories{}, err
+	}
+
+	return r, nil
+}
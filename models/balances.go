@@ -5,6 +5,7 @@ import (
 	"budget-tracker-api/repository"
 	"budget-tracker-api/services"
 	"context"
+	"fmt"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -12,6 +13,28 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// validateBalanceCurrency will normalize the currency of a balance and validate its income and budgets against it
+func validateBalanceCurrency(b *repository.Balance) error {
+	if b.Currency == "" {
+		return validateBudgets(b)
+	}
+
+	currency, err := repository.NormalizeCurrency(b.Currency)
+	if err != nil {
+		return err
+	}
+	b.Currency = currency
+
+	for _, amount := range []repository.Money{b.Income.GrossIncome, b.Income.NetIncome} {
+		err = repository.ValidateAmount(amount, currency)
+		if err != nil {
+			return err
+		}
+	}
+
+	return validateBudgets(b)
+}
+
 // CreateBalance creates a balance for a given owner_id
 func CreateBalance(parentCtx context.Context, b repository.Balance) (id string, err error) {
 	spanTags := []attribute.KeyValue{
@@ -21,7 +44,18 @@ func CreateBalance(parentCtx context.Context, b repository.Balance) (id string,
 	ctx, span := observability.Span(parentCtx, "mongodb", "CreateBalance", spanTags)
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	err = validateBalanceCurrency(&b)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+
+	err = canonicalBudgets(ctx, b.OwnerID, &b)
+	if err != nil {
+		cancel()
+		return "", err
+	}
 
 	// adding timestamp to creationDate
 	t := time.Now()
@@ -30,14 +64,7 @@ func CreateBalance(parentCtx context.Context, b repository.Balance) (id string,
 	b.SpendableAmount = b.Income.NetIncome
 	b.Historic = []repository.Spend{}
 
-	repo := repository.NewBalanceRepository(&repository.BalanceRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbBalanceCollection,
-		},
-	})
+	repo := repositories.Balances
 
 	id, err = repo.Create(ctx, b)
 	if err != nil {
@@ -62,16 +89,9 @@ func GetBalance(parentCtx context.Context, ownerID string, month int64, year int
 	ctx, span := observability.Span(parentCtx, "mongodb", "GetBalance", spanTags)
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 
-	repo := repository.NewBalanceRepository(&repository.BalanceRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbBalanceCollection,
-		},
-	})
+	repo := repositories.Balances
 
 	b, err := repo.Get(ctx, ownerID, month, year)
 	if err != nil {
@@ -83,33 +103,216 @@ func GetBalance(parentCtx context.Context, ownerID string, month int64, year int
 	return &b, nil
 }
 
-// GetAllBalances will return all balances from an owner_id
-func GetAllBalances(parentCtx context.Context, ownerID string) ([]repository.Balance, error) {
+// GetAllBalances will return all balances from an owner_id within a given period, sorted chronologically
+func GetAllBalances(parentCtx context.Context, f repository.BalanceFilter) ([]repository.Balance, error) {
 	spanTags := []attribute.KeyValue{
-		attribute.Key("balance.owner.id").String(ownerID),
+		attribute.Key("balance.owner.id").String(f.OwnerID),
 	}
 
 	ctx, span := observability.Span(parentCtx, "mongodb", "GetAllBalances", spanTags)
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-
-	repo := repository.NewBalanceRepository(&repository.BalanceRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbBalanceCollection,
-		},
-	})
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
 
-	b, err := repo.GetAll(ctx)
+	b, err := repositories.Balances.List(ctx, f)
 	if err != nil {
-		cancel()
 		return []repository.Balance{}, err
 	}
 
+	return b, nil
+}
+
+// GetBalanceByID will return a single balance from an owner_id
+func GetBalanceByID(parentCtx context.Context, ownerID string, id string) (*repository.Balance, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("balance.owner.id").String(ownerID),
+		attribute.Key("balance.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetBalanceByID", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
-	return b, nil
+	b, err := repositories.Balances.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Balance{}, err
+	}
+
+	return &b, nil
+}
+
+// UpdateBalance will change the income, currency and budgets of an existing balance from an owner_id
+func UpdateBalance(parentCtx context.Context, ownerID string, id string, b repository.Balance) (*repository.Balance, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("balance.owner.id").String(ownerID),
+		attribute.Key("balance.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "UpdateBalance", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	repo := repositories.Balances
+
+	current, err := repo.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Balance{}, err
+	}
+
+	err = validateBalanceCurrency(&b)
+	if err != nil {
+		return &repository.Balance{}, err
+	}
+
+	err = canonicalBudgets(ctx, current.OwnerID, &b)
+	if err != nil {
+		return &repository.Balance{}, err
+	}
+
+	// spends are converted when stored, so the currency can only change while every spend is in the new one
+	for _, s := range current.Historic {
+		if s.Currency != "" && s.Currency != b.Currency {
+			return &repository.Balance{}, fmt.Errorf("balance has spends in '%s' and can not be changed to '%s'", s.Currency, b.Currency)
+		}
+	}
+
+	current.Income = b.Income
+	current.Currency = b.Currency
+	current.Budgets = b.Budgets
+	current.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	err = repo.Update(ctx, current)
+	if err != nil {
+		return &repository.Balance{}, err
+	}
+
+	updated, err := repo.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Balance{}, err
+	}
+
+	log.Infoln("updated balance", id)
+	return &updated, nil
+}
+
+// DeleteBalance will delete a single balance from an owner_id
+func DeleteBalance(parentCtx context.Context, ownerID string, id string) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("balance.owner.id").String(ownerID),
+		attribute.Key("balance.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "DeleteBalance", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	repo := repositories.Balances
+
+	// ensures the balance belongs to the given owner before deleting it
+	_, err := repo.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+
+	err = repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("deleted balance", id)
+	return nil
+}
+
+// sameOutcome will return if two versions of a spend are accounted the same way in a balance
+func sameOutcome(a repository.Spend, b repository.Spend) bool {
+	return a.Cost == b.Cost && a.Type == b.Type && a.Currency == b.Currency && a.Date == b.Date
+}
+
+// ReconcileBalance will make a balance account exactly the spends stored for its month. Backends without
+// transactions revert failed spend writes on a best-effort basis, so a balance may be left accounting a
+// spend which was never stored, or missing one which was
+func ReconcileBalance(parentCtx context.Context, ownerID string, id string) (*repository.Balance, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("balance.owner.id").String(ownerID),
+		attribute.Key("balance.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "ReconcileBalance", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	repo := repositories.Balances
+
+	fixed := 0
+	err := repositories.Database.Transaction(ctx, func(ctx context.Context) error {
+		balance, err := repo.GetByID(ctx, ownerID, id)
+		if err != nil {
+			return err
+		}
+
+		from := time.Date(int(balance.Year), time.Month(balance.Month), 1, 0, 0, 0, 0, time.UTC)
+		spends, err := findAllSpends(ctx, repository.SpendFilter{
+			OwnerID: ownerID,
+			From:    from,
+			To:      from.AddDate(0, 1, 0).Add(-time.Millisecond),
+		})
+		if err != nil {
+			return err
+		}
+
+		stored := map[primitive.ObjectID]repository.Spend{}
+		for _, s := range spends {
+			stored[s.ID] = s
+		}
+
+		// spends removed or changed since they were accounted are pulled, and accounted again when still stored
+		accounted := map[primitive.ObjectID]bool{}
+		for _, h := range balance.Historic {
+			if s, ok := stored[h.ID]; ok && sameOutcome(h, s) {
+				accounted[h.ID] = true
+				continue
+			}
+
+			err = repo.RemoveSpend(ctx, h)
+			if err != nil {
+				return err
+			}
+			fixed++
+		}
+
+		for _, s := range spends {
+			if accounted[s.ID] {
+				continue
+			}
+
+			err = repo.AddSpend(ctx, s)
+			if err != nil {
+				return err
+			}
+			fixed++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return &repository.Balance{}, err
+	}
+
+	balance, err := repo.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Balance{}, err
+	}
+
+	span.SetAttributes(attribute.Key("balance.fixes").Int(fixed))
+	log.Infoln("reconciled balance", id, "fixing", fixed, "spends")
+	return &balance, nil
 }
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"budget-tracker-api/repository"
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReconcileBalanceMatchesTheSpendsOfItsMonth(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	UseRepositories(repos)
+
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+	date := primitive.NewDateTimeFromTime(time.Date(2021, 5, 10, 0, 0, 0, 0, time.UTC))
+
+	spend := func(description string, cost float64) repository.Spend {
+		return repository.Spend{
+			ID:          primitive.NewObjectID(),
+			OwnerID:     owner,
+			Description: description,
+			Type:        repository.SpendTypeDynamic,
+			Cost:        repository.NewMoney(cost),
+			Currency:    "BRL",
+			Date:        date,
+		}
+	}
+
+	kept, deleted, unaccounted, changed := spend("groceries", 100), spend("cinema", 40), spend("pharmacy", 25), spend("dinner", 60)
+
+	id, err := repos.Balances.Create(ctx, repository.Balance{
+		OwnerID:         owner,
+		Income:          repository.Income{GrossIncome: repository.NewMoney(1000), NetIncome: repository.NewMoney(1000)},
+		SpendableAmount: repository.NewMoney(1000),
+		Currency:        "BRL",
+		Historic:        []repository.Spend{},
+		Month:           5,
+		Year:            2021,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writes interrupted between the spend and the balance leave them apart
+	for _, s := range []repository.Spend{kept, deleted, changed} {
+		if err := repos.Balances.AddSpend(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed.Cost = repository.NewMoney(80)
+	for _, s := range []repository.Spend{kept, unaccounted, changed} {
+		if _, err := repos.Spends.Create(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		balance, err := ReconcileBalance(ctx, owner.Hex(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if balance.Outcome.DynamicOutcome != repository.NewMoney(205) || balance.SpendableAmount != repository.NewMoney(795) {
+			t.Errorf("unexpected reconciled balance: outcome %s, spendable %s", balance.Outcome.DynamicOutcome, balance.SpendableAmount)
+		}
+
+		historic := map[primitive.ObjectID]repository.Money{}
+		for _, h := range balance.Historic {
+			historic[h.ID] = h.Cost
+		}
+
+		expected := map[primitive.ObjectID]repository.Money{kept.ID: kept.Cost, unaccounted.ID: unaccounted.Cost, changed.ID: changed.Cost}
+		if len(historic) != len(balance.Historic) || len(historic) != len(expected) {
+			t.Fatalf("unexpected reconciled historic: %v", balance.Historic)
+		}
+
+		for id, cost := range expected {
+			if historic[id] != cost {
+				t.Errorf("spend %s accounted for %s, want %s", id.Hex(), historic[id], cost)
+			}
+		}
+	}
+}
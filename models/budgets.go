@@ -0,0 +1,200 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// budgetThresholds defines which shares of a category budget are alerted once reached
+var budgetThresholds = []int64{80, 100}
+
+// validateBudgets will validate the category budgets of a balance against its currency. Categories
+// are matched regardless of case
+func validateBudgets(b *repository.Balance) error {
+	seen := map[string]bool{}
+
+	for i := range b.Budgets {
+		budget := &b.Budgets[i]
+		budget.Category = strings.TrimSpace(budget.Category)
+
+		if budget.Category == "" {
+			return errors.New("invalid budget: category is required")
+		}
+
+		key := strings.ToLower(budget.Category)
+		if seen[key] {
+			return fmt.Errorf("invalid budget: category '%s' is budgeted more than once", budget.Category)
+		}
+		seen[key] = true
+
+		if budget.Limit <= 0 {
+			return fmt.Errorf("invalid budget: limit of category '%s' must be positive", budget.Category)
+		}
+
+		if b.Currency != "" {
+			err := repository.ValidateAmount(budget.Limit, b.Currency)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// canonicalBudgets will replace the budgeted categories of a balance by the owner categories they match
+func canonicalBudgets(ctx context.Context, ownerID primitive.ObjectID, b *repository.Balance) error {
+	names := make([]string, 0, len(b.Budgets))
+	for _, budget := range b.Budgets {
+		names = append(names, budget.Category)
+	}
+
+	// budgeted categories were already validated as unique, so they keep their positions
+	names, err := canonicalCategories(ctx, ownerID, names)
+	if err != nil {
+		return err
+	}
+
+	for i := range b.Budgets {
+		b.Budgets[i].Category = names[i]
+	}
+
+	return nil
+}
+
+// hasCategory will return if a spend belongs to a category
+func hasCategory(s repository.Spend, category string) bool {
+	for _, c := range s.Categories {
+		if strings.EqualFold(strings.TrimSpace(c), category) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// categorySpent will return the outcome of every spend of a category within a balance
+func categorySpent(historic []repository.Spend, category string) (spent repository.Money) {
+	for _, s := range historic {
+		if hasCategory(s, category) {
+			spent += s.Outcome()
+		}
+	}
+
+	return spent
+}
+
+// budgetStatus will return how much of a budget was spent and how much of it remains
+func budgetStatus(budget repository.Budget, spent repository.Money) repository.BudgetStatus {
+	return repository.BudgetStatus{
+		Category:   budget.Category,
+		Limit:      budget.Limit,
+		Spent:      spent,
+		Remaining:  budget.Limit - spent,
+		Percentage: int64(spent * 100 / budget.Limit),
+	}
+}
+
+// GetBudgetReport will return how much was spent on each budgeted category of a month balance,
+// along with the thresholds its spends reached
+func GetBudgetReport(parentCtx context.Context, ownerID string, month int64, year int64) (*repository.BudgetReport, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("balance.owner.id").String(ownerID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetBudgetReport", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	balance, err := repositories.Balances.Get(ctx, ownerID, month, year)
+	if err != nil {
+		return &repository.BudgetReport{}, err
+	}
+
+	alerts, err := repositories.BudgetAlerts.List(ctx, ownerID, month, year)
+	if err != nil {
+		return &repository.BudgetReport{}, err
+	}
+
+	report := repository.BudgetReport{
+		Month:    month,
+		Year:     year,
+		Currency: balance.Currency,
+		Budgets:  make([]repository.BudgetStatus, 0, len(balance.Budgets)),
+		Alerts:   alerts,
+	}
+
+	for _, budget := range balance.Budgets {
+		report.Budgets = append(report.Budgets, budgetStatus(budget, categorySpent(balance.Historic, budget.Category)))
+	}
+
+	return &report, nil
+}
+
+// recordBudgetAlerts will record an alert for every budget threshold a spend made its categories
+// reach. Each threshold is alerted a single time per month, even when spends are later removed
+func recordBudgetAlerts(ctx context.Context, s repository.Spend) error {
+	if len(s.Categories) == 0 || s.Outcome() <= 0 {
+		return nil
+	}
+
+	month, year := s.Period()
+
+	balance, err := repositories.Balances.Get(ctx, s.OwnerID.Hex(), month, year)
+	if err != nil {
+		return err
+	}
+
+	for _, budget := range balance.Budgets {
+		if !hasCategory(s, budget.Category) {
+			continue
+		}
+
+		// the balance already accounts the spend
+		spent := categorySpent(balance.Historic, budget.Category)
+		before := spent - s.Outcome()
+
+		for _, threshold := range budgetThresholds {
+			reach := budget.Limit * repository.Money(threshold) / 100
+			if before >= reach || spent < reach {
+				continue
+			}
+
+			_, err := repositories.BudgetAlerts.Create(ctx, repository.BudgetAlert{
+				ID:        primitive.NewObjectID(),
+				OwnerID:   s.OwnerID,
+				Category:  budget.Category,
+				Month:     month,
+				Year:      year,
+				Threshold: threshold,
+				Limit:     budget.Limit,
+				Spent:     spent,
+				Currency:  balance.Currency,
+				SpendID:   s.ID,
+				CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+			})
+			if err != nil {
+				if strings.Contains(err.Error(), "budget alert already recorded") {
+					continue
+				}
+				return err
+			}
+
+			log.Infoln("budget of category", budget.Category, "reached", threshold, "percent at", year, month)
+		}
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"budget-tracker-api/repository"
 	"budget-tracker-api/services"
 	"context"
+	"errors"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -12,6 +13,27 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 )
 
+// validateCard will validate the billing cycle and credit limit of a card
+func validateCard(c repository.CreditCard) error {
+	if c.ClosingDay < 0 || c.ClosingDay > 31 {
+		return errors.New("invalid card: closing day must be between 1 and 31")
+	}
+
+	if c.DueDay < 0 || c.DueDay > 31 {
+		return errors.New("invalid card: due day must be between 1 and 31")
+	}
+
+	if (c.ClosingDay == 0) != (c.DueDay == 0) {
+		return errors.New("invalid card: closing and due days must be given together")
+	}
+
+	if c.CreditLimit < 0 {
+		return errors.New("invalid card: credit limit must not be negative")
+	}
+
+	return nil
+}
+
 // CreateCard creates a card for a given owner_id
 func CreateCard(parentCtx context.Context, c repository.CreditCard) (id string, err error) {
 	spanTags := []attribute.KeyValue{
@@ -21,20 +43,18 @@ func CreateCard(parentCtx context.Context, c repository.CreditCard) (id string,
 	ctx, span := observability.Span(parentCtx, "mongodb", "CreateCard", spanTags)
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	err = validateCard(c)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 
 	// adding timestamp to creationDate
 	t := time.Now()
 	c.CreatedAt = primitive.NewDateTimeFromTime(t)
 
-	repo := repository.NewCardRepository(&repository.CardRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbCardsCollection,
-		},
-	})
+	repo := repositories.Cards
 
 	id, err = repo.Create(ctx, c)
 	if err != nil {
@@ -55,14 +75,7 @@ func GetAllCards(parentCtx context.Context) ([]repository.CreditCard, error) {
 	ctx, span := observability.Span(parentCtx, "mongodb", "GetAllCards", []attribute.KeyValue{})
 	defer span.End()
 
-	repo := repository.NewCardRepository(&repository.CardRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbCardsCollection,
-		},
-	})
+	repo := repositories.Cards
 
 	cards, err := repo.GetAll(ctx)
 	if err != nil {
@@ -72,8 +85,8 @@ func GetAllCards(parentCtx context.Context) ([]repository.CreditCard, error) {
 	return cards, nil
 }
 
-// GetCards will return a list of cards from a owner_id
-func GetCards(parentCtx context.Context, ownerID string) ([]repository.CreditCard, error) {
+// GetCards will return a list of cards from a owner_id, archived ones only when asked to
+func GetCards(parentCtx context.Context, ownerID string, archived bool) ([]repository.CreditCard, error) {
 	spanTags := []attribute.KeyValue{
 		attribute.Key("card.owner.id").String(ownerID),
 	}
@@ -81,16 +94,9 @@ func GetCards(parentCtx context.Context, ownerID string) ([]repository.CreditCar
 	ctx, span := observability.Span(parentCtx, "mongodb", "GetUserCards", spanTags)
 	defer span.End()
 
-	repo := repository.NewCardRepository(&repository.CardRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbCardsCollection,
-		},
-	})
+	repo := repositories.Cards
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 
 	cards, err := repo.Get(ctx, ownerID)
 	if err != nil {
@@ -100,32 +106,194 @@ func GetCards(parentCtx context.Context, ownerID string) ([]repository.CreditCar
 
 	defer cancel()
 
-	return cards, nil
+	if archived {
+		return cards, nil
+	}
+
+	active := []repository.CreditCard{}
+	for _, c := range cards {
+		if !c.Archived {
+			active = append(active, c)
+		}
+	}
+
+	return active, nil
 }
 
-// DeleteCard creates an user based on request body payload
-func DeleteCard(parentCtx context.Context, id string) error {
+// GetCard will return a single card from a owner_id, even when archived
+func GetCard(parentCtx context.Context, ownerID string, id string) (*repository.CreditCard, error) {
 	spanTags := []attribute.KeyValue{
+		attribute.Key("card.owner.id").String(ownerID),
+		attribute.Key("card.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetUserCard", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	card, err := repositories.Cards.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.CreditCard{}, err
+	}
+
+	return &card, nil
+}
+
+// GetCardOwner will return who owns a card, so routes not scoped by owner can authorize it
+func GetCardOwner(parentCtx context.Context, id string) (string, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("card.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetCardOwner", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	return repositories.Cards.GetOwnerID(ctx, id)
+}
+
+// cardInRecurrences will return if an active or paused recurrence is paid with a card
+func cardInRecurrences(ctx context.Context, card repository.CreditCard) (bool, error) {
+	recurrences, err := repositories.Recurrences.Get(ctx, card.OwnerID.Hex())
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range recurrences {
+		if r.PaymentMethod.Credit.ID == card.ID &&
+			(r.Status == repository.RecurrenceStatusActive || r.Status == repository.RecurrenceStatusPaused) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// snapshotCard will replace the credit card of a payment method by the stored one, so spends keep
+// its attributes even after it's changed. Archived cards can not be used anymore
+func snapshotCard(ctx context.Context, ownerID primitive.ObjectID, pm *repository.PaymentMethod) error {
+	if pm.Credit.ID.IsZero() {
+		return nil
+	}
+
+	card, err := repositories.Cards.GetByID(ctx, ownerID.Hex(), pm.Credit.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	if card.Archived {
+		return errors.New("card is archived and can not be used anymore")
+	}
+
+	pm.Credit = card
+	return nil
+}
+
+// UpdateCard will replace a card from a specific owner_id. Its last digits can not be changed, and it can
+// only be archived when no active or paused recurrence is paid with it
+func UpdateCard(parentCtx context.Context, ownerID string, id string, c repository.CreditCard) (*repository.CreditCard, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("card.owner.id").String(ownerID),
+		attribute.Key("card.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "UpdateUserCard", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	repo := repositories.Cards
+
+	current, err := repo.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.CreditCard{}, err
+	}
+
+	// identity and creation fields can not be changed by an update
+	c.ID = current.ID
+	c.OwnerID = current.OwnerID
+	c.LastDigits = current.LastDigits
+	c.CreatedAt = current.CreatedAt
+	c.ArchivedAt = current.ArchivedAt
+
+	err = validateCard(c)
+	if err != nil {
+		return &repository.CreditCard{}, err
+	}
+
+	if c.Archived && !current.Archived {
+		used, err := cardInRecurrences(ctx, current)
+		if err != nil {
+			return &repository.CreditCard{}, err
+		}
+
+		if used {
+			return &repository.CreditCard{}, errors.New("card is used by recurrences, which must be cancelled before archiving it")
+		}
+
+		c.ArchivedAt = primitive.NewDateTimeFromTime(time.Now())
+	}
+
+	if !c.Archived {
+		c.ArchivedAt = 0
+	}
+
+	err = repo.Update(ctx, c)
+	if err != nil {
+		return &repository.CreditCard{}, err
+	}
+
+	log.Infoln("updated card", id)
+	return &c, nil
+}
+
+// DeleteCard deletes a card from a given owner_id
+func DeleteCard(parentCtx context.Context, ownerID string, id string) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("card.owner.id").String(ownerID),
 		attribute.Key("card.id").String(id),
 	}
 
 	ctx, span := observability.Span(parentCtx, "mongodb", "DeleteUserCard", spanTags)
 	defer span.End()
 
-	repo := repository.NewCardRepository(&repository.CardRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbCardsCollection,
-		},
-	})
+	repo := repositories.Cards
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 
 	log.Infoln("deleting card", id)
 
-	err := repo.Delete(ctx, id)
+	// ensures the card belongs to the given owner before deleting it
+	card, err := repo.GetByID(ctx, ownerID, id)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	// spends keep a snapshot of their card, which would be orphaned by deleting it
+	page, err := repositories.Spends.Find(ctx, repository.SpendFilter{OwnerID: ownerID, CardID: id, Limit: 1})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	used, err := cardInRecurrences(ctx, card)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if len(page.Spends) > 0 || used {
+		cancel()
+		return errors.New("card is used by spends or recurrences and can only be archived")
+	}
+
+	err = repo.Delete(ctx, id)
 	if err != nil {
 		cancel()
 		return err
@@ -0,0 +1,571 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxCategoryName defines how long category names can be
+	maxCategoryName = 64
+	// maxCategoryIcon defines how long category icon names can be
+	maxCategoryIcon = 32
+)
+
+// categoryColor matches colors given as #RRGGBB
+var categoryColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// defaultCategories defines the categories every user starts with, along with their subcategories
+var defaultCategories = []struct {
+	name          string
+	color         string
+	icon          string
+	subcategories []string
+}{
+	{"housing", "#8d6e63", "home", []string{"rent", "utilities", "maintenance"}},
+	{"food", "#ef6c00", "utensils", []string{"groceries", "restaurants"}},
+	{"transport", "#1e88e5", "car", []string{"fuel", "public transport"}},
+	{"health", "#e53935", "heart", []string{"pharmacy", "health insurance"}},
+	{"education", "#5e35b1", "book", []string{}},
+	{"leisure", "#43a047", "smile", []string{"travel", "subscriptions"}},
+	{"shopping", "#d81b60", "shopping-bag", []string{}},
+	{"taxes", "#546e7a", "file-text", []string{}},
+}
+
+// seedCategories will create the default categories of a new owner
+func seedCategories(ctx context.Context, ownerID primitive.ObjectID) error {
+	now := primitive.NewDateTimeFromTime(time.Now())
+
+	for _, d := range defaultCategories {
+		parent := repository.Category{
+			ID:        primitive.NewObjectID(),
+			OwnerID:   ownerID,
+			Name:      d.name,
+			Color:     d.color,
+			Icon:      d.icon,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		_, err := repositories.Categories.Create(ctx, parent)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range d.subcategories {
+			_, err := repositories.Categories.Create(ctx, repository.Category{
+				OwnerID:   ownerID,
+				Name:      name,
+				ParentID:  parent.ID,
+				Color:     d.color,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateCategory will validate the attributes of a category against the other categories of its owner,
+// so hierarchies never have cycles
+func validateCategory(c *repository.Category, categories []repository.Category) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" || len(c.Name) > maxCategoryName {
+		return fmt.Errorf("invalid category: name is required and must have at most %d characters", maxCategoryName)
+	}
+
+	if c.Color != "" && !categoryColor.MatchString(c.Color) {
+		return errors.New("invalid category: color must be given as #RRGGBB")
+	}
+
+	if len(c.Icon) > maxCategoryIcon {
+		return fmt.Errorf("invalid category: icon must have at most %d characters", maxCategoryIcon)
+	}
+
+	parents := map[primitive.ObjectID]primitive.ObjectID{}
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	for parent := c.ParentID; !parent.IsZero(); parent = parents[parent] {
+		if parent == c.ID {
+			return errors.New("invalid category: it can not be nested under itself or its subcategories")
+		}
+
+		if _, ok := parents[parent]; !ok {
+			return errors.New("invalid category: could not find parent category")
+		}
+	}
+
+	return nil
+}
+
+// canonicalCategories will return the owner categories a list of category names matches regardless of
+// case, without duplicates. Owners without any categories still use free-form ones
+func canonicalCategories(ctx context.Context, ownerID primitive.ObjectID, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return names, nil
+	}
+
+	categories, err := repositories.Categories.Get(ctx, ownerID.Hex())
+	if err != nil {
+		return []string{}, err
+	}
+
+	if len(categories) == 0 {
+		return names, nil
+	}
+
+	known := map[string]string{}
+	for _, c := range categories {
+		known[strings.ToLower(c.Name)] = c.Name
+	}
+
+	canonical := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		c, ok := known[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return []string{}, fmt.Errorf("invalid category: '%s' is not one of the owner categories", name)
+		}
+
+		if !seen[c] {
+			seen[c] = true
+			canonical = append(canonical, c)
+		}
+	}
+
+	return canonical, nil
+}
+
+// containsCategory will return if a list of categories contains a given one regardless of case
+func containsCategory(categories []string, name string) bool {
+	for _, c := range categories {
+		if strings.EqualFold(c, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// renameCategory will replace a category of a list regardless of case, without duplicating the new one
+func renameCategory(categories []string, from string, to string) []string {
+	renamed := make([]string, 0, len(categories))
+	seen := map[string]bool{}
+
+	for _, c := range categories {
+		if strings.EqualFold(c, from) {
+			c = to
+		}
+
+		if !seen[strings.ToLower(c)] {
+			seen[strings.ToLower(c)] = true
+			renamed = append(renamed, c)
+		}
+	}
+
+	return renamed
+}
+
+// rewriteCategory will move every spend, recurrence, budget and rule of a category to another one. Budgets
+// of both categories within a month are summed up
+func rewriteCategory(ctx context.Context, ownerID string, from string, to string) error {
+	spends, err := findAllSpends(ctx, repository.SpendFilter{OwnerID: ownerID, Category: from})
+	if err != nil {
+		return err
+	}
+
+	for _, current := range spends {
+		s := current
+		s.Categories = renameCategory(current.Categories, from, to)
+
+		err = replaceSpend(ctx, current, s)
+		if err != nil {
+			return err
+		}
+	}
+
+	recurrences, err := repositories.Recurrences.Get(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for _, r := range recurrences {
+		if !containsCategory(r.Categories, from) {
+			continue
+		}
+
+		r.Categories = renameCategory(r.Categories, from, to)
+		r.UpdatedAt = now
+		err = repositories.Recurrences.UpdateCategories(ctx, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	rules, err := repositories.CategoryRules.Get(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		if !containsCategory(r.Categories, from) {
+			continue
+		}
+
+		r.Categories = renameCategory(r.Categories, from, to)
+		r.UpdatedAt = now
+		err = repositories.CategoryRules.Update(ctx, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	balances, err := repositories.Balances.List(ctx, repository.BalanceFilter{OwnerID: ownerID})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range balances {
+		source := -1
+		for i, budget := range b.Budgets {
+			if strings.EqualFold(budget.Category, from) {
+				source = i
+			}
+		}
+		if source < 0 {
+			continue
+		}
+
+		budgets := []repository.Budget{}
+		merged := false
+		for i, budget := range b.Budgets {
+			if i != source && strings.EqualFold(budget.Category, to) {
+				budget.Limit += b.Budgets[source].Limit
+				merged = true
+			}
+			budgets = append(budgets, budget)
+		}
+
+		if merged {
+			budgets = append(budgets[:source], budgets[source+1:]...)
+		} else {
+			budgets[source].Category = to
+		}
+
+		b.Budgets = budgets
+		b.UpdatedAt = now
+		err = repositories.Balances.Update(ctx, b)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infoln("moved", len(spends), "spends from category", from, "to", to)
+	return nil
+}
+
+// CreateCategory creates a category for a given owner_id
+func CreateCategory(parentCtx context.Context, c repository.Category) (id string, err error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("category.owner.id").String(c.OwnerID.Hex()),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "CreateCategory", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	categories, err := repositories.Categories.Get(ctx, c.OwnerID.Hex())
+	if err != nil {
+		return "", err
+	}
+
+	c.ID = primitive.NewObjectID()
+	err = validateCategory(&c, categories)
+	if err != nil {
+		return "", err
+	}
+
+	t := primitive.NewDateTimeFromTime(time.Now())
+	c.CreatedAt = t
+	c.UpdatedAt = t
+
+	id, err = repositories.Categories.Create(ctx, c)
+	if err != nil {
+		return "", err
+	}
+
+	span.SetAttributes(attribute.Key("category.id").String(id))
+	log.Infoln("created category", c.Name)
+	return id, nil
+}
+
+// GetCategories will return every category of an owner_id, sorted by name
+func GetCategories(parentCtx context.Context, ownerID string) ([]repository.Category, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("category.owner.id").String(ownerID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetCategories", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	return repositories.Categories.Get(ctx, ownerID)
+}
+
+// GetCategory will return a single category from an owner_id
+func GetCategory(parentCtx context.Context, ownerID string, id string) (*repository.Category, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("category.owner.id").String(ownerID),
+		attribute.Key("category.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetCategory", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	c, err := repositories.Categories.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	return &c, nil
+}
+
+// UpdateCategory will change the name, parent, color and icon of a category from an owner_id. Renamed
+// categories are rewritten on every spend, recurrence and budget using them
+func UpdateCategory(parentCtx context.Context, ownerID string, id string, c repository.Category) (*repository.Category, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("category.owner.id").String(ownerID),
+		attribute.Key("category.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "UpdateCategory", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	current, err := repositories.Categories.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	categories, err := repositories.Categories.Get(ctx, ownerID)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	c.ID = current.ID
+	c.OwnerID = current.OwnerID
+	c.CreatedAt = current.CreatedAt
+	c.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	err = validateCategory(&c, categories)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	// the new name is claimed before rewriting spends, so they are never moved to another category
+	err = repositories.Categories.Update(ctx, c)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	if c.Name != current.Name {
+		err = rewriteCategory(ctx, ownerID, current.Name, c.Name)
+		if err != nil {
+			return &repository.Category{}, err
+		}
+	}
+
+	log.Infoln("updated category", id)
+	return &c, nil
+}
+
+// MergeCategory will move every spend, recurrence, budget, rule and subcategory of a category into
+// another one, deleting it afterwards
+func MergeCategory(parentCtx context.Context, ownerID string, id string, intoID string) (*repository.Category, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("category.owner.id").String(ownerID),
+		attribute.Key("category.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "MergeCategory", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	source, err := repositories.Categories.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	target, err := repositories.Categories.GetByID(ctx, ownerID, intoID)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	if source.ID == target.ID {
+		return &repository.Category{}, errors.New("invalid category: it can not be merged into itself")
+	}
+
+	err = rewriteCategory(ctx, ownerID, source.Name, target.Name)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	categories, err := repositories.Categories.Get(ctx, ownerID)
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for _, child := range categories {
+		if child.ParentID != source.ID {
+			continue
+		}
+
+		// a subcategory merged into takes the place of its parent instead
+		child.ParentID = target.ID
+		if child.ID == target.ID {
+			child.ParentID = source.ParentID
+			target.ParentID = source.ParentID
+		}
+		child.UpdatedAt = now
+
+		err = repositories.Categories.Update(ctx, child)
+		if err != nil {
+			return &repository.Category{}, err
+		}
+	}
+
+	err = repositories.Categories.Delete(ctx, source.ID.Hex())
+	if err != nil {
+		return &repository.Category{}, err
+	}
+
+	log.Infoln("merged category", source.Name, "into", target.Name)
+	return &target, nil
+}
+
+// DeleteCategory will delete a category from an owner_id. Categories still used by spends, recurrences,
+// budgets or rules must be merged instead, and subcategories must be moved or deleted first
+func DeleteCategory(parentCtx context.Context, ownerID string, id string) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("category.owner.id").String(ownerID),
+		attribute.Key("category.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "DeleteCategory", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	current, err := repositories.Categories.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+
+	categories, err := repositories.Categories.Get(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range categories {
+		if c.ParentID == current.ID {
+			return errors.New("category has subcategories, which must be moved or deleted first")
+		}
+	}
+
+	used, err := categoryInUse(ctx, ownerID, current.Name)
+	if err != nil {
+		return err
+	}
+
+	if used {
+		return errors.New("category is used by spends, recurrences, budgets or rules and can only be merged")
+	}
+
+	err = repositories.Categories.Delete(ctx, current.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("deleted category", id)
+	return nil
+}
+
+// categoryInUse will return if any spend, recurrence or budget of an owner uses a category
+func categoryInUse(ctx context.Context, ownerID string, name string) (bool, error) {
+	page, err := repositories.Spends.Find(ctx, repository.SpendFilter{OwnerID: ownerID, Category: name, Limit: 1})
+	if err != nil {
+		return false, err
+	}
+
+	if len(page.Spends) > 0 {
+		return true, nil
+	}
+
+	recurrences, err := repositories.Recurrences.Get(ctx, ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range recurrences {
+		if containsCategory(r.Categories, name) {
+			return true, nil
+		}
+	}
+
+	rules, err := repositories.CategoryRules.Get(ctx, ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, r := range rules {
+		if containsCategory(r.Categories, name) {
+			return true, nil
+		}
+	}
+
+	balances, err := repositories.Balances.List(ctx, repository.BalanceFilter{OwnerID: ownerID})
+	if err != nil {
+		return false, err
+	}
+
+	for _, b := range balances {
+		for _, budget := range b.Budgets {
+			if strings.EqualFold(budget.Category, name) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// exchangeRateMaxAge defines how old the latest rate of a pair may be to convert a spend, covering
+// weekends and holidays without quotes
+const exchangeRateMaxAge = 7 * 24 * time.Hour
+
+// day will return the midnight UTC of a given time, which is how exchange rates are dated
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// validateExchangeRate will normalize the currencies and date of an exchange rate
+func validateExchangeRate(r *repository.ExchangeRate) error {
+	base, err := repository.NormalizeCurrency(r.Base)
+	if err != nil {
+		return err
+	}
+
+	quote, err := repository.NormalizeCurrency(r.Quote)
+	if err != nil {
+		return err
+	}
+
+	if base == quote {
+		return fmt.Errorf("exchange rate base and quote must differ, got '%s'", base)
+	}
+
+	if r.Rate <= 0 {
+		return fmt.Errorf("exchange rate from '%s' to '%s' must be positive", base, quote)
+	}
+
+	if r.Date == 0 {
+		return errors.New("exchange rate date must be informed")
+	}
+
+	r.Base = base
+	r.Quote = quote
+	r.Date = primitive.NewDateTimeFromTime(day(r.Date.Time()))
+	return nil
+}
+
+// UpsertExchangeRates will validate and store a list of exchange rates, replacing the rates
+// already stored for the same pairs and days
+func UpsertExchangeRates(parentCtx context.Context, rates []repository.ExchangeRate) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("exchange_rates.count").Int(len(rates)),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "UpsertExchangeRates", spanTags)
+	defer span.End()
+
+	// every rate is validated first, so a bad line does not leave a partial import behind
+	now := primitive.NewDateTimeFromTime(time.Now())
+	for i := range rates {
+		err := validateExchangeRate(&rates[i])
+		if err != nil {
+			return fmt.Errorf("exchange rate %d: %v", i+1, err)
+		}
+		rates[i].UpdatedAt = now
+	}
+
+	for _, r := range rates {
+		err := repositories.ExchangeRates.Upsert(ctx, r)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Infoln("stored exchange rates", len(rates))
+	return nil
+}
+
+// GetExchangeRates will return the exchange rates matching a given filter
+func GetExchangeRates(parentCtx context.Context, f repository.ExchangeRateFilter) ([]repository.ExchangeRate, error) {
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetExchangeRates", []attribute.KeyValue{})
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	for _, c := range []*string{&f.Base, &f.Quote} {
+		if *c == "" {
+			continue
+		}
+
+		currency, err := repository.NormalizeCurrency(*c)
+		if err != nil {
+			return []repository.ExchangeRate{}, err
+		}
+		*c = currency
+	}
+
+	return repositories.ExchangeRates.List(ctx, f)
+}
+
+// exchangeRate will return the rate converting base into quote at a given date, using the
+// inverse of the opposite pair when only that one is known
+func exchangeRate(ctx context.Context, base string, quote string, date time.Time) (float64, error) {
+	date = day(date)
+	notFound := fmt.Errorf("could not find exchange rate from '%s' to '%s' at %s", base, quote, date.Format("2006-01-02"))
+
+	direct, err := repositories.ExchangeRates.Get(ctx, base, quote, date)
+	if err != nil && !strings.Contains(err.Error(), "could not find exchange rate") {
+		return 0, err
+	}
+	found := err == nil
+
+	inverse, err := repositories.ExchangeRates.Get(ctx, quote, base, date)
+	if err != nil && !strings.Contains(err.Error(), "could not find exchange rate") {
+		return 0, err
+	}
+
+	// the most recent of both pairs wins, with the direct one preferred on the same day
+	rate := direct.Rate
+	published := direct.Date
+	if err == nil && (!found || inverse.Date > direct.Date) {
+		found = true
+		rate = 1 / inverse.Rate
+		published = inverse.Date
+	}
+
+	if !found || date.Sub(published.Time()) > exchangeRateMaxAge {
+		return 0, notFound
+	}
+
+	return rate, nil
+}
+
+// convertSpend will convert the cost of a spend into a given currency at the rate of the spend
+// date, keeping its original cost and currency
+func convertSpend(ctx context.Context, s *repository.Spend, currency string) error {
+	err := repository.ValidateAmount(s.Cost, s.Currency)
+	if err != nil {
+		return err
+	}
+
+	rate, err := exchangeRate(ctx, s.Currency, currency, s.Date.Time())
+	if err != nil {
+		return err
+	}
+
+	s.OriginalCost = s.Cost
+	s.OriginalCurrency = s.Currency
+	s.ExchangeRate = rate
+	s.Cost = repository.ConvertAmount(s.Cost, rate, currency)
+	s.Currency = currency
+	return nil
+}
@@ -0,0 +1,462 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxImportTransactions defines how many transactions a single bank statement can hold
+const maxImportTransactions = 1000
+
+var (
+	// ofxTransaction matches every transaction of an OFX statement, in either SGML or XML
+	ofxTransaction = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	// ofxField matches the value of an OFX element, which may not be closed in SGML
+	ofxField = regexp.MustCompile(`(?is)<([A-Z0-9.]+)>([^<]*)`)
+)
+
+// importTransaction holds a transaction read from a bank statement, before being turned into a spend
+type importTransaction struct {
+	line        int64
+	date        string
+	description string
+	amount      string
+	currency    string
+}
+
+// dateLayout will convert a date format using YYYY, MM and DD into a time layout
+func dateLayout(format string) string {
+	if format == "" {
+		format = "YYYY-MM-DD"
+	}
+	return strings.NewReplacer("YYYY", "2006", "MM", "01", "DD", "02").Replace(format)
+}
+
+// parseImportAmount will parse an amount written with a given decimal separator, ignoring the other
+// one as a thousands separator
+func parseImportAmount(value string, decimalSeparator string) (repository.Money, error) {
+	value = strings.TrimSpace(value)
+	if decimalSeparator == "," {
+		value = strings.ReplaceAll(strings.ReplaceAll(value, ".", ""), ",", ".")
+	} else {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+	return repository.ParseMoney(strings.TrimPrefix(value, "+"))
+}
+
+// readCSVTransactions will read every transaction of a CSV statement through its column mapping
+func readCSVTransactions(content string, m repository.SpendImportMapping) ([]importTransaction, error) {
+	if m.Date == "" || m.Description == "" || m.Amount == "" {
+		return nil, errors.New("invalid import: CSV statements require the date, description and amount columns to be mapped")
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if m.Delimiter != "" {
+		delimiter := []rune(m.Delimiter)
+		if len(delimiter) != 1 {
+			return nil, errors.New("invalid import: the CSV delimiter must be a single character")
+		}
+		reader.Comma = delimiter[0]
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("invalid import: CSV statement has no header")
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	column := func(name string) (int, error) {
+		if name == "" {
+			return -1, nil
+		}
+		i, ok := columns[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, fmt.Errorf("invalid import: CSV statement has no column '%s'", name)
+		}
+		return i, nil
+	}
+
+	var indexes [4]int
+	for i, name := range []string{m.Date, m.Description, m.Amount, m.Currency} {
+		indexes[i], err = column(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	// rows are numbered from the header
+	var transactions []importTransaction
+	for row := int64(2); ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("invalid import: malformed CSV at line %d", parseErr.Line)
+			}
+			return nil, err
+		}
+
+		transactions = append(transactions, importTransaction{
+			line:        row,
+			date:        field(record, indexes[0]),
+			description: field(record, indexes[1]),
+			amount:      field(record, indexes[2]),
+			currency:    field(record, indexes[3]),
+		})
+	}
+
+	return transactions, nil
+}
+
+// readOFXTransactions will read every transaction of an OFX statement, numbered by their position.
+// Their currency is the default one of the statement
+func readOFXTransactions(content string) ([]importTransaction, error) {
+	fields := func(s string) map[string]string {
+		values := map[string]string{}
+		for _, match := range ofxField.FindAllStringSubmatch(s, -1) {
+			values[strings.ToUpper(match[1])] = strings.TrimSpace(match[2])
+		}
+		return values
+	}
+
+	if !strings.Contains(strings.ToUpper(content), "<OFX>") {
+		return nil, errors.New("invalid import: content is not an OFX statement")
+	}
+
+	currency := fields(content)["CURDEF"]
+
+	var transactions []importTransaction
+	for i, match := range ofxTransaction.FindAllStringSubmatch(content, -1) {
+		values := fields(match[1])
+
+		description := values["NAME"]
+		if description == "" {
+			description = values["MEMO"]
+		}
+
+		// dates are YYYYMMDD followed by an optional time and timezone
+		date := values["DTPOSTED"]
+		if len(date) > 8 {
+			date = date[:8]
+		}
+
+		transactions = append(transactions, importTransaction{
+			line:        int64(i + 1),
+			date:        date,
+			description: description,
+			amount:      values["TRNAMT"],
+			currency:    currency,
+		})
+	}
+
+	return transactions, nil
+}
+
+// spendFingerprint will return the date, cost and normalized description identifying a spend among the
+// imported ones. Converted spends are identified by their original cost, as their statements list it
+func spendFingerprint(s repository.Spend) string {
+	cost := s.Cost
+	if s.OriginalCurrency != "" {
+		cost = s.OriginalCost
+	}
+
+	description := strings.Join(strings.Fields(strings.ToLower(s.Description)), " ")
+	return fmt.Sprintf("%s|%d|%s", s.Date.Time().UTC().Format("2006-01-02"), cost, description)
+}
+
+// importRow will turn a transaction into the spend it imports, or tell why it can not be imported
+func importRow(t importTransaction, imp repository.SpendImport, ownerID primitive.ObjectID, layout string) repository.SpendImportRow {
+	row := repository.SpendImportRow{Line: t.line, Status: repository.SpendImportStatusInvalid}
+
+	date, err := time.Parse(layout, t.date)
+	if err != nil {
+		row.Details = fmt.Sprintf("date '%s' does not match the statement date format", t.date)
+		return row
+	}
+
+	amount, err := parseImportAmount(t.amount, imp.Mapping.DecimalSeparator)
+	if err != nil {
+		row.Details = err.Error()
+		return row
+	}
+
+	// spends are outcomes, so incomes and refunds are left out
+	cost := -amount
+	if imp.Mapping.PositiveSpends {
+		cost = amount
+	}
+	if cost <= 0 {
+		row.Status = repository.SpendImportStatusSkipped
+		row.Details = "transaction is not a spend"
+		return row
+	}
+
+	row.Status = repository.SpendImportStatusNew
+	row.Spend = repository.Spend{
+		OwnerID:       ownerID,
+		Description:   t.description,
+		Cost:          cost,
+		Currency:      strings.ToUpper(t.currency),
+		PaymentMethod: imp.PaymentMethod,
+		Date:          primitive.NewDateTimeFromTime(date),
+	}
+	row.Fingerprint = spendFingerprint(row.Spend)
+
+	return row
+}
+
+// importToken will return the hash of what importing a statement does, which its commit must give back
+// from its preview so that it creates exactly the spends previewed
+func importToken(rows []repository.SpendImportRow) (string, error) {
+	content, err := json.Marshal(rows)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// existingFingerprints will count the fingerprints of every spend from an owner within a date range
+func existingFingerprints(ctx context.Context, ownerID string, from time.Time, to time.Time) (map[string]int, error) {
+	fingerprints := map[string]int{}
+
+	f := repository.SpendFilter{OwnerID: ownerID, From: from, To: to, Limit: repository.MaxSpendsLimit}
+	for {
+		page, err := repositories.Spends.Find(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range page.Spends {
+			fingerprints[spendFingerprint(s)]++
+		}
+
+		if page.NextCursor == "" {
+			return fingerprints, nil
+		}
+		f.Cursor = page.NextCursor
+	}
+}
+
+// ImportSpends will read the transactions of a CSV or OFX bank statement as spends of an owner_id,
+// categorized by its rules. Transactions matching the fingerprint of an existing spend are duplicates,
+// each existing spend matching a single one of them. Previews only report the transactions, while
+// commits create the new ones, provided they are given the token of a preview reporting the same ones
+func ImportSpends(parentCtx context.Context, ownerID string, imp repository.SpendImport, commit bool) (*repository.SpendImportResult, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("import.owner.id").String(ownerID),
+		attribute.Key("import.format").String(imp.Format),
+		attribute.Key("import.commit").Bool(commit),
+	}
+
+	spanCtx, span := observability.Span(parentCtx, "mongodb", "ImportSpends", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(spanCtx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return &repository.SpendImportResult{}, err
+	}
+
+	if sep := imp.Mapping.DecimalSeparator; sep != "" && sep != "." && sep != "," {
+		return &repository.SpendImportResult{}, errors.New("invalid import: the decimal separator must be '.' or ','")
+	}
+
+	var transactions []importTransaction
+	layout := dateLayout(imp.Mapping.DateFormat)
+
+	switch strings.ToLower(imp.Format) {
+	case repository.SpendImportFormatCSV:
+		transactions, err = readCSVTransactions(imp.Content, imp.Mapping)
+	case repository.SpendImportFormatOFX:
+		// OFX amounts always use '.' and have spends as negative amounts
+		imp.Mapping = repository.SpendImportMapping{}
+		layout = "20060102"
+		transactions, err = readOFXTransactions(imp.Content)
+	default:
+		err = errors.New("invalid import: format must be csv or ofx")
+	}
+	if err != nil {
+		return &repository.SpendImportResult{}, err
+	}
+
+	if len(transactions) == 0 {
+		return &repository.SpendImportResult{}, errors.New("invalid import: statement has no transactions")
+	}
+	if len(transactions) > maxImportTransactions {
+		return &repository.SpendImportResult{}, fmt.Errorf("invalid import: statements can have up to %d transactions", maxImportTransactions)
+	}
+
+	err = validatePaymentMethod(imp.PaymentMethod)
+	if err != nil {
+		return &repository.SpendImportResult{}, err
+	}
+
+	err = snapshotCard(ctx, oid, &imp.PaymentMethod)
+	if err != nil {
+		return &repository.SpendImportResult{}, err
+	}
+
+	rules, err := repositories.CategoryRules.Get(ctx, ownerID)
+	if err != nil {
+		return &repository.SpendImportResult{}, err
+	}
+	matchers := newCategoryRuleMatchers(rules)
+
+	if commit && imp.Token == "" {
+		return &repository.SpendImportResult{}, errors.New("invalid import: commits require the token of their preview")
+	}
+
+	result := repository.SpendImportResult{Committed: commit, Rows: make([]repository.SpendImportRow, 0, len(transactions))}
+
+	// categories are stored with the exact name of the owner categories, which rules may not match anymore
+	categories := map[primitive.ObjectID][]string{}
+	categorize := func(rule repository.CategoryRule) ([]string, error) {
+		if c, ok := categories[rule.ID]; ok {
+			return c, nil
+		}
+
+		c, err := canonicalCategories(ctx, oid, rule.Categories)
+		if err != nil {
+			return nil, err
+		}
+		categories[rule.ID] = c
+		return c, nil
+	}
+
+	var from, to time.Time
+	for _, t := range transactions {
+		row := importRow(t, imp, oid, layout)
+		if row.Status == repository.SpendImportStatusNew {
+			if rule, ok := matchCategoryRule(matchers, row.Spend); ok {
+				c, err := categorize(rule)
+				if err != nil && !strings.Contains(err.Error(), "invalid category") {
+					return &repository.SpendImportResult{}, err
+				}
+
+				// rules may assign free-form categories from before the owner had any, which can not be stored
+				if err != nil {
+					row.Status = repository.SpendImportStatusInvalid
+					row.Details = err.Error()
+				}
+				row.Spend.Categories = append([]string{}, c...)
+				row.Spend.Type = rule.Type
+			}
+		}
+
+		if row.Status == repository.SpendImportStatusNew {
+			if row.Spend.Type == "" {
+				row.Spend.Type = repository.SpendTypeDynamic
+			}
+
+			date := row.Spend.Date.Time()
+			if from.IsZero() || date.Before(from) {
+				from = date
+			}
+			if date.After(to) {
+				to = date
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	fingerprints := map[string]int{}
+	if !from.IsZero() {
+		// spends are matched by day, whatever time they were given
+		fingerprints, err = existingFingerprints(ctx, ownerID, from, to.AddDate(0, 0, 1).Add(-time.Millisecond))
+		if err != nil {
+			return &repository.SpendImportResult{}, err
+		}
+	}
+
+	for i := range result.Rows {
+		row := &result.Rows[i]
+
+		if row.Status == repository.SpendImportStatusNew && fingerprints[row.Fingerprint] > 0 {
+			fingerprints[row.Fingerprint]--
+			row.Status = repository.SpendImportStatusDuplicate
+		}
+	}
+
+	result.Token, err = importToken(result.Rows)
+	if err != nil {
+		return &repository.SpendImportResult{}, err
+	}
+
+	// statements, rules or spends changed since the preview would create other spends than the ones approved
+	if commit && result.Token != imp.Token {
+		return &repository.SpendImportResult{}, errors.New("invalid import: the spends imported changed since its preview, which must be made again")
+	}
+
+	for i := range result.Rows {
+		row := &result.Rows[i]
+
+		if commit && row.Status == repository.SpendImportStatusNew {
+			// each spend has a timeout of its own, so large statements are not bound to a single one
+			id, err := CreateSpend(spanCtx, row.Spend)
+			if err != nil {
+				row.Status = repository.SpendImportStatusFailed
+				row.Details = err.Error()
+			} else {
+				row.Status = repository.SpendImportStatusCreated
+				row.SpendID = id
+			}
+		}
+
+		switch row.Status {
+		case repository.SpendImportStatusNew:
+			result.New++
+		case repository.SpendImportStatusDuplicate:
+			result.Duplicates++
+		case repository.SpendImportStatusSkipped:
+			result.Skipped++
+		case repository.SpendImportStatusInvalid:
+			result.Invalid++
+		case repository.SpendImportStatusCreated:
+			result.Created++
+		case repository.SpendImportStatusFailed:
+			result.Failed++
+		}
+	}
+
+	if commit {
+		log.Infof("imported %d spends, skipping %d duplicates", result.Created, result.Duplicates)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"budget-tracker-api/repository"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReadCSVTransactions(t *testing.T) {
+	content := "Data;Histórico;Valor\n10/07/2021;\"Uber; airport\";-1.045,90\n11/07/2021;Salary;5.000,00\n"
+	mapping := repository.SpendImportMapping{Date: "data", Description: "HISTÓRICO", Amount: "Valor", DateFormat: "DD/MM/YYYY", Delimiter: ";", DecimalSeparator: ","}
+
+	transactions, err := readCSVTransactions(content, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("unexpected transactions: %+v", transactions)
+	}
+
+	imp := repository.SpendImport{Mapping: mapping}
+	spend := importRow(transactions[0], imp, primitive.NilObjectID, dateLayout(mapping.DateFormat))
+	if spend.Status != repository.SpendImportStatusNew || spend.Line != 2 || spend.Fingerprint != "2021-07-10|1045900|uber; airport" {
+		t.Errorf("unexpected spend: %+v", spend)
+	}
+
+	income := importRow(transactions[1], imp, primitive.NilObjectID, dateLayout(mapping.DateFormat))
+	if income.Status != repository.SpendImportStatusSkipped || income.Line != 3 {
+		t.Errorf("unexpected income: %+v", income)
+	}
+
+	for _, invalid := range []repository.SpendImportMapping{
+		{Date: "data", Description: "histórico"},
+		{Date: "data", Description: "histórico", Amount: "amount", Delimiter: ";"},
+		{Date: "data", Description: "histórico", Amount: "valor", Delimiter: ";;"},
+	} {
+		if _, err := readCSVTransactions(content, invalid); err == nil {
+			t.Errorf("mapping %+v was accepted", invalid)
+		}
+	}
+}
+
+func TestReadOFXTransactions(t *testing.T) {
+	content := `OFXHEADER:100
+DATA:OFXSGML
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>brl
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20210710120000[-3:BRT]
+<TRNAMT>-45.90
+<FITID>1
+<MEMO>UBER *TRIP
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20210711
+<TRNAMT>+100.00
+<NAME>Refund</NAME>
+<MEMO>store refund</MEMO>
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+	transactions, err := readOFXTransactions(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, tr := range transactions {
+		row := importRow(tr, repository.SpendImport{}, primitive.NilObjectID, "20060102")
+		got = append(got, fmt.Sprintf("%d %s %s %s", row.Line, row.Status, row.Fingerprint, row.Spend.Currency))
+	}
+
+	want := "[1 new 2021-07-10|45900|uber *trip BRL 2 skipped  ]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("unexpected transactions: got %v want %v", got, want)
+	}
+
+	if _, err := readOFXTransactions("Date,Amount"); err == nil {
+		t.Error("content which is not OFX was accepted")
+	}
+}
+
+func TestConvertedSpendsAreFingerprintedByTheirOriginalCost(t *testing.T) {
+	date := primitive.NewDateTimeFromTime(time.Date(2021, 7, 10, 15, 0, 0, 0, time.UTC))
+
+	spend := repository.Spend{Description: "Hotel  Lisboa", Cost: repository.NewMoney(50.32), Currency: "BRL", Date: date}
+	if got := spendFingerprint(spend); got != "2021-07-10|50320|hotel lisboa" {
+		t.Errorf("unexpected fingerprint: %s", got)
+	}
+
+	spend.OriginalCost = repository.NewMoney(10)
+	spend.OriginalCurrency = "USD"
+	if got := spendFingerprint(spend); got != "2021-07-10|10000|hotel lisboa" {
+		t.Errorf("unexpected fingerprint of converted spend: %s", got)
+	}
+}
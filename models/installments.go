@@ -0,0 +1,193 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxInstallments defines in how many monthly installments a purchase can be split at most
+	maxInstallments = 48
+	// maxCommitmentMonths defines how many months commitments can be listed for at once
+	maxCommitmentMonths = 120
+)
+
+// splitInstallments will split a cost in equal installments of a currency minor unit, where the
+// first installment takes the remainder. Costs without a currency are split in cents
+func splitInstallments(cost repository.Money, installments int64, currency string) []repository.Money {
+	unit := repository.MinorUnit(currency)
+	if currency == "" {
+		unit = repository.MinorUnit("USD")
+	}
+	each := cost / repository.Money(installments) / unit * unit
+
+	costs := make([]repository.Money, installments)
+	for i := range costs {
+		costs[i] = each
+	}
+	costs[0] = cost - each*repository.Money(installments-1)
+
+	return costs
+}
+
+// installmentDate will return the date of an installment, one month after the other. Shorter
+// months use their last day
+func installmentDate(purchase time.Time, index int) time.Time {
+	purchase = purchase.UTC()
+	first := time.Date(purchase.Year(), purchase.Month()+time.Month(index), 1, purchase.Hour(), purchase.Minute(), purchase.Second(), purchase.Nanosecond(), time.UTC)
+
+	d := purchase.Day()
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+
+	return first.AddDate(0, 0, d-1)
+}
+
+// createInstallments will create one spend per monthly installment of a credit card purchase,
+// returning the first one. Installments already created are deleted when any of them fails
+func createInstallments(ctx context.Context, s repository.Spend) (id string, err error) {
+	if s.PaymentMethod.Credit.ID.IsZero() {
+		return "", errors.New("installments require a credit card payment method")
+	}
+
+	// the card was already snapshotted by CreateSpend
+	s.PaymentMethod = repository.PaymentMethod{Credit: s.PaymentMethod.Credit}
+
+	// foreign purchases are converted once, at the purchase date
+	err = reconcileSpendCurrency(ctx, &s)
+	if err != nil {
+		return "", err
+	}
+
+	purchaseID := primitive.NewObjectID()
+	costs := splitInstallments(s.Cost, s.Installments, s.Currency)
+
+	var originalCosts []repository.Money
+	if s.OriginalCurrency != "" {
+		originalCosts = splitInstallments(s.OriginalCost, s.Installments, s.OriginalCurrency)
+	}
+
+	ids := make([]string, 0, len(costs))
+	for i, cost := range costs {
+		installment := s
+		installment.Installments = 0
+		installment.Cost = cost
+		installment.Date = primitive.NewDateTimeFromTime(installmentDate(s.Date.Time(), i))
+		installment.Installment = &repository.Installment{
+			PurchaseID:   purchaseID,
+			Number:       int64(i + 1),
+			Total:        s.Installments,
+			PurchaseCost: s.Cost,
+		}
+
+		var id string
+		if originalCosts != nil {
+			// reconciling each installment again would undo the conversion of the purchase
+			installment.ID = primitive.NewObjectID()
+			installment.OriginalCost = originalCosts[i]
+			id, err = storeSpend(ctx, installment)
+		} else {
+			id, err = createSpend(ctx, installment)
+		}
+		if err != nil {
+			for _, created := range ids {
+				if rerr := DeleteSpend(ctx, s.OwnerID.Hex(), created); rerr != nil {
+					log.Errorln("could not revert installment", created, rerr)
+				}
+			}
+			return "", err
+		}
+		ids = append(ids, id)
+	}
+
+	log.Infoln("created purchase", purchaseID.Hex(), "in", len(ids), "installments")
+	return ids[0], nil
+}
+
+// periodIndex will return a sequential number for a month, so periods can be compared and iterated
+func periodIndex(month int64, year int64) int64 {
+	return year*12 + month - 1
+}
+
+// GetCommitments will return the outcome each month of a period is already committed to, either by
+// installments accounted in its balance or by runs of active recurrences still to be materialized
+func GetCommitments(parentCtx context.Context, f repository.BalanceFilter) ([]repository.Commitment, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("balance.owner.id").String(f.OwnerID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetCommitments", spanTags)
+	defer span.End()
+
+	first := periodIndex(f.From.Month, f.From.Year)
+	last := periodIndex(f.To.Month, f.To.Year)
+	if last < first || last-first >= maxCommitmentMonths {
+		return []repository.Commitment{}, errors.New("commitments period must have between 1 and 120 months")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	commitments := make([]repository.Commitment, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		commitments = append(commitments, repository.Commitment{Month: i%12 + 1, Year: i / 12})
+	}
+
+	balances, err := repositories.Balances.List(ctx, f)
+	if err != nil {
+		return []repository.Commitment{}, err
+	}
+
+	for _, b := range balances {
+		c := &commitments[periodIndex(b.Month, b.Year)-first]
+		c.Currency = b.Currency
+		for _, s := range b.Historic {
+			if s.Installment != nil {
+				c.Installments += s.Cost
+			}
+		}
+	}
+
+	recurrences, err := repositories.Recurrences.Get(ctx, f.OwnerID)
+	if err != nil {
+		return []repository.Commitment{}, err
+	}
+
+	from := time.Date(int(f.From.Year), time.Month(f.From.Month), 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(int(f.To.Year), time.Month(f.To.Month)+1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, r := range recurrences {
+		if r.Status != repository.RecurrenceStatusActive {
+			continue
+		}
+
+		run := r.NextRun.Time()
+		if run.Before(from) {
+			run = occurrence(r, from)
+		}
+
+		for run.Before(until) && (r.End == 0 || !run.After(r.End.Time())) {
+			c := &commitments[periodIndex(int64(run.Month()), int64(run.Year()))-first]
+			c.Recurring += r.Cost
+			if c.Currency == "" {
+				c.Currency = r.Currency
+			}
+			run = occurrence(r, run.AddDate(0, 0, 1))
+		}
+	}
+
+	for i := range commitments {
+		commitments[i].Total = commitments[i].Installments + commitments[i].Recurring
+	}
+
+	return commitments, nil
+}
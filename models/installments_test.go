@@ -0,0 +1,55 @@
+package models
+
+import (
+	"budget-tracker-api/repository"
+	"testing"
+	"time"
+)
+
+func TestSplitInstallments(t *testing.T) {
+	tests := []struct {
+		cost         repository.Money
+		installments int64
+		currency     string
+		expected     []repository.Money
+	}{
+		{1000000, 4, "BRL", []repository.Money{250000, 250000, 250000, 250000}},
+		{100000, 3, "BRL", []repository.Money{33340, 33330, 33330}},
+		{100000, 3, "JPY", []repository.Money{34000, 33000, 33000}},
+		{100000, 3, "", []repository.Money{33340, 33330, 33330}},
+	}
+
+	for _, tt := range tests {
+		costs := splitInstallments(tt.cost, tt.installments, tt.currency)
+
+		var sum repository.Money
+		for i, c := range costs {
+			sum += c
+			if c != tt.expected[i] {
+				t.Errorf("unexpected installments of %s in %s: %v", tt.cost, tt.currency, costs)
+				break
+			}
+		}
+
+		if sum != tt.cost {
+			t.Errorf("installments of %s in %s do not sum up to it: %v", tt.cost, tt.currency, costs)
+		}
+	}
+}
+
+func TestInstallmentDateKeepsPurchaseDay(t *testing.T) {
+	purchase := time.Date(2021, 1, 31, 15, 0, 0, 0, time.UTC)
+
+	expected := []time.Time{
+		time.Date(2021, 1, 31, 15, 0, 0, 0, time.UTC),
+		time.Date(2021, 2, 28, 15, 0, 0, 0, time.UTC),
+		time.Date(2021, 3, 31, 15, 0, 0, 0, time.UTC),
+		time.Date(2022, 1, 31, 15, 0, 0, 0, time.UTC),
+	}
+
+	for i, index := range []int{0, 1, 2, 12} {
+		if got := installmentDate(purchase, index); !got.Equal(expected[i]) {
+			t.Errorf("unexpected date of installment %d: got %v want %v", index+1, got, expected[i])
+		}
+	}
+}
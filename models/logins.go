@@ -0,0 +1,134 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxLoginFailures defines how many consecutive failures are allowed before locking a login
+	maxLoginFailures = 5
+	// loginLockout defines the first lockout duration, doubled on every further failure
+	loginLockout = 1 * time.Minute
+	// maxLoginLockout defines the longest lockout duration
+	maxLoginLockout = 24 * time.Hour
+	// loginFailuresTTL defines for how long failures are kept without new ones
+	loginFailuresTTL = 24 * time.Hour
+)
+
+// LoginLockout will return for how long a login is locked after a number of consecutive failures,
+// growing exponentially once maxLoginFailures is reached
+func LoginLockout(failures int64) time.Duration {
+	if failures < maxLoginFailures {
+		return 0
+	}
+
+	lockout := loginLockout
+	for i := int64(maxLoginFailures); i < failures; i++ {
+		lockout *= 2
+		if lockout >= maxLoginLockout {
+			return maxLoginLockout
+		}
+	}
+
+	return lockout
+}
+
+// GetLoginAttempt will return the failed attempts from a login, which is empty when there is none
+func GetLoginAttempt(parentCtx context.Context, login string) (*repository.LoginAttempt, error) {
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetLoginAttempt", []attribute.KeyValue{})
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	attempt, err := repositories.LoginAttempts.Get(ctx, login)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find login attempt") {
+			return &repository.LoginAttempt{ID: login}, nil
+		}
+		return &repository.LoginAttempt{}, err
+	}
+
+	return &attempt, nil
+}
+
+// RegisterLoginFailure will account a failed login, locking it when too many consecutive failures happened
+func RegisterLoginFailure(parentCtx context.Context, login string) (*repository.LoginAttempt, error) {
+	ctx, span := observability.Span(parentCtx, "mongodb", "RegisterLoginFailure", []attribute.KeyValue{})
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	observability.Metrics.Users.LoginsFailed.Inc()
+
+	repo := repositories.LoginAttempts
+
+	t := time.Now()
+	attempt, err := repo.RegisterFailure(ctx, login, t, t.Add(loginFailuresTTL))
+	if err != nil {
+		return &repository.LoginAttempt{}, err
+	}
+
+	span.SetAttributes(attribute.Key("login.failures").Int64(attempt.Failures))
+
+	lockout := LoginLockout(attempt.Failures)
+	if lockout == 0 {
+		return &attempt, nil
+	}
+
+	until := t.Add(lockout)
+	err = repo.Lock(ctx, login, until)
+	if err != nil {
+		return &repository.LoginAttempt{}, err
+	}
+
+	attempt.LockedUntil = primitive.NewDateTimeFromTime(until)
+
+	observability.Metrics.Users.LoginsLocked.Inc()
+	log.Warnf("locked login '%s' for %s after %d failures", login, lockout, attempt.Failures)
+	return &attempt, nil
+}
+
+// ResetLoginFailures will forget every failed attempt from a login, unlocking it
+func ResetLoginFailures(parentCtx context.Context, login string) error {
+	ctx, span := observability.Span(parentCtx, "mongodb", "ResetLoginFailures", []attribute.KeyValue{})
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	return repositories.LoginAttempts.Reset(ctx, login)
+}
+
+// UnlockUser will unlock the login from a given user
+func UnlockUser(parentCtx context.Context, id string) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("user.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "UnlockUser", spanTags)
+	defer span.End()
+
+	u, err := GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = ResetLoginFailures(ctx, u.Login)
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("unlocked user", u.Login)
+	return nil
+}
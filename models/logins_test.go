@@ -0,0 +1,27 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginLockoutGrowsExponentially(t *testing.T) {
+	tests := []struct {
+		failures int64
+		lockout  time.Duration
+	}{
+		{0, 0},
+		{maxLoginFailures - 1, 0},
+		{maxLoginFailures, time.Minute},
+		{maxLoginFailures + 1, 2 * time.Minute},
+		{maxLoginFailures + 3, 8 * time.Minute},
+		{maxLoginFailures + 20, maxLoginLockout},
+		{1000, maxLoginLockout},
+	}
+
+	for _, tt := range tests {
+		if lockout := LoginLockout(tt.failures); lockout != tt.lockout {
+			t.Errorf("unexpected lockout for %d failures: got %v want %v", tt.failures, lockout, tt.lockout)
+		}
+	}
+}
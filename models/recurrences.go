@@ -0,0 +1,414 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxRecurrenceRunsPerRequest defines how many runs already due are materialized while creating a
+// recurrence, leaving the others to the scheduler so the request never times out
+const maxRecurrenceRunsPerRequest = 12
+
+// occurrence will return the first day a recurrence happens at, starting from a given day
+func occurrence(r repository.Recurrence, from time.Time) time.Time {
+	start := day(r.Start.Time())
+	from = day(from)
+	if from.Before(start) {
+		from = start
+	}
+
+	// the day of month is kept even when previous months were shorter
+	at := func(year int, month time.Month) time.Time {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		d := int(r.Day)
+		if d > last {
+			d = last
+		}
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	switch r.Cadence {
+	case repository.RecurrenceCadenceWeekly:
+		weeks := (from.Sub(start) + 7*24*time.Hour - 1) / (7 * 24 * time.Hour)
+		return start.Add(weeks * 7 * 24 * time.Hour)
+	case repository.RecurrenceCadenceYearly:
+		t := at(from.Year(), start.Month())
+		if t.Before(from) {
+			t = at(from.Year()+1, start.Month())
+		}
+		return t
+	default:
+		t := at(from.Year(), from.Month())
+		if t.Before(from) {
+			t = at(from.Year(), from.Month()+1)
+		}
+		return t
+	}
+}
+
+// schedule will point a recurrence to its first run from a given day, finishing it when it's past its end
+func schedule(r *repository.Recurrence, from time.Time) {
+	next := occurrence(*r, from)
+	r.NextRun = primitive.NewDateTimeFromTime(next)
+	if r.End != 0 && next.After(r.End.Time()) {
+		r.Status = repository.RecurrenceStatusFinished
+	}
+}
+
+// validateRecurrence will validate a recurrence and fill its defaults
+func validateRecurrence(r *repository.Recurrence) error {
+	switch r.Cadence {
+	case repository.RecurrenceCadenceWeekly, repository.RecurrenceCadenceMonthly, repository.RecurrenceCadenceYearly:
+	default:
+		return errors.New("invalid recurrence: cadence must be one of 'weekly', 'monthly' or 'yearly'")
+	}
+
+	if r.Type == "" {
+		r.Type = repository.SpendTypeFixed
+	}
+
+	if r.Type != repository.SpendTypeFixed && r.Type != repository.SpendTypeDynamic {
+		return errors.New("invalid recurrence: type must be either 'fixed' or 'dynamic'")
+	}
+
+	if r.Cost <= 0 {
+		return errors.New("invalid recurrence: cost must be positive")
+	}
+
+	if r.Start == 0 {
+		return errors.New("invalid recurrence: start must be informed")
+	}
+	r.Start = primitive.NewDateTimeFromTime(day(r.Start.Time()))
+
+	if r.Day == 0 {
+		r.Day = int64(r.Start.Time().Day())
+	}
+
+	if r.Day < 1 || r.Day > 31 {
+		return errors.New("invalid recurrence: day must be between 1 and 31")
+	}
+
+	if r.End != 0 {
+		r.End = primitive.NewDateTimeFromTime(day(r.End.Time()))
+		if r.End < r.Start {
+			return errors.New("invalid recurrence: end must not be before start")
+		}
+	}
+
+	if r.Currency != "" {
+		currency, err := repository.NormalizeCurrency(r.Currency)
+		if err != nil {
+			return err
+		}
+		r.Currency = currency
+
+		err = repository.ValidateAmount(r.Cost, r.Currency)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateRecurrence creates a recurrence for a given owner_id, materializing up to a limit of the spends
+// already due. The ones left pending are materialized by the scheduler
+func CreateRecurrence(parentCtx context.Context, r repository.Recurrence) (id string, created int, pending int, err error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("recurrence.owner.id").String(r.OwnerID.String()),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "CreateRecurrence", spanTags)
+	defer span.End()
+
+	err = validateRecurrence(&r)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	err = validatePaymentMethod(r.PaymentMethod)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	err = snapshotCard(ctx, r.OwnerID, &r.PaymentMethod)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	r.Categories, err = canonicalCategories(ctx, r.OwnerID, r.Categories)
+	if err != nil {
+		return "", 0, 0, err
+	}
+
+	now := time.Now()
+	r.ID = primitive.NewObjectID()
+	r.Status = repository.RecurrenceStatusActive
+	r.CreatedAt = primitive.NewDateTimeFromTime(now)
+	r.UpdatedAt = r.CreatedAt
+	schedule(&r, r.Start.Time())
+
+	id, err = createRecurrence(ctx, r)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	span.SetAttributes(attribute.Key("recurrence.id").String(id))
+
+	log.Infoln("created recurrence", id)
+
+	due := dueRuns(r, now)
+	created, err = materializeRecurrence(ctx, r, now, maxRecurrenceRunsPerRequest)
+	if err != nil {
+		log.Errorln("could not materialize recurrence", id, err)
+	}
+
+	return id, created, due - created, nil
+}
+
+// createRecurrence will store a recurrence within its own timeout
+func createRecurrence(parentCtx context.Context, r repository.Recurrence) (id string, err error) {
+	ctx, cancel := context.WithTimeout(parentCtx, services.MongodbTimeout)
+	defer cancel()
+
+	return repositories.Recurrences.Create(ctx, r)
+}
+
+// GetRecurrences will return every recurrence from a specific owner_id
+func GetRecurrences(parentCtx context.Context, ownerID string) ([]repository.Recurrence, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("recurrence.owner.id").String(ownerID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetRecurrences", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	return repositories.Recurrences.Get(ctx, ownerID)
+}
+
+// updateRecurrenceStatus will move a recurrence from one of the allowed statuses into another
+func updateRecurrenceStatus(parentCtx context.Context, ownerID string, id string, status string, allowed ...string) (*repository.Recurrence, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("recurrence.owner.id").String(ownerID),
+		attribute.Key("recurrence.id").String(id),
+		attribute.Key("recurrence.status").String(status),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "UpdateRecurrenceStatus", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := repositories.Recurrences.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Recurrence{}, err
+	}
+
+	valid := false
+	for _, s := range allowed {
+		valid = valid || r.Status == s
+	}
+
+	if !valid {
+		return &repository.Recurrence{}, fmt.Errorf("recurrence is %s and can not be %s", r.Status, status)
+	}
+
+	now := time.Now()
+	previousStatus, previous := r.Status, r.NextRun
+	r.Status = status
+	r.UpdatedAt = primitive.NewDateTimeFromTime(now)
+
+	// runs missed while paused are skipped
+	if status == repository.RecurrenceStatusActive {
+		schedule(&r, now)
+	}
+
+	err = repositories.Recurrences.Schedule(ctx, r, previousStatus, previous)
+	if err != nil {
+		return &repository.Recurrence{}, err
+	}
+
+	log.Infoln("recurrence", id, "is", r.Status)
+
+	if r.Status == repository.RecurrenceStatusActive {
+		_, err = materializeRecurrence(ctx, r, now, 0)
+		if err != nil {
+			log.Errorln("could not materialize recurrence", id, err)
+		}
+	}
+
+	return &r, nil
+}
+
+// PauseRecurrence will stop materializing spends from an active recurrence until resumed
+func PauseRecurrence(parentCtx context.Context, ownerID string, id string) (*repository.Recurrence, error) {
+	return updateRecurrenceStatus(parentCtx, ownerID, id, repository.RecurrenceStatusPaused, repository.RecurrenceStatusActive)
+}
+
+// ResumeRecurrence will materialize spends from a paused recurrence again, starting from today
+func ResumeRecurrence(parentCtx context.Context, ownerID string, id string) (*repository.Recurrence, error) {
+	return updateRecurrenceStatus(parentCtx, ownerID, id, repository.RecurrenceStatusActive, repository.RecurrenceStatusPaused)
+}
+
+// CancelRecurrence will stop a recurrence for good, keeping the spends it already materialized
+func CancelRecurrence(parentCtx context.Context, ownerID string, id string) (*repository.Recurrence, error) {
+	return updateRecurrenceStatus(
+		parentCtx, ownerID, id, repository.RecurrenceStatusCancelled,
+		repository.RecurrenceStatusActive, repository.RecurrenceStatusPaused,
+	)
+}
+
+// materializeRecurrence will create a spend for every run of a recurrence until a given time, up to a
+// limit of runs unless it's zero. Each run is claimed before its spend is created, so concurrent
+// schedulers never create it twice
+func materializeRecurrence(ctx context.Context, r repository.Recurrence, until time.Time, limit int) (created int, err error) {
+	for r.Status == repository.RecurrenceStatusActive && !r.NextRun.Time().After(until) {
+		if limit > 0 && created >= limit {
+			return created, nil
+		}
+
+		claimed := r
+		claimed.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+		schedule(&claimed, r.NextRun.Time().AddDate(0, 0, 1))
+
+		stop, err := materializeRun(ctx, r, claimed)
+		if err != nil || stop {
+			return created, err
+		}
+
+		created++
+		r = claimed
+	}
+
+	return created, nil
+}
+
+// materializeRun will claim the next run of a recurrence and create its spend, returning if the
+// recurrence must not be materialized any further. Every run has its own timeout, so recurrences
+// with many runs due are never cut partway
+func materializeRun(parentCtx context.Context, r repository.Recurrence, claimed repository.Recurrence) (stop bool, err error) {
+	ctx, cancel := context.WithTimeout(parentCtx, services.MongodbTimeout)
+	defer cancel()
+
+	run := r.NextRun
+
+	err = repositories.Recurrences.Schedule(ctx, claimed, repository.RecurrenceStatusActive, run)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find recurrence") {
+			// another scheduler claimed the run, or the recurrence was changed meanwhile
+			return true, nil
+		}
+		return true, err
+	}
+
+	_, err = CreateSpend(ctx, repository.Spend{
+		OwnerID:       r.OwnerID,
+		Type:          r.Type,
+		Description:   r.Description,
+		Cost:          r.Cost,
+		Currency:      r.Currency,
+		PaymentMethod: r.PaymentMethod,
+		Categories:    r.Categories,
+		Date:          run,
+		RecurrenceID:  r.ID,
+	})
+	if err != nil {
+		// releases the run, so it's retried by the next scheduler run, unless it can never succeed
+		released := r
+		if isPermanentRecurrenceError(err) {
+			released.Status = repository.RecurrenceStatusPaused
+			released.UpdatedAt = claimed.UpdatedAt
+		}
+
+		if rerr := repositories.Recurrences.Schedule(ctx, released, claimed.Status, claimed.NextRun); rerr != nil {
+			log.Errorln("could not release recurrence run", r.ID.Hex(), rerr)
+			return true, err
+		}
+
+		if released.Status == repository.RecurrenceStatusPaused {
+			log.Warnln("recurrence", r.ID.Hex(), "is paused, its spends can not be created:", err)
+			return true, nil
+		}
+		return true, err
+	}
+
+	return false, nil
+}
+
+// dueRuns will count the runs of a recurrence from its next run until a given time
+func dueRuns(r repository.Recurrence, until time.Time) (runs int) {
+	for r.Status == repository.RecurrenceStatusActive && !r.NextRun.Time().After(until) {
+		runs++
+		schedule(&r, r.NextRun.Time().AddDate(0, 0, 1))
+	}
+
+	return runs
+}
+
+// isPermanentRecurrenceError will return if a recurrence spend failed for a reason retrying does not fix,
+// such as an archived card, rather than a transient one such as a missing exchange rate
+func isPermanentRecurrenceError(err error) bool {
+	return strings.Contains(err.Error(), "could not find card") ||
+		strings.Contains(err.Error(), "card is archived") ||
+		strings.Contains(err.Error(), "invalid category") ||
+		strings.Contains(err.Error(), "ISO-4217") ||
+		strings.Contains(err.Error(), "decimal places") ||
+		strings.Contains(err.Error(), "does not match balance currency")
+}
+
+// MaterializeRecurrences will create the spends of every active recurrence due until a given time
+func MaterializeRecurrences(parentCtx context.Context, until time.Time) (created int, err error) {
+	ctx, span := observability.Span(parentCtx, "mongodb", "MaterializeRecurrences", []attribute.KeyValue{})
+	defer span.End()
+
+	recurrences, err := repositories.Recurrences.Due(ctx, until)
+	if err != nil {
+		return 0, err
+	}
+
+	// a failing recurrence (e.g. missing exchange rate) does not hold the others back
+	for _, r := range recurrences {
+		n, err := materializeRecurrence(ctx, r, until, 0)
+		created += n
+		if err != nil {
+			log.Errorln("could not materialize recurrence", r.ID.Hex(), err)
+		}
+	}
+
+	span.SetAttributes(attribute.Key("recurrence.spends").Int(created))
+	return created, nil
+}
+
+// RunRecurrenceScheduler will materialize due recurrences on every interval until the context is done
+func RunRecurrenceScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := MaterializeRecurrences(ctx, time.Now())
+		if err != nil {
+			log.Errorln("could not materialize recurrences", err)
+		} else if created > 0 {
+			log.Infoln("materialized recurring spends", created)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	date := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		cadence  string
+		start    time.Time
+		day      int64
+		from     time.Time
+		expected time.Time
+	}{
+		{"monthly before start", repository.RecurrenceCadenceMonthly, date(2021, 5, 10), 10, date(2021, 1, 1), date(2021, 5, 10)},
+		{"monthly same day", repository.RecurrenceCadenceMonthly, date(2021, 5, 10), 10, date(2021, 6, 10), date(2021, 6, 10)},
+		{"monthly after day", repository.RecurrenceCadenceMonthly, date(2021, 5, 10), 10, date(2021, 6, 11), date(2021, 7, 10)},
+		{"monthly shorter month", repository.RecurrenceCadenceMonthly, date(2021, 1, 31), 31, date(2021, 2, 1), date(2021, 2, 28)},
+		{"monthly day kept", repository.RecurrenceCadenceMonthly, date(2021, 1, 31), 31, date(2021, 3, 1), date(2021, 3, 31)},
+		{"monthly across years", repository.RecurrenceCadenceMonthly, date(2021, 5, 10), 10, date(2021, 12, 11), date(2022, 1, 10)},
+		{"weekly", repository.RecurrenceCadenceWeekly, date(2021, 5, 3), 3, date(2021, 5, 4), date(2021, 5, 10)},
+		{"weekly same day", repository.RecurrenceCadenceWeekly, date(2021, 5, 3), 3, date(2021, 5, 17), date(2021, 5, 17)},
+		{"yearly", repository.RecurrenceCadenceYearly, date(2020, 2, 29), 29, date(2020, 3, 1), date(2021, 2, 28)},
+	}
+
+	for _, tt := range tests {
+		r := repository.Recurrence{Cadence: tt.cadence, Day: tt.day, Start: primitive.NewDateTimeFromTime(tt.start)}
+		if got := occurrence(r, tt.from); !got.Equal(tt.expected) {
+			t.Errorf("%s: got %v want %v", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestMaterializeRecurrencesCreatesEveryRunOnce(t *testing.T) {
+	UseRepositories(repository.NewMemoryRepositories())
+	observability.InitMetrics()
+
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+	start := time.Date(2021, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	id, created, pending, err := CreateRecurrence(ctx, repository.Recurrence{
+		OwnerID:     owner,
+		Description: "guitar lessons",
+		Cost:        repository.NewMoney(150),
+		Cadence:     repository.RecurrenceCadenceMonthly,
+		Start:       primitive.NewDateTimeFromTime(start),
+		End:         primitive.NewDateTimeFromTime(start.AddDate(0, 3, 0)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created != 4 || pending != 0 {
+		t.Errorf("unexpected runs materialized along with the recurrence: %d created, %d pending", created, pending)
+	}
+
+	// runs already created along with the recurrence are never created again
+	for i := 0; i < 2; i++ {
+		created, err := MaterializeRecurrences(ctx, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if created != 0 {
+			t.Errorf("runs were materialized twice: %d", created)
+		}
+	}
+
+	spends, err := repositories.Spends.Get(ctx, owner.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spends) != 4 {
+		t.Fatalf("unexpected spends: %+v", spends)
+	}
+
+	for _, s := range spends {
+		if s.RecurrenceID.Hex() != id || !s.IsFixed() || s.Cost != 150000 {
+			t.Errorf("unexpected materialized spend: %+v", s)
+		}
+	}
+
+	balance, err := repositories.Balances.Get(ctx, owner.Hex(), 8, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance.Outcome.FixedOutcome != 150000 {
+		t.Errorf("unexpected balance: %+v", balance)
+	}
+
+	recurrences, err := GetRecurrences(ctx, owner.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recurrences) != 1 || recurrences[0].Status != repository.RecurrenceStatusFinished {
+		t.Errorf("recurrence did not finish after its end: %+v", recurrences)
+	}
+}
+
+func TestRecurrencesLeaveRunsPastTheLimitToTheScheduler(t *testing.T) {
+	UseRepositories(repository.NewMemoryRepositories())
+
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+	start := time.Now().UTC().AddDate(-2, 0, 0)
+
+	_, created, pending, err := CreateRecurrence(ctx, repository.Recurrence{
+		OwnerID:     owner,
+		Description: "cleaning",
+		Cost:        repository.NewMoney(80),
+		Cadence:     repository.RecurrenceCadenceWeekly,
+		Start:       primitive.NewDateTimeFromTime(start),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created != maxRecurrenceRunsPerRequest || pending < 90 {
+		t.Fatalf("unexpected runs materialized along with the recurrence: %d created, %d pending", created, pending)
+	}
+
+	materialized, err := MaterializeRecurrences(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if materialized != pending {
+		t.Errorf("scheduler did not materialize the pending runs: got %d want %d", materialized, pending)
+	}
+
+	spends, err := repositories.Spends.Get(ctx, owner.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(spends) != created+pending {
+		t.Errorf("unexpected spends: got %d want %d", len(spends), created+pending)
+	}
+}
+
+func TestRecurrencesFailingForGoodArePaused(t *testing.T) {
+	UseRepositories(repository.NewMemoryRepositories())
+
+	ctx := context.Background()
+	ownerID := primitive.NewObjectID()
+	start := time.Date(2021, time.May, 10, 0, 0, 0, 0, time.UTC)
+
+	_, err := CreateBalance(ctx, repository.Balance{OwnerID: ownerID, Month: 5, Year: 2021, Currency: "BRL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		currency string
+		method   repository.PaymentMethod
+		status   string
+	}{
+		{"missing card", "BRL", repository.PaymentMethod{Credit: repository.CreditCard{ID: primitive.NewObjectID()}}, repository.RecurrenceStatusPaused},
+		{"missing exchange rate", "JPY", repository.PaymentMethod{Debit: true}, repository.RecurrenceStatusActive},
+	}
+
+	for _, tt := range tests {
+		r := repository.Recurrence{
+			OwnerID:       ownerID,
+			Type:          "fixed",
+			Description:   tt.name,
+			Cost:          repository.NewMoney(10),
+			Currency:      tt.currency,
+			PaymentMethod: tt.method,
+			Cadence:       repository.RecurrenceCadenceMonthly,
+			Day:           10,
+			Start:         primitive.NewDateTimeFromTime(start),
+			Status:        repository.RecurrenceStatusActive,
+			NextRun:       primitive.NewDateTimeFromTime(start),
+		}
+
+		id, err := repositories.Recurrences.Create(ctx, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		created, err := MaterializeRecurrences(ctx, start.AddDate(0, 0, 1))
+		if err != nil || created != 0 {
+			t.Fatalf("%s: unexpected materialization: %d %v", tt.name, created, err)
+		}
+
+		got, err := repositories.Recurrences.GetByID(ctx, ownerID.Hex(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.Status != tt.status || !got.NextRun.Time().Equal(start) {
+			t.Errorf("%s: unexpected recurrence: %+v", tt.name, got)
+		}
+	}
+}
+
+func TestRecurrencesPausedWhileMaterializingStayPaused(t *testing.T) {
+	UseRepositories(repository.NewMemoryRepositories())
+
+	ctx := context.Background()
+	ownerID := primitive.NewObjectID()
+	start := time.Date(2021, time.May, 10, 0, 0, 0, 0, time.UTC)
+
+	id, err := repositories.Recurrences.Create(ctx, repository.Recurrence{
+		OwnerID:       ownerID,
+		Type:          "fixed",
+		Description:   "gym",
+		Cost:          repository.NewMoney(10),
+		Currency:      "BRL",
+		PaymentMethod: repository.PaymentMethod{Debit: true},
+		Cadence:       repository.RecurrenceCadenceMonthly,
+		Day:           10,
+		Start:         primitive.NewDateTimeFromTime(start),
+		Status:        repository.RecurrenceStatusActive,
+		NextRun:       primitive.NewDateTimeFromTime(start),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due, err := repositories.Recurrences.Due(ctx, start.AddDate(0, 0, 1))
+	if err != nil || len(due) != 1 {
+		t.Fatalf("unexpected due recurrences: %+v %v", due, err)
+	}
+
+	// the owner pauses the recurrence after the scheduler loaded it, but before it claims the run
+	_, err = PauseRecurrence(ctx, ownerID.Hex(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := materializeRecurrence(ctx, due[0], start.AddDate(0, 0, 1), 0)
+	if err != nil || created != 0 {
+		t.Fatalf("a paused recurrence was materialized: %d %v", created, err)
+	}
+
+	got, err := repositories.Recurrences.GetByID(ctx, ownerID.Hex(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Status != repository.RecurrenceStatusPaused {
+		t.Errorf("recurrence was revived: %+v", got)
+	}
+
+	spends, err := repositories.Spends.Get(ctx, ownerID.Hex())
+	if err == nil || len(spends) != 0 {
+		t.Errorf("unexpected spends: %+v", spends)
+	}
+}
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// reportGroups defines which report attribute holds the totals of each group
+var reportGroups = []string{
+	repository.SpendGroupCurrency,
+	repository.SpendGroupCategory,
+	repository.SpendGroupPaymentMethod,
+	repository.SpendGroupCard,
+	repository.SpendGroupType,
+}
+
+// reportRange will return the first and the last instant of a period starting at a given date
+func reportRange(from time.Time, months int) (time.Time, time.Time) {
+	return from, from.AddDate(0, months, 0).Add(-time.Millisecond)
+}
+
+// compareTotals will fill how much each of the current totals changed since the previous ones, matching
+// them by key and currency. Groups only found at the previous totals are kept with a zero total
+func compareTotals(current []repository.SpendTotal, previous []repository.SpendTotal) []repository.SpendTotal {
+	type groupKey struct {
+		key      string
+		currency string
+	}
+
+	totals := make([]repository.SpendTotal, 0, len(current))
+	index := map[groupKey]int{}
+	for _, total := range current {
+		index[groupKey{total.Key, total.Currency}] = len(totals)
+		totals = append(totals, total)
+	}
+
+	for _, total := range previous {
+		k := groupKey{total.Key, total.Currency}
+		if i, ok := index[k]; ok {
+			totals[i].Previous = total.Total
+			continue
+		}
+
+		index[k] = len(totals)
+		totals = append(totals, repository.SpendTotal{Key: total.Key, Currency: total.Currency, Previous: total.Total})
+	}
+
+	for i := range totals {
+		totals[i].Delta = totals[i].Total - totals[i].Previous
+	}
+
+	return totals
+}
+
+// sortTotals will sort totals from the most expensive to the cheapest one
+func sortTotals(totals []repository.SpendTotal) {
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Total != totals[j].Total {
+			return totals[i].Total > totals[j].Total
+		}
+		if totals[i].Key != totals[j].Key {
+			return totals[i].Key < totals[j].Key
+		}
+		return totals[i].Currency < totals[j].Currency
+	})
+}
+
+// labelCards will set the alias of the card each total refers to as its label
+func labelCards(ctx context.Context, ownerID string, totals []repository.SpendTotal) error {
+	if len(totals) == 0 {
+		return nil
+	}
+
+	cards, err := repositories.Cards.Get(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	aliases := map[string]string{}
+	for _, card := range cards {
+		aliases[card.ID.Hex()] = card.Alias
+	}
+
+	for i := range totals {
+		totals[i].Label = aliases[totals[i].Key]
+	}
+
+	return nil
+}
+
+// buildSpendReport will compare the totals of every report group within a period against the previous one
+func buildSpendReport(ctx context.Context, ownerID string, from time.Time, to time.Time, previousFrom time.Time, previousTo time.Time) (repository.SpendReport, error) {
+	groups := make([][]repository.SpendTotal, len(reportGroups))
+	for i, group := range reportGroups {
+		current, err := repositories.Spends.Totals(ctx, ownerID, from, to, group)
+		if err != nil {
+			return repository.SpendReport{}, err
+		}
+
+		previous, err := repositories.Spends.Totals(ctx, ownerID, previousFrom, previousTo, group)
+		if err != nil {
+			return repository.SpendReport{}, err
+		}
+
+		groups[i] = compareTotals(current, previous)
+		sortTotals(groups[i])
+	}
+
+	report := repository.SpendReport{
+		Totals:          groups[0],
+		ByCategory:      groups[1],
+		ByPaymentMethod: groups[2],
+		ByCard:          groups[3],
+		ByType:          groups[4],
+	}
+
+	err := labelCards(ctx, ownerID, report.ByCard)
+	if err != nil {
+		return repository.SpendReport{}, err
+	}
+
+	return report, nil
+}
+
+// GetMonthlyReport will return how much an owner spent within a month, by category, payment method,
+// card and type, compared against the previous month
+func GetMonthlyReport(parentCtx context.Context, ownerID string, month int64, year int64) (*repository.SpendReport, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("report.owner.id").String(ownerID),
+		attribute.Key("report.month").Int64(month),
+		attribute.Key("report.year").Int64(year),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetMonthlyReport", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	if month < 1 || month > 12 || year < 1 {
+		return &repository.SpendReport{}, errors.New("invalid report period")
+	}
+
+	start := time.Date(int(year), time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	from, to := reportRange(start, 1)
+	previousFrom, previousTo := reportRange(start.AddDate(0, -1, 0), 1)
+
+	report, err := buildSpendReport(ctx, ownerID, from, to, previousFrom, previousTo)
+	if err != nil {
+		return &repository.SpendReport{}, err
+	}
+
+	report.Period = from.Format("2006-01")
+	report.PreviousPeriod = previousFrom.Format("2006-01")
+
+	return &report, nil
+}
+
+// GetYearlyReport will return how much an owner spent within a year, by category, payment method,
+// card and type, compared against the previous year, along with the totals of each of its months
+func GetYearlyReport(parentCtx context.Context, ownerID string, year int64) (*repository.SpendReport, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("report.owner.id").String(ownerID),
+		attribute.Key("report.year").Int64(year),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetYearlyReport", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	if year < 1 {
+		return &repository.SpendReport{}, errors.New("invalid report period")
+	}
+
+	start := time.Date(int(year), time.January, 1, 0, 0, 0, 0, time.UTC)
+	from, to := reportRange(start, 12)
+	previousFrom, previousTo := reportRange(start.AddDate(-1, 0, 0), 12)
+
+	report, err := buildSpendReport(ctx, ownerID, from, to, previousFrom, previousTo)
+	if err != nil {
+		return &repository.SpendReport{}, err
+	}
+
+	report.Period = strconv.FormatInt(year, 10)
+	report.PreviousPeriod = strconv.FormatInt(year-1, 10)
+
+	// months are compared against the one before them, so the last month of the previous year is needed
+	months, err := repositories.Spends.Totals(ctx, ownerID, from.AddDate(0, -1, 0), to, repository.SpendGroupMonth)
+	if err != nil {
+		return &repository.SpendReport{}, err
+	}
+
+	var current, previous []repository.SpendTotal
+	for _, total := range months {
+		month, err := time.Parse("2006-01", total.Key)
+		if err != nil {
+			return &repository.SpendReport{}, err
+		}
+
+		if month.Year() == int(year) {
+			current = append(current, total)
+		}
+
+		// the following month, within the report year, is compared against this one
+		if next := month.AddDate(0, 1, 0); next.Year() == int(year) {
+			total.Key = next.Format("2006-01")
+			previous = append(previous, total)
+		}
+	}
+
+	// months without spends are only listed when following a month with them, so their drop shows up
+	report.ByMonth = compareTotals(current, previous)
+	sort.Slice(report.ByMonth, func(i, j int) bool {
+		if report.ByMonth[i].Key != report.ByMonth[j].Key {
+			return report.ByMonth[i].Key < report.ByMonth[j].Key
+		}
+		return report.ByMonth[i].Currency < report.ByMonth[j].Currency
+	})
+
+	return &report, nil
+}
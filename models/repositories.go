@@ -0,0 +1,19 @@
+package models
+
+import (
+	"budget-tracker-api/repository"
+)
+
+// repositories holds every repository used by models, defined at startup by UseRepositories
+var repositories repository.Repositories
+
+// UseRepositories will define the repositories used by every model, allowing the storage
+// backend to be swapped (e.g. by in-memory repositories on tests)
+func UseRepositories(r repository.Repositories) {
+	repositories = r
+}
+
+// Health will return if the storage backend is healthy
+func Health() error {
+	return repositories.Database.Health()
+}
@@ -0,0 +1,411 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxRulePattern defines how long category rule patterns can be
+const maxRulePattern = 256
+
+// categoryRuleMatcher holds a category rule along with its compiled pattern
+type categoryRuleMatcher struct {
+	rule    repository.CategoryRule
+	pattern *regexp.Regexp
+}
+
+// newCategoryRuleMatchers will compile the patterns of rules, keeping their order. Rules whose pattern
+// can not be compiled anymore are skipped
+func newCategoryRuleMatchers(rules []repository.CategoryRule) []categoryRuleMatcher {
+	matchers := make([]categoryRuleMatcher, 0, len(rules))
+
+	for _, rule := range rules {
+		m := categoryRuleMatcher{rule: rule}
+
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				log.Errorln("skipping category rule", rule.ID.Hex(), "with invalid pattern", err)
+				continue
+			}
+			m.pattern = pattern
+		}
+
+		matchers = append(matchers, m)
+	}
+
+	return matchers
+}
+
+// matches will return if a spend meets every condition of a rule. Costs are compared as the spend was
+// made, before being converted into its balance currency
+func (m categoryRuleMatcher) matches(s repository.Spend) bool {
+	rule := m.rule
+
+	if rule.Description != "" && !strings.Contains(strings.ToLower(s.Description), strings.ToLower(rule.Description)) {
+		return false
+	}
+
+	if m.pattern != nil && !m.pattern.MatchString(s.Description) {
+		return false
+	}
+
+	cost := s.Cost
+	if s.OriginalCurrency != "" {
+		cost = s.OriginalCost
+	}
+	if rule.MinCost != nil && cost < *rule.MinCost {
+		return false
+	}
+	if rule.MaxCost != nil && cost > *rule.MaxCost {
+		return false
+	}
+
+	switch rule.PaymentMethod {
+	case repository.PaymentMethodDebit:
+		if !s.PaymentMethod.Debit {
+			return false
+		}
+	case repository.PaymentMethodCredit:
+		if s.PaymentMethod.Credit.ID.IsZero() {
+			return false
+		}
+	case repository.PaymentMethodPaymentSlip:
+		if !s.PaymentMethod.PaymentSlip {
+			return false
+		}
+	}
+
+	if !rule.CardID.IsZero() && s.PaymentMethod.Credit.ID != rule.CardID {
+		return false
+	}
+
+	return true
+}
+
+// matchCategoryRule will return the first rule a spend matches, if any
+func matchCategoryRule(matchers []categoryRuleMatcher, s repository.Spend) (repository.CategoryRule, bool) {
+	for _, m := range matchers {
+		if m.matches(s) {
+			return m.rule, true
+		}
+	}
+
+	return repository.CategoryRule{}, false
+}
+
+// validateCategoryRule will validate the conditions of a rule and replace its categories by the owner
+// categories they match
+func validateCategoryRule(ctx context.Context, r *repository.CategoryRule) error {
+	r.Description = strings.TrimSpace(r.Description)
+
+	if len(r.Pattern) > maxRulePattern {
+		return fmt.Errorf("invalid category rule: pattern must have at most %d characters", maxRulePattern)
+	}
+
+	if r.Pattern != "" {
+		_, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return errors.New("invalid category rule: pattern is not a valid regular expression")
+		}
+	}
+
+	if (r.MinCost != nil && *r.MinCost < 0) || (r.MaxCost != nil && *r.MaxCost < 0) {
+		return errors.New("invalid category rule: costs must not be negative")
+	}
+
+	if r.MinCost != nil && r.MaxCost != nil && *r.MinCost > *r.MaxCost {
+		return errors.New("invalid category rule: min_cost must not be greater than max_cost")
+	}
+
+	switch r.PaymentMethod {
+	case "", repository.PaymentMethodDebit, repository.PaymentMethodCredit, repository.PaymentMethodPaymentSlip:
+	default:
+		return errors.New("invalid category rule: payment_method must be one of 'debit', 'credit' or 'payment_slip'")
+	}
+
+	if !r.CardID.IsZero() {
+		if r.PaymentMethod != "" && r.PaymentMethod != repository.PaymentMethodCredit {
+			return errors.New("invalid category rule: cards only match credit spends")
+		}
+
+		_, err := repositories.Cards.GetByID(ctx, r.OwnerID.Hex(), r.CardID.Hex())
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Description == "" && r.Pattern == "" && r.MinCost == nil && r.MaxCost == nil && r.PaymentMethod == "" && r.CardID.IsZero() {
+		return errors.New("invalid category rule: at least one condition is required")
+	}
+
+	switch r.Type {
+	case "", repository.SpendTypeFixed, repository.SpendTypeDynamic:
+	default:
+		return errors.New("invalid category rule: type must be one of 'fixed' or 'dynamic'")
+	}
+
+	categories := []string{}
+	for _, c := range r.Categories {
+		if strings.TrimSpace(c) != "" {
+			categories = append(categories, strings.TrimSpace(c))
+		}
+	}
+
+	if len(categories) == 0 {
+		return errors.New("invalid category rule: at least one category is required")
+	}
+
+	categories, err := canonicalCategories(ctx, r.OwnerID, categories)
+	if err != nil {
+		return err
+	}
+	r.Categories = categories
+
+	return nil
+}
+
+// categorizeSpend will assign the categories of the first rule a spend matches, along with its type when
+// the spend was given none
+func categorizeSpend(ctx context.Context, s *repository.Spend) error {
+	rules, err := repositories.CategoryRules.Get(ctx, s.OwnerID.Hex())
+	if err != nil {
+		return err
+	}
+
+	rule, ok := matchCategoryRule(newCategoryRuleMatchers(rules), *s)
+	if !ok {
+		return nil
+	}
+
+	s.Categories = append([]string{}, rule.Categories...)
+	if s.Type == "" {
+		s.Type = rule.Type
+	}
+
+	log.Debugln("categorized spend by rule", rule.ID.Hex())
+	return nil
+}
+
+// CreateCategoryRule creates a category rule for a given owner_id
+func CreateCategoryRule(parentCtx context.Context, r repository.CategoryRule) (id string, err error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("rule.owner.id").String(r.OwnerID.Hex()),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "CreateCategoryRule", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	err = validateCategoryRule(ctx, &r)
+	if err != nil {
+		return "", err
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	r.ID = primitive.NewObjectID()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+
+	id, err = repositories.CategoryRules.Create(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	span.SetAttributes(attribute.Key("rule.id").String(id))
+
+	log.Infoln("created category rule", id)
+	return id, nil
+}
+
+// GetCategoryRules will return every category rule from an owner_id, in the order they are evaluated
+func GetCategoryRules(parentCtx context.Context, ownerID string) ([]repository.CategoryRule, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("rule.owner.id").String(ownerID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetCategoryRules", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	return repositories.CategoryRules.Get(ctx, ownerID)
+}
+
+// GetCategoryRule will return a single category rule from an owner_id
+func GetCategoryRule(parentCtx context.Context, ownerID string, id string) (*repository.CategoryRule, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("rule.owner.id").String(ownerID),
+		attribute.Key("rule.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetCategoryRule", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := repositories.CategoryRules.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.CategoryRule{}, err
+	}
+
+	return &r, nil
+}
+
+// UpdateCategoryRule will replace the conditions, categories and type of a category rule from an owner_id
+func UpdateCategoryRule(parentCtx context.Context, ownerID string, id string, r repository.CategoryRule) (*repository.CategoryRule, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("rule.owner.id").String(ownerID),
+		attribute.Key("rule.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "UpdateCategoryRule", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	current, err := repositories.CategoryRules.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.CategoryRule{}, err
+	}
+
+	r.ID = current.ID
+	r.OwnerID = current.OwnerID
+	r.CreatedAt = current.CreatedAt
+	r.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	err = validateCategoryRule(ctx, &r)
+	if err != nil {
+		return &repository.CategoryRule{}, err
+	}
+
+	err = repositories.CategoryRules.Update(ctx, r)
+	if err != nil {
+		return &repository.CategoryRule{}, err
+	}
+
+	log.Infoln("updated category rule", id)
+	return &r, nil
+}
+
+// DeleteCategoryRule will delete a category rule from an owner_id
+func DeleteCategoryRule(parentCtx context.Context, ownerID string, id string) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("rule.owner.id").String(ownerID),
+		attribute.Key("rule.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "DeleteCategoryRule", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	_, err := repositories.CategoryRules.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+
+	err = repositories.CategoryRules.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("deleted category rule", id)
+	return nil
+}
+
+// DryRunCategoryRules will evaluate the category rules of an owner_id against its latest spends without
+// changing them. When a rule is given, it's evaluated alone instead, without being stored
+func DryRunCategoryRules(parentCtx context.Context, ownerID string, last int64, rule *repository.CategoryRule) (*repository.CategoryRuleDryRun, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("rule.owner.id").String(ownerID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "DryRunCategoryRules", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	if last < 1 || last > repository.MaxSpendsLimit {
+		return &repository.CategoryRuleDryRun{}, fmt.Errorf("last must be between 1 and %d", repository.MaxSpendsLimit)
+	}
+
+	var rules []repository.CategoryRule
+	if rule != nil {
+		oid, err := primitive.ObjectIDFromHex(ownerID)
+		if err != nil {
+			return &repository.CategoryRuleDryRun{}, err
+		}
+
+		rule.OwnerID = oid
+		err = validateCategoryRule(ctx, rule)
+		if err != nil {
+			return &repository.CategoryRuleDryRun{}, err
+		}
+		rules = []repository.CategoryRule{*rule}
+	} else {
+		var err error
+		rules, err = repositories.CategoryRules.Get(ctx, ownerID)
+		if err != nil {
+			return &repository.CategoryRuleDryRun{}, err
+		}
+	}
+
+	page, err := repositories.Spends.Find(ctx, repository.SpendFilter{OwnerID: ownerID, Limit: last})
+	if err != nil {
+		return &repository.CategoryRuleDryRun{}, err
+	}
+
+	matchers := newCategoryRuleMatchers(rules)
+	result := repository.CategoryRuleDryRun{Matches: []repository.CategoryRuleMatch{}}
+
+	for _, s := range page.Spends {
+		// statement payments are never categorized
+		if s.Type == repository.SpendTypePayment {
+			continue
+		}
+		result.Evaluated++
+
+		matched, ok := matchCategoryRule(matchers, s)
+		if !ok {
+			continue
+		}
+		result.Matched++
+
+		assignedType := s.Type
+		if matched.Type != "" {
+			assignedType = matched.Type
+		}
+
+		result.Matches = append(result.Matches, repository.CategoryRuleMatch{
+			SpendID:            s.ID,
+			Description:        s.Description,
+			Cost:               s.Cost,
+			Date:               s.Date,
+			Categories:         append([]string{}, s.Categories...),
+			Type:               s.Type,
+			RuleID:             matched.ID,
+			AssignedCategories: matched.Categories,
+			AssignedType:       assignedType,
+		})
+	}
+
+	return &result, nil
+}
@@ -5,7 +5,9 @@ import (
 	"budget-tracker-api/repository"
 	"budget-tracker-api/services"
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -13,7 +15,89 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 )
 
-// CreateSpend creates a card for a given owner_id
+// removeSpendFromBalance will revert a spend from its balance. Spends created before being
+// accounted in balances are not found there, so they are safely ignored
+func removeSpendFromBalance(ctx context.Context, repo repository.BalanceRepository, s repository.Spend) error {
+	err := repo.RemoveSpend(ctx, s)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find spend in balance") {
+			log.Warnln("spend was not accounted in any balance", s.ID.Hex())
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// reconcileSpendCurrency will validate the currency and cost of a spend, which defaults to the
+// currency of the balance it belongs to. Spends in another currency are converted into it
+func reconcileSpendCurrency(ctx context.Context, s *repository.Spend) error {
+	// original amounts are only ever set by conversions
+	s.OriginalCost = 0
+	s.OriginalCurrency = ""
+	s.ExchangeRate = 0
+
+	if s.Currency != "" {
+		currency, err := repository.NormalizeCurrency(s.Currency)
+		if err != nil {
+			return err
+		}
+		s.Currency = currency
+	}
+
+	month, year := s.Period()
+
+	balance, err := repositories.Balances.Get(ctx, s.OwnerID.Hex(), month, year)
+	if err != nil && !strings.Contains(err.Error(), "could not find balance") {
+		return err
+	}
+
+	if err == nil && balance.Currency != "" {
+		if s.Currency == "" {
+			s.Currency = balance.Currency
+		}
+
+		if s.Currency != balance.Currency {
+			return convertSpend(ctx, s, balance.Currency)
+		}
+	}
+
+	// balances without a currency still must not mix spends from different ones
+	if err == nil && balance.Currency == "" && s.Currency != "" {
+		for _, h := range balance.Historic {
+			if h.ID != s.ID && h.Currency != "" && h.Currency != s.Currency {
+				return fmt.Errorf("spend currency '%s' does not match balance currency '%s'", s.Currency, h.Currency)
+			}
+		}
+	}
+
+	if s.Currency != "" {
+		return repository.ValidateAmount(s.Cost, s.Currency)
+	}
+
+	return nil
+}
+
+// validatePaymentMethod will return an error when a payment method is more than one of credit, debit
+// and payment slip, which would account a spend in a card it was not paid with
+func validatePaymentMethod(pm repository.PaymentMethod) error {
+	methods := 0
+	for _, set := range []bool{!pm.Credit.ID.IsZero(), pm.Debit, pm.PaymentSlip} {
+		if set {
+			methods++
+		}
+	}
+
+	if methods > 1 {
+		return errors.New("invalid payment method, only one of credit, debit or payment_slip can be set")
+	}
+
+	return nil
+}
+
+// CreateSpend creates a spend for a given owner_id and adds it to the matching month balance.
+// Purchases split in installments create one spend per month, returning the first one
 func CreateSpend(parentCtx context.Context, s repository.Spend) (id string, err error) {
 	spanTags := []attribute.KeyValue{
 		attribute.Key("spend.owner.id").String(s.OwnerID.String()),
@@ -25,58 +109,347 @@ func CreateSpend(parentCtx context.Context, s repository.Spend) (id string, err
 	// adding timestamp to creationDate
 	t := time.Now()
 	s.CreatedAt = primitive.NewDateTimeFromTime(t)
+	if s.Date == 0 {
+		s.Date = s.CreatedAt
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	if s.Type == repository.SpendTypePayment {
+		return "", errors.New("payment spends can only be created by paying a card statement")
+	}
 
-	repo := repository.NewSpendRepository(&repository.SpendRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbSpendsCollection,
-		},
-	})
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	// installments are only ever set when splitting a purchase
+	s.Installment = nil
+	if s.Installments < 0 || s.Installments > maxInstallments {
+		return "", fmt.Errorf("installments must be between 1 and %d", maxInstallments)
+	}
+
+	err = validatePaymentMethod(s.PaymentMethod)
+	if err != nil {
+		return "", err
+	}
+
+	err = snapshotCard(ctx, s.OwnerID, &s.PaymentMethod)
+	if err != nil {
+		return "", err
+	}
 
-	id, err = repo.Create(ctx, s)
-	fmt.Println(id)
+	// spends given no categories are categorized by the rules of their owner
+	if len(s.Categories) == 0 {
+		err = categorizeSpend(ctx, &s)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if s.Type == "" {
+		s.Type = repository.SpendTypeDynamic
+	}
+
+	s.Categories, err = canonicalCategories(ctx, s.OwnerID, s.Categories)
+	if err != nil {
+		return "", err
+	}
+
+	if s.Installments > 1 {
+		id, err = createInstallments(ctx, s)
+	} else {
+		s.Installments = 0
+		id, err = createSpend(ctx, s)
+	}
 	if err != nil {
-		cancel()
 		return "", err
 	}
 	span.SetAttributes(attribute.Key("spend.id").String(id))
-	defer cancel()
+
+	return id, nil
+}
+
+// createSpend will store a single spend, accounting it in its month balance
+func createSpend(ctx context.Context, s repository.Spend) (id string, err error) {
+	s.ID = primitive.NewObjectID()
+
+	err = reconcileSpendCurrency(ctx, &s)
+	if err != nil {
+		return "", err
+	}
+
+	return storeSpend(ctx, s)
+}
+
+// storeSpend will store a single spend whose currency was already reconciled, accounting it in its
+// month balance
+func storeSpend(ctx context.Context, s repository.Spend) (id string, err error) {
+	repo := repositories.Spends
+	balanceRepo := repositories.Balances
+
+	// both writes are committed together when the backend supports transactions. Otherwise the balance,
+	// updated first so a spend is never stored without being accounted, is reverted on a best-effort basis
+	err = repositories.Database.Transaction(ctx, func(ctx context.Context) error {
+		err := balanceRepo.AddSpend(ctx, s)
+		if err != nil {
+			return err
+		}
+
+		id, err = repo.Create(ctx, s)
+		if err != nil {
+			revertSpend(ctx, s, balanceRepo.RemoveSpend)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// alerts are informative, so failing to record them does not fail the spend
+	err = recordBudgetAlerts(ctx, s)
+	if err != nil {
+		log.Errorln("could not record budget alerts of spend", id, err)
+	}
 
 	observability.Metrics.Spends.SpendsCreated.Inc()
 	log.Infoln("created spend", id)
 	return id, nil
 }
 
-// GetSpends will return all spends from a specific owner_id
-func GetSpends(parentCtx context.Context, ownerID string) ([]repository.Spend, error) {
+// findAllSpends will return every spend matching a filter, going through all of its pages
+func findAllSpends(ctx context.Context, f repository.SpendFilter) ([]repository.Spend, error) {
+	f.Limit = repository.MaxSpendsLimit
+	f.Ascending = true
+
+	spends := []repository.Spend{}
+	for {
+		page, err := repositories.Spends.Find(ctx, f)
+		if err != nil {
+			return []repository.Spend{}, err
+		}
+
+		spends = append(spends, page.Spends...)
+		if page.NextCursor == "" {
+			return spends, nil
+		}
+		f.Cursor = page.NextCursor
+	}
+}
+
+// GetSpends will return a page of spends from a specific owner_id matching a given filter
+func GetSpends(parentCtx context.Context, f repository.SpendFilter) (repository.SpendPage, error) {
 	spanTags := []attribute.KeyValue{
-		attribute.Key("spend.owner.id").String(ownerID),
+		attribute.Key("spend.owner.id").String(f.OwnerID),
 	}
 
 	ctx, span := observability.Span(parentCtx, "mongodb", "GetSpends", spanTags)
 	defer span.End()
 
-	repo := repository.NewSpendRepository(&repository.SpendRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbSpendsCollection,
-		},
+	repo := repositories.Spends
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	page, err := repo.Find(ctx, f)
+	if err != nil {
+		return repository.SpendPage{}, err
+	}
+
+	return page, nil
+}
+
+// GetSpend will return a single spend from a specific owner_id
+func GetSpend(parentCtx context.Context, ownerID string, id string) (*repository.Spend, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("spend.owner.id").String(ownerID),
+		attribute.Key("spend.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetSpend", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	spend, err := repositories.Spends.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Spend{}, err
+	}
+
+	return &spend, nil
+}
+
+// UpdateSpend will replace a spend from a specific owner_id, moving it across balances when needed
+func UpdateSpend(parentCtx context.Context, ownerID string, id string, s repository.Spend) (*repository.Spend, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("spend.owner.id").String(ownerID),
+		attribute.Key("spend.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "UpdateSpend", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	current, err := repositories.Spends.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return &repository.Spend{}, err
+	}
+
+	if current.Type == repository.SpendTypePayment || s.Type == repository.SpendTypePayment {
+		return &repository.Spend{}, errors.New("payment spends can only be changed by their card statement")
+	}
+
+	// identity and creation fields can not be changed by an update
+	s.ID = current.ID
+	s.OwnerID = current.OwnerID
+	s.CreatedAt = current.CreatedAt
+	s.RecurrenceID = current.RecurrenceID
+	s.Installment = current.Installment
+	s.Installments = 0
+	if s.Date == 0 {
+		s.Date = current.Date
+	}
+
+	err = validatePaymentMethod(s.PaymentMethod)
+	if err != nil {
+		return &repository.Spend{}, err
+	}
+
+	// spends keep the card snapshot they were created with, even after it's archived
+	if s.PaymentMethod.Credit.ID == current.PaymentMethod.Credit.ID {
+		s.PaymentMethod.Credit = current.PaymentMethod.Credit
+	} else {
+		err = snapshotCard(ctx, s.OwnerID, &s.PaymentMethod)
+		if err != nil {
+			return &repository.Spend{}, err
+		}
+	}
+
+	if s.Type == "" {
+		s.Type = repository.SpendTypeDynamic
+	}
+
+	s.Categories, err = canonicalCategories(ctx, s.OwnerID, s.Categories)
+	if err != nil {
+		return &repository.Spend{}, err
+	}
+
+	// converted spends whose cost was left untouched are converted again from their original amount,
+	// so changing any other attribute keeps it
+	if current.OriginalCurrency != "" && s.Cost == current.Cost && s.Currency == current.Currency {
+		s.Cost = current.OriginalCost
+		s.Currency = current.OriginalCurrency
+	}
+
+	err = reconcileSpendCurrency(ctx, &s)
+	if err != nil {
+		return &repository.Spend{}, err
+	}
+
+	err = replaceSpend(ctx, current, s)
+	if err != nil {
+		return &repository.Spend{}, err
+	}
+
+	log.Infoln("updated spend", id)
+	return &s, nil
+}
+
+// replaceSpend will store a changed spend, moving it between balances when needed
+func replaceSpend(ctx context.Context, current repository.Spend, s repository.Spend) error {
+	repo := repositories.Spends
+	balanceRepo := repositories.Balances
+
+	// writes are committed together when the backend supports transactions, otherwise they are
+	// reverted on a best-effort basis
+	return repositories.Database.Transaction(ctx, func(ctx context.Context) error {
+		err := removeSpendFromBalance(ctx, balanceRepo, current)
+		if err != nil {
+			return err
+		}
+
+		err = balanceRepo.AddSpend(ctx, s)
+		if err != nil {
+			revertSpend(ctx, current, balanceRepo.AddSpend)
+			return err
+		}
+
+		err = repo.Update(ctx, s)
+		if err != nil {
+			revertSpend(ctx, s, balanceRepo.RemoveSpend)
+			revertSpend(ctx, current, balanceRepo.AddSpend)
+			return err
+		}
+
+		return nil
 	})
+}
+
+// revertSpend will undo a balance write after a failed one, unless both are rolled back along with their
+// transaction. Balances which could not be reverted are reported, since they must be reconciled with their spends
+func revertSpend(ctx context.Context, s repository.Spend, write func(context.Context, repository.Spend) error) {
+	if repository.Transactional(ctx) {
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	if err := write(ctx, s); err != nil {
+		month, year := s.Period()
+		log.Errorf("could not revert spend %s in balance %d-%02d, which must be reconciled: %v", s.ID.Hex(), year, month, err)
+	}
+}
+
+// DeleteSpend will delete a spend from a specific owner_id and remove it from its balance
+func DeleteSpend(parentCtx context.Context, ownerID string, id string) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("spend.owner.id").String(ownerID),
+		attribute.Key("spend.id").String(id),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "DeleteSpend", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
-	spends, err := repo.Get(ctx, ownerID)
+	current, err := repositories.Spends.GetByID(ctx, ownerID, id)
+	if err != nil {
+		return err
+	}
+
+	if current.Type == repository.SpendTypePayment {
+		return errors.New("payment spends can only be changed by their card statement")
+	}
+
+	return deleteSpend(ctx, current)
+}
+
+// deleteSpend will delete a stored spend, removing it from its month balance
+func deleteSpend(ctx context.Context, current repository.Spend) error {
+	repo := repositories.Spends
+	balanceRepo := repositories.Balances
+
+	// both writes are committed together when the backend supports transactions, otherwise the balance
+	// is reverted on a best-effort basis
+	err := repositories.Database.Transaction(ctx, func(ctx context.Context) error {
+		err := removeSpendFromBalance(ctx, balanceRepo, current)
+		if err != nil {
+			return err
+		}
+
+		err = repo.Delete(ctx, current.ID.Hex())
+		if err != nil {
+			revertSpend(ctx, current, balanceRepo.AddSpend)
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		cancel()
-		return []repository.Spend{}, err
+		return err
 	}
 
-	return spends, nil
+	log.Infoln("deleted spend", current.ID.Hex())
+	return nil
 }
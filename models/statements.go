@@ -0,0 +1,292 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// dayOfMonth will return a day of a month at midnight UTC. Shorter months use their last day
+func dayOfMonth(year int64, month int64, d int64) time.Time {
+	first := time.Date(int(year), time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	if last := int64(first.AddDate(0, 1, -1).Day()); d > last {
+		d = last
+	}
+
+	return first.AddDate(0, 0, int(d-1))
+}
+
+// billingCycle will return the first and last days of the card statement closing at a given month,
+// along with its due date
+func billingCycle(card repository.CreditCard, month int64, year int64) (start time.Time, closing time.Time, due time.Time) {
+	start = dayOfMonth(year, month-1, card.ClosingDay).AddDate(0, 0, 1)
+	closing = dayOfMonth(year, month, card.ClosingDay)
+
+	// statements are due in the month after closing unless the due day comes after the closing one
+	due = dayOfMonth(year, month+1, card.DueDay)
+	if card.DueDay > card.ClosingDay {
+		due = dayOfMonth(year, month, card.DueDay)
+	}
+
+	return start, closing, due
+}
+
+// statementPeriod will return the month and year of the card statement a date is billed in
+func statementPeriod(card repository.CreditCard, t time.Time) (month int64, year int64) {
+	t = day(t)
+	month, year = int64(t.Month()), int64(t.Year())
+
+	if t.After(dayOfMonth(year, month, card.ClosingDay)) {
+		next := time.Date(int(year), time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+		return int64(next.Month()), int64(next.Year())
+	}
+
+	return month, year
+}
+
+// getBillingCard will return a card whose statements can be computed
+func getBillingCard(ctx context.Context, ownerID string, cardID string) (repository.CreditCard, error) {
+	card, err := repositories.Cards.GetByID(ctx, ownerID, cardID)
+	if err != nil {
+		return repository.CreditCard{}, err
+	}
+
+	if !card.HasBillingCycle() {
+		return repository.CreditCard{}, errors.New("card has no billing cycle, its closing and due days must be set")
+	}
+
+	return card, nil
+}
+
+// statement will return the spends of a card statement along with its total
+func statement(ctx context.Context, card repository.CreditCard, month int64, year int64) (repository.Statement, error) {
+	start, closing, due := billingCycle(card, month, year)
+
+	spends, err := findAllSpends(ctx, repository.SpendFilter{
+		OwnerID: card.OwnerID.Hex(),
+		CardID:  card.ID.Hex(),
+		From:    start,
+		To:      closing.AddDate(0, 0, 1).Add(-time.Millisecond),
+	})
+	if err != nil {
+		return repository.Statement{}, err
+	}
+
+	s := repository.Statement{
+		CardID:      card.ID,
+		Month:       month,
+		Year:        year,
+		Start:       primitive.NewDateTimeFromTime(start),
+		Closing:     primitive.NewDateTimeFromTime(closing),
+		Due:         primitive.NewDateTimeFromTime(due),
+		CreditLimit: card.CreditLimit,
+		Spends:      spends,
+	}
+
+	for _, spend := range spends {
+		s.Total += spend.Cost
+		if s.Currency == "" {
+			s.Currency = spend.Currency
+		}
+	}
+
+	return s, nil
+}
+
+// GetStatement will return the statement of a card closing at a given month, whether it was paid and
+// how much of the card credit limit is still available
+func GetStatement(parentCtx context.Context, ownerID string, cardID string, month int64, year int64) (*repository.Statement, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("statement.owner.id").String(ownerID),
+		attribute.Key("statement.card.id").String(cardID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "GetStatement", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	card, err := getBillingCard(ctx, ownerID, cardID)
+	if err != nil {
+		return &repository.Statement{}, err
+	}
+
+	s, err := statement(ctx, card, month, year)
+	if err != nil {
+		return &repository.Statement{}, err
+	}
+
+	payment, err := repositories.Statements.Get(ctx, ownerID, cardID, month, year)
+	if err != nil && !strings.Contains(err.Error(), "could not find statement payment") {
+		return &repository.Statement{}, err
+	}
+
+	if err == nil {
+		s.Paid = true
+		s.Payment = &payment
+	}
+
+	if card.CreditLimit > 0 {
+		// every spend ever made with the card takes its limit, including future installments,
+		// until the statement it's billed in is paid
+		spends, err := findAllSpends(ctx, repository.SpendFilter{OwnerID: ownerID, CardID: cardID})
+		if err != nil {
+			return &repository.Statement{}, err
+		}
+
+		payments, err := repositories.Statements.List(ctx, ownerID, cardID)
+		if err != nil {
+			return &repository.Statement{}, err
+		}
+
+		s.AvailableLimit = card.CreditLimit
+		for _, spend := range spends {
+			s.AvailableLimit -= spend.Cost
+		}
+		for _, p := range payments {
+			s.AvailableLimit += p.Amount
+		}
+	}
+
+	return &s, nil
+}
+
+// PayStatement will mark a closed card statement as paid, posting its total as a payment spend to the
+// balance of the month it was paid at. Payments are not accounted as outcome, since the statement
+// spends already were
+func PayStatement(parentCtx context.Context, ownerID string, cardID string, month int64, year int64, at time.Time) (*repository.StatementPayment, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("statement.owner.id").String(ownerID),
+		attribute.Key("statement.card.id").String(cardID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "PayStatement", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	card, err := getBillingCard(ctx, ownerID, cardID)
+	if err != nil {
+		return &repository.StatementPayment{}, err
+	}
+
+	_, closing, _ := billingCycle(card, month, year)
+	if at.Before(closing.AddDate(0, 0, 1)) {
+		return &repository.StatementPayment{}, errors.New("statement is still open and can not be paid")
+	}
+
+	_, err = repositories.Statements.Get(ctx, ownerID, cardID, month, year)
+	if err == nil {
+		return &repository.StatementPayment{}, errors.New("statement already paid")
+	}
+	if !strings.Contains(err.Error(), "could not find statement payment") {
+		return &repository.StatementPayment{}, err
+	}
+
+	s, err := statement(ctx, card, month, year)
+	if err != nil {
+		return &repository.StatementPayment{}, err
+	}
+
+	if s.Total <= 0 {
+		return &repository.StatementPayment{}, errors.New("statement has nothing to be paid")
+	}
+
+	paidAt := primitive.NewDateTimeFromTime(at)
+
+	spendID, err := createSpend(ctx, repository.Spend{
+		OwnerID:       card.OwnerID,
+		Type:          repository.SpendTypePayment,
+		Description:   fmt.Sprintf("statement %d-%02d of card '%s'", year, month, card.Alias),
+		Cost:          s.Total,
+		Currency:      s.Currency,
+		PaymentMethod: repository.PaymentMethod{Debit: true},
+		Date:          paidAt,
+		CreatedAt:     primitive.NewDateTimeFromTime(time.Now()),
+	})
+	if err != nil {
+		return &repository.StatementPayment{}, err
+	}
+
+	payment := repository.StatementPayment{
+		ID:       primitive.NewObjectID(),
+		OwnerID:  card.OwnerID,
+		CardID:   card.ID,
+		Month:    month,
+		Year:     year,
+		Amount:   s.Total,
+		Currency: s.Currency,
+		PaidAt:   paidAt,
+	}
+
+	payment.SpendID, err = primitive.ObjectIDFromHex(spendID)
+	if err == nil {
+		_, err = repositories.Statements.Create(ctx, payment)
+	}
+	if err != nil {
+		// the statement was paid concurrently, so the payment spend is reverted
+		if rerr := revertPaymentSpend(ctx, ownerID, spendID); rerr != nil {
+			log.Errorln("could not revert statement payment spend", spendID, rerr)
+		}
+		return &repository.StatementPayment{}, err
+	}
+
+	log.Infoln("paid statement", year, month, "of card", cardID)
+	return &payment, nil
+}
+
+// revertPaymentSpend will delete the spend posted by a statement payment
+func revertPaymentSpend(ctx context.Context, ownerID string, spendID string) error {
+	spend, err := repositories.Spends.GetByID(ctx, ownerID, spendID)
+	if err != nil {
+		return err
+	}
+
+	return deleteSpend(ctx, spend)
+}
+
+// RevertStatementPayment will mark a card statement as unpaid again, deleting its payment spend
+func RevertStatementPayment(parentCtx context.Context, ownerID string, cardID string, month int64, year int64) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("statement.owner.id").String(ownerID),
+		attribute.Key("statement.card.id").String(cardID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "RevertStatementPayment", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	payment, err := repositories.Statements.Get(ctx, ownerID, cardID, month, year)
+	if err != nil {
+		return err
+	}
+
+	err = repositories.Statements.Delete(ctx, payment.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	err = revertPaymentSpend(ctx, ownerID, payment.SpendID.Hex())
+	if err != nil && !strings.Contains(err.Error(), "could not find spend") {
+		if _, rerr := repositories.Statements.Create(ctx, payment); rerr != nil {
+			log.Errorln("could not revert statement payment", payment.ID.Hex(), rerr)
+		}
+		return err
+	}
+
+	log.Infoln("reverted payment of statement", year, month, "of card", cardID)
+	return nil
+}
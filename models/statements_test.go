@@ -0,0 +1,39 @@
+package models
+
+import (
+	"budget-tracker-api/repository"
+	"testing"
+	"time"
+)
+
+func TestBillingCycle(t *testing.T) {
+	tests := []struct {
+		closing, due int64
+		month, year  int64
+		start        time.Time
+		end          time.Time
+		dueAt        time.Time
+	}{
+		{25, 5, 5, 2021, time.Date(2021, 4, 26, 0, 0, 0, 0, time.UTC), time.Date(2021, 5, 25, 0, 0, 0, 0, time.UTC), time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC)},
+		{3, 10, 1, 2021, time.Date(2020, 12, 4, 0, 0, 0, 0, time.UTC), time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC), time.Date(2021, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{31, 10, 3, 2021, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC), time.Date(2021, 4, 10, 0, 0, 0, 0, time.UTC)},
+		{30, 31, 2, 2021, time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{28, 5, 12, 2021, time.Date(2021, 11, 29, 0, 0, 0, 0, time.UTC), time.Date(2021, 12, 28, 0, 0, 0, 0, time.UTC), time.Date(2022, 1, 5, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		card := repository.CreditCard{ClosingDay: tt.closing, DueDay: tt.due}
+
+		start, closing, due := billingCycle(card, tt.month, tt.year)
+		if !start.Equal(tt.start) || !closing.Equal(tt.end) || !due.Equal(tt.dueAt) {
+			t.Errorf("unexpected cycle closing at day %d of %d-%02d: %v, %v, due %v", tt.closing, tt.year, tt.month, start, closing, due)
+		}
+
+		// both boundaries of the cycle are billed in its statement
+		for _, d := range []time.Time{start, closing.Add(23 * time.Hour)} {
+			if month, year := statementPeriod(card, d); month != tt.month || year != tt.year {
+				t.Errorf("%v billed in statement %d-%02d, want %d-%02d", d, year, month, tt.year, tt.month)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"budget-tracker-api/services"
+	"context"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CreateRefreshToken will register a new refresh token for an owner_id. An empty family
+// starts a new one, which happens on every login
+func CreateRefreshToken(parentCtx context.Context, ownerID primitive.ObjectID, family string, ttl time.Duration) (*repository.RefreshToken, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("token.owner.id").String(ownerID.Hex()),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "CreateRefreshToken", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	t := time.Now()
+	token := repository.RefreshToken{
+		ID:        primitive.NewObjectID().Hex(),
+		OwnerID:   ownerID,
+		Family:    family,
+		CreatedAt: primitive.NewDateTimeFromTime(t),
+		ExpiresAt: primitive.NewDateTimeFromTime(t.Add(ttl)),
+	}
+
+	if token.Family == "" {
+		token.Family = token.ID
+	}
+
+	err := repositories.RefreshTokens.Create(ctx, token)
+	if err != nil {
+		return &repository.RefreshToken{}, err
+	}
+
+	span.SetAttributes(attribute.Key("token.family").String(token.Family))
+	return &token, nil
+}
+
+// ConsumeRefreshToken will mark a refresh token as used. Reusing a token is a sign of it being
+// stolen, so its whole family is revoked and the legitimate user must login again
+func ConsumeRefreshToken(parentCtx context.Context, id string) (*repository.RefreshToken, error) {
+	ctx, span := observability.Span(parentCtx, "mongodb", "ConsumeRefreshToken", []attribute.KeyValue{})
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	repo := repositories.RefreshTokens
+
+	token, err := repo.Consume(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "refresh token reuse detected") {
+			log.Warnln("refresh token reuse detected, revoking family", token.Family)
+			if rerr := repo.RevokeFamily(ctx, token.Family); rerr != nil {
+				log.Errorln("could not revoke refresh token family", token.Family, rerr)
+			}
+		}
+		return &repository.RefreshToken{}, err
+	}
+
+	span.SetAttributes(attribute.Key("token.family").String(token.Family))
+	return &token, nil
+}
+
+// RevokeRefreshToken will revoke a refresh token along with its whole family, ending a session
+func RevokeRefreshToken(parentCtx context.Context, id string) error {
+	ctx, span := observability.Span(parentCtx, "mongodb", "RevokeRefreshToken", []attribute.KeyValue{})
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	repo := repositories.RefreshTokens
+
+	// consuming the token prevents it from being used while its family is revoked
+	token, err := repo.Consume(ctx, id)
+	if token.Family == "" {
+		return err
+	}
+
+	err = repo.RevokeFamily(ctx, token.Family)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.Key("token.family").String(token.Family))
+	return nil
+}
+
+// RevokeToken will revoke a single access token until it expires
+func RevokeToken(parentCtx context.Context, id string, ownerID primitive.ObjectID, expiresAt time.Time) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("token.owner.id").String(ownerID.Hex()),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "RevokeToken", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	err := repositories.Revocations.Create(ctx, repository.Revocation{
+		TokenID:   id,
+		OwnerID:   ownerID,
+		ExpiresAt: primitive.NewDateTimeFromTime(expiresAt),
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("revoked token", id)
+	return nil
+}
+
+// RevokeUserSessions will revoke every token issued to an owner_id until the current millisecond, so
+// tokens issued right after it, such as a new login, remain valid. Revocations are kept during the
+// given lifetime, which must be the longest lifetime of an issued token
+func RevokeUserSessions(parentCtx context.Context, ownerID string, lifetime time.Duration) error {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("token.owner.id").String(ownerID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "RevokeUserSessions", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return err
+	}
+
+	t := time.Now()
+	err = repositories.Revocations.Create(ctx, repository.Revocation{
+		OwnerID:   oid,
+		NotBefore: primitive.NewDateTimeFromTime(t),
+		ExpiresAt: primitive.NewDateTimeFromTime(t.Add(lifetime)),
+		CreatedAt: primitive.NewDateTimeFromTime(t),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = repositories.RefreshTokens.RevokeOwner(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("revoked all sessions from user", ownerID)
+	return nil
+}
+
+// IsTokenRevoked will return if a token was revoked, either by itself or along with all sessions from its owner
+func IsTokenRevoked(parentCtx context.Context, id string, ownerID string, issuedAt time.Time) (bool, error) {
+	spanTags := []attribute.KeyValue{
+		attribute.Key("token.owner.id").String(ownerID),
+	}
+
+	ctx, span := observability.Span(parentCtx, "mongodb", "IsTokenRevoked", spanTags)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	return repositories.Revocations.IsRevoked(ctx, id, ownerID, issuedAt)
+}
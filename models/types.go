@@ -1,10 +0,0 @@
-package models
-
-const (
-	mongodbURI               = "mongodb://mongodb:27017/"
-	mongodbDatabase          = "budget-tracker"
-	mongodbUserCollection    = "users"
-	mongodbCardsCollection   = "cards"
-	mongodbBalanceCollection = "balance"
-	mongodbSpendsCollection  = "spends"
-)
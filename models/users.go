@@ -12,7 +12,6 @@ import (
 	log "github.com/sirupsen/logrus"
 	"go.opentelemetry.io/otel/attribute"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -21,14 +20,7 @@ func GetUsers(parentCtx context.Context) ([]repository.SanitizedUser, error) {
 	ctx, span := observability.Span(parentCtx, "mongodb", "getUsers", []attribute.KeyValue{})
 	defer span.End()
 
-	repo := repository.NewUserRepository(&repository.UserRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbUserCollection,
-		},
-	})
+	repo := repositories.Users
 
 	users, err := repo.GetAll(ctx)
 	if err != nil {
@@ -46,16 +38,9 @@ func GetUser(parentCtx context.Context, id string) (*repository.SanitizedUser, e
 	ctx, span := observability.Span(parentCtx, "mongodb", "getUser", spanTags)
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 
-	repo := repository.NewUserRepository(&repository.UserRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbUserCollection,
-		},
-	})
+	repo := repositories.Users
 
 	u, err := repo.Get(ctx, id)
 	if err != nil {
@@ -68,25 +53,21 @@ func GetUser(parentCtx context.Context, id string) (*repository.SanitizedUser, e
 	return &u, nil
 }
 
-// GetUserByFilter will return a user from database based on key,pair BSON
-func GetUserByFilter(parentCtx context.Context, bsonKey string, bsonValue string) (u *repository.User, err error) {
+// GetUserByLogin will return a user, along with its salted password, based on its login
+func GetUserByLogin(parentCtx context.Context, login string) (*repository.User, error) {
 	ctx, span := observability.Span(parentCtx, "mongodb", "getUser", []attribute.KeyValue{})
 	defer span.End()
 
-	var user repository.User
-
-	col := services.MongoClient.Database(mongodbDatabase).Collection(mongodbUserCollection)
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
 
-	err = col.FindOne(ctx, bson.M{bsonKey: bsonValue}).Decode(&user)
+	user, err := repositories.Users.GetByLogin(ctx, login)
 	if err != nil {
-		cancel()
 		return &repository.User{}, err
 	}
 
 	span.SetAttributes(attribute.Key("user.id").String(user.ID.String()))
 	span.SetAttributes(attribute.Key("user.login").String(user.Login))
-	defer cancel()
 	return &user, nil
 }
 
@@ -100,7 +81,7 @@ func CreateUser(parentCtx context.Context, u repository.User) (id string, err er
 	ctx, span := observability.Span(parentCtx, "mongodb", "CreateUser", spanTags)
 	defer span.End()
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 
 	// adding timestamp to creationDate
 	t := time.Now()
@@ -118,14 +99,7 @@ func CreateUser(parentCtx context.Context, u repository.User) (id string, err er
 		return "", err
 	}
 
-	repo := repository.NewUserRepository(&repository.UserRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbUserCollection,
-		},
-	})
+	repo := repositories.Users
 
 	id, err = repo.Create(ctx, u)
 	if err != nil {
@@ -135,6 +109,15 @@ func CreateUser(parentCtx context.Context, u repository.User) (id string, err er
 
 	defer cancel()
 
+	// users are usable without categories, so failing to seed them does not fail the user creation
+	ownerID, err := primitive.ObjectIDFromHex(id)
+	if err == nil {
+		err = seedCategories(ctx, ownerID)
+	}
+	if err != nil {
+		log.Errorln("could not create default categories of user", u.Login, err)
+	}
+
 	observability.Metrics.Users.UsersCreated.Inc()
 	log.Infoln("created user", u.Login)
 	return id, nil
@@ -149,14 +132,7 @@ func DeleteUser(parentCtx context.Context, id string) (err error) {
 	ctx, span := observability.Span(parentCtx, "mongodb", "DeleteUser", spanTags)
 	defer span.End()
 
-	repo := repository.NewUserRepository(&repository.UserRepositoryMongoDB{
-		Client: services.MongoClient,
-		Config: services.MongoCfg{
-			URI:       services.MongodbURI,
-			Database:  services.MongodbDatabase,
-			Colletion: services.MongodbUserCollection,
-		},
-	})
+	repo := repositories.Users
 
 	err = repo.Delete(ctx, id)
 	if err != nil {
@@ -11,6 +11,8 @@ var Metrics *MetricsCollectors
 // MetricsUsers will return a set of users' related prometheus metrics
 type MetricsUsers struct {
 	UsersCreated prometheus.Counter
+	LoginsFailed prometheus.Counter
+	LoginsLocked prometheus.Counter
 }
 
 // MetricsCards will return a set of cards' related prometheus metrics
@@ -43,6 +45,16 @@ func InitMetrics() (m *MetricsCollectors) {
 		Help: "The total number of created users",
 	})
 
+	loginsFailed := promauto.NewCounter(prometheus.CounterOpts{
+		Name: "budget_tracker_logins_failed_total",
+		Help: "The total number of failed logins",
+	})
+
+	loginsLocked := promauto.NewCounter(prometheus.CounterOpts{
+		Name: "budget_tracker_logins_locked_total",
+		Help: "The total number of logins locked after repeated failures",
+	})
+
 	cardsCreated := promauto.NewCounter(prometheus.CounterOpts{
 		Name: "budget_tracker_cards_created_total",
 		Help: "The total number of created cards",
@@ -61,6 +73,8 @@ func InitMetrics() (m *MetricsCollectors) {
 	Metrics = &MetricsCollectors{
 		&MetricsUsers{
 			UsersCreated: usersCreated,
+			LoginsFailed: loginsFailed,
+			LoginsLocked: loginsLocked,
 		},
 		&MetricsCards{
 			CardsCreated: cardsCreated,
@@ -75,6 +89,8 @@ func InitMetrics() (m *MetricsCollectors) {
 
 	prometheus.Unregister(prometheus.NewGoCollector())
 	prometheus.Register(Metrics.Users.UsersCreated)
+	prometheus.Register(Metrics.Users.LoginsFailed)
+	prometheus.Register(Metrics.Users.LoginsLocked)
 	prometheus.Register(Metrics.Cards.CardsCreated)
 	prometheus.Register(Metrics.Balances.BalancesCreated)
 	prometheus.Register(Metrics.Spends.SpendsCreated)
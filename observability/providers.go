@@ -1,6 +1,8 @@
 package observability
 
 import (
+	"fmt"
+
 	"go.opentelemetry.io/otel/exporters/stdout"
 	"go.opentelemetry.io/otel/exporters/trace/jaeger"
 	zipkinExporter "go.opentelemetry.io/otel/exporters/trace/zipkin"
@@ -23,6 +25,20 @@ type Providers struct {
 	Jaeger *tracesdk.TracerProvider
 }
 
+// Provider will return the tracer provider of a given exporter: "jaeger", "zipkin" or "stdout"
+func (p Providers) Provider(exporter string) (*tracesdk.TracerProvider, error) {
+	switch exporter {
+	case "jaeger":
+		return p.Jaeger, nil
+	case "zipkin":
+		return p.Zipkin, nil
+	case "stdout":
+		return p.Stdout, nil
+	}
+
+	return nil, fmt.Errorf("unsupported tracing exporter '%s'", exporter)
+}
+
 // InitTracerProviders will return a struct with both providers: jaeger and stdout
 func (c ProvidersConfig) InitTracerProviders() (p Providers, err error) {
 	resourceAttributes := resource.NewWithAttributes(
@@ -1,9 +1,76 @@
 package repository
 
 import (
+	"fmt"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	// SpendTypeFixed defines a spend which repeats every month (rent, lessons...)
+	SpendTypeFixed = "fixed"
+	// SpendTypeDynamic defines a spend which does not repeat
+	SpendTypeDynamic = "dynamic"
+	// SpendTypePayment defines a credit card statement payment. It's kept in the balance historic
+	// without being accounted as an outcome, since the statement spends already were
+	SpendTypePayment = "payment"
+
+	// PaymentMethodDebit filters spends paid with debit
+	PaymentMethodDebit = "debit"
+	// PaymentMethodCredit filters spends paid with a credit card
+	PaymentMethodCredit = "credit"
+	// PaymentMethodPaymentSlip filters spends paid with a payment slip
+	PaymentMethodPaymentSlip = "payment_slip"
+
+	// RecurrenceCadenceWeekly defines a recurrence repeated every 7 days from its start
+	RecurrenceCadenceWeekly = "weekly"
+	// RecurrenceCadenceMonthly defines a recurrence repeated every month at its day
+	RecurrenceCadenceMonthly = "monthly"
+	// RecurrenceCadenceYearly defines a recurrence repeated every year at its start month and day
+	RecurrenceCadenceYearly = "yearly"
+
+	// RecurrenceStatusActive defines a recurrence materializing spends
+	RecurrenceStatusActive = "active"
+	// RecurrenceStatusPaused defines a recurrence skipping spends until resumed
+	RecurrenceStatusPaused = "paused"
+	// RecurrenceStatusCancelled defines a recurrence cancelled by its owner
+	RecurrenceStatusCancelled = "cancelled"
+	// RecurrenceStatusFinished defines a recurrence which reached its end
+	RecurrenceStatusFinished = "finished"
+
+	// SpendGroupCategory groups spends by each of their categories
+	SpendGroupCategory = "category"
+	// SpendGroupPaymentMethod groups spends by their payment method (see PaymentMethod*)
+	SpendGroupPaymentMethod = "payment_method"
+	// SpendGroupCard groups credit spends by the card they were paid with
+	SpendGroupCard = "card"
+	// SpendGroupType groups spends by their type, fixed or dynamic
+	SpendGroupType = "type"
+	// SpendGroupMonth groups spends by the month they happened at, as "YYYY-MM"
+	SpendGroupMonth = "month"
+	// SpendGroupCurrency groups spends by their currency only
+	SpendGroupCurrency = "currency"
+
+	// SpendImportFormatCSV defines a bank statement exported as CSV, read through a column mapping
+	SpendImportFormatCSV = "csv"
+	// SpendImportFormatOFX defines a bank statement exported as OFX
+	SpendImportFormatOFX = "ofx"
+
+	// SpendImportStatusNew defines an imported transaction not found among the spends of its owner
+	SpendImportStatusNew = "new"
+	// SpendImportStatusDuplicate defines an imported transaction matching the fingerprint of an existing spend
+	SpendImportStatusDuplicate = "duplicate"
+	// SpendImportStatusSkipped defines an imported transaction which is not a spend, such as an income
+	SpendImportStatusSkipped = "skipped"
+	// SpendImportStatusInvalid defines an imported transaction whose date or amount can not be read
+	SpendImportStatusInvalid = "invalid"
+	// SpendImportStatusCreated defines a new transaction created as a spend by an import commit
+	SpendImportStatusCreated = "created"
+	// SpendImportStatusFailed defines a new transaction which could not be created by an import commit
+	SpendImportStatusFailed = "failed"
+)
+
 // User struct defines a user
 // swagger:model
 type User struct {
@@ -19,6 +86,8 @@ type User struct {
 	Email string `json:"email,omitempty" bson:"email,omitempty"`
 	// example: myplaintextpassword
 	SaltedPassword string `json:"password,omitempty" bson:"password,omitempty"`
+	// example: ["admin"]
+	Roles []string `json:"roles,omitempty" bson:"roles,omitempty"`
 	// swagger:ignore
 	CreatedAt primitive.DateTime `json:"created_at,omitempty" bson:"created_at,omitempty"`
 }
@@ -50,6 +119,54 @@ type SanitizedUser struct {
 	Firstname string             `json:"firstname,omitempty" bson:"firstname,omitempty"`
 	Lastname  string             `json:"lastname,omitempty" bson:"lastname,omitempty"`
 	Email     string             `json:"email,omitempty" bson:"email,omitempty"`
+	Roles     []string           `json:"roles,omitempty" bson:"roles,omitempty"`
+}
+
+// JWTRefresh defines a refresh token to be exchanged by a new access token
+// swagger:model
+type JWTRefresh struct {
+	// example: <REFRESH_TOKEN>
+	RefreshToken string `json:"refresh"`
+}
+
+// RefreshToken defines an issued refresh token. Every token can be used a single time and
+// belongs to a family, started at login, which is revoked as a whole when a reuse is detected
+type RefreshToken struct {
+	// ID is the token 'jti' claim
+	ID        string             `json:"id" bson:"_id"`
+	OwnerID   primitive.ObjectID `json:"owner_id" bson:"owner_id"`
+	Family    string             `json:"family" bson:"family"`
+	Used      bool               `json:"used" bson:"used"`
+	Revoked   bool               `json:"revoked" bson:"revoked"`
+	ExpiresAt primitive.DateTime `json:"expires_at" bson:"expires_at"`
+	CreatedAt primitive.DateTime `json:"created_at" bson:"created_at"`
+}
+
+// Revocation defines either a single revoked token, identified by its 'jti' claim, or all
+// tokens from an owner issued until a given millisecond (when revoking all sessions)
+type Revocation struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	TokenID   string             `json:"jti,omitempty" bson:"jti,omitempty"`
+	OwnerID   primitive.ObjectID `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
+	NotBefore primitive.DateTime `json:"not_before,omitempty" bson:"not_before,omitempty"`
+	ExpiresAt primitive.DateTime `json:"expires_at" bson:"expires_at"`
+	CreatedAt primitive.DateTime `json:"created_at" bson:"created_at"`
+}
+
+// LoginAttempt defines the failed logins for a given login, which is tracked even when no user
+// owns it so lockouts never reveal if a login exists
+type LoginAttempt struct {
+	// ID is the attempted login
+	ID            string             `json:"login" bson:"_id"`
+	Failures      int64              `json:"failures" bson:"failures"`
+	LockedUntil   primitive.DateTime `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+	LastFailureAt primitive.DateTime `json:"last_failure_at" bson:"last_failure_at"`
+	ExpiresAt     primitive.DateTime `json:"expires_at" bson:"expires_at"`
+}
+
+// IsLocked will return if the login is locked at a given time
+func (a LoginAttempt) IsLocked(t time.Time) bool {
+	return a.LockedUntil != 0 && a.LockedUntil.Time().After(t)
 }
 
 // CreditCard defines a user credit card
@@ -67,20 +184,38 @@ type CreditCard struct {
 	Color string `json:"color" bson:"color"`
 	// example: 1234
 	LastDigits int32 `json:"last_digits" bson:"last_digits"`
+	// day of month the statement closes at, months shorter than it close at their last day
+	// example: 25
+	ClosingDay int64 `json:"closing_day,omitempty" bson:"closing_day,omitempty"`
+	// day of month the statement is due at, in the month after closing when not after the closing day
+	// example: 5
+	DueDay int64 `json:"due_day,omitempty" bson:"due_day,omitempty"`
+	// example: 5000
+	CreditLimit Money `json:"credit_limit,omitempty" bson:"credit_limit,omitempty"`
+	// archived cards are kept for the spends made with them, but can not be used anymore
+	// example: false
+	Archived bool `json:"archived,omitempty" bson:"archived,omitempty"`
+	// swagger:ignore
+	ArchivedAt primitive.DateTime `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
 	// swagger:ignore
 	CreatedAt primitive.DateTime `json:"created_at,omitempty" bson:"created_at,omitempty"`
 }
 
+// HasBillingCycle will return if the card closing and due days are known, so it has statements
+func (c CreditCard) HasBillingCycle() bool {
+	return c.ClosingDay != 0 && c.DueDay != 0
+}
+
 // Income defines an user outcome for a certain month
 type Income struct {
-	GrossIncome float64 `json:"gross" bson:"gross"`
-	NetIncome   float64 `json:"net" bson:"net"`
+	GrossIncome Money `json:"gross" bson:"gross"`
+	NetIncome   Money `json:"net" bson:"net"`
 }
 
 // Outcome defines an user outcome for a certain month
 type Outcome struct {
-	FixedOutcome   float64 `json:"fixed" bson:"fixed"`
-	DynamicOutcome float64 `json:"dynamic" bson:"dynamic"`
+	FixedOutcome   Money `json:"fixed" bson:"fixed"`
+	DynamicOutcome Money `json:"dynamic" bson:"dynamic"`
 }
 
 // PaymentMethod defines which payment method was used for a certain spend
@@ -102,15 +237,194 @@ type Spend struct {
 	// example: guitar lessons
 	Description string `json:"description" bson:"description"`
 	// example: 12.90
-	Cost float64 `json:"cost" bson:"cost"`
+	Cost Money `json:"cost" bson:"cost"`
+	// ISO-4217 code, defaults to the currency of the balance the spend belongs to
+	// example: BRL
+	Currency string `json:"currency,omitempty" bson:"currency,omitempty"`
+	// cost and currency the spend was made in, when converted into the balance currency
+	// example: 2.5
+	OriginalCost Money `json:"original_cost,omitempty" bson:"original_cost,omitempty"`
+	// example: USD
+	OriginalCurrency string `json:"original_currency,omitempty" bson:"original_currency,omitempty"`
+	// example: 5.16
+	ExchangeRate float64 `json:"exchange_rate,omitempty" bson:"exchange_rate,omitempty"`
 	// example: debit: true
 	PaymentMethod PaymentMethod `json:"payment_method,omitempty" bson:"payment_method,omitempty"`
 	// example: "categories": ["personal development"]
 	Categories []string `json:"categories,omitempty" bson:"categories,omitempty"`
+	// date in which the spend happened, defaults to its creation date
+	// example: 2021-05-01T00:00:00Z
+	Date primitive.DateTime `json:"date,omitempty" bson:"date,omitempty"`
+	// recurrence which materialized the spend, if any
+	// swagger:ignore
+	RecurrenceID primitive.ObjectID `json:"recurrence_id,omitempty" bson:"recurrence_id,omitempty"`
+	// number of monthly installments a credit card purchase is split into, only read on creation
+	// example: 10
+	Installments int64 `json:"installments,omitempty" bson:"-"`
+	// installment of a purchase split in monthly ones, if any
+	Installment *Installment `json:"installment,omitempty" bson:"installment,omitempty"`
 	// swagger:ignore
 	CreatedAt primitive.DateTime `json:"created_at,omitempty" bson:"created_at,omitempty"`
 }
 
+// Installment defines the position of a spend within a credit card purchase split in monthly installments
+// swagger:model
+type Installment struct {
+	// shared by every installment of the same purchase
+	// example: 60b1c2d3e4f5a60718293a4b
+	PurchaseID primitive.ObjectID `json:"purchase_id" bson:"purchase_id"`
+	// example: 3
+	Number int64 `json:"number" bson:"number"`
+	// example: 10
+	Total int64 `json:"total" bson:"total"`
+	// cost of the whole purchase
+	// example: 2999.90
+	PurchaseCost Money `json:"purchase_cost" bson:"purchase_cost"`
+}
+
+// String will describe the installment, e.g. "installment 3 of 10"
+func (i Installment) String() string {
+	return fmt.Sprintf("installment %d of %d", i.Number, i.Total)
+}
+
+// IsFixed will return if a spend must be accounted as a fixed outcome
+func (s Spend) IsFixed() bool {
+	return s.Type == SpendTypeFixed
+}
+
+// Outcome will return how much a spend must be accounted as outcome in its balance
+func (s Spend) Outcome() Money {
+	if s.Type == SpendTypePayment {
+		return 0
+	}
+	return s.Cost
+}
+
+// Period will return the month and year of the balance a spend belongs to
+func (s Spend) Period() (month int64, year int64) {
+	t := s.Date.Time().UTC()
+	return int64(t.Month()), int64(t.Year())
+}
+
+// SpendFilter defines the criteria used to search spends from an owner.
+// Zero values are ignored, so an empty filter returns every spend from the owner
+type SpendFilter struct {
+	OwnerID string
+	// From and To are inclusive boundaries for the spend date
+	From time.Time
+	To   time.Time
+	Type string
+	// Category matches spends containing the given category
+	Category string
+	// PaymentMethod is one of "debit", "credit" or "payment_slip"
+	PaymentMethod string
+	// CardID matches spends paid with a given credit card
+	CardID  string
+	MinCost *Money
+	MaxCost *Money
+	// Description is a case insensitive text to be contained in the spend description
+	Description string
+	// Cursor is the opaque value returned by a previous page
+	Cursor    string
+	Limit     int64
+	Ascending bool
+}
+
+// SpendPage defines a page of spends along with the cursor of the next one
+type SpendPage struct {
+	Spends []Spend
+	// NextCursor is empty when there are no more pages
+	NextCursor string
+}
+
+// SpendTotal defines how much a group of spends cost, in a single currency
+// swagger:model
+type SpendTotal struct {
+	// category, payment method, card ID, type or month ("YYYY-MM") shared by the spends, empty when they have none
+	// example: restaurants
+	Key string `json:"key" bson:"key"`
+	// alias of the card, when grouped by card
+	// example: black
+	Label string `json:"label,omitempty" bson:"label,omitempty"`
+	// example: BRL
+	Currency string `json:"currency" bson:"currency"`
+	// example: 842.5
+	Total Money `json:"total" bson:"total"`
+	// example: 12
+	Count int64 `json:"count" bson:"count"`
+	// total of the same group within the previous period
+	// example: 700
+	Previous Money `json:"previous" bson:"previous"`
+	// how much the total changed since the previous period
+	// example: 142.5
+	Delta Money `json:"delta" bson:"delta"`
+}
+
+// SpendReport defines how much an owner spent within a month or a year, compared against the previous one.
+// Statement payments are not accounted, and spends with multiple categories count towards each of them
+// swagger:model
+type SpendReport struct {
+	// "YYYY-MM" for monthly reports or "YYYY" for yearly ones
+	// example: 2021-05
+	Period string `json:"period" bson:"period"`
+	// example: 2021-04
+	PreviousPeriod string `json:"previous_period" bson:"previous_period"`
+	// totals per currency
+	Totals          []SpendTotal `json:"totals" bson:"totals"`
+	ByCategory      []SpendTotal `json:"by_category" bson:"by_category"`
+	ByPaymentMethod []SpendTotal `json:"by_payment_method" bson:"by_payment_method"`
+	ByCard          []SpendTotal `json:"by_card" bson:"by_card"`
+	ByType          []SpendTotal `json:"by_type" bson:"by_type"`
+	// totals of every month of yearly reports, each one compared against the month before it
+	ByMonth []SpendTotal `json:"by_month,omitempty" bson:"by_month,omitempty"`
+}
+
+// BalancePeriod defines the month and year of a balance
+type BalancePeriod struct {
+	Month int64
+	Year  int64
+}
+
+// IsZero will return if a period was not defined
+func (p BalancePeriod) IsZero() bool {
+	return p.Month == 0 && p.Year == 0
+}
+
+// BalanceFilter defines the criteria used to list balances from an owner.
+// Zero periods are ignored, so an empty filter returns every balance from the owner
+type BalanceFilter struct {
+	OwnerID string
+	// From and To are inclusive boundaries
+	From BalancePeriod
+	To   BalancePeriod
+}
+
+// ExchangeRate defines how many units of a quote currency a unit of a base currency was worth on a given day
+// swagger:model
+type ExchangeRate struct {
+	// example: USD
+	Base string `json:"base" bson:"base"`
+	// example: BRL
+	Quote string `json:"quote" bson:"quote"`
+	// day the rate was published at, always at midnight UTC
+	// example: 2021-05-01T00:00:00Z
+	Date primitive.DateTime `json:"date" bson:"date"`
+	// example: 5.16
+	Rate float64 `json:"rate" bson:"rate"`
+	// swagger:ignore
+	UpdatedAt primitive.DateTime `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// ExchangeRateFilter defines the criteria used to list exchange rates.
+// Zero values are ignored, so an empty filter returns every rate
+type ExchangeRateFilter struct {
+	Base  string
+	Quote string
+	// From and To are inclusive boundaries for the rate date
+	From time.Time
+	To   time.Time
+}
+
 // Balance defines an user balance
 // swagger:model
 type Balance struct {
@@ -118,11 +432,374 @@ type Balance struct {
 	OwnerID         primitive.ObjectID `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
 	Income          Income             `json:"income,omitempty" bson:"income,omitempty"`
 	Outcome         Outcome            `json:"outcome" bson:"outcome"`
-	SpendableAmount float64            `json:"spendable_amount" bson:"spendable_amount"`
+	SpendableAmount Money              `json:"spendable_amount" bson:"spendable_amount"`
 	Historic        []Spend            `json:"historic" bson:"historic"`
-	Currency        string             `json:"currency" bson:"currency"`
-	Month           int64              `json:"month" bson:"month"`
-	Year            int64              `json:"year" bson:"year"`
-	CreatedAt       primitive.DateTime `json:"created_at" bson:"created_at"`
-	UpdatedAt       primitive.DateTime `json:"updated_at" bson:"updated_at"`
+	// ISO-4217 code shared by the balance and all of its spends
+	Currency string `json:"currency" bson:"currency"`
+	// spending limits of the month per spend category
+	Budgets   []Budget           `json:"budgets,omitempty" bson:"budgets,omitempty"`
+	Month     int64              `json:"month" bson:"month"`
+	Year      int64              `json:"year" bson:"year"`
+	CreatedAt primitive.DateTime `json:"created_at" bson:"created_at"`
+	UpdatedAt primitive.DateTime `json:"updated_at" bson:"updated_at"`
+}
+
+// Category defines a spend category managed by its owner, optionally nested under a parent one
+// swagger:model
+type Category struct {
+	// swagger:ignore
+	ID primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	// example: 5f4e76699c362be701856be6
+	OwnerID primitive.ObjectID `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
+	// unique per owner regardless of case
+	// example: restaurants
+	Name string `json:"name" bson:"name"`
+	// parent category, if any
+	// example: 60b1c2d3e4f5a60718293a4b
+	ParentID primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	// example: #ff8800
+	Color string `json:"color,omitempty" bson:"color,omitempty"`
+	// example: utensils
+	Icon string `json:"icon,omitempty" bson:"icon,omitempty"`
+	// swagger:ignore
+	CreatedAt primitive.DateTime `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	// swagger:ignore
+	UpdatedAt primitive.DateTime `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// CategoryRule defines a rule assigning categories to the spends of its owner created without any. Every
+// condition given must be met by a spend for it to match
+// swagger:model
+type CategoryRule struct {
+	// swagger:ignore
+	ID primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	// example: 5f4e76699c362be701856be6
+	OwnerID primitive.ObjectID `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
+	// rules are evaluated from the lowest priority, the first matching one being applied
+	// example: 10
+	Priority int64 `json:"priority" bson:"priority"`
+	// case insensitive text to be contained in the spend description
+	// example: uber
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+	// regular expression to be matched by the spend description
+	// example: (?i)^(uber|99) trip
+	Pattern string `json:"pattern,omitempty" bson:"pattern,omitempty"`
+	// inclusive boundaries for the spend cost
+	// example: 5
+	MinCost *Money `json:"min_cost,omitempty" bson:"min_cost,omitempty"`
+	// example: 80
+	MaxCost *Money `json:"max_cost,omitempty" bson:"max_cost,omitempty"`
+	// one of "debit", "credit" or "payment_slip"
+	// example: credit
+	PaymentMethod string `json:"payment_method,omitempty" bson:"payment_method,omitempty"`
+	// credit card the spend was paid with
+	// example: 60b1c2d3e4f5a60718293a4b
+	CardID primitive.ObjectID `json:"card_id,omitempty" bson:"card_id,omitempty"`
+	// categories assigned to matching spends
+	// example: ["transport"]
+	Categories []string `json:"categories" bson:"categories"`
+	// type assigned to matching spends created without one, if any
+	// example: dynamic
+	Type string `json:"type,omitempty" bson:"type,omitempty"`
+	// swagger:ignore
+	CreatedAt primitive.DateTime `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	// swagger:ignore
+	UpdatedAt primitive.DateTime `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// CategoryRuleMatch defines the categories and type a spend would be given by the rule it matches
+// swagger:model
+type CategoryRuleMatch struct {
+	SpendID     primitive.ObjectID `json:"spend_id" bson:"spend_id"`
+	Description string             `json:"description" bson:"description"`
+	Cost        Money              `json:"cost" bson:"cost"`
+	Date        primitive.DateTime `json:"date" bson:"date"`
+	// current categories and type of the spend
+	Categories []string           `json:"categories" bson:"categories"`
+	Type       string             `json:"type" bson:"type"`
+	RuleID     primitive.ObjectID `json:"rule_id" bson:"rule_id"`
+	// categories and type the rule assigns
+	AssignedCategories []string `json:"assigned_categories" bson:"assigned_categories"`
+	AssignedType       string   `json:"assigned_type" bson:"assigned_type"`
+}
+
+// CategoryRuleDryRun defines which of the latest spends of an owner would be matched by its rules
+// swagger:model
+type CategoryRuleDryRun struct {
+	// example: 50
+	Evaluated int64 `json:"evaluated" bson:"evaluated"`
+	// example: 12
+	Matched int64               `json:"matched" bson:"matched"`
+	Matches []CategoryRuleMatch `json:"matches" bson:"matches"`
+}
+
+// SpendImportMapping defines which columns of a CSV bank statement hold each attribute of its
+// transactions, matched against its header regardless of case
+// swagger:model
+type SpendImportMapping struct {
+	// example: Date
+	Date string `json:"date"`
+	// example: Description
+	Description string `json:"description"`
+	// example: Amount
+	Amount string `json:"amount"`
+	// optional ISO-4217 code column, transactions default to the currency of their balance
+	// example: Currency
+	Currency string `json:"currency,omitempty"`
+	// layout of the dates using YYYY, MM and DD, defaults to YYYY-MM-DD
+	// example: DD/MM/YYYY
+	DateFormat string `json:"date_format,omitempty"`
+	// defaults to ","
+	// example: ;
+	Delimiter string `json:"delimiter,omitempty"`
+	// "." or ",", defaults to "."
+	// example: ,
+	DecimalSeparator string `json:"decimal_separator,omitempty"`
+	// spends are negative amounts unless set, as credit card statements list them as positive ones
+	// example: true
+	PositiveSpends bool `json:"positive_spends,omitempty"`
+}
+
+// SpendImport defines a bank statement whose transactions are imported as spends
+// swagger:model
+type SpendImport struct {
+	// csv or ofx
+	// example: csv
+	Format string `json:"format"`
+	// the statement file itself
+	// example: Date,Description,Amount\n2021-07-10,Uber trip,-45.90
+	Content string `json:"content"`
+	// required by CSV statements
+	Mapping SpendImportMapping `json:"mapping,omitempty"`
+	// how every imported spend was paid
+	// example: debit: true
+	PaymentMethod PaymentMethod `json:"payment_method,omitempty"`
+	// required by commits, the token returned by their preview
+	// example: 3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b
+	Token string `json:"token,omitempty"`
+}
+
+// SpendImportRow defines a transaction read from a bank statement, along with what importing it does
+// swagger:model
+type SpendImportRow struct {
+	// CSV row, the header being the first one, or OFX transaction number
+	// example: 2
+	Line int64 `json:"line"`
+	// see SpendImportStatus*
+	// example: new
+	Status string `json:"status"`
+	// why a transaction was skipped, is invalid or failed to be created
+	Details string `json:"details,omitempty"`
+	// date, cost and normalized description deduplicating spends
+	// example: 2021-07-10|45900|uber trip
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// the spend as it's created, categorized by the rules of its owner
+	Spend Spend `json:"spend"`
+	// example: 60b1c2d3e4f5a60718293a56
+	SpendID string `json:"spend_id,omitempty"`
+}
+
+// SpendImportResult defines every transaction read from a bank statement. Previews only report them,
+// while commits create the new ones as spends
+// swagger:model
+type SpendImportResult struct {
+	// example: false
+	Committed bool `json:"committed"`
+	// example: 12
+	New int64 `json:"new"`
+	// example: 3
+	Duplicates int64 `json:"duplicates"`
+	// example: 1
+	Skipped int64 `json:"skipped"`
+	// example: 0
+	Invalid int64 `json:"invalid"`
+	// example: 0
+	Created int64 `json:"created"`
+	// example: 0
+	Failed int64            `json:"failed"`
+	Rows   []SpendImportRow `json:"rows"`
+	// hash of the rows, which commits must be given back from the preview they approve
+	// example: 3a7bd3e2360a3d29eea436fcfb7e44c735d117c42d1c1835420b6b9942dd4f1b
+	Token string `json:"token"`
+}
+
+// Budget defines how much can be spent within a month on spends of a category
+// swagger:model
+type Budget struct {
+	// example: restaurants
+	Category string `json:"category" bson:"category"`
+	// example: 800
+	Limit Money `json:"limit" bson:"limit"`
+}
+
+// BudgetStatus defines how much of a category budget was already spent within a month
+// swagger:model
+type BudgetStatus struct {
+	// example: restaurants
+	Category string `json:"category"`
+	// example: 800
+	Limit Money `json:"limit"`
+	// example: 650.5
+	Spent Money `json:"spent"`
+	// negative once the budget is exceeded
+	// example: 149.5
+	Remaining Money `json:"remaining"`
+	// share of the limit already spent, rounded down
+	// example: 81
+	Percentage int64 `json:"percentage"`
+}
+
+// BudgetReport defines the category budgets of a month balance along with the alerts they raised
+// swagger:model
+type BudgetReport struct {
+	// example: 5
+	Month int64 `json:"month"`
+	// example: 2021
+	Year int64 `json:"year"`
+	// example: BRL
+	Currency string         `json:"currency,omitempty"`
+	Budgets  []BudgetStatus `json:"budgets"`
+	Alerts   []BudgetAlert  `json:"alerts"`
+}
+
+// BudgetAlert defines a category budget threshold reached by a spend, recorded a single time per month
+// swagger:model
+type BudgetAlert struct {
+	ID      primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	OwnerID primitive.ObjectID `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
+	// example: restaurants
+	Category string `json:"category" bson:"category"`
+	// example: 5
+	Month int64 `json:"month" bson:"month"`
+	// example: 2021
+	Year int64 `json:"year" bson:"year"`
+	// share of the limit reached, either 80 or 100
+	// example: 80
+	Threshold int64 `json:"threshold" bson:"threshold"`
+	// example: 800
+	Limit Money `json:"limit" bson:"limit"`
+	// amount spent on the category once the spend was created
+	// example: 650.5
+	Spent Money `json:"spent" bson:"spent"`
+	// example: BRL
+	Currency string `json:"currency,omitempty" bson:"currency,omitempty"`
+	// spend which reached the threshold
+	SpendID   primitive.ObjectID `json:"spend_id" bson:"spend_id"`
+	CreatedAt primitive.DateTime `json:"created_at" bson:"created_at"`
+}
+
+// Recurrence defines a spend repeated over time (rent, subscriptions...), materialized as a
+// concrete Spend into each month's balance by a scheduler
+// swagger:model
+type Recurrence struct {
+	// swagger:ignore
+	ID      primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	OwnerID primitive.ObjectID `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
+	// type of the materialized spends, defaults to fixed
+	// example: fixed
+	Type string `json:"type" bson:"type"`
+	// example: guitar lessons
+	Description string `json:"description" bson:"description"`
+	// example: 150
+	Cost Money `json:"cost" bson:"cost"`
+	// example: BRL
+	Currency string `json:"currency,omitempty" bson:"currency,omitempty"`
+	// example: debit: true
+	PaymentMethod PaymentMethod `json:"payment_method,omitempty" bson:"payment_method,omitempty"`
+	// example: "categories": ["personal development"]
+	Categories []string `json:"categories,omitempty" bson:"categories,omitempty"`
+	// one of 'weekly', 'monthly' or 'yearly'
+	// example: monthly
+	Cadence string `json:"cadence" bson:"cadence"`
+	// day of month of monthly and yearly recurrences, defaults to the start day. Shorter months
+	// use their last day
+	// example: 10
+	Day int64 `json:"day,omitempty" bson:"day,omitempty"`
+	// first day a spend may be materialized at
+	// example: 2021-05-01T00:00:00Z
+	Start primitive.DateTime `json:"start" bson:"start"`
+	// last day a spend may be materialized at, if any
+	// example: 2021-12-31T00:00:00Z
+	End primitive.DateTime `json:"end,omitempty" bson:"end,omitempty"`
+	// one of 'active', 'paused', 'cancelled' or 'finished'
+	// example: active
+	Status string `json:"status,omitempty" bson:"status,omitempty"`
+	// date of the next spend to be materialized
+	// example: 2021-06-10T00:00:00Z
+	NextRun primitive.DateTime `json:"next_run,omitempty" bson:"next_run,omitempty"`
+	// swagger:ignore
+	CreatedAt primitive.DateTime `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	// swagger:ignore
+	UpdatedAt primitive.DateTime `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// Commitment defines the outcome a future month is already committed to, either by installments
+// already accounted in its balance or by active recurrences still to be materialized
+// swagger:model
+type Commitment struct {
+	// example: 7
+	Month int64 `json:"month"`
+	// example: 2021
+	Year int64 `json:"year"`
+	// example: BRL
+	Currency string `json:"currency,omitempty"`
+	// example: 300
+	Installments Money `json:"installments"`
+	// example: 150
+	Recurring Money `json:"recurring"`
+	// example: 450
+	Total Money `json:"total"`
+}
+
+// StatementPayment defines the payment of a credit card statement, posted to the balance as a payment spend
+// swagger:model
+type StatementPayment struct {
+	ID      primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	OwnerID primitive.ObjectID `json:"owner_id,omitempty" bson:"owner_id,omitempty"`
+	CardID  primitive.ObjectID `json:"card_id" bson:"card_id"`
+	// month and year the statement closes at
+	// example: 5
+	Month int64 `json:"month" bson:"month"`
+	// example: 2021
+	Year int64 `json:"year" bson:"year"`
+	// example: 1530.45
+	Amount Money `json:"amount" bson:"amount"`
+	// example: BRL
+	Currency string `json:"currency,omitempty" bson:"currency,omitempty"`
+	// spend posted to the balance of the month the statement was paid at
+	SpendID primitive.ObjectID `json:"spend_id" bson:"spend_id"`
+	// example: 2021-06-05T00:00:00Z
+	PaidAt primitive.DateTime `json:"paid_at" bson:"paid_at"`
+}
+
+// Statement defines the credit spends of a card within a billing cycle
+// swagger:model
+type Statement struct {
+	CardID primitive.ObjectID `json:"card_id"`
+	// month and year the statement closes at
+	// example: 5
+	Month int64 `json:"month"`
+	// example: 2021
+	Year int64 `json:"year"`
+	// first day of the billing cycle
+	// example: 2021-04-26T00:00:00Z
+	Start primitive.DateTime `json:"start"`
+	// last day of the billing cycle
+	// example: 2021-05-25T00:00:00Z
+	Closing primitive.DateTime `json:"closing"`
+	// example: 2021-06-05T00:00:00Z
+	Due primitive.DateTime `json:"due"`
+	// example: BRL
+	Currency string `json:"currency,omitempty"`
+	// example: 1530.45
+	Total Money `json:"total"`
+	// example: 5000
+	CreditLimit Money `json:"credit_limit,omitempty"`
+	// credit limit not taken by unpaid spends, including future installments
+	// example: 2469.55
+	AvailableLimit Money `json:"available_limit,omitempty"`
+	// example: false
+	Paid    bool              `json:"paid"`
+	Payment *StatementPayment `json:"payment,omitempty"`
+	Spends  []Spend           `json:"spends"`
 }
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// migrations holds the SQL schema of every supported dialect, applied in lexical order
+//
+//go:embed migrations
+var migrations embed.FS
+
+// MigrateSQL will apply every pending migration of a dialect, recording applied ones at 'schema_migrations'
+func MigrateSQL(ctx context.Context, db *sql.DB, dialect string) error {
+	dir := "migrations/" + dialect
+
+	files, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return fmt.Errorf("unsupported SQL dialect '%s'", dialect)
+	}
+
+	d := &SQLDatabase{DB: db, Dialect: dialect}
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("could not create migrations table: %w", err)
+	}
+
+	var versions []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".sql") {
+			versions = append(versions, strings.TrimSuffix(f.Name(), ".sql"))
+		}
+	}
+	sort.Strings(versions)
+
+	for _, version := range versions {
+		var applied int
+		err = d.conn(db).queryRow(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, version).Scan(&applied)
+		if err != nil {
+			return err
+		}
+
+		if applied > 0 {
+			continue
+		}
+
+		script, err := migrations.ReadFile(dir + "/" + version + ".sql")
+		if err != nil {
+			return err
+		}
+
+		err = d.transaction(ctx, func(c sqlConn) error {
+			_, err := c.exec(ctx, string(script))
+			if err != nil {
+				return err
+			}
+
+			_, err = c.exec(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().Unix())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("could not apply migration '%s': %w", version, err)
+		}
+
+		log.Infoln("applied migration", version)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+-- amounts are stored as integer thousandths (repository.Money) instead of floats, and spends have a currency
+
+ALTER TABLE spends
+    ALTER COLUMN cost TYPE BIGINT USING ROUND(cost * 1000);
+
+ALTER TABLE balances
+    ALTER COLUMN income_gross TYPE BIGINT USING ROUND(income_gross * 1000),
+    ALTER COLUMN income_net TYPE BIGINT USING ROUND(income_net * 1000),
+    ALTER COLUMN outcome_fixed TYPE BIGINT USING ROUND(outcome_fixed * 1000),
+    ALTER COLUMN outcome_dynamic TYPE BIGINT USING ROUND(outcome_dynamic * 1000),
+    ALTER COLUMN spendable_amount TYPE BIGINT USING ROUND(spendable_amount * 1000);
+
+ALTER TABLE spends ADD COLUMN currency TEXT NOT NULL DEFAULT '';
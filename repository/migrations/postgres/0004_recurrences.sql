@@ -0,0 +1,26 @@
+-- spends materialized by a recurrence point back to it
+ALTER TABLE spends ADD COLUMN recurrence_id TEXT;
+
+CREATE INDEX spends_recurrence ON spends (recurrence_id);
+
+CREATE TABLE recurrences (
+    id             TEXT COLLATE "C" PRIMARY KEY,
+    owner_id       TEXT NOT NULL,
+    type           TEXT NOT NULL DEFAULT '',
+    description    TEXT NOT NULL DEFAULT '',
+    cost           BIGINT NOT NULL DEFAULT 0,
+    currency       TEXT NOT NULL DEFAULT '',
+    payment_method TEXT NOT NULL DEFAULT '{}',
+    categories     TEXT NOT NULL DEFAULT '[]',
+    cadence        TEXT NOT NULL,
+    day            BIGINT NOT NULL DEFAULT 0,
+    start_date     BIGINT NOT NULL,
+    end_date       BIGINT NOT NULL DEFAULT 0,
+    status         TEXT NOT NULL,
+    next_run       BIGINT NOT NULL DEFAULT 0,
+    created_at     BIGINT NOT NULL DEFAULT 0,
+    updated_at     BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX recurrences_owner ON recurrences (owner_id);
+CREATE INDEX recurrences_status_next_run ON recurrences (status, next_run);
@@ -0,0 +1,17 @@
+-- cards have a billing cycle and a credit limit, and their statements are paid a single time
+ALTER TABLE cards ADD COLUMN closing_day BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE cards ADD COLUMN due_day BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE cards ADD COLUMN credit_limit BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE statement_payments (
+    id       TEXT COLLATE "C" PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    card_id  TEXT NOT NULL,
+    month    BIGINT NOT NULL,
+    year     BIGINT NOT NULL,
+    amount   BIGINT NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT '',
+    spend_id TEXT NOT NULL,
+    paid_at  BIGINT NOT NULL DEFAULT 0,
+    UNIQUE (card_id, year, month)
+);
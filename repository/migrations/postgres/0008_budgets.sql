@@ -0,0 +1,17 @@
+-- balances hold spending limits per category, whose thresholds are alerted a single time per month
+ALTER TABLE balances ADD COLUMN budgets TEXT NOT NULL DEFAULT '[]';
+
+CREATE TABLE budget_alerts (
+    id         TEXT COLLATE "C" PRIMARY KEY,
+    owner_id   TEXT NOT NULL,
+    category   TEXT NOT NULL,
+    month      BIGINT NOT NULL,
+    year       BIGINT NOT NULL,
+    threshold  BIGINT NOT NULL,
+    budget     BIGINT NOT NULL DEFAULT 0,
+    spent      BIGINT NOT NULL DEFAULT 0,
+    currency   TEXT NOT NULL DEFAULT '',
+    spend_id   TEXT NOT NULL,
+    created_at BIGINT NOT NULL DEFAULT 0,
+    UNIQUE (owner_id, year, month, category, threshold)
+);
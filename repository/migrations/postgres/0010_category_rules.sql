@@ -0,0 +1,18 @@
+-- rules categorize the spends created without categories, evaluated by priority
+CREATE TABLE category_rules (
+    id             TEXT COLLATE "C" PRIMARY KEY,
+    owner_id       TEXT NOT NULL,
+    priority       BIGINT NOT NULL DEFAULT 0,
+    description    TEXT NOT NULL DEFAULT '',
+    pattern        TEXT NOT NULL DEFAULT '',
+    min_cost       BIGINT,
+    max_cost       BIGINT,
+    payment_method TEXT NOT NULL DEFAULT '',
+    card_id        TEXT,
+    categories     TEXT NOT NULL DEFAULT '[]',
+    type           TEXT NOT NULL DEFAULT '',
+    created_at     BIGINT NOT NULL DEFAULT 0,
+    updated_at     BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX category_rules_owner_priority ON category_rules (owner_id, priority);
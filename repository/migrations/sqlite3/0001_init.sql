@@ -0,0 +1,107 @@
+-- uniqueness rules mirror the mongoDB indexes from services.setIndexes
+CREATE TABLE users (
+    id         TEXT PRIMARY KEY,
+    login      TEXT NOT NULL UNIQUE,
+    firstname  TEXT NOT NULL DEFAULT '',
+    lastname   TEXT NOT NULL DEFAULT '',
+    email      TEXT NOT NULL DEFAULT '',
+    password   TEXT NOT NULL DEFAULT '',
+    roles      TEXT NOT NULL DEFAULT '[]',
+    created_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE cards (
+    id          TEXT PRIMARY KEY,
+    owner_id    TEXT NOT NULL,
+    alias       TEXT NOT NULL DEFAULT '',
+    network     TEXT NOT NULL DEFAULT '',
+    color       TEXT NOT NULL DEFAULT '',
+    last_digits INTEGER NOT NULL,
+    created_at  INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (owner_id, last_digits)
+);
+
+CREATE TABLE balances (
+    id               TEXT PRIMARY KEY,
+    owner_id         TEXT NOT NULL,
+    month            INTEGER NOT NULL,
+    year             INTEGER NOT NULL,
+    income_gross     REAL NOT NULL DEFAULT 0,
+    income_net       REAL NOT NULL DEFAULT 0,
+    outcome_fixed    REAL NOT NULL DEFAULT 0,
+    outcome_dynamic  REAL NOT NULL DEFAULT 0,
+    spendable_amount REAL NOT NULL DEFAULT 0,
+    currency         TEXT NOT NULL DEFAULT '',
+    created_at       INTEGER NOT NULL DEFAULT 0,
+    updated_at       INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (owner_id, month, year)
+);
+
+-- balance_spends holds the spends snapshot of a balance (Balance.Historic)
+CREATE TABLE balance_spends (
+    balance_id TEXT NOT NULL REFERENCES balances (id) ON DELETE CASCADE,
+    spend_id   TEXT NOT NULL,
+    position   INTEGER NOT NULL,
+    spend      TEXT NOT NULL,
+    PRIMARY KEY (balance_id, spend_id)
+);
+
+CREATE TABLE spends (
+    id             TEXT PRIMARY KEY,
+    owner_id       TEXT NOT NULL,
+    type           TEXT NOT NULL DEFAULT '',
+    description    TEXT NOT NULL DEFAULT '',
+    cost           REAL NOT NULL DEFAULT 0,
+    payment_method TEXT NOT NULL DEFAULT '{}',
+    card_id        TEXT,
+    debit          BOOLEAN NOT NULL DEFAULT FALSE,
+    payment_slip   BOOLEAN NOT NULL DEFAULT FALSE,
+    date           INTEGER NOT NULL DEFAULT 0,
+    created_at     INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX spends_owner_date ON spends (owner_id, date DESC, id DESC);
+CREATE INDEX spends_owner_type_date ON spends (owner_id, type, date DESC);
+CREATE INDEX spends_owner_card_date ON spends (owner_id, card_id, date DESC);
+
+CREATE TABLE spend_categories (
+    spend_id TEXT NOT NULL REFERENCES spends (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    category TEXT NOT NULL,
+    PRIMARY KEY (spend_id, position)
+);
+
+CREATE INDEX spend_categories_category ON spend_categories (category, spend_id);
+
+CREATE TABLE refresh_tokens (
+    id         TEXT PRIMARY KEY,
+    owner_id   TEXT NOT NULL,
+    family     TEXT NOT NULL,
+    used       BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked    BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX refresh_tokens_family ON refresh_tokens (family);
+CREATE INDEX refresh_tokens_owner ON refresh_tokens (owner_id);
+
+CREATE TABLE revocations (
+    id         TEXT PRIMARY KEY,
+    jti        TEXT,
+    owner_id   TEXT,
+    not_before INTEGER,
+    expires_at INTEGER NOT NULL,
+    created_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX revocations_jti ON revocations (jti);
+CREATE INDEX revocations_owner_not_before ON revocations (owner_id, not_before);
+
+CREATE TABLE login_attempts (
+    login           TEXT PRIMARY KEY,
+    failures        INTEGER NOT NULL DEFAULT 0,
+    locked_until    INTEGER NOT NULL DEFAULT 0,
+    last_failure_at INTEGER NOT NULL DEFAULT 0,
+    expires_at      INTEGER NOT NULL
+);
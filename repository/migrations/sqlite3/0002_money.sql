@@ -0,0 +1,33 @@
+-- amounts are stored as integer thousandths (repository.Money) instead of floats, and spends have a currency
+
+ALTER TABLE spends ADD COLUMN cost_money INTEGER NOT NULL DEFAULT 0;
+UPDATE spends SET cost_money = CAST(ROUND(cost * 1000) AS INTEGER);
+ALTER TABLE spends DROP COLUMN cost;
+ALTER TABLE spends RENAME COLUMN cost_money TO cost;
+
+ALTER TABLE balances ADD COLUMN income_gross_money INTEGER NOT NULL DEFAULT 0;
+UPDATE balances SET income_gross_money = CAST(ROUND(income_gross * 1000) AS INTEGER);
+ALTER TABLE balances DROP COLUMN income_gross;
+ALTER TABLE balances RENAME COLUMN income_gross_money TO income_gross;
+
+ALTER TABLE balances ADD COLUMN income_net_money INTEGER NOT NULL DEFAULT 0;
+UPDATE balances SET income_net_money = CAST(ROUND(income_net * 1000) AS INTEGER);
+ALTER TABLE balances DROP COLUMN income_net;
+ALTER TABLE balances RENAME COLUMN income_net_money TO income_net;
+
+ALTER TABLE balances ADD COLUMN outcome_fixed_money INTEGER NOT NULL DEFAULT 0;
+UPDATE balances SET outcome_fixed_money = CAST(ROUND(outcome_fixed * 1000) AS INTEGER);
+ALTER TABLE balances DROP COLUMN outcome_fixed;
+ALTER TABLE balances RENAME COLUMN outcome_fixed_money TO outcome_fixed;
+
+ALTER TABLE balances ADD COLUMN outcome_dynamic_money INTEGER NOT NULL DEFAULT 0;
+UPDATE balances SET outcome_dynamic_money = CAST(ROUND(outcome_dynamic * 1000) AS INTEGER);
+ALTER TABLE balances DROP COLUMN outcome_dynamic;
+ALTER TABLE balances RENAME COLUMN outcome_dynamic_money TO outcome_dynamic;
+
+ALTER TABLE balances ADD COLUMN spendable_amount_money INTEGER NOT NULL DEFAULT 0;
+UPDATE balances SET spendable_amount_money = CAST(ROUND(spendable_amount * 1000) AS INTEGER);
+ALTER TABLE balances DROP COLUMN spendable_amount;
+ALTER TABLE balances RENAME COLUMN spendable_amount_money TO spendable_amount;
+
+ALTER TABLE spends ADD COLUMN currency TEXT NOT NULL DEFAULT '';
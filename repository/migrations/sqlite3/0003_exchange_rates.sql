@@ -0,0 +1,13 @@
+-- spends made in a foreign currency keep their original amount along with the rate used to convert it
+ALTER TABLE spends ADD COLUMN original_cost INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE spends ADD COLUMN original_currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE spends ADD COLUMN exchange_rate REAL NOT NULL DEFAULT 0;
+
+CREATE TABLE exchange_rates (
+    base       TEXT NOT NULL,
+    quote      TEXT NOT NULL,
+    date       INTEGER NOT NULL,
+    rate       REAL NOT NULL,
+    updated_at INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (base, quote, date)
+);
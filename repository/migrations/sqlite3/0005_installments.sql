@@ -0,0 +1,7 @@
+-- installments of a credit card purchase split in monthly ones share its purchase id
+ALTER TABLE spends ADD COLUMN installment_purchase_id TEXT;
+ALTER TABLE spends ADD COLUMN installment_number INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE spends ADD COLUMN installment_total INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE spends ADD COLUMN installment_purchase_cost INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX spends_installment_purchase ON spends (installment_purchase_id);
@@ -0,0 +1,17 @@
+-- cards have a billing cycle and a credit limit, and their statements are paid a single time
+ALTER TABLE cards ADD COLUMN closing_day INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE cards ADD COLUMN due_day INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE cards ADD COLUMN credit_limit INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE statement_payments (
+    id       TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    card_id  TEXT NOT NULL,
+    month    INTEGER NOT NULL,
+    year     INTEGER NOT NULL,
+    amount   INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT '',
+    spend_id TEXT NOT NULL,
+    paid_at  INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (card_id, year, month)
+);
@@ -0,0 +1,3 @@
+-- cards referenced by spends are archived instead of deleted
+ALTER TABLE cards ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE cards ADD COLUMN archived_at INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- balances hold spending limits per category, whose thresholds are alerted a single time per month
+ALTER TABLE balances ADD COLUMN budgets TEXT NOT NULL DEFAULT '[]';
+
+CREATE TABLE budget_alerts (
+    id         TEXT PRIMARY KEY,
+    owner_id   TEXT NOT NULL,
+    category   TEXT NOT NULL,
+    month      INTEGER NOT NULL,
+    year       INTEGER NOT NULL,
+    threshold  INTEGER NOT NULL,
+    budget     INTEGER NOT NULL DEFAULT 0,
+    spent      INTEGER NOT NULL DEFAULT 0,
+    currency   TEXT NOT NULL DEFAULT '',
+    spend_id   TEXT NOT NULL,
+    created_at INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (owner_id, year, month, category, threshold)
+);
@@ -0,0 +1,13 @@
+-- categories are managed per owner, their names being unique regardless of case
+CREATE TABLE categories (
+    id         TEXT PRIMARY KEY,
+    owner_id   TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    parent_id  TEXT,
+    color      TEXT NOT NULL DEFAULT '',
+    icon       TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL DEFAULT 0,
+    updated_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX categories_owner_name ON categories (owner_id, lower(name));
@@ -0,0 +1,18 @@
+-- rules categorize the spends created without categories, evaluated by priority
+CREATE TABLE category_rules (
+    id             TEXT PRIMARY KEY,
+    owner_id       TEXT NOT NULL,
+    priority       INTEGER NOT NULL DEFAULT 0,
+    description    TEXT NOT NULL DEFAULT '',
+    pattern        TEXT NOT NULL DEFAULT '',
+    min_cost       INTEGER,
+    max_cost       INTEGER,
+    payment_method TEXT NOT NULL DEFAULT '',
+    card_id        TEXT,
+    categories     TEXT NOT NULL DEFAULT '[]',
+    type           TEXT NOT NULL DEFAULT '',
+    created_at     INTEGER NOT NULL DEFAULT 0,
+    updated_at     INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX category_rules_owner_priority ON category_rules (owner_id, priority);
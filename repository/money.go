@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MoneyDecimals defines how many decimal places an amount holds, enough for any ISO-4217 currency
+	MoneyDecimals = 3
+	// MoneyScale defines how many units of Money make a single unit of currency
+	MoneyScale = 1000
+)
+
+// Money defines an amount as an integer number of thousandths, so sums never drift as floats do.
+// It is written as a decimal number in JSON (e.g. 12.9), as decimal128 in mongoDB and as an
+// integer number of thousandths in SQL databases
+// swagger:type number
+type Money int64
+
+// NewMoney will return an amount from a float, rounding it to the nearest thousandth
+func NewMoney(f float64) Money {
+	return Money(math.Round(f * MoneyScale))
+}
+
+// ParseMoney will parse an exact decimal amount such as "12.90" or "-3"
+func ParseMoney(s string) (Money, error) {
+	invalid := fmt.Errorf("invalid amount '%s'", s)
+
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	// a single leading '-' is the only sign allowed, so the overflow check below is on the absolute value
+	if strings.ContainsAny(s, "+-") {
+		return 0, invalid
+	}
+
+	parts := strings.SplitN(s, ".", 2)
+	if parts[0] == "" && (len(parts) == 1 || parts[1] == "") {
+		return 0, invalid
+	}
+
+	units := int64(0)
+	if parts[0] != "" {
+		var err error
+		units, err = strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return 0, invalid
+		}
+	}
+
+	fraction := int64(0)
+	if len(parts) == 2 {
+		digits := strings.TrimRight(parts[1], "0")
+		if len(digits) > MoneyDecimals {
+			return 0, fmt.Errorf("amount '%s' has more than %d decimal places", s, MoneyDecimals)
+		}
+
+		if digits != "" {
+			var err error
+			fraction, err = strconv.ParseInt(digits+strings.Repeat("0", MoneyDecimals-len(digits)), 10, 64)
+			if err != nil {
+				return 0, invalid
+			}
+		}
+	}
+
+	if units > (math.MaxInt64-fraction)/MoneyScale {
+		return 0, invalid
+	}
+
+	m := Money(units*MoneyScale + fraction)
+	if negative {
+		m = -m
+	}
+
+	return m, nil
+}
+
+// Float64 will return the amount as a float, meant for display purposes only
+func (m Money) Float64() float64 {
+	return float64(m) / MoneyScale
+}
+
+// String will return the amount as a decimal number without trailing zeros
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	units := strconv.FormatInt(v/MoneyScale, 10)
+	fraction := strings.TrimRight(fmt.Sprintf("%0*d", MoneyDecimals, v%MoneyScale), "0")
+	if fraction == "" {
+		return sign + units
+	}
+
+	return sign + units + "." + fraction
+}
+
+// MarshalJSON will write the amount as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON will read the amount either from a JSON number or string, without going through floats
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	s = strings.Trim(s, `"`)
+	if strings.ContainsAny(s, "eE") {
+		var err error
+		s, err = shiftExponent(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = v
+	return nil
+}
+
+// shiftExponent will write an amount such as "1.5e2" as a plain decimal number, moving its decimal point
+// instead of going through floats, so it's parsed exactly as any other amount
+func shiftExponent(s string) (string, error) {
+	invalid := fmt.Errorf("invalid amount '%s'", s)
+
+	i := strings.IndexAny(s, "eE")
+	mantissa, sign := s[:i], ""
+	if strings.HasPrefix(mantissa, "-") {
+		mantissa, sign = mantissa[1:], "-"
+	}
+
+	exponent, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return "", invalid
+	}
+
+	parts := strings.SplitN(mantissa, ".", 2)
+	if len(parts) == 1 {
+		parts = append(parts, "")
+	}
+
+	digits := parts[0] + parts[1]
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return "", invalid
+	}
+
+	// point is the position of the decimal point within the digits
+	point := len(parts[0]) + exponent
+	for strings.HasPrefix(digits, "0") {
+		digits = digits[1:]
+		point--
+	}
+	digits = strings.TrimRight(digits, "0")
+
+	switch {
+	case digits == "":
+		return "0", nil
+	case point < -MoneyDecimals:
+		return "", fmt.Errorf("amount '%s' has more than %d decimal places", s, MoneyDecimals)
+	case point > len(strconv.FormatInt(math.MaxInt64/MoneyScale, 10)):
+		return "", invalid
+	case point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits, nil
+	case point >= len(digits):
+		return sign + digits + strings.Repeat("0", point-len(digits)), nil
+	default:
+		return sign + digits[:point] + "." + digits[point:], nil
+	}
+}
+
+// MarshalBSONValue will write the amount as decimal128
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	d, ok := primitive.ParseDecimal128FromBigInt(big.NewInt(int64(m)), -MoneyDecimals)
+	if !ok {
+		return 0, nil, fmt.Errorf("can not encode %s as decimal128", m)
+	}
+
+	return bson.MarshalValue(d)
+}
+
+// UnmarshalBSONValue will read the amount from decimal128, or from doubles and integers written before Money existed
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+
+	switch t {
+	case bsontype.Decimal128:
+		v, exp, err := raw.Decimal128().BigInt()
+		if err != nil {
+			return err
+		}
+
+		// rescales the decimal to thousandths, rounding half away from zero
+		exp += MoneyDecimals
+		if exp >= 0 {
+			v.Mul(v, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+		} else {
+			div := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-exp)), nil)
+			q, r := new(big.Int).QuoRem(v, div, new(big.Int))
+			if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(div) >= 0 {
+				q.Add(q, big.NewInt(int64(v.Sign())))
+			}
+			v = q
+		}
+
+		if !v.IsInt64() {
+			return fmt.Errorf("decimal128 %s overflows money", raw.Decimal128())
+		}
+		*m = Money(v.Int64())
+	case bsontype.Double:
+		*m = NewMoney(raw.Double())
+	case bsontype.Int32:
+		*m = Money(int64(raw.Int32()) * MoneyScale)
+	case bsontype.Int64:
+		*m = Money(raw.Int64() * MoneyScale)
+	case bsontype.Null:
+		*m = 0
+	default:
+		return fmt.Errorf("can not decode %s into money", t)
+	}
+
+	return nil
+}
+
+// Value will write the amount as an integer number of thousandths
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan will read the amount from an integer number of thousandths
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		i, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return err
+		}
+		*m = Money(i)
+	case nil:
+		*m = 0
+	default:
+		return errors.New("can not scan money")
+	}
+
+	return nil
+}
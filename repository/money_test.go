@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Money
+		err   bool
+	}{
+		{"12.90", 12900, false},
+		{"0.1", 100, false},
+		{"-3", -3000, false},
+		{".5", 500, false},
+		{"1.2345", 0, true},
+		{"1.2a", 0, true},
+		{"+1", 0, true},
+		{"--5", 0, true},
+		{"-+5", 0, true},
+		{"1.-5", 0, true},
+		{"--9223372036854775807", 0, true},
+		{"-9223372036854775.808", 0, true},
+		{"-9223372036854775.807", -9223372036854775807, false},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.value)
+		if (err != nil) != tt.err {
+			t.Errorf("unexpected error parsing '%s': %v", tt.value, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("unexpected amount parsing '%s': got %v want %v", tt.value, int64(got), int64(tt.want))
+		}
+	}
+}
+
+func TestMoneySumsDoNotDrift(t *testing.T) {
+	var spend struct {
+		Cost Money `json:"cost"`
+	}
+
+	var total Money
+	for i := 0; i < 10; i++ {
+		if err := json.Unmarshal([]byte(`{"cost": 0.1}`), &spend); err != nil {
+			t.Fatal(err)
+		}
+		total += spend.Cost
+	}
+
+	if total.String() != "1" {
+		t.Errorf("unexpected total: got %v want %v", total, "1")
+	}
+
+	out, err := json.Marshal(Money(12900))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != "12.9" {
+		t.Errorf("unexpected JSON: got %s want %s", out, "12.9")
+	}
+}
+
+func TestUnmarshalMoneyJSON(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Money
+		err   bool
+	}{
+		{`12.9`, 12900, false},
+		{`"12.90"`, 12900, false},
+		{`1.5e2`, 150000, false},
+		{`-2E-1`, -200, false},
+		{`1500e-3`, 1500, false},
+		{`0e400`, 0, false},
+		{`9.2e15`, 9200000000000000000, false},
+		{`9.123456789012345e15`, 9123456789012345000, false},
+		{`1.2345e0`, 0, true},
+		{`1e-4`, 0, true},
+		{`1e30`, 0, true},
+		{`-1e16`, 0, true},
+		{`1e400`, 0, true},
+		{`1e99999999999999999999`, 0, true},
+		{`"1.2345"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.value), &got)
+		if (err != nil) != tt.err {
+			t.Errorf("unexpected error reading %s: %v", tt.value, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("unexpected amount reading %s: got %v want %v", tt.value, int64(got), int64(tt.want))
+		}
+	}
+}
+
+func TestMoneyBSON(t *testing.T) {
+	out, err := bson.Marshal(bson.M{"cost": Money(-12905)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded struct {
+		Cost Money `bson:"cost"`
+	}
+	if err := bson.Unmarshal(out, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Cost != -12905 {
+		t.Errorf("unexpected decimal128 round trip: got %v want %v", decoded.Cost, Money(-12905))
+	}
+
+	// amounts stored as doubles before Money existed must still be read
+	legacy, err := bson.Marshal(bson.M{"cost": 12.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bson.Unmarshal(legacy, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Cost != 12900 {
+		t.Errorf("unexpected legacy amount: got %v want %v", decoded.Cost, Money(12900))
+	}
+}
+
+func TestValidateAmount(t *testing.T) {
+	if err := ValidateAmount(Money(12900), "BRL"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := ValidateAmount(Money(12500), "JPY"); err == nil {
+		t.Errorf("expected an error for fractional yens")
+	}
+
+	if err := ValidateAmount(Money(12345), "KWD"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if _, err := NormalizeCurrency("xyz"); err == nil {
+		t.Errorf("expected an error for an unknown currency")
+	}
+}
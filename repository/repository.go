@@ -1,6 +1,71 @@
 package repository
 
-import "context"
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// DefaultSpendsLimit defines the page size when listing spends without a limit
+	DefaultSpendsLimit = 50
+	// MaxSpendsLimit defines the biggest page size allowed when listing spends
+	MaxSpendsLimit = 200
+)
+
+// EncodeSpendCursor will return an opaque cursor pointing to the position right after a given spend
+func EncodeSpendCursor(s Spend) string {
+	raw := strconv.FormatInt(int64(s.Date), 10) + ":" + s.ID.Hex()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeSpendCursor will return the spend date and ID a cursor is pointing to
+func DecodeSpendCursor(cursor string) (date primitive.DateTime, id primitive.ObjectID, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, primitive.NilObjectID, errors.New("invalid cursor")
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 2 {
+		return 0, primitive.NilObjectID, errors.New("invalid cursor")
+	}
+
+	millis, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, primitive.NilObjectID, errors.New("invalid cursor")
+	}
+
+	id, err = primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return 0, primitive.NilObjectID, errors.New("invalid cursor")
+	}
+
+	return primitive.NewDateTimeFromTime(time.Unix(0, millis*int64(time.Millisecond))), id, nil
+}
+
+// Repositories defines every repository used by the application, so storage backends can be swapped
+type Repositories struct {
+	Database      DatabaseManagerRepository
+	Users         UserRepository
+	Cards         CardRepository
+	Balances      BalanceRepository
+	Spends        SpendRepository
+	RefreshTokens RefreshTokenRepository
+	Revocations   RevocationRepository
+	LoginAttempts LoginAttemptRepository
+	ExchangeRates ExchangeRateRepository
+	Recurrences   RecurrenceRepository
+	Statements    StatementPaymentRepository
+	BudgetAlerts  BudgetAlertRepository
+	Categories    CategoryRepository
+	CategoryRules CategoryRuleRepository
+}
 
 // NewDatabaseManagerRepository will return a UserRepository interface based on a struct
 func NewDatabaseManagerRepository(d DatabaseManagerRepository) DatabaseManagerRepository {
@@ -17,6 +82,51 @@ func NewCardRepository(c CardRepository) CardRepository {
 	return c
 }
 
+// NewRefreshTokenRepository will return a RefreshTokenRepository interface based on a struct
+func NewRefreshTokenRepository(r RefreshTokenRepository) RefreshTokenRepository {
+	return r
+}
+
+// NewRevocationRepository will return a RevocationRepository interface based on a struct
+func NewRevocationRepository(r RevocationRepository) RevocationRepository {
+	return r
+}
+
+// NewLoginAttemptRepository will return a LoginAttemptRepository interface based on a struct
+func NewLoginAttemptRepository(l LoginAttemptRepository) LoginAttemptRepository {
+	return l
+}
+
+// NewExchangeRateRepository will return a ExchangeRateRepository interface based on a struct
+func NewExchangeRateRepository(e ExchangeRateRepository) ExchangeRateRepository {
+	return e
+}
+
+// NewRecurrenceRepository will return a RecurrenceRepository interface based on a struct
+func NewRecurrenceRepository(r RecurrenceRepository) RecurrenceRepository {
+	return r
+}
+
+// NewStatementPaymentRepository will return a StatementPaymentRepository interface based on a struct
+func NewStatementPaymentRepository(s StatementPaymentRepository) StatementPaymentRepository {
+	return s
+}
+
+// NewBudgetAlertRepository will return a BudgetAlertRepository interface based on a struct
+func NewBudgetAlertRepository(b BudgetAlertRepository) BudgetAlertRepository {
+	return b
+}
+
+// NewCategoryRepository will return a CategoryRepository interface based on a struct
+func NewCategoryRepository(c CategoryRepository) CategoryRepository {
+	return c
+}
+
+// NewCategoryRuleRepository will return a CategoryRuleRepository interface based on a struct
+func NewCategoryRuleRepository(c CategoryRuleRepository) CategoryRuleRepository {
+	return c
+}
+
 // NewBalanceRepository will return a BalanceRepository interface based on a struct
 func NewBalanceRepository(b BalanceRepository) BalanceRepository {
 	return b
@@ -30,37 +140,163 @@ func NewSpendRepository(s SpendRepository) SpendRepository {
 // DatabaseManagerRepository will define instance operations
 type DatabaseManagerRepository interface {
 	Health() (err error)
+	// Transaction will run a function whose writes are committed together, given the context it must
+	// use for them. Backends unable to do so run it as is, relying on the function to revert itself
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
 	// CreateIndex() (err error)
 }
 
+// transactionKey defines the context key marking writes committed together by a Transaction
+type transactionKey struct{}
+
+// withinTransaction will mark a context whose writes are committed together
+func withinTransaction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, transactionKey{}, true)
+}
+
+// Transactional will tell whether writes given a context are rolled back along with their transaction,
+// so they do not need to be reverted by hand
+func Transactional(ctx context.Context) bool {
+	within, _ := ctx.Value(transactionKey{}).(bool)
+	return within
+}
+
 // UserRepository defines a User
 type UserRepository interface {
 	Get(ctx context.Context, id string) (SanitizedUser, error)
+	// GetByLogin will return the whole user, including its salted password
+	GetByLogin(ctx context.Context, login string) (User, error)
 	GetAll(ctx context.Context) ([]SanitizedUser, error)
 	Create(ctx context.Context, d User) (id string, err error)
 	Delete(ctx context.Context, id string) error
 }
 
+// RefreshTokenRepository defines a RefreshToken
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, t RefreshToken) error
+	// Consume will atomically mark a token as used, returning an error when it was already used or revoked
+	Consume(ctx context.Context, id string) (RefreshToken, error)
+	RevokeFamily(ctx context.Context, family string) error
+	RevokeOwner(ctx context.Context, ownerID string) error
+}
+
+// RevocationRepository defines a Revocation
+type RevocationRepository interface {
+	Create(ctx context.Context, r Revocation) error
+	// IsRevoked will return if a token was revoked either by its ID or by revoking all sessions from its owner
+	IsRevoked(ctx context.Context, tokenID string, ownerID string, issuedAt time.Time) (bool, error)
+}
+
+// LoginAttemptRepository defines a LoginAttempt
+type LoginAttemptRepository interface {
+	Get(ctx context.Context, login string) (LoginAttempt, error)
+	// RegisterFailure will atomically increase the failures from a login, returning the updated attempt
+	RegisterFailure(ctx context.Context, login string, at time.Time, expiresAt time.Time) (LoginAttempt, error)
+	Lock(ctx context.Context, login string, until time.Time) error
+	Reset(ctx context.Context, login string) error
+}
+
+// ExchangeRateRepository defines an ExchangeRate
+type ExchangeRateRepository interface {
+	// Upsert will create or replace the rate of a currency pair for its day
+	Upsert(ctx context.Context, r ExchangeRate) error
+	// Get will return the most recent rate of a currency pair published until a given day
+	Get(ctx context.Context, base string, quote string, date time.Time) (ExchangeRate, error)
+	List(ctx context.Context, f ExchangeRateFilter) ([]ExchangeRate, error)
+}
+
+// RecurrenceRepository defines a Recurrence
+type RecurrenceRepository interface {
+	Create(ctx context.Context, r Recurrence) (id string, err error)
+	Get(ctx context.Context, ownerID string) ([]Recurrence, error)
+	GetByID(ctx context.Context, ownerID string, id string) (Recurrence, error)
+	// Due will return every active recurrence whose next run is until a given date
+	Due(ctx context.Context, until time.Time) ([]Recurrence, error)
+	// Schedule will update the status and next run of a recurrence only while its status and next run are
+	// still the previous ones, so concurrent schedulers never materialize the same spend twice nor revive
+	// a recurrence paused or cancelled meanwhile
+	Schedule(ctx context.Context, r Recurrence, status string, previous primitive.DateTime) error
+	// UpdateCategories will replace the categories of a recurrence, used by its future spends
+	UpdateCategories(ctx context.Context, r Recurrence) error
+}
+
+// StatementPaymentRepository defines a StatementPayment
+type StatementPaymentRepository interface {
+	// Create will store a payment, returning an error when the statement was already paid
+	Create(ctx context.Context, p StatementPayment) (id string, err error)
+	Get(ctx context.Context, ownerID string, cardID string, month int64, year int64) (StatementPayment, error)
+	// List will return every payment of a card, sorted by statement
+	List(ctx context.Context, ownerID string, cardID string) ([]StatementPayment, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// BudgetAlertRepository defines a BudgetAlert
+type BudgetAlertRepository interface {
+	// Create will store an alert, returning an error when its threshold was already alerted that month
+	Create(ctx context.Context, a BudgetAlert) (id string, err error)
+	// List will return every alert of a month, sorted by creation
+	List(ctx context.Context, ownerID string, month int64, year int64) ([]BudgetAlert, error)
+}
+
+// CategoryRepository defines a Category
+type CategoryRepository interface {
+	// Get will return every category of an owner, sorted by name
+	Get(ctx context.Context, ownerID string) ([]Category, error)
+	GetByID(ctx context.Context, ownerID string, id string) (Category, error)
+	// Create will store a category, returning an error when its name is already used by the owner regardless of case
+	Create(ctx context.Context, c Category) (id string, err error)
+	// Update will replace every attribute of a category but its owner and creation date
+	Update(ctx context.Context, c Category) error
+	Delete(ctx context.Context, id string) error
+}
+
+// CategoryRuleRepository defines a CategoryRule
+type CategoryRuleRepository interface {
+	// Get will return every rule of an owner, sorted by priority and then by creation
+	Get(ctx context.Context, ownerID string) ([]CategoryRule, error)
+	GetByID(ctx context.Context, ownerID string, id string) (CategoryRule, error)
+	Create(ctx context.Context, r CategoryRule) (id string, err error)
+	// Update will replace every attribute of a rule but its owner and creation date
+	Update(ctx context.Context, r CategoryRule) error
+	Delete(ctx context.Context, id string) error
+}
+
 // CardRepository defines a Card
 type CardRepository interface {
 	Get(ctx context.Context, ownerID string) ([]CreditCard, error)
+	GetByID(ctx context.Context, ownerID string, id string) (CreditCard, error)
+	// GetOwnerID will return who owns a card, for routes which are not scoped by owner
+	GetOwnerID(ctx context.Context, id string) (string, error)
 	GetAll(ctx context.Context) ([]CreditCard, error)
 	Create(ctx context.Context, c CreditCard) (id string, err error)
+	// Update will replace every attribute of a card but its owner, last digits and creation date
+	Update(ctx context.Context, c CreditCard) error
 	Delete(ctx context.Context, id string) error
 }
 
 // SpendRepository defines a Spend
 type SpendRepository interface {
 	Get(ctx context.Context, ownerID string) ([]Spend, error)
+	GetByID(ctx context.Context, ownerID string, id string) (Spend, error)
+	Find(ctx context.Context, f SpendFilter) (SpendPage, error)
+	// Totals will return how much the spends of an owner within a date range cost, grouped by one of the
+	// SpendGroup* criteria and by currency. Statement payments are not accounted
+	Totals(ctx context.Context, ownerID string, from time.Time, to time.Time, group string) ([]SpendTotal, error)
 	GetAll(ctx context.Context) ([]Spend, error)
 	Create(ctx context.Context, s Spend) (id string, err error)
+	Update(ctx context.Context, s Spend) error
 	Delete(ctx context.Context, id string) error
 }
 
 // BalanceRepository defines a Balance
 type BalanceRepository interface {
 	Get(ctx context.Context, ownerID string, month int64, year int64) (Balance, error)
+	GetByID(ctx context.Context, ownerID string, id string) (Balance, error)
+	List(ctx context.Context, f BalanceFilter) ([]Balance, error)
 	GetAll(ctx context.Context) ([]Balance, error)
 	Create(ctx context.Context, b Balance) (id string, err error)
+	Update(ctx context.Context, b Balance) error
 	Delete(ctx context.Context, id string) error
+	AddSpend(ctx context.Context, s Spend) error
+	RemoveSpend(ctx context.Context, s Spend) error
 }
@@ -0,0 +1,1693 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore holds every entity of the in-memory repositories. It's meant for development and
+// tests, so nothing survives restarts
+type MemoryStore struct {
+	mu            sync.RWMutex
+	users         map[primitive.ObjectID]User
+	cards         map[primitive.ObjectID]CreditCard
+	balances      map[primitive.ObjectID]Balance
+	spends        map[primitive.ObjectID]Spend
+	refreshTokens map[string]RefreshToken
+	revocations   []Revocation
+	loginAttempts map[string]LoginAttempt
+	exchangeRates map[string]ExchangeRate
+	recurrences   map[primitive.ObjectID]Recurrence
+	statements    map[primitive.ObjectID]StatementPayment
+	budgetAlerts  map[primitive.ObjectID]BudgetAlert
+	categories    map[primitive.ObjectID]Category
+	categoryRules map[primitive.ObjectID]CategoryRule
+}
+
+// NewMemoryStore will return an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:         map[primitive.ObjectID]User{},
+		cards:         map[primitive.ObjectID]CreditCard{},
+		balances:      map[primitive.ObjectID]Balance{},
+		spends:        map[primitive.ObjectID]Spend{},
+		refreshTokens: map[string]RefreshToken{},
+		loginAttempts: map[string]LoginAttempt{},
+		exchangeRates: map[string]ExchangeRate{},
+		recurrences:   map[primitive.ObjectID]Recurrence{},
+		statements:    map[primitive.ObjectID]StatementPayment{},
+		budgetAlerts:  map[primitive.ObjectID]BudgetAlert{},
+		categories:    map[primitive.ObjectID]Category{},
+		categoryRules: map[primitive.ObjectID]CategoryRule{},
+	}
+}
+
+// NewMemoryRepositories will return every repository backed by a single in-memory store
+func NewMemoryRepositories() Repositories {
+	store := NewMemoryStore()
+
+	return Repositories{
+		Database:      &DatabaseRepositoryMemory{Store: store},
+		Users:         &UserRepositoryMemory{Store: store},
+		Cards:         &CardRepositoryMemory{Store: store},
+		Balances:      &BalanceRepositoryMemory{Store: store},
+		Spends:        &SpendRepositoryMemory{Store: store},
+		RefreshTokens: &RefreshTokenRepositoryMemory{Store: store},
+		Revocations:   &RevocationRepositoryMemory{Store: store},
+		LoginAttempts: &LoginAttemptRepositoryMemory{Store: store},
+		ExchangeRates: &ExchangeRateRepositoryMemory{Store: store},
+		Recurrences:   &RecurrenceRepositoryMemory{Store: store},
+		Statements:    &StatementPaymentRepositoryMemory{Store: store},
+		BudgetAlerts:  &BudgetAlertRepositoryMemory{Store: store},
+		Categories:    &CategoryRepositoryMemory{Store: store},
+		CategoryRules: &CategoryRuleRepositoryMemory{Store: store},
+	}
+}
+
+// DatabaseRepositoryMemory defines a struct for in-memory database operations
+type DatabaseRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// UserRepositoryMemory defines a struct for in-memory User operations
+type UserRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// CardRepositoryMemory defines a struct for in-memory Card operations
+type CardRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// BalanceRepositoryMemory defines a struct for in-memory Balance operations
+type BalanceRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// SpendRepositoryMemory defines a struct for in-memory Spend operations
+type SpendRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// RefreshTokenRepositoryMemory defines a struct for in-memory RefreshToken operations
+type RefreshTokenRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// RevocationRepositoryMemory defines a struct for in-memory Revocation operations
+type RevocationRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// LoginAttemptRepositoryMemory defines a struct for in-memory LoginAttempt operations
+type LoginAttemptRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// ExchangeRateRepositoryMemory defines a struct for in-memory ExchangeRate operations
+type ExchangeRateRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// RecurrenceRepositoryMemory defines a struct for in-memory Recurrence operations
+type RecurrenceRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// CategoryRuleRepositoryMemory defines a struct for in-memory CategoryRule operations
+type CategoryRuleRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// CategoryRepositoryMemory defines a struct for in-memory Category operations
+type CategoryRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// BudgetAlertRepositoryMemory defines a struct for in-memory BudgetAlert operations
+type BudgetAlertRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// StatementPaymentRepositoryMemory defines a struct for in-memory StatementPayment operations
+type StatementPaymentRepositoryMemory struct {
+	Store *MemoryStore
+}
+
+// copySpend will return a spend which shares no slices with the stored one
+func copySpend(s Spend) Spend {
+	if s.Categories != nil {
+		s.Categories = append([]string{}, s.Categories...)
+	}
+	if s.Installment != nil {
+		installment := *s.Installment
+		s.Installment = &installment
+	}
+	return s
+}
+
+// copyRecurrence will return a recurrence which shares no slices with the stored one
+func copyRecurrence(r Recurrence) Recurrence {
+	if r.Categories != nil {
+		r.Categories = append([]string{}, r.Categories...)
+	}
+	return r
+}
+
+// copyCategoryRule will return a rule which shares no slices nor amounts with the stored one
+func copyCategoryRule(r CategoryRule) CategoryRule {
+	if r.Categories != nil {
+		r.Categories = append([]string{}, r.Categories...)
+	}
+	if r.MinCost != nil {
+		min := *r.MinCost
+		r.MinCost = &min
+	}
+	if r.MaxCost != nil {
+		max := *r.MaxCost
+		r.MaxCost = &max
+	}
+	return r
+}
+
+// sortRecurrences will sort recurrences by their next run
+func sortRecurrences(recurrences []Recurrence) {
+	sort.Slice(recurrences, func(i, j int) bool {
+		if recurrences[i].NextRun != recurrences[j].NextRun {
+			return recurrences[i].NextRun < recurrences[j].NextRun
+		}
+		return recurrences[i].ID.Hex() < recurrences[j].ID.Hex()
+	})
+}
+
+// copyBalance will return a balance which shares no slices with the stored one
+func copyBalance(b Balance) Balance {
+	historic := make([]Spend, 0, len(b.Historic))
+	for _, s := range b.Historic {
+		historic = append(historic, copySpend(s))
+	}
+	b.Historic = historic
+	return b
+}
+
+// sanitizeUser will return a user without its salted password
+func sanitizeUser(u User) SanitizedUser {
+	return SanitizedUser{
+		ID:        u.ID,
+		Login:     u.Login,
+		Firstname: u.Firstname,
+		Lastname:  u.Lastname,
+		Email:     u.Email,
+		Roles:     append([]string(nil), u.Roles...),
+	}
+}
+
+// Health will always succeed since there is no external database
+func (d *DatabaseRepositoryMemory) Health() error {
+	return nil
+}
+
+// Transaction will run a function as is, since in-memory writes can not be rolled back
+func (d *DatabaseRepositoryMemory) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// Get will return a user based on it's ID
+func (u *UserRepositoryMemory) Get(ctx context.Context, id string) (SanitizedUser, error) {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return SanitizedUser{}, err
+	}
+
+	u.Store.mu.RLock()
+	defer u.Store.mu.RUnlock()
+
+	user, ok := u.Store.users[pid]
+	if !ok {
+		return SanitizedUser{}, errors.New("could not find user")
+	}
+
+	return sanitizeUser(user), nil
+}
+
+// GetByLogin will return a user, along with its salted password, based on its login
+func (u *UserRepositoryMemory) GetByLogin(ctx context.Context, login string) (User, error) {
+	u.Store.mu.RLock()
+	defer u.Store.mu.RUnlock()
+
+	for _, user := range u.Store.users {
+		if user.Login == login {
+			user.Roles = append([]string(nil), user.Roles...)
+			return user, nil
+		}
+	}
+
+	return User{}, errors.New("could not find user")
+}
+
+// GetAll will return all Users (in a sanitized way)
+func (u *UserRepositoryMemory) GetAll(ctx context.Context) ([]SanitizedUser, error) {
+	u.Store.mu.RLock()
+	defer u.Store.mu.RUnlock()
+
+	var users []SanitizedUser
+	for _, user := range u.Store.users {
+		users = append(users, sanitizeUser(user))
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID.Hex() < users[j].ID.Hex() })
+	return users, nil
+}
+
+// Create will create a user, whose login must be unique
+func (u *UserRepositoryMemory) Create(ctx context.Context, user User) (id string, err error) {
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	for _, existing := range u.Store.users {
+		if existing.ID == user.ID || existing.Login == user.Login {
+			return "", errors.New("user already exists")
+		}
+	}
+
+	user.Roles = append([]string(nil), user.Roles...)
+	u.Store.users[user.ID] = user
+	return user.ID.Hex(), nil
+}
+
+// Delete will delete a user based on it's ID
+func (u *UserRepositoryMemory) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	u.Store.mu.Lock()
+	defer u.Store.mu.Unlock()
+
+	if _, ok := u.Store.users[pid]; !ok {
+		return errors.New("non existent user")
+	}
+
+	delete(u.Store.users, pid)
+	return nil
+}
+
+// Get will return all cards from a given owner ID
+func (c *CardRepositoryMemory) Get(ctx context.Context, ownerID string) ([]CreditCard, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []CreditCard{}, err
+	}
+
+	c.Store.mu.RLock()
+	defer c.Store.mu.RUnlock()
+
+	var cards []CreditCard
+	for _, card := range c.Store.cards {
+		if card.OwnerID == oid {
+			cards = append(cards, card)
+		}
+	}
+
+	if len(cards) == 0 {
+		return []CreditCard{}, errors.New("could not find any cards")
+	}
+
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID.Hex() < cards[j].ID.Hex() })
+	return cards, nil
+}
+
+// GetByID will return a single card from a given owner ID
+func (c *CardRepositoryMemory) GetByID(ctx context.Context, ownerID string, id string) (CreditCard, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return CreditCard{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return CreditCard{}, err
+	}
+
+	c.Store.mu.RLock()
+	defer c.Store.mu.RUnlock()
+
+	card, ok := c.Store.cards[pid]
+	if !ok || card.OwnerID != oid {
+		return CreditCard{}, errors.New("could not find card")
+	}
+
+	return card, nil
+}
+
+// GetOwnerID will return who owns a card, for routes which are not scoped by owner
+func (c *CardRepositoryMemory) GetOwnerID(ctx context.Context, id string) (string, error) {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return "", err
+	}
+
+	c.Store.mu.RLock()
+	defer c.Store.mu.RUnlock()
+
+	card, ok := c.Store.cards[pid]
+	if !ok {
+		return "", errors.New("could not find card")
+	}
+
+	return card.OwnerID.Hex(), nil
+}
+
+// GetAll will return literally all cards
+func (c *CardRepositoryMemory) GetAll(ctx context.Context) ([]CreditCard, error) {
+	c.Store.mu.RLock()
+	defer c.Store.mu.RUnlock()
+
+	var cards []CreditCard
+	for _, card := range c.Store.cards {
+		cards = append(cards, card)
+	}
+
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID.Hex() < cards[j].ID.Hex() })
+	return cards, nil
+}
+
+// Create will create a card, whose last digits must be unique per owner
+func (c *CardRepositoryMemory) Create(ctx context.Context, card CreditCard) (id string, err error) {
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	if card.ID.IsZero() {
+		card.ID = primitive.NewObjectID()
+	}
+
+	for _, existing := range c.Store.cards {
+		if existing.ID == card.ID || (existing.OwnerID == card.OwnerID && existing.LastDigits == card.LastDigits) {
+			return "", errors.New("card already exists")
+		}
+	}
+
+	c.Store.cards[card.ID] = card
+	return card.ID.Hex(), nil
+}
+
+// Update will replace every attribute of a card but its owner, last digits and creation date
+func (c *CardRepositoryMemory) Update(ctx context.Context, card CreditCard) error {
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	current, ok := c.Store.cards[card.ID]
+	if !ok || current.OwnerID != card.OwnerID {
+		return errors.New("could not find card")
+	}
+
+	card.LastDigits = current.LastDigits
+	card.CreatedAt = current.CreatedAt
+	c.Store.cards[card.ID] = card
+	return nil
+}
+
+// Delete will delete a card based on it's ID
+func (c *CardRepositoryMemory) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	if _, ok := c.Store.cards[pid]; !ok {
+		return errors.New("non existent card")
+	}
+
+	delete(c.Store.cards, pid)
+	return nil
+}
+
+// find will return the balance from an owner for a given month and year
+func (b *BalanceRepositoryMemory) find(ownerID primitive.ObjectID, month int64, year int64) (Balance, bool) {
+	for _, balance := range b.Store.balances {
+		if balance.OwnerID == ownerID && balance.Month == month && balance.Year == year {
+			return balance, true
+		}
+	}
+
+	return Balance{}, false
+}
+
+// Get will return a balance from an owner based on month and year
+func (b *BalanceRepositoryMemory) Get(ctx context.Context, ownerID string, month int64, year int64) (Balance, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	b.Store.mu.RLock()
+	defer b.Store.mu.RUnlock()
+
+	balance, ok := b.find(oid, month, year)
+	if !ok {
+		return Balance{}, errors.New("could not find balance")
+	}
+
+	return copyBalance(balance), nil
+}
+
+// GetByID will return a single balance from a given ownerID
+func (b *BalanceRepositoryMemory) GetByID(ctx context.Context, ownerID string, id string) (Balance, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	b.Store.mu.RLock()
+	defer b.Store.mu.RUnlock()
+
+	balance, ok := b.Store.balances[pid]
+	if !ok || balance.OwnerID != oid {
+		return Balance{}, errors.New("could not find balance")
+	}
+
+	return copyBalance(balance), nil
+}
+
+// List will return balances from an owner within a given period, sorted chronologically
+func (b *BalanceRepositoryMemory) List(ctx context.Context, f BalanceFilter) ([]Balance, error) {
+	oid, err := primitive.ObjectIDFromHex(f.OwnerID)
+	if err != nil {
+		return []Balance{}, err
+	}
+
+	b.Store.mu.RLock()
+	defer b.Store.mu.RUnlock()
+
+	balances := []Balance{}
+	for _, balance := range b.Store.balances {
+		if balance.OwnerID != oid {
+			continue
+		}
+
+		period := balance.Year*12 + balance.Month
+		if !f.From.IsZero() && period < f.From.Year*12+f.From.Month {
+			continue
+		}
+		if !f.To.IsZero() && period > f.To.Year*12+f.To.Month {
+			continue
+		}
+
+		balances = append(balances, copyBalance(balance))
+	}
+
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].Year*12+balances[i].Month < balances[j].Year*12+balances[j].Month
+	})
+
+	return balances, nil
+}
+
+// GetAll will return literally all balances
+func (b *BalanceRepositoryMemory) GetAll(ctx context.Context) ([]Balance, error) {
+	b.Store.mu.RLock()
+	defer b.Store.mu.RUnlock()
+
+	var balances []Balance
+	for _, balance := range b.Store.balances {
+		balances = append(balances, copyBalance(balance))
+	}
+
+	if len(balances) == 0 {
+		return []Balance{}, errors.New("could not find any balances")
+	}
+
+	sort.Slice(balances, func(i, j int) bool { return balances[i].ID.Hex() < balances[j].ID.Hex() })
+	return balances, nil
+}
+
+// Create will create a balance, which must be unique per owner, month and year
+func (b *BalanceRepositoryMemory) Create(ctx context.Context, balance Balance) (id string, err error) {
+	b.Store.mu.Lock()
+	defer b.Store.mu.Unlock()
+
+	if balance.ID.IsZero() {
+		balance.ID = primitive.NewObjectID()
+	}
+
+	if _, ok := b.Store.balances[balance.ID]; ok {
+		return "", errors.New("balance already exists")
+	}
+
+	if _, ok := b.find(balance.OwnerID, balance.Month, balance.Year); ok {
+		return "", errors.New("balance already exists")
+	}
+
+	b.Store.balances[balance.ID] = copyBalance(balance)
+	return balance.ID.Hex(), nil
+}
+
+// Update will change a balance income, currency and budgets, recomputing its spendable amount against the recorded outcome
+func (b *BalanceRepositoryMemory) Update(ctx context.Context, balance Balance) error {
+	b.Store.mu.Lock()
+	defer b.Store.mu.Unlock()
+
+	current, ok := b.Store.balances[balance.ID]
+	if !ok || current.OwnerID != balance.OwnerID {
+		return errors.New("could not find balance")
+	}
+
+	current.Income = balance.Income
+	current.Currency = balance.Currency
+	current.Budgets = append([]Budget{}, balance.Budgets...)
+	current.UpdatedAt = balance.UpdatedAt
+	current.SpendableAmount = current.Income.NetIncome - (current.Outcome.FixedOutcome + current.Outcome.DynamicOutcome)
+
+	b.Store.balances[current.ID] = current
+	return nil
+}
+
+// Delete will delete a balance based on it's ID
+func (b *BalanceRepositoryMemory) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	b.Store.mu.Lock()
+	defer b.Store.mu.Unlock()
+
+	if _, ok := b.Store.balances[pid]; !ok {
+		return errors.New("non existent balance")
+	}
+
+	delete(b.Store.balances, pid)
+	return nil
+}
+
+// AddSpend will append a spend to its month's balance, creating the balance when needed
+func (b *BalanceRepositoryMemory) AddSpend(ctx context.Context, spend Spend) error {
+	b.Store.mu.Lock()
+	defer b.Store.mu.Unlock()
+
+	month, year := spend.Period()
+	t := primitive.NewDateTimeFromTime(time.Now())
+
+	balance, ok := b.find(spend.OwnerID, month, year)
+	if !ok {
+		balance = Balance{
+			ID:        primitive.NewObjectID(),
+			OwnerID:   spend.OwnerID,
+			Month:     month,
+			Year:      year,
+			Currency:  spend.Currency,
+			Historic:  []Spend{},
+			CreatedAt: t,
+		}
+	}
+
+	if spend.IsFixed() {
+		balance.Outcome.FixedOutcome += spend.Outcome()
+	} else {
+		balance.Outcome.DynamicOutcome += spend.Outcome()
+	}
+
+	balance.SpendableAmount -= spend.Outcome()
+	balance.Historic = append(balance.Historic, copySpend(spend))
+	balance.UpdatedAt = t
+
+	b.Store.balances[balance.ID] = balance
+	return nil
+}
+
+// RemoveSpend will pull a spend from its month's balance, reverting its outcome
+func (b *BalanceRepositoryMemory) RemoveSpend(ctx context.Context, spend Spend) error {
+	b.Store.mu.Lock()
+	defer b.Store.mu.Unlock()
+
+	month, year := spend.Period()
+
+	balance, ok := b.find(spend.OwnerID, month, year)
+	if !ok {
+		return errors.New("could not find spend in balance")
+	}
+
+	historic := []Spend{}
+	for _, s := range balance.Historic {
+		if s.ID != spend.ID {
+			historic = append(historic, s)
+		}
+	}
+
+	if len(historic) == len(balance.Historic) {
+		return errors.New("could not find spend in balance")
+	}
+
+	if spend.IsFixed() {
+		balance.Outcome.FixedOutcome -= spend.Outcome()
+	} else {
+		balance.Outcome.DynamicOutcome -= spend.Outcome()
+	}
+
+	balance.SpendableAmount += spend.Outcome()
+	balance.Historic = historic
+	balance.UpdatedAt = primitive.NewDateTimeFromTime(time.Now())
+
+	b.Store.balances[balance.ID] = balance
+	return nil
+}
+
+// Get will return a list of spends from a given ownerID
+func (s *SpendRepositoryMemory) Get(ctx context.Context, ownerID string) ([]Spend, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []Spend{}, err
+	}
+
+	s.Store.mu.RLock()
+	defer s.Store.mu.RUnlock()
+
+	var spends []Spend
+	for _, spend := range s.Store.spends {
+		if spend.OwnerID == oid {
+			spends = append(spends, copySpend(spend))
+		}
+	}
+
+	if len(spends) == 0 {
+		return []Spend{}, errors.New("could not find any spends")
+	}
+
+	sort.Slice(spends, func(i, j int) bool { return spends[i].ID.Hex() < spends[j].ID.Hex() })
+	return spends, nil
+}
+
+// GetByID will return a single spend from a given ownerID
+func (s *SpendRepositoryMemory) GetByID(ctx context.Context, ownerID string, id string) (Spend, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	s.Store.mu.RLock()
+	defer s.Store.mu.RUnlock()
+
+	spend, ok := s.Store.spends[pid]
+	if !ok || spend.OwnerID != oid {
+		return Spend{}, errors.New("could not find spend")
+	}
+
+	return copySpend(spend), nil
+}
+
+// matchesSpendFilter will return if a spend matches every criteria from a filter but its owner and cursor
+func matchesSpendFilter(spend Spend, f SpendFilter, cardID primitive.ObjectID) bool {
+	date := spend.Date.Time()
+	if !f.From.IsZero() && date.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && date.After(f.To) {
+		return false
+	}
+
+	if f.Type != "" && spend.Type != f.Type {
+		return false
+	}
+
+	if f.Category != "" {
+		found := false
+		for _, c := range spend.Categories {
+			if c == f.Category {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	switch f.PaymentMethod {
+	case PaymentMethodDebit:
+		if !spend.PaymentMethod.Debit {
+			return false
+		}
+	case PaymentMethodCredit:
+		if spend.PaymentMethod.Credit.ID.IsZero() {
+			return false
+		}
+	case PaymentMethodPaymentSlip:
+		if !spend.PaymentMethod.PaymentSlip {
+			return false
+		}
+	}
+
+	if !cardID.IsZero() && spend.PaymentMethod.Credit.ID != cardID {
+		return false
+	}
+
+	if f.MinCost != nil && spend.Cost < *f.MinCost {
+		return false
+	}
+	if f.MaxCost != nil && spend.Cost > *f.MaxCost {
+		return false
+	}
+
+	if f.Description != "" && !strings.Contains(strings.ToLower(spend.Description), strings.ToLower(f.Description)) {
+		return false
+	}
+
+	return true
+}
+
+// Find will return a page of spends from an owner matching a given filter, sorted by date
+func (s *SpendRepositoryMemory) Find(ctx context.Context, f SpendFilter) (SpendPage, error) {
+	oid, err := primitive.ObjectIDFromHex(f.OwnerID)
+	if err != nil {
+		return SpendPage{}, err
+	}
+
+	var cardID primitive.ObjectID
+	if f.CardID != "" {
+		cardID, err = primitive.ObjectIDFromHex(f.CardID)
+		if err != nil {
+			return SpendPage{}, err
+		}
+	}
+
+	var cursorDate primitive.DateTime
+	var cursorID primitive.ObjectID
+	if f.Cursor != "" {
+		cursorDate, cursorID, err = DecodeSpendCursor(f.Cursor)
+		if err != nil {
+			return SpendPage{}, err
+		}
+	}
+
+	// before will return if a spend comes before another one in the requested order
+	before := func(a Spend, b Spend) bool {
+		if a.Date != b.Date {
+			return (a.Date < b.Date) == f.Ascending
+		}
+		// a spend never comes before itself, otherwise descending pages would repeat their cursor
+		if a.ID == b.ID {
+			return false
+		}
+		return (a.ID.Hex() < b.ID.Hex()) == f.Ascending
+	}
+
+	s.Store.mu.RLock()
+	defer s.Store.mu.RUnlock()
+
+	spends := []Spend{}
+	for _, spend := range s.Store.spends {
+		if spend.OwnerID != oid || !matchesSpendFilter(spend, f, cardID) {
+			continue
+		}
+
+		if f.Cursor != "" && !before(Spend{ID: cursorID, Date: cursorDate}, spend) {
+			continue
+		}
+
+		spends = append(spends, copySpend(spend))
+	}
+
+	sort.Slice(spends, func(i, j int) bool { return before(spends[i], spends[j]) })
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultSpendsLimit
+	}
+
+	page := SpendPage{Spends: spends}
+	if int64(len(spends)) > limit {
+		page.Spends = spends[:limit]
+		page.NextCursor = EncodeSpendCursor(page.Spends[limit-1])
+	}
+
+	return page, nil
+}
+
+// spendGroupKeys will return the keys of a spend within a group, as many as its categories when grouped by them
+func spendGroupKeys(spend Spend, group string) []string {
+	switch group {
+	case SpendGroupCategory:
+		if len(spend.Categories) == 0 {
+			return []string{""}
+		}
+		return spend.Categories
+	case SpendGroupPaymentMethod:
+		switch {
+		case !spend.PaymentMethod.Credit.ID.IsZero():
+			return []string{PaymentMethodCredit}
+		case spend.PaymentMethod.Debit:
+			return []string{PaymentMethodDebit}
+		case spend.PaymentMethod.PaymentSlip:
+			return []string{PaymentMethodPaymentSlip}
+		}
+		return []string{""}
+	case SpendGroupCard:
+		return []string{spend.PaymentMethod.Credit.ID.Hex()}
+	case SpendGroupType:
+		return []string{spend.Type}
+	case SpendGroupMonth:
+		return []string{spend.Date.Time().UTC().Format("2006-01")}
+	}
+
+	return []string{""}
+}
+
+// Totals will return how much the spends of an owner within a date range cost, grouped by one of the
+// SpendGroup* criteria and by currency
+func (s *SpendRepositoryMemory) Totals(ctx context.Context, ownerID string, from time.Time, to time.Time, group string) ([]SpendTotal, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []SpendTotal{}, err
+	}
+
+	switch group {
+	case SpendGroupCategory, SpendGroupPaymentMethod, SpendGroupCard, SpendGroupType, SpendGroupMonth, SpendGroupCurrency:
+	default:
+		return []SpendTotal{}, fmt.Errorf("spends can not be grouped by '%s'", group)
+	}
+
+	s.Store.mu.RLock()
+	defer s.Store.mu.RUnlock()
+
+	type groupKey struct {
+		key      string
+		currency string
+	}
+
+	groups := map[groupKey]*SpendTotal{}
+	totals := []SpendTotal{}
+	for _, spend := range s.Store.spends {
+		if spend.OwnerID != oid || spend.Type == SpendTypePayment {
+			continue
+		}
+
+		date := spend.Date.Time()
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+
+		if group == SpendGroupCard && spend.PaymentMethod.Credit.ID.IsZero() {
+			continue
+		}
+
+		for _, key := range spendGroupKeys(spend, group) {
+			k := groupKey{key: key, currency: spend.Currency}
+			if _, ok := groups[k]; !ok {
+				groups[k] = &SpendTotal{Key: key, Currency: spend.Currency}
+			}
+			groups[k].Total += spend.Cost
+			groups[k].Count++
+		}
+	}
+
+	for _, total := range groups {
+		totals = append(totals, *total)
+	}
+
+	return totals, nil
+}
+
+// GetAll will return literally all spends
+func (s *SpendRepositoryMemory) GetAll(ctx context.Context) ([]Spend, error) {
+	s.Store.mu.RLock()
+	defer s.Store.mu.RUnlock()
+
+	var spends []Spend
+	for _, spend := range s.Store.spends {
+		spends = append(spends, copySpend(spend))
+	}
+
+	sort.Slice(spends, func(i, j int) bool { return spends[i].ID.Hex() < spends[j].ID.Hex() })
+	return spends, nil
+}
+
+// Create will create a spend
+func (s *SpendRepositoryMemory) Create(ctx context.Context, spend Spend) (id string, err error) {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	if spend.ID.IsZero() {
+		spend.ID = primitive.NewObjectID()
+	}
+
+	if _, ok := s.Store.spends[spend.ID]; ok {
+		return "", errors.New("spend already exists")
+	}
+
+	s.Store.spends[spend.ID] = copySpend(spend)
+	return spend.ID.Hex(), nil
+}
+
+// Update will replace an existing spend based on it's ID
+func (s *SpendRepositoryMemory) Update(ctx context.Context, spend Spend) error {
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	current, ok := s.Store.spends[spend.ID]
+	if !ok || current.OwnerID != spend.OwnerID {
+		return errors.New("could not find spend")
+	}
+
+	s.Store.spends[spend.ID] = copySpend(spend)
+	return nil
+}
+
+// Delete will delete a spend based on it's ID
+func (s *SpendRepositoryMemory) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	s.Store.mu.Lock()
+	defer s.Store.mu.Unlock()
+
+	if _, ok := s.Store.spends[pid]; !ok {
+		return errors.New("could not find spend")
+	}
+
+	delete(s.Store.spends, pid)
+	return nil
+}
+
+// Create will store an issued refresh token
+func (t *RefreshTokenRepositoryMemory) Create(ctx context.Context, token RefreshToken) error {
+	t.Store.mu.Lock()
+	defer t.Store.mu.Unlock()
+
+	if _, ok := t.Store.refreshTokens[token.ID]; ok {
+		return errors.New("refresh token already exists")
+	}
+
+	t.Store.refreshTokens[token.ID] = token
+	return nil
+}
+
+// Consume will atomically mark a refresh token as used
+func (t *RefreshTokenRepositoryMemory) Consume(ctx context.Context, id string) (RefreshToken, error) {
+	t.Store.mu.Lock()
+	defer t.Store.mu.Unlock()
+
+	token, ok := t.Store.refreshTokens[id]
+	if !ok || token.ExpiresAt.Time().Before(time.Now()) {
+		return RefreshToken{}, errors.New("could not find refresh token")
+	}
+
+	if token.Revoked {
+		return token, errors.New("refresh token revoked")
+	}
+
+	if token.Used {
+		return token, errors.New("refresh token reuse detected")
+	}
+
+	token.Used = true
+	t.Store.refreshTokens[id] = token
+	return token, nil
+}
+
+// RevokeFamily will revoke all refresh tokens from a given family
+func (t *RefreshTokenRepositoryMemory) RevokeFamily(ctx context.Context, family string) error {
+	t.Store.mu.Lock()
+	defer t.Store.mu.Unlock()
+
+	for id, token := range t.Store.refreshTokens {
+		if token.Family == family {
+			token.Revoked = true
+			t.Store.refreshTokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+// RevokeOwner will revoke all refresh tokens from a given owner
+func (t *RefreshTokenRepositoryMemory) RevokeOwner(ctx context.Context, ownerID string) error {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return err
+	}
+
+	t.Store.mu.Lock()
+	defer t.Store.mu.Unlock()
+
+	for id, token := range t.Store.refreshTokens {
+		if token.OwnerID == oid {
+			token.Revoked = true
+			t.Store.refreshTokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+// Create will store a revocation
+func (r *RevocationRepositoryMemory) Create(ctx context.Context, revocation Revocation) error {
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	if revocation.ID.IsZero() {
+		revocation.ID = primitive.NewObjectID()
+	}
+
+	r.Store.revocations = append(r.Store.revocations, revocation)
+	return nil
+}
+
+// IsRevoked will return if a token was revoked by its ID or by revoking all sessions from its owner
+func (r *RevocationRepositoryMemory) IsRevoked(ctx context.Context, tokenID string, ownerID string, issuedAt time.Time) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+
+	now := time.Now()
+	for _, revocation := range r.Store.revocations {
+		if revocation.ExpiresAt.Time().Before(now) {
+			continue
+		}
+
+		if tokenID != "" && revocation.TokenID == tokenID {
+			return true, nil
+		}
+
+		if revocation.NotBefore != 0 && revocation.OwnerID == oid && primitive.NewDateTimeFromTime(issuedAt) <= revocation.NotBefore {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Get will return the failed attempts from a login
+func (l *LoginAttemptRepositoryMemory) Get(ctx context.Context, login string) (LoginAttempt, error) {
+	l.Store.mu.RLock()
+	defer l.Store.mu.RUnlock()
+
+	attempt, ok := l.Store.loginAttempts[login]
+	if !ok || attempt.ExpiresAt.Time().Before(time.Now()) {
+		return LoginAttempt{}, errors.New("could not find login attempt")
+	}
+
+	return attempt, nil
+}
+
+// RegisterFailure will increase the failures from a login, creating it on demand
+func (l *LoginAttemptRepositoryMemory) RegisterFailure(ctx context.Context, login string, at time.Time, expiresAt time.Time) (LoginAttempt, error) {
+	l.Store.mu.Lock()
+	defer l.Store.mu.Unlock()
+
+	attempt, ok := l.Store.loginAttempts[login]
+	if !ok || attempt.ExpiresAt.Time().Before(at) {
+		attempt = LoginAttempt{ID: login}
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = primitive.NewDateTimeFromTime(at)
+	attempt.ExpiresAt = primitive.NewDateTimeFromTime(expiresAt)
+
+	l.Store.loginAttempts[login] = attempt
+	return attempt, nil
+}
+
+// Lock will lock a login until a given time
+func (l *LoginAttemptRepositoryMemory) Lock(ctx context.Context, login string, until time.Time) error {
+	l.Store.mu.Lock()
+	defer l.Store.mu.Unlock()
+
+	attempt, ok := l.Store.loginAttempts[login]
+	if !ok {
+		return errors.New("could not find login attempt")
+	}
+
+	attempt.LockedUntil = primitive.NewDateTimeFromTime(until)
+	l.Store.loginAttempts[login] = attempt
+	return nil
+}
+
+// Reset will remove all failed attempts from a login, unlocking it
+func (l *LoginAttemptRepositoryMemory) Reset(ctx context.Context, login string) error {
+	l.Store.mu.Lock()
+	defer l.Store.mu.Unlock()
+
+	delete(l.Store.loginAttempts, login)
+	return nil
+}
+
+// Upsert will create or replace the rate of a currency pair for its day
+func (e *ExchangeRateRepositoryMemory) Upsert(ctx context.Context, rate ExchangeRate) error {
+	e.Store.mu.Lock()
+	defer e.Store.mu.Unlock()
+
+	key := rate.Base + "/" + rate.Quote + "/" + strconv.FormatInt(int64(rate.Date), 10)
+	e.Store.exchangeRates[key] = rate
+	return nil
+}
+
+// Get will return the most recent rate of a currency pair published until a given day
+func (e *ExchangeRateRepositoryMemory) Get(ctx context.Context, base string, quote string, date time.Time) (ExchangeRate, error) {
+	e.Store.mu.RLock()
+	defer e.Store.mu.RUnlock()
+
+	until := primitive.NewDateTimeFromTime(date)
+
+	var found ExchangeRate
+	for _, rate := range e.Store.exchangeRates {
+		if rate.Base == base && rate.Quote == quote && rate.Date <= until && rate.Date >= found.Date {
+			found = rate
+		}
+	}
+
+	if found.Base == "" {
+		return ExchangeRate{}, errors.New("could not find exchange rate")
+	}
+
+	return found, nil
+}
+
+// List will return the exchange rates matching a given filter, sorted by date
+func (e *ExchangeRateRepositoryMemory) List(ctx context.Context, f ExchangeRateFilter) ([]ExchangeRate, error) {
+	e.Store.mu.RLock()
+	defer e.Store.mu.RUnlock()
+
+	rates := []ExchangeRate{}
+	for _, rate := range e.Store.exchangeRates {
+		if f.Base != "" && rate.Base != f.Base {
+			continue
+		}
+		if f.Quote != "" && rate.Quote != f.Quote {
+			continue
+		}
+
+		date := rate.Date.Time()
+		if !f.From.IsZero() && date.Before(f.From) {
+			continue
+		}
+		if !f.To.IsZero() && date.After(f.To) {
+			continue
+		}
+
+		rates = append(rates, rate)
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Date != rates[j].Date {
+			return rates[i].Date < rates[j].Date
+		}
+		return rates[i].Base+rates[i].Quote < rates[j].Base+rates[j].Quote
+	})
+
+	return rates, nil
+}
+
+// Create will create a recurrence
+func (r *RecurrenceRepositoryMemory) Create(ctx context.Context, recurrence Recurrence) (id string, err error) {
+	if recurrence.ID.IsZero() {
+		recurrence.ID = primitive.NewObjectID()
+	}
+
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	if _, ok := r.Store.recurrences[recurrence.ID]; ok {
+		return "", errors.New("recurrence already exists")
+	}
+
+	r.Store.recurrences[recurrence.ID] = copyRecurrence(recurrence)
+	return recurrence.ID.Hex(), nil
+}
+
+// Get will return every recurrence from a given ownerID
+func (r *RecurrenceRepositoryMemory) Get(ctx context.Context, ownerID string) ([]Recurrence, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []Recurrence{}, err
+	}
+
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+
+	recurrences := []Recurrence{}
+	for _, recurrence := range r.Store.recurrences {
+		if recurrence.OwnerID == oid {
+			recurrences = append(recurrences, copyRecurrence(recurrence))
+		}
+	}
+
+	sortRecurrences(recurrences)
+	return recurrences, nil
+}
+
+// GetByID will return a single recurrence from a given ownerID
+func (r *RecurrenceRepositoryMemory) GetByID(ctx context.Context, ownerID string, id string) (Recurrence, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Recurrence{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Recurrence{}, err
+	}
+
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+
+	recurrence, ok := r.Store.recurrences[pid]
+	if !ok || recurrence.OwnerID != oid {
+		return Recurrence{}, errors.New("could not find recurrence")
+	}
+
+	return copyRecurrence(recurrence), nil
+}
+
+// Due will return every active recurrence whose next run is until a given date
+func (r *RecurrenceRepositoryMemory) Due(ctx context.Context, until time.Time) ([]Recurrence, error) {
+	r.Store.mu.RLock()
+	defer r.Store.mu.RUnlock()
+
+	limit := primitive.NewDateTimeFromTime(until)
+
+	recurrences := []Recurrence{}
+	for _, recurrence := range r.Store.recurrences {
+		if recurrence.Status == RecurrenceStatusActive && recurrence.NextRun <= limit {
+			recurrences = append(recurrences, copyRecurrence(recurrence))
+		}
+	}
+
+	sortRecurrences(recurrences)
+	return recurrences, nil
+}
+
+// Schedule will update the status and next run of a recurrence while its status and next run are still the previous ones
+func (r *RecurrenceRepositoryMemory) Schedule(ctx context.Context, recurrence Recurrence, status string, previous primitive.DateTime) error {
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	current, ok := r.Store.recurrences[recurrence.ID]
+	if !ok || current.OwnerID != recurrence.OwnerID || current.Status != status || current.NextRun != previous {
+		return errors.New("could not find recurrence")
+	}
+
+	current.Status = recurrence.Status
+	current.NextRun = recurrence.NextRun
+	current.UpdatedAt = recurrence.UpdatedAt
+	r.Store.recurrences[recurrence.ID] = current
+	return nil
+}
+
+// UpdateCategories will replace the categories of a recurrence, used by its future spends
+func (r *RecurrenceRepositoryMemory) UpdateCategories(ctx context.Context, recurrence Recurrence) error {
+	r.Store.mu.Lock()
+	defer r.Store.mu.Unlock()
+
+	current, ok := r.Store.recurrences[recurrence.ID]
+	if !ok || current.OwnerID != recurrence.OwnerID {
+		return errors.New("could not find recurrence")
+	}
+
+	current.Categories = append([]string{}, recurrence.Categories...)
+	current.UpdatedAt = recurrence.UpdatedAt
+	r.Store.recurrences[recurrence.ID] = current
+	return nil
+}
+
+// Create will store a statement payment, a single one per card statement
+func (p *StatementPaymentRepositoryMemory) Create(ctx context.Context, payment StatementPayment) (id string, err error) {
+	if payment.ID.IsZero() {
+		payment.ID = primitive.NewObjectID()
+	}
+
+	p.Store.mu.Lock()
+	defer p.Store.mu.Unlock()
+
+	for _, current := range p.Store.statements {
+		if current.ID == payment.ID || (current.CardID == payment.CardID && current.Month == payment.Month && current.Year == payment.Year) {
+			return "", errors.New("statement already paid")
+		}
+	}
+
+	p.Store.statements[payment.ID] = payment
+	return payment.ID.Hex(), nil
+}
+
+// Get will return the payment of a card statement
+func (p *StatementPaymentRepositoryMemory) Get(ctx context.Context, ownerID string, cardID string, month int64, year int64) (StatementPayment, error) {
+	payments, err := p.List(ctx, ownerID, cardID)
+	if err != nil {
+		return StatementPayment{}, err
+	}
+
+	for _, payment := range payments {
+		if payment.Month == month && payment.Year == year {
+			return payment, nil
+		}
+	}
+
+	return StatementPayment{}, errors.New("could not find statement payment")
+}
+
+// List will return every payment of a card, sorted by statement
+func (p *StatementPaymentRepositoryMemory) List(ctx context.Context, ownerID string, cardID string) ([]StatementPayment, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []StatementPayment{}, err
+	}
+
+	cid, err := primitive.ObjectIDFromHex(cardID)
+	if err != nil {
+		return []StatementPayment{}, err
+	}
+
+	p.Store.mu.RLock()
+	defer p.Store.mu.RUnlock()
+
+	payments := []StatementPayment{}
+	for _, payment := range p.Store.statements {
+		if payment.OwnerID == oid && payment.CardID == cid {
+			payments = append(payments, payment)
+		}
+	}
+
+	sort.Slice(payments, func(i, j int) bool {
+		if payments[i].Year != payments[j].Year {
+			return payments[i].Year < payments[j].Year
+		}
+		return payments[i].Month < payments[j].Month
+	})
+
+	return payments, nil
+}
+
+// Delete will delete a statement payment based on it's ID
+func (p *StatementPaymentRepositoryMemory) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	p.Store.mu.Lock()
+	defer p.Store.mu.Unlock()
+
+	if _, ok := p.Store.statements[pid]; !ok {
+		return errors.New("could not find statement payment")
+	}
+
+	delete(p.Store.statements, pid)
+	return nil
+}
+
+// Create will store a budget alert, a single one per category threshold and month
+func (a *BudgetAlertRepositoryMemory) Create(ctx context.Context, alert BudgetAlert) (id string, err error) {
+	if alert.ID.IsZero() {
+		alert.ID = primitive.NewObjectID()
+	}
+
+	a.Store.mu.Lock()
+	defer a.Store.mu.Unlock()
+
+	for _, current := range a.Store.budgetAlerts {
+		if current.ID == alert.ID || (current.OwnerID == alert.OwnerID && current.Month == alert.Month && current.Year == alert.Year &&
+			current.Category == alert.Category && current.Threshold == alert.Threshold) {
+			return "", errors.New("budget alert already recorded")
+		}
+	}
+
+	a.Store.budgetAlerts[alert.ID] = alert
+	return alert.ID.Hex(), nil
+}
+
+// List will return every alert of a month, sorted by creation
+func (a *BudgetAlertRepositoryMemory) List(ctx context.Context, ownerID string, month int64, year int64) ([]BudgetAlert, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []BudgetAlert{}, err
+	}
+
+	a.Store.mu.RLock()
+	defer a.Store.mu.RUnlock()
+
+	alerts := []BudgetAlert{}
+	for _, alert := range a.Store.budgetAlerts {
+		if alert.OwnerID == oid && alert.Month == month && alert.Year == year {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].CreatedAt != alerts[j].CreatedAt {
+			return alerts[i].CreatedAt < alerts[j].CreatedAt
+		}
+		return alerts[i].ID.Hex() < alerts[j].ID.Hex()
+	})
+
+	return alerts, nil
+}
+
+// Get will return every category of an owner, sorted by name
+func (c *CategoryRepositoryMemory) Get(ctx context.Context, ownerID string) ([]Category, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []Category{}, err
+	}
+
+	c.Store.mu.RLock()
+	defer c.Store.mu.RUnlock()
+
+	categories := []Category{}
+	for _, category := range c.Store.categories {
+		if category.OwnerID == oid {
+			categories = append(categories, category)
+		}
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].Name != categories[j].Name {
+			return categories[i].Name < categories[j].Name
+		}
+		return categories[i].ID.Hex() < categories[j].ID.Hex()
+	})
+
+	return categories, nil
+}
+
+// GetByID will return a single category from a given owner ID
+func (c *CategoryRepositoryMemory) GetByID(ctx context.Context, ownerID string, id string) (Category, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Category{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Category{}, err
+	}
+
+	c.Store.mu.RLock()
+	defer c.Store.mu.RUnlock()
+
+	category, ok := c.Store.categories[pid]
+	if !ok || category.OwnerID != oid {
+		return Category{}, errors.New("could not find category")
+	}
+
+	return category, nil
+}
+
+// nameTaken will return if another category of an owner already uses a name regardless of case
+func (c *CategoryRepositoryMemory) nameTaken(category Category) bool {
+	for _, current := range c.Store.categories {
+		if current.ID != category.ID && current.OwnerID == category.OwnerID && strings.EqualFold(current.Name, category.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Create will store a category, whose name must be unique per owner regardless of case
+func (c *CategoryRepositoryMemory) Create(ctx context.Context, category Category) (id string, err error) {
+	if category.ID.IsZero() {
+		category.ID = primitive.NewObjectID()
+	}
+
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	if _, ok := c.Store.categories[category.ID]; ok || c.nameTaken(category) {
+		return "", errors.New("category already exists")
+	}
+
+	c.Store.categories[category.ID] = category
+	return category.ID.Hex(), nil
+}
+
+// Update will replace every attribute of a category but its owner and creation date
+func (c *CategoryRepositoryMemory) Update(ctx context.Context, category Category) error {
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	current, ok := c.Store.categories[category.ID]
+	if !ok || current.OwnerID != category.OwnerID {
+		return errors.New("could not find category")
+	}
+
+	if c.nameTaken(category) {
+		return errors.New("category already exists")
+	}
+
+	current.Name = category.Name
+	current.ParentID = category.ParentID
+	current.Color = category.Color
+	current.Icon = category.Icon
+	current.UpdatedAt = category.UpdatedAt
+	c.Store.categories[current.ID] = current
+	return nil
+}
+
+// Delete will delete a category based on it's ID
+func (c *CategoryRepositoryMemory) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	if _, ok := c.Store.categories[pid]; !ok {
+		return errors.New("could not find category")
+	}
+
+	delete(c.Store.categories, pid)
+	return nil
+}
+
+// Get will return every rule of an owner, sorted by priority and then by creation
+func (c *CategoryRuleRepositoryMemory) Get(ctx context.Context, ownerID string) ([]CategoryRule, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []CategoryRule{}, err
+	}
+
+	c.Store.mu.RLock()
+	defer c.Store.mu.RUnlock()
+
+	rules := []CategoryRule{}
+	for _, rule := range c.Store.categoryRules {
+		if rule.OwnerID == oid {
+			rules = append(rules, copyCategoryRule(rule))
+		}
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority < rules[j].Priority
+		}
+		return rules[i].ID.Hex() < rules[j].ID.Hex()
+	})
+
+	return rules, nil
+}
+
+// GetByID will return a single rule from a given owner ID
+func (c *CategoryRuleRepositoryMemory) GetByID(ctx context.Context, ownerID string, id string) (CategoryRule, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return CategoryRule{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return CategoryRule{}, err
+	}
+
+	c.Store.mu.RLock()
+	defer c.Store.mu.RUnlock()
+
+	rule, ok := c.Store.categoryRules[pid]
+	if !ok || rule.OwnerID != oid {
+		return CategoryRule{}, errors.New("could not find category rule")
+	}
+
+	return copyCategoryRule(rule), nil
+}
+
+// Create will store a rule
+func (c *CategoryRuleRepositoryMemory) Create(ctx context.Context, rule CategoryRule) (id string, err error) {
+	if rule.ID.IsZero() {
+		rule.ID = primitive.NewObjectID()
+	}
+
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	if _, ok := c.Store.categoryRules[rule.ID]; ok {
+		return "", errors.New("category rule already exists")
+	}
+
+	c.Store.categoryRules[rule.ID] = copyCategoryRule(rule)
+	return rule.ID.Hex(), nil
+}
+
+// Update will replace every attribute of a rule but its owner and creation date
+func (c *CategoryRuleRepositoryMemory) Update(ctx context.Context, rule CategoryRule) error {
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	current, ok := c.Store.categoryRules[rule.ID]
+	if !ok || current.OwnerID != rule.OwnerID {
+		return errors.New("could not find category rule")
+	}
+
+	rule.CreatedAt = current.CreatedAt
+	c.Store.categoryRules[rule.ID] = copyCategoryRule(rule)
+	return nil
+}
+
+// Delete will delete a rule based on it's ID
+func (c *CategoryRuleRepositoryMemory) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	c.Store.mu.Lock()
+	defer c.Store.mu.Unlock()
+
+	if _, ok := c.Store.categoryRules[pid]; !ok {
+		return errors.New("could not find category rule")
+	}
+
+	delete(c.Store.categoryRules, pid)
+	return nil
+}
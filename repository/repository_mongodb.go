@@ -4,11 +4,14 @@ import (
 	"budget-tracker-api/services"
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -31,6 +34,60 @@ type CardRepositoryMongoDB struct {
 	Config services.MongoCfg
 }
 
+// RefreshTokenRepositoryMongoDB defines a struct for mongoDB RefreshToken operations
+type RefreshTokenRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
+// RevocationRepositoryMongoDB defines a struct for mongoDB Revocation operations
+type RevocationRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
+// LoginAttemptRepositoryMongoDB defines a struct for mongoDB LoginAttempt operations
+type LoginAttemptRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
+// ExchangeRateRepositoryMongoDB defines a struct for mongoDB ExchangeRate operations
+type ExchangeRateRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
+// RecurrenceRepositoryMongoDB defines a struct for mongoDB Recurrence operations
+type RecurrenceRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
+// StatementPaymentRepositoryMongoDB defines a struct for mongoDB StatementPayment operations
+type StatementPaymentRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
+// CategoryRuleRepositoryMongoDB defines a struct for mongoDB CategoryRule operations
+type CategoryRuleRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
+// CategoryRepositoryMongoDB defines a struct for mongoDB Category operations
+type CategoryRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
+// BudgetAlertRepositoryMongoDB defines a struct for mongoDB BudgetAlert operations
+type BudgetAlertRepositoryMongoDB struct {
+	Client *mongo.Client
+	Config services.MongoCfg
+}
+
 // BalanceRepositoryMongoDB defines a struct for mongoDB Balance operations
 type BalanceRepositoryMongoDB struct {
 	Client *mongo.Client
@@ -43,6 +100,42 @@ type SpendRepositoryMongoDB struct {
 	Config services.MongoCfg
 }
 
+// NewMongoDBRepositories will return every repository backed by mongoDB collections
+func NewMongoDBRepositories(client *mongo.Client) Repositories {
+	cfg := func(collection string) services.MongoCfg {
+		return services.MongoCfg{
+			URI:       services.MongodbURI,
+			Database:  services.MongodbDatabase,
+			Colletion: collection,
+		}
+	}
+
+	return Repositories{
+		Database:      &DatabaseRepositoryMongoDB{Client: client, Config: cfg("")},
+		Users:         &UserRepositoryMongoDB{Client: client, Config: cfg(services.MongodbUserCollection)},
+		Cards:         &CardRepositoryMongoDB{Client: client, Config: cfg(services.MongodbCardsCollection)},
+		Balances:      &BalanceRepositoryMongoDB{Client: client, Config: cfg(services.MongodbBalanceCollection)},
+		Spends:        &SpendRepositoryMongoDB{Client: client, Config: cfg(services.MongodbSpendsCollection)},
+		RefreshTokens: &RefreshTokenRepositoryMongoDB{Client: client, Config: cfg(services.MongodbRefreshTokensCollection)},
+		Revocations:   &RevocationRepositoryMongoDB{Client: client, Config: cfg(services.MongodbRevocationsCollection)},
+		LoginAttempts: &LoginAttemptRepositoryMongoDB{Client: client, Config: cfg(services.MongodbLoginAttemptsCollection)},
+		ExchangeRates: &ExchangeRateRepositoryMongoDB{Client: client, Config: cfg(services.MongodbExchangeRatesCollection)},
+		Recurrences:   &RecurrenceRepositoryMongoDB{Client: client, Config: cfg(services.MongodbRecurrencesCollection)},
+		Statements:    &StatementPaymentRepositoryMongoDB{Client: client, Config: cfg(services.MongodbStatementPaymentsCollection)},
+		BudgetAlerts:  &BudgetAlertRepositoryMongoDB{Client: client, Config: cfg(services.MongodbBudgetAlertsCollection)},
+		Categories:    &CategoryRepositoryMongoDB{Client: client, Config: cfg(services.MongodbCategoriesCollection)},
+		CategoryRules: &CategoryRuleRepositoryMongoDB{Client: client, Config: cfg(services.MongodbCategoryRulesCollection)},
+	}
+}
+
+// spendOutcomeField will return which balance outcome field a spend must be accounted in
+func spendOutcomeField(s Spend) string {
+	if s.IsFixed() {
+		return "outcome.fixed"
+	}
+	return "outcome.dynamic"
+}
+
 // Health will define a mongoDB healthcheck
 func (d *DatabaseRepositoryMongoDB) Health() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -56,12 +149,36 @@ func (d *DatabaseRepositoryMongoDB) Health() error {
 	return nil
 }
 
+// Transaction will run a function within a mongoDB transaction. Standalone servers do not support
+// them, in which case the function runs as is
+func (d *DatabaseRepositoryMongoDB) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// functions running within a transaction already take part in it
+	if Transactional(ctx) {
+		return fn(ctx)
+	}
+
+	session, err := d.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(withinTransaction(sc))
+	})
+	if err != nil && strings.Contains(err.Error(), "Transaction numbers are only allowed") {
+		return fn(ctx)
+	}
+
+	return err
+}
+
 // Get will
 func (u *UserRepositoryMongoDB) Get(ctx context.Context, id string) (SanitizedUser, error) {
 	var user SanitizedUser
 
 	// col := u.Client.Database(u.Config.Database).Collection(u.Config.Colletion)
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 
 	pid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -85,9 +202,31 @@ func (u *UserRepositoryMongoDB) Get(ctx context.Context, id string) (SanitizedUs
 	return user, nil
 }
 
+// GetByLogin will return a user, along with its salted password, based on its login
+func (u *UserRepositoryMongoDB) GetByLogin(ctx context.Context, login string) (User, error) {
+	var user User
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := u.Config.Get(ctx, bson.M{"login": login})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return User{}, errors.New("could not find user")
+		}
+		cancel()
+		return User{}, err
+	}
+
+	r.Decode(&user)
+
+	return user, nil
+}
+
 // GetAll will return all Users (in a sanitized way)
 func (u *UserRepositoryMongoDB) GetAll(ctx context.Context) ([]SanitizedUser, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	cursor, err := u.Config.GetAll(ctx, bson.M{})
 	if err != nil {
 		cancel()
@@ -115,7 +254,7 @@ func (u *UserRepositoryMongoDB) GetAll(ctx context.Context) ([]SanitizedUser, er
 
 // Create will create a user based
 func (u *UserRepositoryMongoDB) Create(ctx context.Context, user User) (id string, err error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	r, err := u.Config.Create(ctx, user)
@@ -134,7 +273,7 @@ func (u *UserRepositoryMongoDB) Create(ctx context.Context, user User) (id strin
 
 // Delete will delete a user based on it's ID
 func (u *UserRepositoryMongoDB) Delete(ctx context.Context, id string) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	pid, err := primitive.ObjectIDFromHex(id)
@@ -160,7 +299,7 @@ func (u *UserRepositoryMongoDB) Delete(ctx context.Context, id string) error {
 
 // Get will return all cards from a given owner ID
 func (c *CardRepositoryMongoDB) Get(ctx context.Context, ownerID string) ([]CreditCard, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	oid, err := primitive.ObjectIDFromHex(ownerID)
@@ -195,9 +334,75 @@ func (c *CardRepositoryMongoDB) Get(ctx context.Context, ownerID string) ([]Cred
 	return cards, nil
 }
 
+// GetByID will return a single card from a given owner ID
+func (c *CardRepositoryMongoDB) GetByID(ctx context.Context, ownerID string, id string) (CreditCard, error) {
+	var card CreditCard
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return CreditCard{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return CreditCard{}, err
+	}
+
+	r, err := c.Config.Get(ctx, bson.M{"_id": pid, "owner_id": oid})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return CreditCard{}, errors.New("could not find card")
+		}
+		cancel()
+		return CreditCard{}, err
+	}
+
+	r.Decode(&card)
+
+	return card, nil
+}
+
+// GetOwnerID will return who owns a card, for routes which are not scoped by owner
+func (c *CardRepositoryMongoDB) GetOwnerID(ctx context.Context, id string) (string, error) {
+	var card CreditCard
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return "", err
+	}
+
+	r, err := c.Config.Get(ctx, bson.M{"_id": pid})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return "", errors.New("could not find card")
+		}
+		cancel()
+		return "", err
+	}
+
+	err = r.Decode(&card)
+	if err != nil {
+		cancel()
+		return "", err
+	}
+
+	return card.OwnerID.Hex(), nil
+}
+
 // GetAll will return literally all cards from the database
 func (c *CardRepositoryMongoDB) GetAll(ctx context.Context) ([]CreditCard, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	cursor, err := c.Config.GetAll(ctx, bson.M{})
@@ -224,7 +429,7 @@ func (c *CardRepositoryMongoDB) GetAll(ctx context.Context) ([]CreditCard, error
 
 // Create will create a card
 func (c *CardRepositoryMongoDB) Create(ctx context.Context, card CreditCard) (id string, err error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	r, err := c.Config.Create(ctx, card)
@@ -241,9 +446,41 @@ func (c *CardRepositoryMongoDB) Create(ctx context.Context, card CreditCard) (id
 	return r.InsertedID.(primitive.ObjectID).Hex(), nil
 }
 
+// Update will replace every attribute of a card but its owner, last digits and creation date
+func (c *CardRepositoryMongoDB) Update(ctx context.Context, card CreditCard) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := c.Config.Update(
+		ctx,
+		bson.M{"_id": card.ID, "owner_id": card.OwnerID},
+		bson.M{"$set": bson.M{
+			"alias":        card.Alias,
+			"network":      card.Network,
+			"color":        card.Color,
+			"closing_day":  card.ClosingDay,
+			"due_day":      card.DueDay,
+			"credit_limit": card.CreditLimit,
+			"archived":     card.Archived,
+			"archived_at":  card.ArchivedAt,
+		}},
+	)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find card")
+	}
+
+	return nil
+}
+
 // Delete will delete a card based on it's ID
 func (c *CardRepositoryMongoDB) Delete(ctx context.Context, id string) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	pid, err := primitive.ObjectIDFromHex(id)
@@ -270,7 +507,7 @@ func (c *CardRepositoryMongoDB) Delete(ctx context.Context, id string) error {
 func (b *BalanceRepositoryMongoDB) Get(ctx context.Context, ownerID string, month int64, year int64) (Balance, error) {
 	var balance Balance
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	oid, err := primitive.ObjectIDFromHex(ownerID)
@@ -299,9 +536,95 @@ func (b *BalanceRepositoryMongoDB) Get(ctx context.Context, ownerID string, mont
 	return balance, nil
 }
 
+// GetByID will return a single balance from a given ownerID
+func (b *BalanceRepositoryMongoDB) GetByID(ctx context.Context, ownerID string, id string) (Balance, error) {
+	var balance Balance
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return Balance{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return Balance{}, err
+	}
+
+	r, err := b.Config.Get(ctx, bson.M{"_id": pid, "owner_id": oid})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return Balance{}, errors.New("could not find balance")
+		}
+		cancel()
+		return Balance{}, err
+	}
+
+	r.Decode(&balance)
+
+	return balance, nil
+}
+
+// List will return balances from an owner within a given period, sorted chronologically
+func (b *BalanceRepositoryMongoDB) List(ctx context.Context, f BalanceFilter) ([]Balance, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(f.OwnerID)
+	if err != nil {
+		cancel()
+		return []Balance{}, err
+	}
+
+	conditions := []bson.M{{"owner_id": oid}}
+
+	if !f.From.IsZero() {
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"year": bson.M{"$gt": f.From.Year}},
+			{"year": f.From.Year, "month": bson.M{"$gte": f.From.Month}},
+		}})
+	}
+
+	if !f.To.IsZero() {
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"year": bson.M{"$lt": f.To.Year}},
+			{"year": f.To.Year, "month": bson.M{"$lte": f.To.Month}},
+		}})
+	}
+
+	opts := options.Find().SetSort(primitive.D{{Key: "year", Value: 1}, {Key: "month", Value: 1}})
+
+	cursor, err := b.Config.GetAll(ctx, bson.M{"$and": conditions}, opts)
+	if err != nil {
+		cancel()
+		return []Balance{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	balances := []Balance{}
+	for cursor.Next(ctx) {
+		var balance Balance
+		cursor.Decode(&balance)
+		balances = append(balances, balance)
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return []Balance{}, err
+	}
+
+	return balances, nil
+}
+
 // GetAll will
 func (b *BalanceRepositoryMongoDB) GetAll(ctx context.Context) ([]Balance, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	cursor, err := b.Config.GetAll(ctx, bson.M{})
@@ -332,7 +655,7 @@ func (b *BalanceRepositoryMongoDB) GetAll(ctx context.Context) ([]Balance, error
 
 // Create will
 func (b *BalanceRepositoryMongoDB) Create(ctx context.Context, balance Balance) (id string, err error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	r, err := b.Config.Create(ctx, balance)
@@ -349,9 +672,50 @@ func (b *BalanceRepositoryMongoDB) Create(ctx context.Context, balance Balance)
 	return r.InsertedID.(primitive.ObjectID).Hex(), nil
 }
 
+// Update will change a balance income, currency and budgets, recomputing its spendable amount against the recorded outcome
+func (b *BalanceRepositoryMongoDB) Update(ctx context.Context, balance Balance) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	// an update pipeline is used so the spendable amount is computed from the stored outcome atomically
+	r, err := b.Config.Update(
+		ctx,
+		bson.M{"_id": balance.ID, "owner_id": balance.OwnerID},
+		[]bson.M{
+			{"$set": bson.M{
+				"income.gross": balance.Income.GrossIncome,
+				"income.net":   balance.Income.NetIncome,
+				"currency":     bson.M{"$literal": balance.Currency},
+				"budgets":      bson.M{"$literal": balance.Budgets},
+				"updated_at":   balance.UpdatedAt,
+			}},
+			{"$set": bson.M{
+				"spendable_amount": bson.M{"$subtract": []interface{}{
+					"$income.net",
+					bson.M{"$add": []interface{}{
+						bson.M{"$ifNull": []interface{}{"$outcome.fixed", 0}},
+						bson.M{"$ifNull": []interface{}{"$outcome.dynamic", 0}},
+					}},
+				}},
+			}},
+		},
+	)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find balance")
+	}
+
+	return nil
+}
+
 // Delete will
 func (b *BalanceRepositoryMongoDB) Delete(ctx context.Context, id string) error {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	pid, err := primitive.ObjectIDFromHex(id)
@@ -374,9 +738,84 @@ func (b *BalanceRepositoryMongoDB) Delete(ctx context.Context, id string) error
 	return nil
 }
 
+// AddSpend will atomically append a spend to its month's balance, creating the balance when needed
+func (b *BalanceRepositoryMongoDB) AddSpend(ctx context.Context, spend Spend) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	month, year := spend.Period()
+	t := primitive.NewDateTimeFromTime(time.Now())
+
+	_, err := b.Config.Update(
+		ctx,
+		bson.M{
+			"owner_id": spend.OwnerID,
+			"month":    month,
+			"year":     year,
+		},
+		bson.M{
+			"$setOnInsert": bson.M{
+				"income":     Income{},
+				"currency":   spend.Currency,
+				"created_at": t,
+			},
+			"$set":  bson.M{"updated_at": t},
+			"$push": bson.M{"historic": spend},
+			"$inc": bson.M{
+				spendOutcomeField(spend): spend.Outcome(),
+				"spendable_amount":       -spend.Outcome(),
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	return nil
+}
+
+// RemoveSpend will atomically pull a spend from its month's balance, reverting its outcome
+func (b *BalanceRepositoryMongoDB) RemoveSpend(ctx context.Context, spend Spend) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	month, year := spend.Period()
+
+	r, err := b.Config.Update(
+		ctx,
+		bson.M{
+			"owner_id":     spend.OwnerID,
+			"month":        month,
+			"year":         year,
+			"historic._id": spend.ID,
+		},
+		bson.M{
+			"$set":  bson.M{"updated_at": primitive.NewDateTimeFromTime(time.Now())},
+			"$pull": bson.M{"historic": bson.M{"_id": spend.ID}},
+			"$inc": bson.M{
+				spendOutcomeField(spend): -spend.Outcome(),
+				"spendable_amount":       spend.Outcome(),
+			},
+		},
+	)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find spend in balance")
+	}
+
+	return nil
+}
+
 // Get will return a list of spends from a given ownerID
 func (s *SpendRepositoryMongoDB) Get(ctx context.Context, ownerID string) ([]Spend, error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
 	oid, err := primitive.ObjectIDFromHex(ownerID)
@@ -411,31 +850,1336 @@ func (s *SpendRepositoryMongoDB) Get(ctx context.Context, ownerID string) ([]Spe
 	return spends, nil
 }
 
-// GetAll will all spends in database but currently is not supported
-func (s *SpendRepositoryMongoDB) GetAll(ctx context.Context) ([]Spend, error) {
-	return []Spend{}, nil
-}
+// GetByID will return a single spend from a given ownerID
+func (s *SpendRepositoryMongoDB) GetByID(ctx context.Context, ownerID string, id string) (Spend, error) {
+	var spend Spend
 
-// Create will
-func (s *SpendRepositoryMongoDB) Create(ctx context.Context, spend Spend) (id string, err error) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
 	defer cancel()
 
-	r, err := s.Config.Create(ctx, spend)
+	oid, err := primitive.ObjectIDFromHex(ownerID)
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key error collection") {
+		cancel()
+		return Spend{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return Spend{}, err
+	}
+
+	r, err := s.Config.Get(ctx, bson.M{"_id": pid, "owner_id": oid})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
 			cancel()
-			return "", errors.New("balance already exists")
+			return Spend{}, errors.New("could not find spend")
 		}
-
 		cancel()
-		return "", err
+		return Spend{}, err
 	}
 
-	return r.InsertedID.(primitive.ObjectID).Hex(), nil
+	r.Decode(&spend)
+
+	return spend, nil
 }
 
-// Delete will
-func (s *SpendRepositoryMongoDB) Delete(ctx context.Context, id string) error {
+// Find will return a page of spends from an owner matching a given filter, sorted by date
+func (s *SpendRepositoryMongoDB) Find(ctx context.Context, f SpendFilter) (SpendPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(f.OwnerID)
+	if err != nil {
+		cancel()
+		return SpendPage{}, err
+	}
+
+	conditions := []bson.M{{"owner_id": oid}}
+
+	date := bson.M{}
+	if !f.From.IsZero() {
+		date["$gte"] = primitive.NewDateTimeFromTime(f.From)
+	}
+	if !f.To.IsZero() {
+		date["$lte"] = primitive.NewDateTimeFromTime(f.To)
+	}
+	if len(date) > 0 {
+		conditions = append(conditions, bson.M{"date": date})
+	}
+
+	if f.Type != "" {
+		conditions = append(conditions, bson.M{"type": f.Type})
+	}
+
+	if f.Category != "" {
+		conditions = append(conditions, bson.M{"categories": f.Category})
+	}
+
+	switch f.PaymentMethod {
+	case PaymentMethodDebit:
+		conditions = append(conditions, bson.M{"payment_method.debit": true})
+	case PaymentMethodCredit:
+		conditions = append(conditions, bson.M{"payment_method.credit._id": bson.M{"$exists": true}})
+	case PaymentMethodPaymentSlip:
+		conditions = append(conditions, bson.M{"payment_method.payment_slip": true})
+	}
+
+	if f.CardID != "" {
+		cid, err := primitive.ObjectIDFromHex(f.CardID)
+		if err != nil {
+			cancel()
+			return SpendPage{}, err
+		}
+		conditions = append(conditions, bson.M{"payment_method.credit._id": cid})
+	}
+
+	cost := bson.M{}
+	if f.MinCost != nil {
+		cost["$gte"] = *f.MinCost
+	}
+	if f.MaxCost != nil {
+		cost["$lte"] = *f.MaxCost
+	}
+	if len(cost) > 0 {
+		conditions = append(conditions, bson.M{"cost": cost})
+	}
+
+	if f.Description != "" {
+		conditions = append(conditions, bson.M{"description": primitive.Regex{
+			Pattern: regexp.QuoteMeta(f.Description),
+			Options: "i",
+		}})
+	}
+
+	order, operator := -1, "$lt"
+	if f.Ascending {
+		order, operator = 1, "$gt"
+	}
+
+	if f.Cursor != "" {
+		cursorDate, cursorID, err := DecodeSpendCursor(f.Cursor)
+		if err != nil {
+			cancel()
+			return SpendPage{}, err
+		}
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"date": bson.M{operator: cursorDate}},
+			{"date": cursorDate, "_id": bson.M{operator: cursorID}},
+		}})
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultSpendsLimit
+	}
+
+	// one extra spend is requested to know if there is a next page
+	opts := options.Find().
+		SetSort(primitive.D{{Key: "date", Value: order}, {Key: "_id", Value: order}}).
+		SetLimit(limit + 1)
+
+	cursor, err := s.Config.GetAll(ctx, bson.M{"$and": conditions}, opts)
+	if err != nil {
+		cancel()
+		return SpendPage{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	spends := []Spend{}
+	for cursor.Next(ctx) {
+		var spend Spend
+		cursor.Decode(&spend)
+		spends = append(spends, spend)
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return SpendPage{}, err
+	}
+
+	page := SpendPage{Spends: spends}
+	if int64(len(spends)) > limit {
+		page.Spends = spends[:limit]
+		page.NextCursor = EncodeSpendCursor(page.Spends[limit-1])
+	}
+
+	return page, nil
+}
+
+// spendGroupKey will return the aggregation expression computing the key of a spend within a group
+func spendGroupKey(group string) (interface{}, error) {
+	switch group {
+	case SpendGroupCategory:
+		return bson.M{"$ifNull": []interface{}{"$categories", ""}}, nil
+	case SpendGroupPaymentMethod:
+		return bson.M{"$switch": bson.M{
+			"branches": []bson.M{
+				{"case": bson.M{"$ne": []interface{}{bson.M{"$type": "$payment_method.credit._id"}, "missing"}}, "then": PaymentMethodCredit},
+				{"case": bson.M{"$eq": []interface{}{"$payment_method.debit", true}}, "then": PaymentMethodDebit},
+				{"case": bson.M{"$eq": []interface{}{"$payment_method.payment_slip", true}}, "then": PaymentMethodPaymentSlip},
+			},
+			"default": "",
+		}}, nil
+	case SpendGroupCard:
+		return bson.M{"$toString": "$payment_method.credit._id"}, nil
+	case SpendGroupType:
+		return bson.M{"$ifNull": []interface{}{"$type", ""}}, nil
+	case SpendGroupMonth:
+		return bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$date"}}, nil
+	case SpendGroupCurrency:
+		return "", nil
+	}
+
+	return nil, fmt.Errorf("spends can not be grouped by '%s'", group)
+}
+
+// Totals will return how much the spends of an owner within a date range cost, grouped by one of the
+// SpendGroup* criteria and by currency, through an aggregation pipeline
+func (s *SpendRepositoryMongoDB) Totals(ctx context.Context, ownerID string, from time.Time, to time.Time, group string) ([]SpendTotal, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return []SpendTotal{}, err
+	}
+
+	key, err := spendGroupKey(group)
+	if err != nil {
+		cancel()
+		return []SpendTotal{}, err
+	}
+
+	match := bson.M{
+		"owner_id": oid,
+		"date": bson.M{
+			"$gte": primitive.NewDateTimeFromTime(from),
+			"$lte": primitive.NewDateTimeFromTime(to),
+		},
+		"type": bson.M{"$ne": SpendTypePayment},
+	}
+	if group == SpendGroupCard {
+		match["payment_method.credit._id"] = bson.M{"$exists": true}
+	}
+
+	pipeline := []bson.M{{"$match": match}}
+	if group == SpendGroupCategory {
+		// spends count towards each of their categories, and uncategorized ones towards an empty one
+		pipeline = append(pipeline, bson.M{"$unwind": bson.M{"path": "$categories", "preserveNullAndEmptyArrays": true}})
+	}
+	pipeline = append(pipeline, bson.M{"$group": bson.M{
+		"_id":   bson.M{"key": key, "currency": bson.M{"$ifNull": []interface{}{"$currency", ""}}},
+		"total": bson.M{"$sum": "$cost"},
+		"count": bson.M{"$sum": 1},
+	}})
+
+	cursor, err := s.Config.Aggregate(ctx, pipeline)
+	if err != nil {
+		cancel()
+		return []SpendTotal{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	totals := []SpendTotal{}
+	for cursor.Next(ctx) {
+		var result struct {
+			ID struct {
+				Key      string `bson:"key"`
+				Currency string `bson:"currency"`
+			} `bson:"_id"`
+			Total Money `bson:"total"`
+			Count int64 `bson:"count"`
+		}
+
+		err := cursor.Decode(&result)
+		if err != nil {
+			cancel()
+			return []SpendTotal{}, err
+		}
+
+		totals = append(totals, SpendTotal{Key: result.ID.Key, Currency: result.ID.Currency, Total: result.Total, Count: result.Count})
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return []SpendTotal{}, err
+	}
+
+	return totals, nil
+}
+
+// GetAll will return literally all spends from the database
+func (s *SpendRepositoryMongoDB) GetAll(ctx context.Context) ([]Spend, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	cursor, err := s.Config.GetAll(ctx, bson.M{})
+	if err != nil {
+		cancel()
+		return []Spend{}, err
+	}
+
+	var spends []Spend
+	for cursor.Next(ctx) {
+		var spend Spend
+		cursor.Decode(&spend)
+		spends = append(spends, spend)
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return []Spend{}, err
+	}
+
+	return spends, nil
+}
+
+// Create will create a spend
+func (s *SpendRepositoryMongoDB) Create(ctx context.Context, spend Spend) (id string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := s.Config.Create(ctx, spend)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key error collection") {
+			cancel()
+			return "", errors.New("spend already exists")
+		}
+
+		cancel()
+		return "", err
+	}
+
+	return r.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// Update will replace an existing spend based on it's ID
+func (s *SpendRepositoryMongoDB) Update(ctx context.Context, spend Spend) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := s.Config.Replace(ctx, bson.M{"_id": spend.ID, "owner_id": spend.OwnerID}, spend)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find spend")
+	}
+
+	return nil
+}
+
+// Delete will delete a spend based on it's ID
+func (s *SpendRepositoryMongoDB) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	r, err := s.Config.Delete(ctx, bson.M{"_id": pid})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.DeletedCount == 0 {
+		cancel()
+		return errors.New("could not find spend")
+	}
+
+	return nil
+}
+
+// Create will store an issued refresh token
+func (t *RefreshTokenRepositoryMongoDB) Create(ctx context.Context, token RefreshToken) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	_, err := t.Config.Create(ctx, token)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	return nil
+}
+
+// Consume will atomically mark a refresh token as used
+func (t *RefreshTokenRepositoryMongoDB) Consume(ctx context.Context, id string) (RefreshToken, error) {
+	var token RefreshToken
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := t.Config.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id, "used": false, "revoked": false},
+		bson.M{"$set": bson.M{"used": true}},
+	)
+	if err == nil {
+		r.Decode(&token)
+		return token, nil
+	}
+
+	if !strings.Contains(err.Error(), "no documents in result") {
+		cancel()
+		return RefreshToken{}, err
+	}
+
+	// the token exists but was already used or revoked
+	r, err = t.Config.Get(ctx, bson.M{"_id": id})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return RefreshToken{}, errors.New("could not find refresh token")
+		}
+		cancel()
+		return RefreshToken{}, err
+	}
+
+	r.Decode(&token)
+	if token.Revoked {
+		return token, errors.New("refresh token revoked")
+	}
+
+	return token, errors.New("refresh token reuse detected")
+}
+
+// RevokeFamily will revoke all refresh tokens from a given family
+func (t *RefreshTokenRepositoryMongoDB) RevokeFamily(ctx context.Context, family string) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	_, err := t.Config.UpdateMany(ctx, bson.M{"family": family}, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	return nil
+}
+
+// RevokeOwner will revoke all refresh tokens from a given owner
+func (t *RefreshTokenRepositoryMongoDB) RevokeOwner(ctx context.Context, ownerID string) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	_, err = t.Config.UpdateMany(ctx, bson.M{"owner_id": oid}, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	return nil
+}
+
+// Create will store a revocation
+func (r *RevocationRepositoryMongoDB) Create(ctx context.Context, revocation Revocation) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	_, err := r.Config.Create(ctx, revocation)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	return nil
+}
+
+// IsRevoked will return if a token was revoked by its ID or by revoking all sessions from its owner
+func (r *RevocationRepositoryMongoDB) IsRevoked(ctx context.Context, tokenID string, ownerID string, issuedAt time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return false, err
+	}
+
+	conditions := []bson.M{
+		{"owner_id": oid, "not_before": bson.M{"$gte": primitive.NewDateTimeFromTime(issuedAt)}},
+	}
+
+	if tokenID != "" {
+		conditions = append(conditions, bson.M{"jti": tokenID})
+	}
+
+	_, err = r.Config.Get(ctx, bson.M{"$or": conditions})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			return false, nil
+		}
+		cancel()
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Get will return the failed attempts from a login
+func (l *LoginAttemptRepositoryMongoDB) Get(ctx context.Context, login string) (LoginAttempt, error) {
+	var attempt LoginAttempt
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := l.Config.Get(ctx, bson.M{"_id": login})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return LoginAttempt{}, errors.New("could not find login attempt")
+		}
+		cancel()
+		return LoginAttempt{}, err
+	}
+
+	r.Decode(&attempt)
+	return attempt, nil
+}
+
+// RegisterFailure will atomically increase the failures from a login, creating it on demand
+func (l *LoginAttemptRepositoryMongoDB) RegisterFailure(ctx context.Context, login string, at time.Time, expiresAt time.Time) (LoginAttempt, error) {
+	var attempt LoginAttempt
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := l.Config.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": login},
+		bson.M{
+			"$inc": bson.M{"failures": 1},
+			"$set": bson.M{
+				"last_failure_at": primitive.NewDateTimeFromTime(at),
+				"expires_at":      primitive.NewDateTimeFromTime(expiresAt),
+			},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	)
+	if err != nil {
+		cancel()
+		return LoginAttempt{}, err
+	}
+
+	r.Decode(&attempt)
+	return attempt, nil
+}
+
+// Lock will lock a login until a given time
+func (l *LoginAttemptRepositoryMongoDB) Lock(ctx context.Context, login string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	r, err := l.Config.Update(ctx, bson.M{"_id": login}, bson.M{"$set": bson.M{"locked_until": primitive.NewDateTimeFromTime(until)}})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find login attempt")
+	}
+
+	return nil
+}
+
+// Reset will remove all failed attempts from a login, unlocking it
+func (l *LoginAttemptRepositoryMongoDB) Reset(ctx context.Context, login string) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	_, err := l.Config.Delete(ctx, bson.M{"_id": login})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	return nil
+}
+
+// Upsert will create or replace the rate of a currency pair for its day
+func (e *ExchangeRateRepositoryMongoDB) Upsert(ctx context.Context, rate ExchangeRate) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	_, err := e.Config.Update(
+		ctx,
+		bson.M{"base": rate.Base, "quote": rate.Quote, "date": rate.Date},
+		bson.M{"$set": bson.M{"rate": rate.Rate, "updated_at": rate.UpdatedAt}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	return nil
+}
+
+// Get will return the most recent rate of a currency pair published until a given day
+func (e *ExchangeRateRepositoryMongoDB) Get(ctx context.Context, base string, quote string, date time.Time) (ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(primitive.D{{Key: "date", Value: -1}}).SetLimit(1)
+
+	cursor, err := e.Config.GetAll(
+		ctx,
+		bson.M{"base": base, "quote": quote, "date": bson.M{"$lte": primitive.NewDateTimeFromTime(date)}},
+		opts,
+	)
+	if err != nil {
+		cancel()
+		return ExchangeRate{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err := cursor.Err(); err != nil {
+			return ExchangeRate{}, err
+		}
+		return ExchangeRate{}, errors.New("could not find exchange rate")
+	}
+
+	var rate ExchangeRate
+	err = cursor.Decode(&rate)
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+
+	return rate, nil
+}
+
+// List will return the exchange rates matching a given filter, sorted by date
+func (e *ExchangeRateRepositoryMongoDB) List(ctx context.Context, f ExchangeRateFilter) ([]ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	filter := bson.M{}
+	if f.Base != "" {
+		filter["base"] = f.Base
+	}
+	if f.Quote != "" {
+		filter["quote"] = f.Quote
+	}
+
+	date := bson.M{}
+	if !f.From.IsZero() {
+		date["$gte"] = primitive.NewDateTimeFromTime(f.From)
+	}
+	if !f.To.IsZero() {
+		date["$lte"] = primitive.NewDateTimeFromTime(f.To)
+	}
+	if len(date) > 0 {
+		filter["date"] = date
+	}
+
+	opts := options.Find().SetSort(primitive.D{{Key: "date", Value: 1}, {Key: "base", Value: 1}, {Key: "quote", Value: 1}})
+
+	cursor, err := e.Config.GetAll(ctx, filter, opts)
+	if err != nil {
+		cancel()
+		return []ExchangeRate{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	rates := []ExchangeRate{}
+	for cursor.Next(ctx) {
+		var rate ExchangeRate
+		cursor.Decode(&rate)
+		rates = append(rates, rate)
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return []ExchangeRate{}, err
+	}
+
+	return rates, nil
+}
+
+// Create will create a recurrence
+func (r *RecurrenceRepositoryMongoDB) Create(ctx context.Context, recurrence Recurrence) (id string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	result, err := r.Config.Create(ctx, recurrence)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key error collection") {
+			cancel()
+			return "", errors.New("recurrence already exists")
+		}
+
+		cancel()
+		return "", err
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// findRecurrences will return every recurrence matching a filter, sorted by their next run
+func (r *RecurrenceRepositoryMongoDB) findRecurrences(ctx context.Context, filter bson.M) ([]Recurrence, error) {
+	opts := options.Find().SetSort(primitive.D{{Key: "next_run", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.Config.GetAll(ctx, filter, opts)
+	if err != nil {
+		return []Recurrence{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	recurrences := []Recurrence{}
+	for cursor.Next(ctx) {
+		var recurrence Recurrence
+		cursor.Decode(&recurrence)
+		recurrences = append(recurrences, recurrence)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return []Recurrence{}, err
+	}
+
+	return recurrences, nil
+}
+
+// Get will return every recurrence from a given ownerID
+func (r *RecurrenceRepositoryMongoDB) Get(ctx context.Context, ownerID string) ([]Recurrence, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return []Recurrence{}, err
+	}
+
+	return r.findRecurrences(ctx, bson.M{"owner_id": oid})
+}
+
+// GetByID will return a single recurrence from a given ownerID
+func (r *RecurrenceRepositoryMongoDB) GetByID(ctx context.Context, ownerID string, id string) (Recurrence, error) {
+	var recurrence Recurrence
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return Recurrence{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return Recurrence{}, err
+	}
+
+	result, err := r.Config.Get(ctx, bson.M{"_id": pid, "owner_id": oid})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return Recurrence{}, errors.New("could not find recurrence")
+		}
+		cancel()
+		return Recurrence{}, err
+	}
+
+	result.Decode(&recurrence)
+
+	return recurrence, nil
+}
+
+// Due will return every active recurrence whose next run is until a given date
+func (r *RecurrenceRepositoryMongoDB) Due(ctx context.Context, until time.Time) ([]Recurrence, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	return r.findRecurrences(ctx, bson.M{
+		"status":   RecurrenceStatusActive,
+		"next_run": bson.M{"$lte": primitive.NewDateTimeFromTime(until)},
+	})
+}
+
+// Schedule will update the status and next run of a recurrence while its status and next run are still the previous ones
+func (r *RecurrenceRepositoryMongoDB) Schedule(ctx context.Context, recurrence Recurrence, status string, previous primitive.DateTime) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	result, err := r.Config.Update(
+		ctx,
+		bson.M{"_id": recurrence.ID, "owner_id": recurrence.OwnerID, "status": status, "next_run": previous},
+		bson.M{"$set": bson.M{
+			"status":     recurrence.Status,
+			"next_run":   recurrence.NextRun,
+			"updated_at": recurrence.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find recurrence")
+	}
+
+	return nil
+}
+
+// UpdateCategories will replace the categories of a recurrence, used by its future spends
+func (r *RecurrenceRepositoryMongoDB) UpdateCategories(ctx context.Context, recurrence Recurrence) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	result, err := r.Config.Update(
+		ctx,
+		bson.M{"_id": recurrence.ID, "owner_id": recurrence.OwnerID},
+		bson.M{"$set": bson.M{
+			"categories": recurrence.Categories,
+			"updated_at": recurrence.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find recurrence")
+	}
+
+	return nil
+}
+
+// Create will store a statement payment, a single one per card statement
+func (p *StatementPaymentRepositoryMongoDB) Create(ctx context.Context, payment StatementPayment) (id string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	if payment.ID.IsZero() {
+		payment.ID = primitive.NewObjectID()
+	}
+
+	result, err := p.Config.Create(ctx, payment)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key error collection") {
+			cancel()
+			return "", errors.New("statement already paid")
+		}
+
+		cancel()
+		return "", err
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// Get will return the payment of a card statement
+func (p *StatementPaymentRepositoryMongoDB) Get(ctx context.Context, ownerID string, cardID string, month int64, year int64) (StatementPayment, error) {
+	var payment StatementPayment
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return StatementPayment{}, err
+	}
+
+	cid, err := primitive.ObjectIDFromHex(cardID)
+	if err != nil {
+		cancel()
+		return StatementPayment{}, err
+	}
+
+	result, err := p.Config.Get(ctx, bson.M{"owner_id": oid, "card_id": cid, "month": month, "year": year})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return StatementPayment{}, errors.New("could not find statement payment")
+		}
+		cancel()
+		return StatementPayment{}, err
+	}
+
+	result.Decode(&payment)
+
+	return payment, nil
+}
+
+// List will return every payment of a card, sorted by statement
+func (p *StatementPaymentRepositoryMongoDB) List(ctx context.Context, ownerID string, cardID string) ([]StatementPayment, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return []StatementPayment{}, err
+	}
+
+	cid, err := primitive.ObjectIDFromHex(cardID)
+	if err != nil {
+		cancel()
+		return []StatementPayment{}, err
+	}
+
+	opts := options.Find().SetSort(primitive.D{{Key: "year", Value: 1}, {Key: "month", Value: 1}})
+
+	cursor, err := p.Config.GetAll(ctx, bson.M{"owner_id": oid, "card_id": cid}, opts)
+	if err != nil {
+		cancel()
+		return []StatementPayment{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	payments := []StatementPayment{}
+	for cursor.Next(ctx) {
+		var payment StatementPayment
+		cursor.Decode(&payment)
+		payments = append(payments, payment)
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return []StatementPayment{}, err
+	}
+
+	return payments, nil
+}
+
+// Delete will delete a statement payment based on it's ID
+func (p *StatementPaymentRepositoryMongoDB) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	r, err := p.Config.Delete(ctx, bson.M{"_id": pid})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.DeletedCount == 0 {
+		cancel()
+		return errors.New("could not find statement payment")
+	}
+
+	return nil
+}
+
+// Create will store a budget alert, a single one per category threshold and month
+func (a *BudgetAlertRepositoryMongoDB) Create(ctx context.Context, alert BudgetAlert) (id string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	if alert.ID.IsZero() {
+		alert.ID = primitive.NewObjectID()
+	}
+
+	result, err := a.Config.Create(ctx, alert)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key error collection") {
+			cancel()
+			return "", errors.New("budget alert already recorded")
+		}
+
+		cancel()
+		return "", err
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// List will return every alert of a month, sorted by creation
+func (a *BudgetAlertRepositoryMongoDB) List(ctx context.Context, ownerID string, month int64, year int64) ([]BudgetAlert, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return []BudgetAlert{}, err
+	}
+
+	opts := options.Find().SetSort(primitive.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := a.Config.GetAll(ctx, bson.M{"owner_id": oid, "month": month, "year": year}, opts)
+	if err != nil {
+		cancel()
+		return []BudgetAlert{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	alerts := []BudgetAlert{}
+	for cursor.Next(ctx) {
+		var alert BudgetAlert
+		cursor.Decode(&alert)
+		alerts = append(alerts, alert)
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return []BudgetAlert{}, err
+	}
+
+	return alerts, nil
+}
+
+// Get will return every category of an owner, sorted by name
+func (c *CategoryRepositoryMongoDB) Get(ctx context.Context, ownerID string) ([]Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return []Category{}, err
+	}
+
+	opts := options.Find().SetSort(primitive.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := c.Config.GetAll(ctx, bson.M{"owner_id": oid}, opts)
+	if err != nil {
+		cancel()
+		return []Category{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	categories := []Category{}
+	for cursor.Next(ctx) {
+		var category Category
+		cursor.Decode(&category)
+		categories = append(categories, category)
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return []Category{}, err
+	}
+
+	return categories, nil
+}
+
+// GetByID will return a single category from a given owner ID
+func (c *CategoryRepositoryMongoDB) GetByID(ctx context.Context, ownerID string, id string) (Category, error) {
+	var category Category
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return Category{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return Category{}, err
+	}
+
+	result, err := c.Config.Get(ctx, bson.M{"_id": pid, "owner_id": oid})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return Category{}, errors.New("could not find category")
+		}
+		cancel()
+		return Category{}, err
+	}
+
+	result.Decode(&category)
+
+	return category, nil
+}
+
+// Create will store a category, whose name must be unique per owner regardless of case
+func (c *CategoryRepositoryMongoDB) Create(ctx context.Context, category Category) (id string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	if category.ID.IsZero() {
+		category.ID = primitive.NewObjectID()
+	}
+
+	result, err := c.Config.Create(ctx, category)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key error collection") {
+			cancel()
+			return "", errors.New("category already exists")
+		}
+
+		cancel()
+		return "", err
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// Update will replace every attribute of a category but its owner and creation date
+func (c *CategoryRepositoryMongoDB) Update(ctx context.Context, category Category) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	set := bson.M{
+		"name":       category.Name,
+		"color":      category.Color,
+		"icon":       category.Icon,
+		"updated_at": category.UpdatedAt,
+	}
+	update := bson.M{"$set": set}
+	if category.ParentID.IsZero() {
+		update["$unset"] = bson.M{"parent_id": ""}
+	} else {
+		set["parent_id"] = category.ParentID
+	}
+
+	r, err := c.Config.Update(ctx, bson.M{"_id": category.ID, "owner_id": category.OwnerID}, update)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key error collection") {
+			cancel()
+			return errors.New("category already exists")
+		}
+
+		cancel()
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find category")
+	}
+
+	return nil
+}
+
+// Delete will delete a category based on it's ID
+func (c *CategoryRepositoryMongoDB) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	r, err := c.Config.Delete(ctx, bson.M{"_id": pid})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.DeletedCount == 0 {
+		cancel()
+		return errors.New("could not find category")
+	}
+
+	return nil
+}
+
+// Get will return every rule of an owner, sorted by priority and then by creation
+func (c *CategoryRuleRepositoryMongoDB) Get(ctx context.Context, ownerID string) ([]CategoryRule, error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return []CategoryRule{}, err
+	}
+
+	opts := options.Find().SetSort(primitive.D{{Key: "priority", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := c.Config.GetAll(ctx, bson.M{"owner_id": oid}, opts)
+	if err != nil {
+		cancel()
+		return []CategoryRule{}, err
+	}
+
+	defer cursor.Close(ctx)
+
+	rules := []CategoryRule{}
+	for cursor.Next(ctx) {
+		var rule CategoryRule
+		cursor.Decode(&rule)
+		rules = append(rules, rule)
+	}
+
+	if err := cursor.Err(); err != nil {
+		cancel()
+		return []CategoryRule{}, err
+	}
+
+	return rules, nil
+}
+
+// GetByID will return a single rule from a given owner ID
+func (c *CategoryRuleRepositoryMongoDB) GetByID(ctx context.Context, ownerID string, id string) (CategoryRule, error) {
+	var rule CategoryRule
+
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		cancel()
+		return CategoryRule{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return CategoryRule{}, err
+	}
+
+	result, err := c.Config.Get(ctx, bson.M{"_id": pid, "owner_id": oid})
+	if err != nil {
+		if strings.Contains(err.Error(), "no documents in result") {
+			cancel()
+			return CategoryRule{}, errors.New("could not find category rule")
+		}
+		cancel()
+		return CategoryRule{}, err
+	}
+
+	result.Decode(&rule)
+
+	return rule, nil
+}
+
+// Create will store a rule
+func (c *CategoryRuleRepositoryMongoDB) Create(ctx context.Context, rule CategoryRule) (id string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	if rule.ID.IsZero() {
+		rule.ID = primitive.NewObjectID()
+	}
+
+	result, err := c.Config.Create(ctx, rule)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key error collection") {
+			cancel()
+			return "", errors.New("category rule already exists")
+		}
+
+		cancel()
+		return "", err
+	}
+
+	return result.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+// Update will replace every attribute of a rule but its owner and creation date
+func (c *CategoryRuleRepositoryMongoDB) Update(ctx context.Context, rule CategoryRule) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	set := bson.M{
+		"priority":       rule.Priority,
+		"description":    rule.Description,
+		"pattern":        rule.Pattern,
+		"payment_method": rule.PaymentMethod,
+		"categories":     rule.Categories,
+		"type":           rule.Type,
+		"updated_at":     rule.UpdatedAt,
+	}
+	unset := bson.M{}
+
+	// optional conditions are removed instead of stored as empty ones
+	if rule.MinCost != nil {
+		set["min_cost"] = *rule.MinCost
+	} else {
+		unset["min_cost"] = ""
+	}
+	if rule.MaxCost != nil {
+		set["max_cost"] = *rule.MaxCost
+	} else {
+		unset["max_cost"] = ""
+	}
+	if !rule.CardID.IsZero() {
+		set["card_id"] = rule.CardID
+	} else {
+		unset["card_id"] = ""
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	r, err := c.Config.Update(ctx, bson.M{"_id": rule.ID, "owner_id": rule.OwnerID}, update)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		cancel()
+		return errors.New("could not find category rule")
+	}
+
+	return nil
+}
+
+// Delete will delete a rule based on it's ID
+func (c *CategoryRuleRepositoryMongoDB) Delete(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, services.MongodbTimeout)
+	defer cancel()
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	r, err := c.Config.Delete(ctx, bson.M{"_id": pid})
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	if r.DeletedCount == 0 {
+		cancel()
+		return errors.New("could not find category rule")
+	}
+
 	return nil
 }
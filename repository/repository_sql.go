@@ -0,0 +1,2333 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// SQLDialectSQLite defines the SQLite dialect, also used as its driver name
+	SQLDialectSQLite = "sqlite3"
+	// SQLDialectPostgres defines the PostgreSQL dialect, also used as its driver name
+	SQLDialectPostgres = "postgres"
+)
+
+// SQLDatabase defines a SQL database along with the dialect its queries must be written in
+type SQLDatabase struct {
+	DB      *sql.DB
+	Dialect string
+}
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// sqlConn runs queries written with '?' placeholders, rebinding them to the database dialect
+type sqlConn struct {
+	ex      sqlExecutor
+	dialect string
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// sqlTxKey defines the context key of the transaction queries must run within
+type sqlTxKey struct{}
+
+// conn will return a connection running queries through a given executor
+func (d *SQLDatabase) conn(ex sqlExecutor) sqlConn {
+	return sqlConn{ex: ex, dialect: d.Dialect}
+}
+
+// executor will return the transaction a context is running within, or the database otherwise
+func (d *SQLDatabase) executor(ctx context.Context) sqlExecutor {
+	if tx, ok := ctx.Value(sqlTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return d.DB
+}
+
+// transaction will run a function within a transaction, committing it only when no error is returned.
+// Contexts already running within a transaction keep using it, committed along with it
+func (d *SQLDatabase) transaction(ctx context.Context, fn func(c sqlConn) error) error {
+	if tx, ok := ctx.Value(sqlTxKey{}).(*sql.Tx); ok {
+		return fn(d.conn(tx))
+	}
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(d.conn(tx))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rebind will replace '?' placeholders by '$n' ones when the dialect requires it
+func (c sqlConn) rebind(query string) string {
+	if c.dialect != SQLDialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (c sqlConn) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.ex.ExecContext(ctx, c.rebind(query), args...)
+}
+
+func (c sqlConn) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.ex.QueryContext(ctx, c.rebind(query), args...)
+}
+
+func (c sqlConn) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.ex.QueryRowContext(ctx, c.rebind(query), args...)
+}
+
+// isUniqueViolation will return if an error was caused by a unique constraint, for any supported dialect
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "UNIQUE constraint failed") ||
+		strings.Contains(err.Error(), "duplicate key value violates unique constraint")
+}
+
+// affected will return an error when a statement changed no rows
+func affected(result sql.Result, notFound string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return errors.New(notFound)
+	}
+
+	return nil
+}
+
+// nullString will return a NULL value for empty strings
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullObjectID will return a NULL value for zero IDs
+func nullObjectID(id primitive.ObjectID) sql.NullString {
+	if id.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: id.Hex(), Valid: true}
+}
+
+// nullMoney will return an optional amount as a nullable column value
+func nullMoney(m *Money) sql.NullInt64 {
+	if m == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*m), Valid: true}
+}
+
+// NewSQLRepositories will return every repository backed by a single SQL database, whose schema
+// must be up to date (see MigrateSQL)
+func NewSQLRepositories(db *sql.DB, dialect string) Repositories {
+	d := &SQLDatabase{DB: db, Dialect: dialect}
+
+	return Repositories{
+		Database:      &DatabaseRepositorySQL{DB: d},
+		Users:         &UserRepositorySQL{DB: d},
+		Cards:         &CardRepositorySQL{DB: d},
+		Balances:      &BalanceRepositorySQL{DB: d},
+		Spends:        &SpendRepositorySQL{DB: d},
+		RefreshTokens: &RefreshTokenRepositorySQL{DB: d},
+		Revocations:   &RevocationRepositorySQL{DB: d},
+		LoginAttempts: &LoginAttemptRepositorySQL{DB: d},
+		ExchangeRates: &ExchangeRateRepositorySQL{DB: d},
+		Recurrences:   &RecurrenceRepositorySQL{DB: d},
+		Statements:    &StatementPaymentRepositorySQL{DB: d},
+		BudgetAlerts:  &BudgetAlertRepositorySQL{DB: d},
+		Categories:    &CategoryRepositorySQL{DB: d},
+		CategoryRules: &CategoryRuleRepositorySQL{DB: d},
+	}
+}
+
+// DatabaseRepositorySQL defines a struct for SQL database operations
+type DatabaseRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// UserRepositorySQL defines a struct for SQL User operations
+type UserRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// CardRepositorySQL defines a struct for SQL Card operations
+type CardRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// BalanceRepositorySQL defines a struct for SQL Balance operations
+type BalanceRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// SpendRepositorySQL defines a struct for SQL Spend operations
+type SpendRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// RefreshTokenRepositorySQL defines a struct for SQL RefreshToken operations
+type RefreshTokenRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// RevocationRepositorySQL defines a struct for SQL Revocation operations
+type RevocationRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// LoginAttemptRepositorySQL defines a struct for SQL LoginAttempt operations
+type LoginAttemptRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// ExchangeRateRepositorySQL defines a struct for SQL ExchangeRate operations
+type ExchangeRateRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// RecurrenceRepositorySQL defines a struct for SQL Recurrence operations
+type RecurrenceRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// StatementPaymentRepositorySQL defines a struct for SQL StatementPayment operations
+type StatementPaymentRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// BudgetAlertRepositorySQL defines a struct for SQL BudgetAlert operations
+type BudgetAlertRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// CategoryRuleRepositorySQL defines a struct for SQL CategoryRule operations
+type CategoryRuleRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// CategoryRepositorySQL defines a struct for SQL Category operations
+type CategoryRepositorySQL struct {
+	DB *SQLDatabase
+}
+
+// Health will ping the database
+func (d *DatabaseRepositorySQL) Health() error {
+	return d.DB.DB.Ping()
+}
+
+// Transaction will run a function within a SQL transaction, which every repository given its context joins
+func (d *DatabaseRepositorySQL) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return d.DB.transaction(ctx, func(c sqlConn) error {
+		return fn(withinTransaction(context.WithValue(ctx, sqlTxKey{}, c.ex)))
+	})
+}
+
+const userColumns = `id, login, firstname, lastname, email, password, roles, created_at`
+
+// scanUser will read a row selected with userColumns
+func scanUser(row rowScanner) (User, error) {
+	var user User
+	var id, roles string
+	var createdAt int64
+
+	err := row.Scan(&id, &user.Login, &user.Firstname, &user.Lastname, &user.Email, &user.SaltedPassword, &roles, &createdAt)
+	if err != nil {
+		return User{}, err
+	}
+
+	user.ID, err = primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return User{}, err
+	}
+
+	err = json.Unmarshal([]byte(roles), &user.Roles)
+	if err != nil {
+		return User{}, err
+	}
+
+	user.CreatedAt = primitive.DateTime(createdAt)
+	return user, nil
+}
+
+// Get will return a user based on it's ID
+func (u *UserRepositorySQL) Get(ctx context.Context, id string) (SanitizedUser, error) {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return SanitizedUser{}, err
+	}
+
+	row := u.DB.conn(u.DB.executor(ctx)).queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, pid.Hex())
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return SanitizedUser{}, errors.New("could not find user")
+	}
+	if err != nil {
+		return SanitizedUser{}, err
+	}
+
+	return sanitizeUser(user), nil
+}
+
+// GetByLogin will return a user, along with its salted password, based on its login
+func (u *UserRepositorySQL) GetByLogin(ctx context.Context, login string) (User, error) {
+	row := u.DB.conn(u.DB.executor(ctx)).queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE login = ?`, login)
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return User{}, errors.New("could not find user")
+	}
+
+	return user, err
+}
+
+// GetAll will return all Users (in a sanitized way)
+func (u *UserRepositorySQL) GetAll(ctx context.Context) ([]SanitizedUser, error) {
+	rows, err := u.DB.conn(u.DB.executor(ctx)).query(ctx, `SELECT `+userColumns+` FROM users ORDER BY id`)
+	if err != nil {
+		return []SanitizedUser{}, err
+	}
+	defer rows.Close()
+
+	var users []SanitizedUser
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return []SanitizedUser{}, err
+		}
+		users = append(users, sanitizeUser(user))
+	}
+
+	return users, rows.Err()
+}
+
+// Create will create a user, whose login must be unique
+func (u *UserRepositorySQL) Create(ctx context.Context, user User) (id string, err error) {
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+
+	roles, err := json.Marshal(append([]string{}, user.Roles...))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = u.DB.conn(u.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID.Hex(), user.Login, user.Firstname, user.Lastname, user.Email, user.SaltedPassword, string(roles), int64(user.CreatedAt),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("user already exists")
+		}
+		return "", err
+	}
+
+	return user.ID.Hex(), nil
+}
+
+// Delete will delete a user based on it's ID
+func (u *UserRepositorySQL) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := u.DB.conn(u.DB.executor(ctx)).exec(ctx, `DELETE FROM users WHERE id = ?`, pid.Hex())
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "non existent user")
+}
+
+const cardColumns = `id, owner_id, alias, network, color, last_digits, closing_day, due_day, credit_limit, archived, archived_at, created_at`
+
+// scanCard will read a row selected with cardColumns
+func scanCard(row rowScanner) (CreditCard, error) {
+	var card CreditCard
+	var id, ownerID string
+	var archivedAt, createdAt int64
+
+	err := row.Scan(
+		&id, &ownerID, &card.Alias, &card.Network, &card.Color, &card.LastDigits,
+		&card.ClosingDay, &card.DueDay, &card.CreditLimit, &card.Archived, &archivedAt, &createdAt,
+	)
+	if err != nil {
+		return CreditCard{}, err
+	}
+
+	card.ID, err = primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return CreditCard{}, err
+	}
+
+	card.OwnerID, err = primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return CreditCard{}, err
+	}
+
+	card.ArchivedAt = primitive.DateTime(archivedAt)
+	card.CreatedAt = primitive.DateTime(createdAt)
+	return card, nil
+}
+
+// queryCards will return every card selected by a query
+func queryCards(ctx context.Context, c sqlConn, query string, args ...interface{}) ([]CreditCard, error) {
+	rows, err := c.query(ctx, query, args...)
+	if err != nil {
+		return []CreditCard{}, err
+	}
+	defer rows.Close()
+
+	var cards []CreditCard
+	for rows.Next() {
+		card, err := scanCard(rows)
+		if err != nil {
+			return []CreditCard{}, err
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rows.Err()
+}
+
+// Get will return all cards from a given owner ID
+func (c *CardRepositorySQL) Get(ctx context.Context, ownerID string) ([]CreditCard, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []CreditCard{}, err
+	}
+
+	cards, err := queryCards(ctx, c.DB.conn(c.DB.executor(ctx)), `SELECT `+cardColumns+` FROM cards WHERE owner_id = ? ORDER BY id`, oid.Hex())
+	if err != nil {
+		return []CreditCard{}, err
+	}
+
+	if len(cards) == 0 {
+		return []CreditCard{}, errors.New("could not find any cards")
+	}
+
+	return cards, nil
+}
+
+// GetByID will return a single card from a given owner ID
+func (c *CardRepositorySQL) GetByID(ctx context.Context, ownerID string, id string) (CreditCard, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return CreditCard{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return CreditCard{}, err
+	}
+
+	row := c.DB.conn(c.DB.executor(ctx)).queryRow(ctx, `SELECT `+cardColumns+` FROM cards WHERE id = ? AND owner_id = ?`, pid.Hex(), oid.Hex())
+
+	card, err := scanCard(row)
+	if err == sql.ErrNoRows {
+		return CreditCard{}, errors.New("could not find card")
+	}
+
+	return card, err
+}
+
+// GetOwnerID will return who owns a card, for routes which are not scoped by owner
+func (c *CardRepositorySQL) GetOwnerID(ctx context.Context, id string) (string, error) {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return "", err
+	}
+
+	var ownerID string
+	err = c.DB.conn(c.DB.executor(ctx)).queryRow(ctx, `SELECT owner_id FROM cards WHERE id = ?`, pid.Hex()).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return "", errors.New("could not find card")
+	}
+
+	return ownerID, err
+}
+
+// GetAll will return literally all cards
+func (c *CardRepositorySQL) GetAll(ctx context.Context) ([]CreditCard, error) {
+	return queryCards(ctx, c.DB.conn(c.DB.executor(ctx)), `SELECT `+cardColumns+` FROM cards ORDER BY id`)
+}
+
+// Create will create a card, whose last digits must be unique per owner
+func (c *CardRepositorySQL) Create(ctx context.Context, card CreditCard) (id string, err error) {
+	if card.ID.IsZero() {
+		card.ID = primitive.NewObjectID()
+	}
+
+	_, err = c.DB.conn(c.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO cards (`+cardColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		card.ID.Hex(), card.OwnerID.Hex(), card.Alias, card.Network, card.Color, card.LastDigits,
+		card.ClosingDay, card.DueDay, card.CreditLimit, card.Archived, int64(card.ArchivedAt), int64(card.CreatedAt),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("card already exists")
+		}
+		return "", err
+	}
+
+	return card.ID.Hex(), nil
+}
+
+// Update will replace every attribute of a card but its owner, last digits and creation date
+func (c *CardRepositorySQL) Update(ctx context.Context, card CreditCard) error {
+	result, err := c.DB.conn(c.DB.executor(ctx)).exec(ctx,
+		`UPDATE cards SET alias = ?, network = ?, color = ?, closing_day = ?, due_day = ?, credit_limit = ?, archived = ?, archived_at = ?
+		WHERE id = ? AND owner_id = ?`,
+		card.Alias, card.Network, card.Color, card.ClosingDay, card.DueDay, card.CreditLimit, card.Archived, int64(card.ArchivedAt),
+		card.ID.Hex(), card.OwnerID.Hex(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find card")
+}
+
+// Delete will delete a card based on it's ID
+func (c *CardRepositorySQL) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.DB.conn(c.DB.executor(ctx)).exec(ctx, `DELETE FROM cards WHERE id = ?`, pid.Hex())
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "non existent card")
+}
+
+const balanceColumns = `id, owner_id, month, year, income_gross, income_net, outcome_fixed, outcome_dynamic, spendable_amount, currency, budgets, created_at, updated_at`
+
+// scanBalance will read a row selected with balanceColumns, without its historic
+func scanBalance(row rowScanner) (Balance, error) {
+	var b Balance
+	var id, ownerID, budgets string
+	var createdAt, updatedAt int64
+
+	err := row.Scan(
+		&id, &ownerID, &b.Month, &b.Year,
+		&b.Income.GrossIncome, &b.Income.NetIncome, &b.Outcome.FixedOutcome, &b.Outcome.DynamicOutcome,
+		&b.SpendableAmount, &b.Currency, &budgets, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	err = json.Unmarshal([]byte(budgets), &b.Budgets)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	b.ID, err = primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	b.OwnerID, err = primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	b.CreatedAt = primitive.DateTime(createdAt)
+	b.UpdatedAt = primitive.DateTime(updatedAt)
+	return b, nil
+}
+
+// queryBalances will return every balance selected by a query, along with their historic
+func queryBalances(ctx context.Context, c sqlConn, query string, args ...interface{}) ([]Balance, error) {
+	rows, err := c.query(ctx, query, args...)
+	if err != nil {
+		return []Balance{}, err
+	}
+
+	balances := []Balance{}
+	for rows.Next() {
+		b, err := scanBalance(rows)
+		if err != nil {
+			rows.Close()
+			return []Balance{}, err
+		}
+		balances = append(balances, b)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return []Balance{}, err
+	}
+
+	for i := range balances {
+		balances[i].Historic, err = queryHistoric(ctx, c, balances[i].ID)
+		if err != nil {
+			return []Balance{}, err
+		}
+	}
+
+	return balances, nil
+}
+
+// queryHistoric will return the spends snapshot of a balance, in the order they were added
+func queryHistoric(ctx context.Context, c sqlConn, balanceID primitive.ObjectID) ([]Spend, error) {
+	rows, err := c.query(ctx, `SELECT spend FROM balance_spends WHERE balance_id = ? ORDER BY position`, balanceID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	historic := []Spend{}
+	for rows.Next() {
+		var raw string
+		err := rows.Scan(&raw)
+		if err != nil {
+			return nil, err
+		}
+
+		var s Spend
+		err = json.Unmarshal([]byte(raw), &s)
+		if err != nil {
+			return nil, err
+		}
+		historic = append(historic, s)
+	}
+
+	return historic, rows.Err()
+}
+
+// insertHistoric will append a spend to the snapshot of a balance
+func insertHistoric(ctx context.Context, c sqlConn, balanceID primitive.ObjectID, s Spend) error {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.exec(ctx,
+		`INSERT INTO balance_spends (balance_id, spend_id, position, spend)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM balance_spends WHERE balance_id = ?), ?)`,
+		balanceID.Hex(), s.ID.Hex(), balanceID.Hex(), string(raw),
+	)
+	return err
+}
+
+// single will return the only balance selected by a query
+func (b *BalanceRepositorySQL) single(ctx context.Context, query string, args ...interface{}) (Balance, error) {
+	balances, err := queryBalances(ctx, b.DB.conn(b.DB.executor(ctx)), query, args...)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	if len(balances) == 0 {
+		return Balance{}, errors.New("could not find balance")
+	}
+
+	return balances[0], nil
+}
+
+// Get will return a balance from an owner based on month and year
+func (b *BalanceRepositorySQL) Get(ctx context.Context, ownerID string, month int64, year int64) (Balance, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	return b.single(ctx, `SELECT `+balanceColumns+` FROM balances WHERE owner_id = ? AND month = ? AND year = ?`, oid.Hex(), month, year)
+}
+
+// GetByID will return a single balance from a given ownerID
+func (b *BalanceRepositorySQL) GetByID(ctx context.Context, ownerID string, id string) (Balance, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Balance{}, err
+	}
+
+	return b.single(ctx, `SELECT `+balanceColumns+` FROM balances WHERE id = ? AND owner_id = ?`, pid.Hex(), oid.Hex())
+}
+
+// List will return balances from an owner within a given period, sorted chronologically
+func (b *BalanceRepositorySQL) List(ctx context.Context, f BalanceFilter) ([]Balance, error) {
+	oid, err := primitive.ObjectIDFromHex(f.OwnerID)
+	if err != nil {
+		return []Balance{}, err
+	}
+
+	query := `SELECT ` + balanceColumns + ` FROM balances WHERE owner_id = ?`
+	args := []interface{}{oid.Hex()}
+
+	if !f.From.IsZero() {
+		query += ` AND year * 12 + month >= ?`
+		args = append(args, f.From.Year*12+f.From.Month)
+	}
+
+	if !f.To.IsZero() {
+		query += ` AND year * 12 + month <= ?`
+		args = append(args, f.To.Year*12+f.To.Month)
+	}
+
+	return queryBalances(ctx, b.DB.conn(b.DB.executor(ctx)), query+` ORDER BY year, month`, args...)
+}
+
+// GetAll will return literally all balances
+func (b *BalanceRepositorySQL) GetAll(ctx context.Context) ([]Balance, error) {
+	balances, err := queryBalances(ctx, b.DB.conn(b.DB.executor(ctx)), `SELECT `+balanceColumns+` FROM balances ORDER BY id`)
+	if err != nil {
+		return []Balance{}, err
+	}
+
+	if len(balances) == 0 {
+		return []Balance{}, errors.New("could not find any balances")
+	}
+
+	return balances, nil
+}
+
+// Create will create a balance, which must be unique per owner, month and year
+func (b *BalanceRepositorySQL) Create(ctx context.Context, balance Balance) (id string, err error) {
+	if balance.ID.IsZero() {
+		balance.ID = primitive.NewObjectID()
+	}
+
+	budgets, err := json.Marshal(append([]Budget{}, balance.Budgets...))
+	if err != nil {
+		return "", err
+	}
+
+	err = b.DB.transaction(ctx, func(c sqlConn) error {
+		_, err := c.exec(ctx,
+			`INSERT INTO balances (`+balanceColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			balance.ID.Hex(), balance.OwnerID.Hex(), balance.Month, balance.Year,
+			balance.Income.GrossIncome, balance.Income.NetIncome, balance.Outcome.FixedOutcome, balance.Outcome.DynamicOutcome,
+			balance.SpendableAmount, balance.Currency, string(budgets), int64(balance.CreatedAt), int64(balance.UpdatedAt),
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range balance.Historic {
+			err = insertHistoric(ctx, c, balance.ID, s)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("balance already exists")
+		}
+		return "", err
+	}
+
+	return balance.ID.Hex(), nil
+}
+
+// Update will change a balance income, currency and budgets, recomputing its spendable amount against the recorded outcome
+func (b *BalanceRepositorySQL) Update(ctx context.Context, balance Balance) error {
+	budgets, err := json.Marshal(append([]Budget{}, balance.Budgets...))
+	if err != nil {
+		return err
+	}
+
+	result, err := b.DB.conn(b.DB.executor(ctx)).exec(ctx,
+		`UPDATE balances
+		SET income_gross = ?, income_net = ?, currency = ?, budgets = ?, updated_at = ?,
+			spendable_amount = ? - (outcome_fixed + outcome_dynamic)
+		WHERE id = ? AND owner_id = ?`,
+		balance.Income.GrossIncome, balance.Income.NetIncome, balance.Currency, string(budgets), int64(balance.UpdatedAt),
+		balance.Income.NetIncome, balance.ID.Hex(), balance.OwnerID.Hex(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find balance")
+}
+
+// Delete will delete a balance based on it's ID
+func (b *BalanceRepositorySQL) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	return b.DB.transaction(ctx, func(c sqlConn) error {
+		_, err := c.exec(ctx, `DELETE FROM balance_spends WHERE balance_id = ?`, pid.Hex())
+		if err != nil {
+			return err
+		}
+
+		result, err := c.exec(ctx, `DELETE FROM balances WHERE id = ?`, pid.Hex())
+		if err != nil {
+			return err
+		}
+
+		return affected(result, "non existent balance")
+	})
+}
+
+// outcomeDelta will return how much a spend changes the fixed and dynamic outcomes
+func outcomeDelta(s Spend) (fixed Money, dynamic Money) {
+	if s.IsFixed() {
+		return s.Outcome(), 0
+	}
+	return 0, s.Outcome()
+}
+
+// AddSpend will append a spend to its month's balance, creating the balance when needed
+func (b *BalanceRepositorySQL) AddSpend(ctx context.Context, spend Spend) error {
+	month, year := spend.Period()
+	t := int64(primitive.NewDateTimeFromTime(time.Now()))
+	fixed, dynamic := outcomeDelta(spend)
+
+	return b.DB.transaction(ctx, func(c sqlConn) error {
+		_, err := c.exec(ctx,
+			`INSERT INTO balances (id, owner_id, month, year, currency, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (owner_id, month, year) DO NOTHING`,
+			primitive.NewObjectID().Hex(), spend.OwnerID.Hex(), month, year, spend.Currency, t, t,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.exec(ctx,
+			`UPDATE balances
+			SET outcome_fixed = outcome_fixed + ?, outcome_dynamic = outcome_dynamic + ?,
+				spendable_amount = spendable_amount - ?, updated_at = ?
+			WHERE owner_id = ? AND month = ? AND year = ?`,
+			fixed, dynamic, spend.Outcome(), t, spend.OwnerID.Hex(), month, year,
+		)
+		if err != nil {
+			return err
+		}
+
+		var id string
+		err = c.queryRow(ctx, `SELECT id FROM balances WHERE owner_id = ? AND month = ? AND year = ?`, spend.OwnerID.Hex(), month, year).Scan(&id)
+		if err != nil {
+			return err
+		}
+
+		balanceID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return err
+		}
+
+		return insertHistoric(ctx, c, balanceID, spend)
+	})
+}
+
+// RemoveSpend will pull a spend from its month's balance, reverting its outcome
+func (b *BalanceRepositorySQL) RemoveSpend(ctx context.Context, spend Spend) error {
+	month, year := spend.Period()
+	fixed, dynamic := outcomeDelta(spend)
+
+	return b.DB.transaction(ctx, func(c sqlConn) error {
+		var id string
+		err := c.queryRow(ctx, `SELECT id FROM balances WHERE owner_id = ? AND month = ? AND year = ?`, spend.OwnerID.Hex(), month, year).Scan(&id)
+		if err == sql.ErrNoRows {
+			return errors.New("could not find spend in balance")
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := c.exec(ctx, `DELETE FROM balance_spends WHERE balance_id = ? AND spend_id = ?`, id, spend.ID.Hex())
+		if err != nil {
+			return err
+		}
+
+		err = affected(result, "could not find spend in balance")
+		if err != nil {
+			return err
+		}
+
+		_, err = c.exec(ctx,
+			`UPDATE balances
+			SET outcome_fixed = outcome_fixed - ?, outcome_dynamic = outcome_dynamic - ?,
+				spendable_amount = spendable_amount + ?, updated_at = ?
+			WHERE id = ?`,
+			fixed, dynamic, spend.Outcome(), int64(primitive.NewDateTimeFromTime(time.Now())), id,
+		)
+		return err
+	})
+}
+
+const spendColumns = `id, owner_id, type, description, cost, currency, original_cost, original_currency, exchange_rate, payment_method, date, created_at, recurrence_id,
+	installment_purchase_id, installment_number, installment_total, installment_purchase_cost`
+
+// scanSpend will read a row selected with spendColumns, without its categories
+func scanSpend(row rowScanner) (Spend, error) {
+	var s Spend
+	var id, ownerID, paymentMethod string
+	var recurrenceID, purchaseID sql.NullString
+	var date, createdAt int64
+	var installment Installment
+
+	err := row.Scan(
+		&id, &ownerID, &s.Type, &s.Description, &s.Cost, &s.Currency,
+		&s.OriginalCost, &s.OriginalCurrency, &s.ExchangeRate, &paymentMethod, &date, &createdAt, &recurrenceID,
+		&purchaseID, &installment.Number, &installment.Total, &installment.PurchaseCost,
+	)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	if purchaseID.Valid {
+		installment.PurchaseID, err = primitive.ObjectIDFromHex(purchaseID.String)
+		if err != nil {
+			return Spend{}, err
+		}
+		s.Installment = &installment
+	}
+
+	if recurrenceID.Valid {
+		s.RecurrenceID, err = primitive.ObjectIDFromHex(recurrenceID.String)
+		if err != nil {
+			return Spend{}, err
+		}
+	}
+
+	s.ID, err = primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	s.OwnerID, err = primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	err = json.Unmarshal([]byte(paymentMethod), &s.PaymentMethod)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	s.Date = primitive.DateTime(date)
+	s.CreatedAt = primitive.DateTime(createdAt)
+	return s, nil
+}
+
+// querySpends will return every spend selected by a query, along with their categories
+func querySpends(ctx context.Context, c sqlConn, query string, args ...interface{}) ([]Spend, error) {
+	rows, err := c.query(ctx, query, args...)
+	if err != nil {
+		return []Spend{}, err
+	}
+
+	spends := []Spend{}
+	for rows.Next() {
+		s, err := scanSpend(rows)
+		if err != nil {
+			rows.Close()
+			return []Spend{}, err
+		}
+		spends = append(spends, s)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return []Spend{}, err
+	}
+
+	if len(spends) == 0 {
+		return spends, nil
+	}
+
+	index := map[string]int{}
+	placeholders := make([]string, 0, len(spends))
+	ids := make([]interface{}, 0, len(spends))
+	for i, s := range spends {
+		index[s.ID.Hex()] = i
+		placeholders = append(placeholders, "?")
+		ids = append(ids, s.ID.Hex())
+	}
+
+	rows, err = c.query(ctx,
+		`SELECT spend_id, category FROM spend_categories WHERE spend_id IN (`+strings.Join(placeholders, ", ")+`) ORDER BY spend_id, position`,
+		ids...,
+	)
+	if err != nil {
+		return []Spend{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var spendID, category string
+		err := rows.Scan(&spendID, &category)
+		if err != nil {
+			return []Spend{}, err
+		}
+
+		i := index[spendID]
+		spends[i].Categories = append(spends[i].Categories, category)
+	}
+
+	return spends, rows.Err()
+}
+
+// spendValues will return the values of every spend column, followed by the derived card_id, debit and payment_slip ones
+func spendValues(s Spend) ([]interface{}, error) {
+	paymentMethod, err := json.Marshal(s.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	var installment Installment
+	if s.Installment != nil {
+		installment = *s.Installment
+	}
+
+	return []interface{}{
+		s.ID.Hex(), s.OwnerID.Hex(), s.Type, s.Description, s.Cost, s.Currency,
+		s.OriginalCost, s.OriginalCurrency, s.ExchangeRate, string(paymentMethod), int64(s.Date), int64(s.CreatedAt),
+		nullObjectID(s.RecurrenceID), nullObjectID(installment.PurchaseID), installment.Number, installment.Total, installment.PurchaseCost,
+		nullObjectID(s.PaymentMethod.Credit.ID), s.PaymentMethod.Debit, s.PaymentMethod.PaymentSlip,
+	}, nil
+}
+
+// insertCategories will store the categories of a spend, keeping their order
+func insertCategories(ctx context.Context, c sqlConn, s Spend) error {
+	for i, category := range s.Categories {
+		_, err := c.exec(ctx, `INSERT INTO spend_categories (spend_id, position, category) VALUES (?, ?, ?)`, s.ID.Hex(), i, category)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get will return a list of spends from a given ownerID
+func (s *SpendRepositorySQL) Get(ctx context.Context, ownerID string) ([]Spend, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []Spend{}, err
+	}
+
+	spends, err := querySpends(ctx, s.DB.conn(s.DB.executor(ctx)), `SELECT `+spendColumns+` FROM spends WHERE owner_id = ? ORDER BY id`, oid.Hex())
+	if err != nil {
+		return []Spend{}, err
+	}
+
+	if len(spends) == 0 {
+		return []Spend{}, errors.New("could not find any spends")
+	}
+
+	return spends, nil
+}
+
+// GetByID will return a single spend from a given ownerID
+func (s *SpendRepositorySQL) GetByID(ctx context.Context, ownerID string, id string) (Spend, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Spend{}, err
+	}
+
+	spends, err := querySpends(ctx, s.DB.conn(s.DB.executor(ctx)), `SELECT `+spendColumns+` FROM spends WHERE id = ? AND owner_id = ?`, pid.Hex(), oid.Hex())
+	if err != nil {
+		return Spend{}, err
+	}
+
+	if len(spends) == 0 {
+		return Spend{}, errors.New("could not find spend")
+	}
+
+	return spends[0], nil
+}
+
+// likeEscaper escapes LIKE wildcards so descriptions are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Find will return a page of spends from an owner matching a given filter, sorted by date
+func (s *SpendRepositorySQL) Find(ctx context.Context, f SpendFilter) (SpendPage, error) {
+	oid, err := primitive.ObjectIDFromHex(f.OwnerID)
+	if err != nil {
+		return SpendPage{}, err
+	}
+
+	conditions := []string{"owner_id = ?"}
+	args := []interface{}{oid.Hex()}
+
+	if !f.From.IsZero() {
+		conditions = append(conditions, "date >= ?")
+		args = append(args, int64(primitive.NewDateTimeFromTime(f.From)))
+	}
+
+	if !f.To.IsZero() {
+		conditions = append(conditions, "date <= ?")
+		args = append(args, int64(primitive.NewDateTimeFromTime(f.To)))
+	}
+
+	if f.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, f.Type)
+	}
+
+	if f.Category != "" {
+		conditions = append(conditions, "id IN (SELECT spend_id FROM spend_categories WHERE category = ?)")
+		args = append(args, f.Category)
+	}
+
+	switch f.PaymentMethod {
+	case PaymentMethodDebit:
+		conditions = append(conditions, "debit = ?")
+		args = append(args, true)
+	case PaymentMethodCredit:
+		conditions = append(conditions, "card_id IS NOT NULL")
+	case PaymentMethodPaymentSlip:
+		conditions = append(conditions, "payment_slip = ?")
+		args = append(args, true)
+	}
+
+	if f.CardID != "" {
+		cardID, err := primitive.ObjectIDFromHex(f.CardID)
+		if err != nil {
+			return SpendPage{}, err
+		}
+
+		conditions = append(conditions, "card_id = ?")
+		args = append(args, cardID.Hex())
+	}
+
+	if f.MinCost != nil {
+		conditions = append(conditions, "cost >= ?")
+		args = append(args, *f.MinCost)
+	}
+
+	if f.MaxCost != nil {
+		conditions = append(conditions, "cost <= ?")
+		args = append(args, *f.MaxCost)
+	}
+
+	if f.Description != "" {
+		conditions = append(conditions, `LOWER(description) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(f.Description))+"%")
+	}
+
+	order, comparison := "DESC", "<"
+	if f.Ascending {
+		order, comparison = "ASC", ">"
+	}
+
+	if f.Cursor != "" {
+		cursorDate, cursorID, err := DecodeSpendCursor(f.Cursor)
+		if err != nil {
+			return SpendPage{}, err
+		}
+
+		conditions = append(conditions, "(date "+comparison+" ? OR (date = ? AND id "+comparison+" ?))")
+		args = append(args, int64(cursorDate), int64(cursorDate), cursorID.Hex())
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultSpendsLimit
+	}
+
+	query := `SELECT ` + spendColumns + ` FROM spends WHERE ` + strings.Join(conditions, " AND ") +
+		` ORDER BY date ` + order + `, id ` + order + ` LIMIT ?`
+	args = append(args, limit+1)
+
+	spends, err := querySpends(ctx, s.DB.conn(s.DB.executor(ctx)), query, args...)
+	if err != nil {
+		return SpendPage{}, err
+	}
+
+	page := SpendPage{Spends: spends}
+	if int64(len(spends)) > limit {
+		page.Spends = spends[:limit]
+		page.NextCursor = EncodeSpendCursor(page.Spends[limit-1])
+	}
+
+	return page, nil
+}
+
+// spendGroupKey will return the expression computing the key of a spend within a group
+func (s *SpendRepositorySQL) spendGroupKey(group string) (string, error) {
+	switch group {
+	case SpendGroupCategory:
+		return "COALESCE(c.category, '')", nil
+	case SpendGroupPaymentMethod:
+		return `CASE WHEN s.card_id IS NOT NULL THEN '` + PaymentMethodCredit + `'
+			WHEN s.debit THEN '` + PaymentMethodDebit + `'
+			WHEN s.payment_slip THEN '` + PaymentMethodPaymentSlip + `'
+			ELSE '' END`, nil
+	case SpendGroupCard:
+		return "COALESCE(s.card_id, '')", nil
+	case SpendGroupType:
+		return "s.type", nil
+	case SpendGroupMonth:
+		if s.DB.Dialect == SQLDialectPostgres {
+			return "to_char(to_timestamp(s.date / 1000) AT TIME ZONE 'UTC', 'YYYY-MM')", nil
+		}
+		return "strftime('%Y-%m', s.date / 1000, 'unixepoch')", nil
+	case SpendGroupCurrency:
+		return "''", nil
+	}
+
+	return "", fmt.Errorf("spends can not be grouped by '%s'", group)
+}
+
+// Totals will return how much the spends of an owner within a date range cost, grouped by one of the
+// SpendGroup* criteria and by currency
+func (s *SpendRepositorySQL) Totals(ctx context.Context, ownerID string, from time.Time, to time.Time, group string) ([]SpendTotal, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []SpendTotal{}, err
+	}
+
+	key, err := s.spendGroupKey(group)
+	if err != nil {
+		return []SpendTotal{}, err
+	}
+
+	query := `SELECT ` + key + `, s.currency, SUM(s.cost), COUNT(*) FROM spends s`
+	if group == SpendGroupCategory {
+		// spends count towards each of their categories, and uncategorized ones towards an empty one
+		query += ` LEFT JOIN spend_categories c ON c.spend_id = s.id`
+	}
+	query += ` WHERE s.owner_id = ? AND s.date >= ? AND s.date <= ? AND s.type <> ?`
+	if group == SpendGroupCard {
+		query += ` AND s.card_id IS NOT NULL`
+	}
+	query += ` GROUP BY 1, 2`
+
+	rows, err := s.DB.conn(s.DB.executor(ctx)).query(ctx, query,
+		oid.Hex(), int64(primitive.NewDateTimeFromTime(from)), int64(primitive.NewDateTimeFromTime(to)), SpendTypePayment,
+	)
+	if err != nil {
+		return []SpendTotal{}, err
+	}
+	defer rows.Close()
+
+	totals := []SpendTotal{}
+	for rows.Next() {
+		var total SpendTotal
+		err := rows.Scan(&total.Key, &total.Currency, &total.Total, &total.Count)
+		if err != nil {
+			return []SpendTotal{}, err
+		}
+		totals = append(totals, total)
+	}
+
+	return totals, rows.Err()
+}
+
+// GetAll will return literally all spends
+func (s *SpendRepositorySQL) GetAll(ctx context.Context) ([]Spend, error) {
+	return querySpends(ctx, s.DB.conn(s.DB.executor(ctx)), `SELECT `+spendColumns+` FROM spends ORDER BY id`)
+}
+
+// Create will create a spend
+func (s *SpendRepositorySQL) Create(ctx context.Context, spend Spend) (id string, err error) {
+	if spend.ID.IsZero() {
+		spend.ID = primitive.NewObjectID()
+	}
+
+	values, err := spendValues(spend)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.DB.transaction(ctx, func(c sqlConn) error {
+		_, err := c.exec(ctx,
+			`INSERT INTO spends (`+spendColumns+`, card_id, debit, payment_slip) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			values...,
+		)
+		if err != nil {
+			return err
+		}
+
+		return insertCategories(ctx, c, spend)
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("spend already exists")
+		}
+		return "", err
+	}
+
+	return spend.ID.Hex(), nil
+}
+
+// Update will replace an existing spend based on it's ID
+func (s *SpendRepositorySQL) Update(ctx context.Context, spend Spend) error {
+	values, err := spendValues(spend)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.transaction(ctx, func(c sqlConn) error {
+		result, err := c.exec(ctx,
+			`UPDATE spends
+			SET type = ?, description = ?, cost = ?, currency = ?, original_cost = ?, original_currency = ?, exchange_rate = ?,
+				payment_method = ?, date = ?, created_at = ?, recurrence_id = ?,
+				installment_purchase_id = ?, installment_number = ?, installment_total = ?, installment_purchase_cost = ?,
+				card_id = ?, debit = ?, payment_slip = ?
+			WHERE id = ? AND owner_id = ?`,
+			append(values[2:], values[0], values[1])...,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = affected(result, "could not find spend")
+		if err != nil {
+			return err
+		}
+
+		_, err = c.exec(ctx, `DELETE FROM spend_categories WHERE spend_id = ?`, spend.ID.Hex())
+		if err != nil {
+			return err
+		}
+
+		return insertCategories(ctx, c, spend)
+	})
+}
+
+// Delete will delete a spend based on it's ID
+func (s *SpendRepositorySQL) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.transaction(ctx, func(c sqlConn) error {
+		_, err := c.exec(ctx, `DELETE FROM spend_categories WHERE spend_id = ?`, pid.Hex())
+		if err != nil {
+			return err
+		}
+
+		result, err := c.exec(ctx, `DELETE FROM spends WHERE id = ?`, pid.Hex())
+		if err != nil {
+			return err
+		}
+
+		return affected(result, "could not find spend")
+	})
+}
+
+const refreshTokenColumns = `id, owner_id, family, used, revoked, expires_at, created_at`
+
+// scanRefreshToken will read a row selected with refreshTokenColumns
+func scanRefreshToken(row rowScanner) (RefreshToken, error) {
+	var token RefreshToken
+	var ownerID string
+	var expiresAt, createdAt int64
+
+	err := row.Scan(&token.ID, &ownerID, &token.Family, &token.Used, &token.Revoked, &expiresAt, &createdAt)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	token.OwnerID, err = primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	token.ExpiresAt = primitive.DateTime(expiresAt)
+	token.CreatedAt = primitive.DateTime(createdAt)
+	return token, nil
+}
+
+// Create will store an issued refresh token
+func (t *RefreshTokenRepositorySQL) Create(ctx context.Context, token RefreshToken) error {
+	_, err := t.DB.conn(t.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO refresh_tokens (`+refreshTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		token.ID, token.OwnerID.Hex(), token.Family, token.Used, token.Revoked, int64(token.ExpiresAt), int64(token.CreatedAt),
+	)
+	if err != nil && isUniqueViolation(err) {
+		return errors.New("refresh token already exists")
+	}
+
+	return err
+}
+
+// Consume will atomically mark a refresh token as used
+func (t *RefreshTokenRepositorySQL) Consume(ctx context.Context, id string) (RefreshToken, error) {
+	var token RefreshToken
+
+	err := t.DB.transaction(ctx, func(c sqlConn) error {
+		now := int64(primitive.NewDateTimeFromTime(time.Now()))
+
+		result, err := c.exec(ctx,
+			`UPDATE refresh_tokens SET used = ? WHERE id = ? AND used = ? AND revoked = ? AND expires_at >= ?`,
+			true, id, false, false, now,
+		)
+		if err != nil {
+			return err
+		}
+
+		consumed, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		token, err = scanRefreshToken(c.queryRow(ctx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE id = ?`, id))
+		if err == sql.ErrNoRows || (err == nil && int64(token.ExpiresAt) < now) {
+			token = RefreshToken{}
+			return errors.New("could not find refresh token")
+		}
+		if err != nil {
+			return err
+		}
+
+		if consumed == 1 {
+			return nil
+		}
+
+		if token.Revoked {
+			return errors.New("refresh token revoked")
+		}
+
+		return errors.New("refresh token reuse detected")
+	})
+
+	return token, err
+}
+
+// RevokeFamily will revoke all refresh tokens from a given family
+func (t *RefreshTokenRepositorySQL) RevokeFamily(ctx context.Context, family string) error {
+	_, err := t.DB.conn(t.DB.executor(ctx)).exec(ctx, `UPDATE refresh_tokens SET revoked = ? WHERE family = ?`, true, family)
+	return err
+}
+
+// RevokeOwner will revoke all refresh tokens from a given owner
+func (t *RefreshTokenRepositorySQL) RevokeOwner(ctx context.Context, ownerID string) error {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.DB.conn(t.DB.executor(ctx)).exec(ctx, `UPDATE refresh_tokens SET revoked = ? WHERE owner_id = ?`, true, oid.Hex())
+	return err
+}
+
+// Create will store a revocation
+func (r *RevocationRepositorySQL) Create(ctx context.Context, revocation Revocation) error {
+	if revocation.ID.IsZero() {
+		revocation.ID = primitive.NewObjectID()
+	}
+
+	notBefore := sql.NullInt64{Int64: int64(revocation.NotBefore), Valid: revocation.NotBefore != 0}
+
+	_, err := r.DB.conn(r.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO revocations (id, jti, owner_id, not_before, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		revocation.ID.Hex(), nullString(revocation.TokenID), nullObjectID(revocation.OwnerID), notBefore,
+		int64(revocation.ExpiresAt), int64(revocation.CreatedAt),
+	)
+	return err
+}
+
+// IsRevoked will return if a token was revoked by its ID or by revoking all sessions from its owner
+func (r *RevocationRepositorySQL) IsRevoked(ctx context.Context, tokenID string, ownerID string, issuedAt time.Time) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return false, err
+	}
+
+	conditions := []string{"(owner_id = ? AND not_before >= ?)"}
+	args := []interface{}{int64(primitive.NewDateTimeFromTime(time.Now())), oid.Hex(), int64(primitive.NewDateTimeFromTime(issuedAt))}
+
+	if tokenID != "" {
+		conditions = append(conditions, "jti = ?")
+		args = append(args, tokenID)
+	}
+
+	var count int64
+	err = r.DB.conn(r.DB.executor(ctx)).queryRow(ctx,
+		`SELECT COUNT(*) FROM revocations WHERE expires_at >= ? AND (`+strings.Join(conditions, " OR ")+`)`,
+		args...,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+const loginAttemptColumns = `login, failures, locked_until, last_failure_at, expires_at`
+
+// scanLoginAttempt will read a row selected with loginAttemptColumns
+func scanLoginAttempt(row rowScanner) (LoginAttempt, error) {
+	var attempt LoginAttempt
+	var lockedUntil, lastFailureAt, expiresAt int64
+
+	err := row.Scan(&attempt.ID, &attempt.Failures, &lockedUntil, &lastFailureAt, &expiresAt)
+	if err != nil {
+		return LoginAttempt{}, err
+	}
+
+	attempt.LockedUntil = primitive.DateTime(lockedUntil)
+	attempt.LastFailureAt = primitive.DateTime(lastFailureAt)
+	attempt.ExpiresAt = primitive.DateTime(expiresAt)
+	return attempt, nil
+}
+
+// Get will return the failed attempts from a login
+func (l *LoginAttemptRepositorySQL) Get(ctx context.Context, login string) (LoginAttempt, error) {
+	row := l.DB.conn(l.DB.executor(ctx)).queryRow(ctx,
+		`SELECT `+loginAttemptColumns+` FROM login_attempts WHERE login = ? AND expires_at >= ?`,
+		login, int64(primitive.NewDateTimeFromTime(time.Now())),
+	)
+
+	attempt, err := scanLoginAttempt(row)
+	if err == sql.ErrNoRows {
+		return LoginAttempt{}, errors.New("could not find login attempt")
+	}
+
+	return attempt, err
+}
+
+// RegisterFailure will atomically increase the failures from a login, creating it on demand
+func (l *LoginAttemptRepositorySQL) RegisterFailure(ctx context.Context, login string, at time.Time, expiresAt time.Time) (LoginAttempt, error) {
+	var attempt LoginAttempt
+	now := int64(primitive.NewDateTimeFromTime(at))
+
+	err := l.DB.transaction(ctx, func(c sqlConn) error {
+		_, err := c.exec(ctx,
+			`INSERT INTO login_attempts (`+loginAttemptColumns+`) VALUES (?, 1, 0, ?, ?)
+			ON CONFLICT (login) DO UPDATE SET
+				failures = CASE WHEN login_attempts.expires_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+				locked_until = CASE WHEN login_attempts.expires_at < ? THEN 0 ELSE login_attempts.locked_until END,
+				last_failure_at = excluded.last_failure_at,
+				expires_at = excluded.expires_at`,
+			login, now, int64(primitive.NewDateTimeFromTime(expiresAt)), now, now,
+		)
+		if err != nil {
+			return err
+		}
+
+		attempt, err = scanLoginAttempt(c.queryRow(ctx, `SELECT `+loginAttemptColumns+` FROM login_attempts WHERE login = ?`, login))
+		return err
+	})
+
+	return attempt, err
+}
+
+// Lock will lock a login until a given time
+func (l *LoginAttemptRepositorySQL) Lock(ctx context.Context, login string, until time.Time) error {
+	result, err := l.DB.conn(l.DB.executor(ctx)).exec(ctx,
+		`UPDATE login_attempts SET locked_until = ? WHERE login = ?`,
+		int64(primitive.NewDateTimeFromTime(until)), login,
+	)
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find login attempt")
+}
+
+// Reset will remove all failed attempts from a login, unlocking it
+func (l *LoginAttemptRepositorySQL) Reset(ctx context.Context, login string) error {
+	_, err := l.DB.conn(l.DB.executor(ctx)).exec(ctx, `DELETE FROM login_attempts WHERE login = ?`, login)
+	return err
+}
+
+// Upsert will create or replace the rate of a currency pair for its day
+func (e *ExchangeRateRepositorySQL) Upsert(ctx context.Context, rate ExchangeRate) error {
+	_, err := e.DB.conn(e.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO exchange_rates (base, quote, date, rate, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (base, quote, date) DO UPDATE SET rate = excluded.rate, updated_at = excluded.updated_at`,
+		rate.Base, rate.Quote, int64(rate.Date), rate.Rate, int64(rate.UpdatedAt),
+	)
+	return err
+}
+
+// queryExchangeRates will return every exchange rate selected by a query
+func queryExchangeRates(ctx context.Context, c sqlConn, query string, args ...interface{}) ([]ExchangeRate, error) {
+	rows, err := c.query(ctx, query, args...)
+	if err != nil {
+		return []ExchangeRate{}, err
+	}
+	defer rows.Close()
+
+	rates := []ExchangeRate{}
+	for rows.Next() {
+		var rate ExchangeRate
+		var date, updatedAt int64
+
+		err := rows.Scan(&rate.Base, &rate.Quote, &date, &rate.Rate, &updatedAt)
+		if err != nil {
+			return []ExchangeRate{}, err
+		}
+
+		rate.Date = primitive.DateTime(date)
+		rate.UpdatedAt = primitive.DateTime(updatedAt)
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
+
+// Get will return the most recent rate of a currency pair published until a given day
+func (e *ExchangeRateRepositorySQL) Get(ctx context.Context, base string, quote string, date time.Time) (ExchangeRate, error) {
+	rates, err := queryExchangeRates(ctx, e.DB.conn(e.DB.executor(ctx)),
+		`SELECT base, quote, date, rate, updated_at FROM exchange_rates
+		WHERE base = ? AND quote = ? AND date <= ? ORDER BY date DESC LIMIT 1`,
+		base, quote, int64(primitive.NewDateTimeFromTime(date)),
+	)
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+
+	if len(rates) == 0 {
+		return ExchangeRate{}, errors.New("could not find exchange rate")
+	}
+
+	return rates[0], nil
+}
+
+// List will return the exchange rates matching a given filter, sorted by date
+func (e *ExchangeRateRepositorySQL) List(ctx context.Context, f ExchangeRateFilter) ([]ExchangeRate, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+
+	if f.Base != "" {
+		conditions = append(conditions, "base = ?")
+		args = append(args, f.Base)
+	}
+
+	if f.Quote != "" {
+		conditions = append(conditions, "quote = ?")
+		args = append(args, f.Quote)
+	}
+
+	if !f.From.IsZero() {
+		conditions = append(conditions, "date >= ?")
+		args = append(args, int64(primitive.NewDateTimeFromTime(f.From)))
+	}
+
+	if !f.To.IsZero() {
+		conditions = append(conditions, "date <= ?")
+		args = append(args, int64(primitive.NewDateTimeFromTime(f.To)))
+	}
+
+	return queryExchangeRates(ctx, e.DB.conn(e.DB.executor(ctx)),
+		`SELECT base, quote, date, rate, updated_at FROM exchange_rates WHERE `+strings.Join(conditions, " AND ")+` ORDER BY date, base, quote`,
+		args...,
+	)
+}
+
+const recurrenceColumns = `id, owner_id, type, description, cost, currency, payment_method, categories, cadence, day, start_date, end_date, status, next_run, created_at, updated_at`
+
+// queryRecurrences will return every recurrence selected by a query
+func queryRecurrences(ctx context.Context, c sqlConn, query string, args ...interface{}) ([]Recurrence, error) {
+	rows, err := c.query(ctx, query, args...)
+	if err != nil {
+		return []Recurrence{}, err
+	}
+	defer rows.Close()
+
+	recurrences := []Recurrence{}
+	for rows.Next() {
+		var r Recurrence
+		var id, ownerID, paymentMethod, categories string
+		var start, end, nextRun, createdAt, updatedAt int64
+
+		err := rows.Scan(
+			&id, &ownerID, &r.Type, &r.Description, &r.Cost, &r.Currency, &paymentMethod, &categories,
+			&r.Cadence, &r.Day, &start, &end, &r.Status, &nextRun, &createdAt, &updatedAt,
+		)
+		if err != nil {
+			return []Recurrence{}, err
+		}
+
+		r.ID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return []Recurrence{}, err
+		}
+
+		r.OwnerID, err = primitive.ObjectIDFromHex(ownerID)
+		if err != nil {
+			return []Recurrence{}, err
+		}
+
+		err = json.Unmarshal([]byte(paymentMethod), &r.PaymentMethod)
+		if err != nil {
+			return []Recurrence{}, err
+		}
+
+		err = json.Unmarshal([]byte(categories), &r.Categories)
+		if err != nil {
+			return []Recurrence{}, err
+		}
+
+		r.Start = primitive.DateTime(start)
+		r.End = primitive.DateTime(end)
+		r.NextRun = primitive.DateTime(nextRun)
+		r.CreatedAt = primitive.DateTime(createdAt)
+		r.UpdatedAt = primitive.DateTime(updatedAt)
+		recurrences = append(recurrences, r)
+	}
+
+	return recurrences, rows.Err()
+}
+
+// Create will create a recurrence
+func (r *RecurrenceRepositorySQL) Create(ctx context.Context, recurrence Recurrence) (id string, err error) {
+	if recurrence.ID.IsZero() {
+		recurrence.ID = primitive.NewObjectID()
+	}
+
+	paymentMethod, err := json.Marshal(recurrence.PaymentMethod)
+	if err != nil {
+		return "", err
+	}
+
+	categories, err := json.Marshal(recurrence.Categories)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = r.DB.conn(r.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO recurrences (`+recurrenceColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		recurrence.ID.Hex(), recurrence.OwnerID.Hex(), recurrence.Type, recurrence.Description, recurrence.Cost, recurrence.Currency,
+		string(paymentMethod), string(categories), recurrence.Cadence, recurrence.Day, int64(recurrence.Start), int64(recurrence.End),
+		recurrence.Status, int64(recurrence.NextRun), int64(recurrence.CreatedAt), int64(recurrence.UpdatedAt),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("recurrence already exists")
+		}
+		return "", err
+	}
+
+	return recurrence.ID.Hex(), nil
+}
+
+// Get will return every recurrence from a given ownerID
+func (r *RecurrenceRepositorySQL) Get(ctx context.Context, ownerID string) ([]Recurrence, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []Recurrence{}, err
+	}
+
+	return queryRecurrences(ctx, r.DB.conn(r.DB.executor(ctx)),
+		`SELECT `+recurrenceColumns+` FROM recurrences WHERE owner_id = ? ORDER BY next_run, id`, oid.Hex(),
+	)
+}
+
+// GetByID will return a single recurrence from a given ownerID
+func (r *RecurrenceRepositorySQL) GetByID(ctx context.Context, ownerID string, id string) (Recurrence, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Recurrence{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Recurrence{}, err
+	}
+
+	recurrences, err := queryRecurrences(ctx, r.DB.conn(r.DB.executor(ctx)),
+		`SELECT `+recurrenceColumns+` FROM recurrences WHERE id = ? AND owner_id = ?`, pid.Hex(), oid.Hex(),
+	)
+	if err != nil {
+		return Recurrence{}, err
+	}
+
+	if len(recurrences) == 0 {
+		return Recurrence{}, errors.New("could not find recurrence")
+	}
+
+	return recurrences[0], nil
+}
+
+// Due will return every active recurrence whose next run is until a given date
+func (r *RecurrenceRepositorySQL) Due(ctx context.Context, until time.Time) ([]Recurrence, error) {
+	return queryRecurrences(ctx, r.DB.conn(r.DB.executor(ctx)),
+		`SELECT `+recurrenceColumns+` FROM recurrences WHERE status = ? AND next_run <= ? ORDER BY next_run, id`,
+		RecurrenceStatusActive, int64(primitive.NewDateTimeFromTime(until)),
+	)
+}
+
+// Schedule will update the status and next run of a recurrence while its status and next run are still the previous ones
+func (r *RecurrenceRepositorySQL) Schedule(ctx context.Context, recurrence Recurrence, status string, previous primitive.DateTime) error {
+	result, err := r.DB.conn(r.DB.executor(ctx)).exec(ctx,
+		`UPDATE recurrences SET status = ?, next_run = ?, updated_at = ? WHERE id = ? AND owner_id = ? AND status = ? AND next_run = ?`,
+		recurrence.Status, int64(recurrence.NextRun), int64(recurrence.UpdatedAt),
+		recurrence.ID.Hex(), recurrence.OwnerID.Hex(), status, int64(previous),
+	)
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find recurrence")
+}
+
+// UpdateCategories will replace the categories of a recurrence, used by its future spends
+func (r *RecurrenceRepositorySQL) UpdateCategories(ctx context.Context, recurrence Recurrence) error {
+	categories, err := json.Marshal(recurrence.Categories)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.DB.conn(r.DB.executor(ctx)).exec(ctx,
+		`UPDATE recurrences SET categories = ?, updated_at = ? WHERE id = ? AND owner_id = ?`,
+		string(categories), int64(recurrence.UpdatedAt), recurrence.ID.Hex(), recurrence.OwnerID.Hex(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find recurrence")
+}
+
+const statementPaymentColumns = `id, owner_id, card_id, month, year, amount, currency, spend_id, paid_at`
+
+// queryStatementPayments will return every statement payment selected by a query
+func queryStatementPayments(ctx context.Context, c sqlConn, query string, args ...interface{}) ([]StatementPayment, error) {
+	rows, err := c.query(ctx, query, args...)
+	if err != nil {
+		return []StatementPayment{}, err
+	}
+	defer rows.Close()
+
+	payments := []StatementPayment{}
+	for rows.Next() {
+		var p StatementPayment
+		var id, ownerID, cardID, spendID string
+		var paidAt int64
+
+		err := rows.Scan(&id, &ownerID, &cardID, &p.Month, &p.Year, &p.Amount, &p.Currency, &spendID, &paidAt)
+		if err != nil {
+			return []StatementPayment{}, err
+		}
+
+		p.ID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return []StatementPayment{}, err
+		}
+
+		p.OwnerID, err = primitive.ObjectIDFromHex(ownerID)
+		if err != nil {
+			return []StatementPayment{}, err
+		}
+
+		p.CardID, err = primitive.ObjectIDFromHex(cardID)
+		if err != nil {
+			return []StatementPayment{}, err
+		}
+
+		p.SpendID, err = primitive.ObjectIDFromHex(spendID)
+		if err != nil {
+			return []StatementPayment{}, err
+		}
+
+		p.PaidAt = primitive.DateTime(paidAt)
+		payments = append(payments, p)
+	}
+
+	return payments, rows.Err()
+}
+
+// Create will store a statement payment, a single one per card statement
+func (p *StatementPaymentRepositorySQL) Create(ctx context.Context, payment StatementPayment) (id string, err error) {
+	if payment.ID.IsZero() {
+		payment.ID = primitive.NewObjectID()
+	}
+
+	_, err = p.DB.conn(p.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO statement_payments (`+statementPaymentColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		payment.ID.Hex(), payment.OwnerID.Hex(), payment.CardID.Hex(), payment.Month, payment.Year,
+		payment.Amount, payment.Currency, payment.SpendID.Hex(), int64(payment.PaidAt),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("statement already paid")
+		}
+		return "", err
+	}
+
+	return payment.ID.Hex(), nil
+}
+
+// Get will return the payment of a card statement
+func (p *StatementPaymentRepositorySQL) Get(ctx context.Context, ownerID string, cardID string, month int64, year int64) (StatementPayment, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return StatementPayment{}, err
+	}
+
+	cid, err := primitive.ObjectIDFromHex(cardID)
+	if err != nil {
+		return StatementPayment{}, err
+	}
+
+	payments, err := queryStatementPayments(ctx, p.DB.conn(p.DB.executor(ctx)),
+		`SELECT `+statementPaymentColumns+` FROM statement_payments WHERE owner_id = ? AND card_id = ? AND month = ? AND year = ?`,
+		oid.Hex(), cid.Hex(), month, year,
+	)
+	if err != nil {
+		return StatementPayment{}, err
+	}
+
+	if len(payments) == 0 {
+		return StatementPayment{}, errors.New("could not find statement payment")
+	}
+
+	return payments[0], nil
+}
+
+// List will return every payment of a card, sorted by statement
+func (p *StatementPaymentRepositorySQL) List(ctx context.Context, ownerID string, cardID string) ([]StatementPayment, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []StatementPayment{}, err
+	}
+
+	cid, err := primitive.ObjectIDFromHex(cardID)
+	if err != nil {
+		return []StatementPayment{}, err
+	}
+
+	return queryStatementPayments(ctx, p.DB.conn(p.DB.executor(ctx)),
+		`SELECT `+statementPaymentColumns+` FROM statement_payments WHERE owner_id = ? AND card_id = ? ORDER BY year, month`,
+		oid.Hex(), cid.Hex(),
+	)
+}
+
+// Delete will delete a statement payment based on it's ID
+func (p *StatementPaymentRepositorySQL) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := p.DB.conn(p.DB.executor(ctx)).exec(ctx, `DELETE FROM statement_payments WHERE id = ?`, pid.Hex())
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find statement payment")
+}
+
+const budgetAlertColumns = `id, owner_id, category, month, year, threshold, budget, spent, currency, spend_id, created_at`
+
+// Create will store a budget alert, a single one per category threshold and month
+func (a *BudgetAlertRepositorySQL) Create(ctx context.Context, alert BudgetAlert) (id string, err error) {
+	if alert.ID.IsZero() {
+		alert.ID = primitive.NewObjectID()
+	}
+
+	_, err = a.DB.conn(a.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO budget_alerts (`+budgetAlertColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		alert.ID.Hex(), alert.OwnerID.Hex(), alert.Category, alert.Month, alert.Year, alert.Threshold,
+		alert.Limit, alert.Spent, alert.Currency, alert.SpendID.Hex(), int64(alert.CreatedAt),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("budget alert already recorded")
+		}
+		return "", err
+	}
+
+	return alert.ID.Hex(), nil
+}
+
+// List will return every alert of a month, sorted by creation
+func (a *BudgetAlertRepositorySQL) List(ctx context.Context, ownerID string, month int64, year int64) ([]BudgetAlert, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []BudgetAlert{}, err
+	}
+
+	rows, err := a.DB.conn(a.DB.executor(ctx)).query(ctx,
+		`SELECT `+budgetAlertColumns+` FROM budget_alerts WHERE owner_id = ? AND month = ? AND year = ? ORDER BY created_at, id`,
+		oid.Hex(), month, year,
+	)
+	if err != nil {
+		return []BudgetAlert{}, err
+	}
+	defer rows.Close()
+
+	alerts := []BudgetAlert{}
+	for rows.Next() {
+		var alert BudgetAlert
+		var id, owner, spendID string
+		var createdAt int64
+
+		err := rows.Scan(
+			&id, &owner, &alert.Category, &alert.Month, &alert.Year, &alert.Threshold,
+			&alert.Limit, &alert.Spent, &alert.Currency, &spendID, &createdAt,
+		)
+		if err != nil {
+			return []BudgetAlert{}, err
+		}
+
+		alert.ID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return []BudgetAlert{}, err
+		}
+
+		alert.OwnerID, err = primitive.ObjectIDFromHex(owner)
+		if err != nil {
+			return []BudgetAlert{}, err
+		}
+
+		alert.SpendID, err = primitive.ObjectIDFromHex(spendID)
+		if err != nil {
+			return []BudgetAlert{}, err
+		}
+
+		alert.CreatedAt = primitive.DateTime(createdAt)
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+const categoryColumns = `id, owner_id, name, parent_id, color, icon, created_at, updated_at`
+
+// queryCategories will return every category selected by a query
+func queryCategories(ctx context.Context, c sqlConn, query string, args ...interface{}) ([]Category, error) {
+	rows, err := c.query(ctx, query, args...)
+	if err != nil {
+		return []Category{}, err
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var category Category
+		var id, ownerID string
+		var parentID sql.NullString
+		var createdAt, updatedAt int64
+
+		err := rows.Scan(&id, &ownerID, &category.Name, &parentID, &category.Color, &category.Icon, &createdAt, &updatedAt)
+		if err != nil {
+			return []Category{}, err
+		}
+
+		category.ID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return []Category{}, err
+		}
+
+		category.OwnerID, err = primitive.ObjectIDFromHex(ownerID)
+		if err != nil {
+			return []Category{}, err
+		}
+
+		if parentID.Valid {
+			category.ParentID, err = primitive.ObjectIDFromHex(parentID.String)
+			if err != nil {
+				return []Category{}, err
+			}
+		}
+
+		category.CreatedAt = primitive.DateTime(createdAt)
+		category.UpdatedAt = primitive.DateTime(updatedAt)
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+// Get will return every category of an owner, sorted by name
+func (c *CategoryRepositorySQL) Get(ctx context.Context, ownerID string) ([]Category, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []Category{}, err
+	}
+
+	return queryCategories(ctx, c.DB.conn(c.DB.executor(ctx)),
+		`SELECT `+categoryColumns+` FROM categories WHERE owner_id = ? ORDER BY name, id`, oid.Hex(),
+	)
+}
+
+// GetByID will return a single category from a given owner ID
+func (c *CategoryRepositorySQL) GetByID(ctx context.Context, ownerID string, id string) (Category, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return Category{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Category{}, err
+	}
+
+	categories, err := queryCategories(ctx, c.DB.conn(c.DB.executor(ctx)),
+		`SELECT `+categoryColumns+` FROM categories WHERE id = ? AND owner_id = ?`, pid.Hex(), oid.Hex(),
+	)
+	if err != nil {
+		return Category{}, err
+	}
+
+	if len(categories) == 0 {
+		return Category{}, errors.New("could not find category")
+	}
+
+	return categories[0], nil
+}
+
+// Create will store a category, whose name must be unique per owner regardless of case
+func (c *CategoryRepositorySQL) Create(ctx context.Context, category Category) (id string, err error) {
+	if category.ID.IsZero() {
+		category.ID = primitive.NewObjectID()
+	}
+
+	_, err = c.DB.conn(c.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO categories (`+categoryColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		category.ID.Hex(), category.OwnerID.Hex(), category.Name, nullObjectID(category.ParentID),
+		category.Color, category.Icon, int64(category.CreatedAt), int64(category.UpdatedAt),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("category already exists")
+		}
+		return "", err
+	}
+
+	return category.ID.Hex(), nil
+}
+
+// Update will replace every attribute of a category but its owner and creation date
+func (c *CategoryRepositorySQL) Update(ctx context.Context, category Category) error {
+	result, err := c.DB.conn(c.DB.executor(ctx)).exec(ctx,
+		`UPDATE categories SET name = ?, parent_id = ?, color = ?, icon = ?, updated_at = ? WHERE id = ? AND owner_id = ?`,
+		category.Name, nullObjectID(category.ParentID), category.Color, category.Icon, int64(category.UpdatedAt),
+		category.ID.Hex(), category.OwnerID.Hex(),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return errors.New("category already exists")
+		}
+		return err
+	}
+
+	return affected(result, "could not find category")
+}
+
+// Delete will delete a category based on it's ID
+func (c *CategoryRepositorySQL) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.DB.conn(c.DB.executor(ctx)).exec(ctx, `DELETE FROM categories WHERE id = ?`, pid.Hex())
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find category")
+}
+
+const categoryRuleColumns = `id, owner_id, priority, description, pattern, min_cost, max_cost, payment_method, card_id, categories, type, created_at, updated_at`
+
+// queryCategoryRules will return every rule selected by a query
+func queryCategoryRules(ctx context.Context, c sqlConn, query string, args ...interface{}) ([]CategoryRule, error) {
+	rows, err := c.query(ctx, query, args...)
+	if err != nil {
+		return []CategoryRule{}, err
+	}
+	defer rows.Close()
+
+	rules := []CategoryRule{}
+	for rows.Next() {
+		var rule CategoryRule
+		var id, ownerID, categories string
+		var minCost, maxCost sql.NullInt64
+		var cardID sql.NullString
+		var createdAt, updatedAt int64
+
+		err := rows.Scan(&id, &ownerID, &rule.Priority, &rule.Description, &rule.Pattern, &minCost, &maxCost,
+			&rule.PaymentMethod, &cardID, &categories, &rule.Type, &createdAt, &updatedAt)
+		if err != nil {
+			return []CategoryRule{}, err
+		}
+
+		rule.ID, err = primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return []CategoryRule{}, err
+		}
+
+		rule.OwnerID, err = primitive.ObjectIDFromHex(ownerID)
+		if err != nil {
+			return []CategoryRule{}, err
+		}
+
+		if minCost.Valid {
+			min := Money(minCost.Int64)
+			rule.MinCost = &min
+		}
+		if maxCost.Valid {
+			max := Money(maxCost.Int64)
+			rule.MaxCost = &max
+		}
+
+		if cardID.Valid {
+			rule.CardID, err = primitive.ObjectIDFromHex(cardID.String)
+			if err != nil {
+				return []CategoryRule{}, err
+			}
+		}
+
+		err = json.Unmarshal([]byte(categories), &rule.Categories)
+		if err != nil {
+			return []CategoryRule{}, err
+		}
+
+		rule.CreatedAt = primitive.DateTime(createdAt)
+		rule.UpdatedAt = primitive.DateTime(updatedAt)
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// Get will return every rule of an owner, sorted by priority and then by creation
+func (c *CategoryRuleRepositorySQL) Get(ctx context.Context, ownerID string) ([]CategoryRule, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return []CategoryRule{}, err
+	}
+
+	return queryCategoryRules(ctx, c.DB.conn(c.DB.executor(ctx)),
+		`SELECT `+categoryRuleColumns+` FROM category_rules WHERE owner_id = ? ORDER BY priority, id`, oid.Hex(),
+	)
+}
+
+// GetByID will return a single rule from a given owner ID
+func (c *CategoryRuleRepositorySQL) GetByID(ctx context.Context, ownerID string, id string) (CategoryRule, error) {
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return CategoryRule{}, err
+	}
+
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return CategoryRule{}, err
+	}
+
+	rules, err := queryCategoryRules(ctx, c.DB.conn(c.DB.executor(ctx)),
+		`SELECT `+categoryRuleColumns+` FROM category_rules WHERE id = ? AND owner_id = ?`, pid.Hex(), oid.Hex(),
+	)
+	if err != nil {
+		return CategoryRule{}, err
+	}
+
+	if len(rules) == 0 {
+		return CategoryRule{}, errors.New("could not find category rule")
+	}
+
+	return rules[0], nil
+}
+
+// Create will store a rule
+func (c *CategoryRuleRepositorySQL) Create(ctx context.Context, rule CategoryRule) (id string, err error) {
+	if rule.ID.IsZero() {
+		rule.ID = primitive.NewObjectID()
+	}
+
+	categories, err := json.Marshal(append([]string{}, rule.Categories...))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = c.DB.conn(c.DB.executor(ctx)).exec(ctx,
+		`INSERT INTO category_rules (`+categoryRuleColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.ID.Hex(), rule.OwnerID.Hex(), rule.Priority, rule.Description, rule.Pattern,
+		nullMoney(rule.MinCost), nullMoney(rule.MaxCost), rule.PaymentMethod, nullObjectID(rule.CardID),
+		string(categories), rule.Type, int64(rule.CreatedAt), int64(rule.UpdatedAt),
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", errors.New("category rule already exists")
+		}
+		return "", err
+	}
+
+	return rule.ID.Hex(), nil
+}
+
+// Update will replace every attribute of a rule but its owner and creation date
+func (c *CategoryRuleRepositorySQL) Update(ctx context.Context, rule CategoryRule) error {
+	categories, err := json.Marshal(append([]string{}, rule.Categories...))
+	if err != nil {
+		return err
+	}
+
+	result, err := c.DB.conn(c.DB.executor(ctx)).exec(ctx,
+		`UPDATE category_rules SET priority = ?, description = ?, pattern = ?, min_cost = ?, max_cost = ?, payment_method = ?,
+		card_id = ?, categories = ?, type = ?, updated_at = ? WHERE id = ? AND owner_id = ?`,
+		rule.Priority, rule.Description, rule.Pattern, nullMoney(rule.MinCost), nullMoney(rule.MaxCost), rule.PaymentMethod,
+		nullObjectID(rule.CardID), string(categories), rule.Type, int64(rule.UpdatedAt), rule.ID.Hex(), rule.OwnerID.Hex(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find category rule")
+}
+
+// Delete will delete a rule based on it's ID
+func (c *CategoryRuleRepositorySQL) Delete(ctx context.Context, id string) error {
+	pid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	result, err := c.DB.conn(c.DB.executor(ctx)).exec(ctx, `DELETE FROM category_rules WHERE id = ?`, pid.Hex())
+	if err != nil {
+		return err
+	}
+
+	return affected(result, "could not find category rule")
+}
@@ -0,0 +1,759 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func sqliteRepositories(t *testing.T) Repositories {
+	dir, err := ioutil.TempDir("", "repository")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := sql.Open(SQLDialectSQLite, filepath.Join(dir, "budget-tracker.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	// migrations must be idempotent
+	for i := 0; i < 2; i++ {
+		err = MigrateSQL(context.Background(), db, SQLDialectSQLite)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewSQLRepositories(db, SQLDialectSQLite)
+}
+
+func TestSQLUniquenessRules(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	_, err := r.Users.Create(ctx, User{Login: "vsantos", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Users.Create(ctx, User{Login: "vsantos"})
+	if err == nil || err.Error() != "user already exists" {
+		t.Errorf("unexpected error creating a duplicated user: %v", err)
+	}
+
+	owner := primitive.NewObjectID()
+	_, err = r.Cards.Create(ctx, CreditCard{OwnerID: owner, LastDigits: 1234})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Cards.Create(ctx, CreditCard{OwnerID: owner, LastDigits: 1234})
+	if err == nil || err.Error() != "card already exists" {
+		t.Errorf("unexpected error creating a duplicated card: %v", err)
+	}
+
+	_, err = r.Cards.Create(ctx, CreditCard{OwnerID: primitive.NewObjectID(), LastDigits: 1234})
+	if err != nil {
+		t.Errorf("cards from different owners must not conflict: %v", err)
+	}
+
+	_, err = r.Balances.Create(ctx, Balance{OwnerID: owner, Month: 5, Year: 2021})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Balances.Create(ctx, Balance{OwnerID: owner, Month: 5, Year: 2021})
+	if err == nil || err.Error() != "balance already exists" {
+		t.Errorf("unexpected error creating a duplicated balance: %v", err)
+	}
+
+	user, err := r.Users.GetByLogin(ctx, "vsantos")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(user.Roles) != 1 || user.Roles[0] != "admin" {
+		t.Errorf("unexpected roles: %v", user.Roles)
+	}
+}
+
+func TestSQLSpendsUpdateBalanceAndPaginate(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	owner := primitive.NewObjectID()
+	card := primitive.NewObjectID()
+	date := time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	var spends []Spend
+	for i := 0; i < 3; i++ {
+		s := Spend{
+			OwnerID:     owner,
+			Type:        SpendTypeDynamic,
+			Description: "Guitar 100% lessons",
+			Cost:        NewMoney(10.3),
+			Currency:    "BRL",
+			Categories:  []string{"education", "music"},
+			Date:        primitive.NewDateTimeFromTime(date.AddDate(0, 0, i)),
+		}
+		if i == 0 {
+			s.PaymentMethod.Credit.ID = card
+		}
+
+		id, err := r.Spends.Create(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.ID, _ = primitive.ObjectIDFromHex(id)
+
+		err = r.Balances.AddSpend(ctx, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spends = append(spends, s)
+	}
+
+	err := r.Balances.RemoveSpend(ctx, spends[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := r.Balances.Get(ctx, owner.Hex(), 5, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance.Outcome.DynamicOutcome != 20600 || balance.SpendableAmount != -20600 || balance.Currency != "BRL" || len(balance.Historic) != 2 {
+		t.Errorf("unexpected balance: %+v", balance)
+	}
+
+	page, err := r.Spends.Find(ctx, SpendFilter{OwnerID: owner.Hex(), Category: "music", Description: "100%", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Spends) != 2 || page.Spends[0].ID != spends[2].ID || page.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+
+	if len(page.Spends[0].Categories) != 2 || page.Spends[0].Categories[1] != "music" {
+		t.Errorf("unexpected categories: %v", page.Spends[0].Categories)
+	}
+
+	page, err = r.Spends.Find(ctx, SpendFilter{OwnerID: owner.Hex(), Cursor: page.NextCursor, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Spends) != 1 || page.Spends[0].ID != spends[0].ID || page.NextCursor != "" {
+		t.Errorf("unexpected last page: %+v", page)
+	}
+
+	page, err = r.Spends.Find(ctx, SpendFilter{OwnerID: owner.Hex(), CardID: card.Hex()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Spends) != 1 || page.Spends[0].PaymentMethod.Credit.ID != card {
+		t.Errorf("unexpected spends paid with card: %+v", page.Spends)
+	}
+}
+
+func TestSQLTransactionsRollSpendAndBalanceBackTogether(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	owner := primitive.NewObjectID()
+	s := Spend{
+		ID:          primitive.NewObjectID(),
+		OwnerID:     owner,
+		Type:        SpendTypeDynamic,
+		Description: "Guitar lessons",
+		Cost:        NewMoney(10),
+		Currency:    "BRL",
+		Date:        primitive.NewDateTimeFromTime(time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	for _, fail := range []bool{true, false} {
+		err := r.Database.Transaction(ctx, func(ctx context.Context) error {
+			if _, err := r.Spends.Create(ctx, s); err != nil {
+				return err
+			}
+
+			if err := r.Balances.AddSpend(ctx, s); err != nil {
+				return err
+			}
+
+			if fail {
+				return fmt.Errorf("could not finish writes")
+			}
+			return nil
+		})
+		if fail != (err != nil) {
+			t.Fatalf("unexpected transaction error: %v", err)
+		}
+
+		_, spendErr := r.Spends.GetByID(ctx, owner.Hex(), s.ID.Hex())
+		balance, balanceErr := r.Balances.Get(ctx, owner.Hex(), 5, 2021)
+		if fail && (spendErr == nil || balanceErr == nil) {
+			t.Errorf("failed transaction left writes behind: spend %v, balance %v", spendErr, balanceErr)
+		}
+
+		if !fail && (spendErr != nil || balanceErr != nil || balance.Outcome.DynamicOutcome != NewMoney(10)) {
+			t.Errorf("writes of transaction not committed: spend %v, balance %v", spendErr, balanceErr)
+		}
+	}
+}
+
+func TestSQLExchangeRatesAndConvertedSpends(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	june := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i, rate := range []float64{5.1, 5.2, 5.3} {
+		err := r.ExchangeRates.Upsert(ctx, ExchangeRate{Base: "USD", Quote: "BRL", Date: primitive.NewDateTimeFromTime(june.AddDate(0, 0, i*2)), Rate: rate})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// upserting the same day replaces its rate
+	err := r.ExchangeRates.Upsert(ctx, ExchangeRate{Base: "USD", Quote: "BRL", Date: primitive.NewDateTimeFromTime(june.AddDate(0, 0, 2)), Rate: 5.25})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rate, err := r.ExchangeRates.Get(ctx, "USD", "BRL", june.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rate.Rate != 5.25 || rate.Date.Time().Day() != 3 {
+		t.Errorf("unexpected rate: %+v", rate)
+	}
+
+	_, err = r.ExchangeRates.Get(ctx, "USD", "BRL", june.AddDate(0, 0, -1))
+	if err == nil || err.Error() != "could not find exchange rate" {
+		t.Errorf("unexpected error looking up a rate before the first one: %v", err)
+	}
+
+	rates, err := r.ExchangeRates.List(ctx, ExchangeRateFilter{Base: "USD", From: june.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rates) != 2 || rates[0].Rate != 5.25 || rates[1].Rate != 5.3 {
+		t.Errorf("unexpected rates: %+v", rates)
+	}
+
+	s := Spend{
+		OwnerID:          primitive.NewObjectID(),
+		Cost:             ConvertAmount(NewMoney(10), rate.Rate, "BRL"),
+		Currency:         "BRL",
+		OriginalCost:     NewMoney(10),
+		OriginalCurrency: "USD",
+		ExchangeRate:     rate.Rate,
+		Date:             primitive.NewDateTimeFromTime(june.AddDate(0, 0, 3)),
+	}
+
+	id, err := r.Spends.Create(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := r.Spends.GetByID(ctx, s.OwnerID.Hex(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Cost != 52500 || stored.OriginalCost != 10000 || stored.OriginalCurrency != "USD" || stored.ExchangeRate != 5.25 {
+		t.Errorf("unexpected converted spend: %+v", stored)
+	}
+}
+
+func TestSQLRecurrencesAreScheduledOnce(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	run := primitive.NewDateTimeFromTime(time.Date(2021, 5, 10, 0, 0, 0, 0, time.UTC))
+	recurrence := Recurrence{
+		OwnerID:    primitive.NewObjectID(),
+		Cost:       NewMoney(150),
+		Cadence:    RecurrenceCadenceMonthly,
+		Day:        10,
+		Categories: []string{"education"},
+		Start:      run,
+		Status:     RecurrenceStatusActive,
+		NextRun:    run,
+	}
+
+	id, err := r.Recurrences.Create(ctx, recurrence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recurrence.ID, _ = primitive.ObjectIDFromHex(id)
+
+	due, err := r.Recurrences.Due(ctx, run.Time())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(due) != 1 || due[0].Categories[0] != "education" || due[0].Cost != 150000 {
+		t.Fatalf("unexpected due recurrences: %+v", due)
+	}
+
+	claimed := recurrence
+	claimed.NextRun = primitive.NewDateTimeFromTime(run.Time().AddDate(0, 1, 0))
+
+	err = r.Recurrences.Schedule(ctx, claimed, RecurrenceStatusActive, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.Recurrences.Schedule(ctx, claimed, RecurrenceStatusActive, run)
+	if err == nil || err.Error() != "could not find recurrence" {
+		t.Errorf("a run was claimed twice: %v", err)
+	}
+
+	s := Spend{
+		OwnerID:      recurrence.OwnerID,
+		Cost:         recurrence.Cost,
+		Date:         run,
+		RecurrenceID: recurrence.ID,
+		Installment:  &Installment{PurchaseID: primitive.NewObjectID(), Number: 2, Total: 3, PurchaseCost: NewMoney(450)},
+	}
+	spendID, err := r.Spends.Create(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := r.Spends.GetByID(ctx, s.OwnerID.Hex(), spendID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.RecurrenceID != recurrence.ID || stored.Installment == nil || *stored.Installment != *s.Installment {
+		t.Errorf("unexpected recurrence of spend: %+v", stored)
+	}
+}
+
+func TestSQLStatementPaymentsAreNotAccountedAsOutcome(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	owner := primitive.NewObjectID()
+	card := CreditCard{OwnerID: owner, LastDigits: 4321, ClosingDay: 25, DueDay: 5, CreditLimit: NewMoney(5000)}
+
+	cardID, err := r.Cards.Create(ctx, card)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := r.Cards.GetByID(ctx, owner.Hex(), cardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.ClosingDay != 25 || stored.DueDay != 5 || stored.CreditLimit != 5000000 {
+		t.Errorf("unexpected card: %+v", stored)
+	}
+
+	payment := StatementPayment{
+		OwnerID: owner,
+		CardID:  stored.ID,
+		Month:   5,
+		Year:    2021,
+		Amount:  NewMoney(150),
+		SpendID: primitive.NewObjectID(),
+		PaidAt:  primitive.NewDateTimeFromTime(time.Date(2021, 6, 5, 0, 0, 0, 0, time.UTC)),
+	}
+
+	id, err := r.Statements.Create(ctx, payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.Statements.Create(ctx, payment)
+	if err == nil || err.Error() != "statement already paid" {
+		t.Errorf("a statement was paid twice: %v", err)
+	}
+
+	paid, err := r.Statements.Get(ctx, owner.Hex(), cardID, 5, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if paid.ID.Hex() != id || paid.SpendID != payment.SpendID || paid.Amount != payment.Amount {
+		t.Errorf("unexpected statement payment: %+v", paid)
+	}
+
+	err = r.Balances.AddSpend(ctx, Spend{ID: payment.SpendID, OwnerID: owner, Type: SpendTypePayment, Cost: payment.Amount, Date: payment.PaidAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := r.Balances.Get(ctx, owner.Hex(), 6, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(balance.Historic) != 1 || balance.Outcome.DynamicOutcome != 0 || balance.SpendableAmount != 0 {
+		t.Errorf("statement payment was accounted as outcome: %+v", balance)
+	}
+
+	err = r.Statements.Delete(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payments, err := r.Statements.List(ctx, owner.Hex(), cardID)
+	if err != nil || len(payments) != 0 {
+		t.Errorf("unexpected statement payments after deleting them: %+v, %v", payments, err)
+	}
+}
+
+func TestSQLCardsAreUpdatedAndArchived(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	card := CreditCard{OwnerID: primitive.NewObjectID(), Alias: "black", Network: "visa", LastDigits: 2222}
+
+	id, err := r.Cards.Create(ctx, card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card.ID, _ = primitive.ObjectIDFromHex(id)
+
+	card.Alias = "old black"
+	card.LastDigits = 9999
+	card.Archived = true
+	card.ArchivedAt = primitive.NewDateTimeFromTime(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	err = r.Cards.Update(ctx, card)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := r.Cards.GetByID(ctx, card.OwnerID.Hex(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Alias != "old black" || stored.LastDigits != 2222 || !stored.Archived || stored.ArchivedAt != card.ArchivedAt {
+		t.Errorf("unexpected updated card: %+v", stored)
+	}
+
+	card.OwnerID = primitive.NewObjectID()
+	err = r.Cards.Update(ctx, card)
+	if err == nil || err.Error() != "could not find card" {
+		t.Errorf("a card was updated by another owner: %v", err)
+	}
+}
+
+func TestSQLBalanceBudgetsAndAlerts(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	owner := primitive.NewObjectID()
+	balance := Balance{OwnerID: owner, Month: 5, Year: 2021, Currency: "BRL", Budgets: []Budget{{Category: "restaurants", Limit: NewMoney(800)}}}
+
+	id, err := r.Balances.Create(ctx, balance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := r.Balances.GetByID(ctx, owner.Hex(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stored.Budgets) != 1 || stored.Budgets[0] != balance.Budgets[0] {
+		t.Errorf("unexpected budgets: %+v", stored.Budgets)
+	}
+
+	stored.Budgets = append(stored.Budgets, Budget{Category: "groceries", Limit: NewMoney(1200.5)})
+	err = r.Balances.Update(ctx, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := r.Balances.Get(ctx, owner.Hex(), 5, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(updated.Budgets) != 2 || updated.Budgets[1].Limit != 1200500 {
+		t.Errorf("unexpected updated budgets: %+v", updated.Budgets)
+	}
+
+	alert := BudgetAlert{
+		OwnerID:   owner,
+		Category:  "restaurants",
+		Month:     5,
+		Year:      2021,
+		Threshold: 80,
+		Limit:     NewMoney(800),
+		Spent:     NewMoney(650.5),
+		Currency:  "BRL",
+		SpendID:   primitive.NewObjectID(),
+		CreatedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+
+	_, err = r.BudgetAlerts.Create(ctx, alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = r.BudgetAlerts.Create(ctx, alert)
+	if err == nil || err.Error() != "budget alert already recorded" {
+		t.Errorf("a budget threshold was alerted twice: %v", err)
+	}
+
+	alert.Threshold = 100
+	_, err = r.BudgetAlerts.Create(ctx, alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err := r.BudgetAlerts.List(ctx, owner.Hex(), 5, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(alerts) != 2 || alerts[1].Threshold != 100 || alerts[0].Spent != 650500 || alerts[0].SpendID != alert.SpendID {
+		t.Errorf("unexpected budget alerts: %+v", alerts)
+	}
+}
+
+func TestSQLCategoriesAreUniquePerOwnerRegardlessOfCase(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	owner := primitive.NewObjectID()
+	food := Category{ID: primitive.NewObjectID(), OwnerID: owner, Name: "food", Color: "#ef6c00", Icon: "utensils"}
+
+	if _, err := r.Categories.Create(ctx, food); err != nil {
+		t.Fatal(err)
+	}
+
+	groceries := Category{OwnerID: owner, Name: "groceries", ParentID: food.ID}
+	id, err := r.Categories.Create(ctx, groceries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Categories.Create(ctx, Category{OwnerID: owner, Name: "FOOD"}); err == nil || err.Error() != "category already exists" {
+		t.Errorf("unexpected error creating a duplicated category: %v", err)
+	}
+
+	// other owners may use the same names
+	if _, err := r.Categories.Create(ctx, Category{OwnerID: primitive.NewObjectID(), Name: "food"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := r.Categories.GetByID(ctx, owner.Hex(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ParentID != food.ID {
+		t.Errorf("unexpected parent: %+v", stored)
+	}
+
+	stored.Name = "Food"
+	if err := r.Categories.Update(ctx, stored); err == nil || err.Error() != "category already exists" {
+		t.Errorf("unexpected error renaming into a taken name: %v", err)
+	}
+
+	stored.Name = "supermarket"
+	stored.ParentID = primitive.NilObjectID
+	if err := r.Categories.Update(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	categories, err := r.Categories.Get(ctx, owner.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 2 || categories[0].Name != "food" || categories[1].Name != "supermarket" || !categories[1].ParentID.IsZero() {
+		t.Errorf("unexpected categories: %+v", categories)
+	}
+
+	if err := r.Categories.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Categories.GetByID(ctx, owner.Hex(), id); err == nil {
+		t.Error("deleted category was still found")
+	}
+}
+
+func TestSQLCategoryRulesAreSortedByPriority(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	owner := primitive.NewObjectID()
+	min, max := NewMoney(10), NewMoney(99.9)
+
+	uber := CategoryRule{OwnerID: owner, Priority: 10, Pattern: "(?i)^uber", MinCost: &min, MaxCost: &max, CardID: primitive.NewObjectID(), Categories: []string{"transport"}, Type: SpendTypeDynamic}
+	id, err := r.CategoryRules.Create(ctx, uber)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.CategoryRules.Create(ctx, CategoryRule{OwnerID: owner, Priority: 5, Description: "netflix", PaymentMethod: PaymentMethodCredit, Categories: []string{"streaming"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := r.CategoryRules.Get(ctx, owner.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Description != "netflix" || rules[0].MinCost != nil || !rules[0].CardID.IsZero() {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	stored := rules[1]
+	if stored.ID.Hex() != id || *stored.MinCost != min || *stored.MaxCost != max || stored.CardID != uber.CardID || stored.Categories[0] != "transport" {
+		t.Errorf("unexpected rule: %+v", stored)
+	}
+
+	stored.Priority = 1
+	stored.MaxCost = nil
+	stored.CardID = primitive.NilObjectID
+	stored.Categories = []string{"mobility"}
+	if err := r.CategoryRules.Update(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := r.CategoryRules.GetByID(ctx, owner.Hex(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Priority != 1 || updated.MaxCost != nil || *updated.MinCost != min || !updated.CardID.IsZero() || updated.Categories[0] != "mobility" {
+		t.Errorf("unexpected updated rule: %+v", updated)
+	}
+
+	if err := r.CategoryRules.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CategoryRules.GetByID(ctx, owner.Hex(), id); err == nil || err.Error() != "could not find category rule" {
+		t.Errorf("unexpected error fetching a deleted rule: %v", err)
+	}
+}
+
+func TestSQLSpendTotalsAreGroupedByCurrency(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	owner := primitive.NewObjectID()
+	card := primitive.NewObjectID()
+
+	may := time.Date(2021, time.May, 31, 23, 0, 0, 0, time.UTC)
+	june := time.Date(2021, time.June, 1, 1, 0, 0, 0, time.UTC)
+	spends := []Spend{
+		{Type: SpendTypeDynamic, Cost: NewMoney(10), Currency: "BRL", Categories: []string{"food", "transport"}, PaymentMethod: PaymentMethod{Debit: true}, Date: primitive.NewDateTimeFromTime(may)},
+		{Type: SpendTypeFixed, Cost: NewMoney(20), Currency: "BRL", PaymentMethod: PaymentMethod{Credit: CreditCard{ID: card}}, Date: primitive.NewDateTimeFromTime(june)},
+		{Type: SpendTypeDynamic, Cost: NewMoney(5), Currency: "USD", Categories: []string{"food"}, PaymentMethod: PaymentMethod{PaymentSlip: true}, Date: primitive.NewDateTimeFromTime(june)},
+		{Type: SpendTypePayment, Cost: NewMoney(20), Currency: "BRL", PaymentMethod: PaymentMethod{Debit: true}, Date: primitive.NewDateTimeFromTime(june)},
+		// other owners and spends out of range are not accounted
+		{OwnerID: primitive.NewObjectID(), Cost: NewMoney(99), Currency: "BRL", Date: primitive.NewDateTimeFromTime(june)},
+		{Cost: NewMoney(99), Currency: "BRL", Date: primitive.NewDateTimeFromTime(june.AddDate(0, 2, 0))},
+	}
+	for _, spend := range spends {
+		if spend.OwnerID.IsZero() {
+			spend.OwnerID = owner
+		}
+		if _, err := r.Spends.Create(ctx, spend); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	from := time.Date(2021, time.May, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, time.July, 1, 0, 0, 0, 0, time.UTC).Add(-time.Millisecond)
+
+	tests := []struct {
+		group string
+		want  string
+	}{
+		{SpendGroupCurrency, "/BRL:30/2 /USD:5/1"},
+		{SpendGroupCategory, "/BRL:20/1 food/BRL:10/1 food/USD:5/1 transport/BRL:10/1"},
+		{SpendGroupPaymentMethod, "credit/BRL:20/1 debit/BRL:10/1 payment_slip/USD:5/1"},
+		{SpendGroupCard, card.Hex() + "/BRL:20/1"},
+		{SpendGroupType, "dynamic/BRL:10/1 dynamic/USD:5/1 fixed/BRL:20/1"},
+		{SpendGroupMonth, "2021-05/BRL:10/1 2021-06/BRL:20/1 2021-06/USD:5/1"},
+	}
+
+	for _, tt := range tests {
+		totals, err := r.Spends.Totals(ctx, owner.Hex(), from, to, tt.group)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, total := range totals {
+			got = append(got, fmt.Sprintf("%s/%s:%v/%d", total.Key, total.Currency, total.Total, total.Count))
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("totals by %s: got %s want %s", tt.group, strings.Join(got, " "), tt.want)
+		}
+	}
+
+	if _, err := r.Spends.Totals(ctx, owner.Hex(), from, to, "weekday"); err == nil {
+		t.Error("spends were grouped by an unknown criteria")
+	}
+}
+
+func TestSQLSessionRevocationsOnlyRejectTokensIssuedUntilThem(t *testing.T) {
+	r := sqliteRepositories(t)
+	ctx := context.Background()
+
+	owner := primitive.NewObjectID()
+	notBefore := time.Now().Truncate(time.Millisecond)
+
+	err := r.Revocations.Create(ctx, Revocation{
+		OwnerID:   owner,
+		NotBefore: primitive.NewDateTimeFromTime(notBefore),
+		ExpiresAt: primitive.NewDateTimeFromTime(notBefore.Add(time.Hour)),
+		CreatedAt: primitive.NewDateTimeFromTime(notBefore),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{notBefore.Add(-time.Second), true},
+		{notBefore.Add(-time.Millisecond), true},
+		{notBefore, true},
+		// tokens issued right after it, such as a new login, remain valid
+		{notBefore.Add(time.Millisecond), false},
+		{notBefore.Add(time.Second), false},
+	}
+
+	for _, tt := range tests {
+		revoked, err := r.Revocations.IsRevoked(ctx, "", owner.Hex(), tt.issuedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != tt.revoked {
+			t.Errorf("token issued at %v: got revoked %v want %v", tt.issuedAt, revoked, tt.revoked)
+		}
+	}
+}
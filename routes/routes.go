@@ -57,6 +57,20 @@ func InitRoutes(service string, router *mux.Router) {
 	//     type: json
 	router.Handle("/api/v1/swagger.yaml", h.SwaggerHandler).Methods("GET")
 
+	// swagger:operation GET /.well-known/jwks.json Authentication jwks
+	//
+	// Returns the public keys used to verify issued JWT tokens. Shared (HS256) secrets are never published
+	// ---
+	// produces:
+	// - application/json
+	// responses:
+	//   '200':
+	//     description: JSON Web Key Set
+	//     examples:
+	//       application/json: { "keys": [{ "kty": "EC", "use": "sig", "kid": "<KEY_ID>", "alg": "ES256", "crv": "P-256", "x": "<X>", "y": "<Y>" }] }
+	//     type: json
+	router.Handle("/.well-known/jwks.json", h.JWKSHandler).Methods("GET")
+
 	// swagger:operation POST /api/v1/jwt/issue Authentication issue
 	//
 	// Returns a JWT signed token to be used for the next 5 minutes
@@ -90,7 +104,12 @@ func InitRoutes(service string, router *mux.Router) {
 	//   '401':
 	//     description: invalid credentials
 	//     examples:
-	//       application/json: { "message": "invalid credentials for user 'vsantos'" }
+	//       application/json: { "message": "invalid credentials" }
+	//     type: json
+	//   '429':
+	//     description: login locked after repeated failures, retry after the 'Retry-After' header seconds
+	//     examples:
+	//       application/json: { "message": "too many failed login attempts, try again later" }
 	//     type: json
 	router.Handle("/api/v1/jwt/issue", m.JSON(h.CreateJWTTokenHandler)).Methods("POST")
 
@@ -103,11 +122,92 @@ func InitRoutes(service string, router *mux.Router) {
 	//     description: returned options
 	router.Handle("/api/v1/jwt/issue", h.OptionsJWTTokenHandler).Methods("OPTIONS")
 
-	router.Handle("/api/v1/jwt/refresh", m.JSON(h.CreateJWTTokenHandler)).Methods("POST")
+	// swagger:operation POST /api/v1/jwt/refresh Authentication refresh
+	//
+	// Exchanges a refresh token by a new JWT access token and a rotated refresh token. Refresh tokens
+	// can be used a single time: reusing one revokes every token issued since its login
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: refresh token
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/JWTRefresh"
+	// responses:
+	//   '201':
+	//     description: returned JWT token
+	//     examples:
+	//       application/json: { "type": "bearer", "refresh": "<REFRESH_TOKEN>", "token": "<JWT_TOKEN>" }
+	//     type: json
+	//   '400':
+	//     description: bad request (missing one of params)
+	//     examples:
+	//       application/json: { "message": "empty required payload attributes" }
+	//     type: json
+	//   '401':
+	//     description: invalid, expired or reused refresh token
+	//     examples:
+	//       application/json: { "message": "could not refresh token", "details": "refresh token reuse detected" }
+	//     type: json
+	router.Handle("/api/v1/jwt/refresh", m.JSON(h.RefreshJWTTokenHandler)).Methods("POST")
+
+	// swagger:operation OPTIONS /api/v1/jwt/refresh Authentication options
+	//
+	// OPTIONS
+	// ---
+	// responses:
+	//   '200':
+	//     description: returned options
+	router.Handle("/api/v1/jwt/refresh", h.OptionsJWTTokenHandler).Methods("OPTIONS")
+
+	// swagger:operation POST /api/v1/jwt/revoke Authentication revoke
+	//
+	// Revokes the access token used at the request (logout). When a refresh token is given, its whole session is revoked as well
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: optional refresh token
+	//   schema:
+	//     "$ref": "#/definitions/JWTRefresh"
+	// responses:
+	//   '200':
+	//     description: revoked token
+	//     examples:
+	//       application/json: { "message": "revoked token" }
+	//     type: json
+	//   '403':
+	//     description: refresh token from another user
+	//     examples:
+	//       application/json: { "message": "could not revoke token", "details": "refresh token does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not revoke token", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/jwt/revoke", m.JSON(m.Auth(h.RevokeJWTTokenHandler))).Methods("POST")
 
 	// swagger:operation POST /api/v1/users Users create
 	//
-	// Creates an user
+	// Creates an user (admin only)
 	// ---
 	// consumes:
 	// - application/json
@@ -135,16 +235,21 @@ func InitRoutes(service string, router *mux.Router) {
 	//     examples:
 	//       application/json: { "message": "could not create user", "details": "user already exists" }
 	//     type: json
+	//   '403':
+	//     description: authenticated user is not an admin
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "missing required role 'admin'" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
 	//       application/json: { "message": "could not create user", "details": "<ERROR_DETAILS>" }
 	//     type: json
-	router.Handle("/api/v1/users", m.JSON(m.Auth(h.CreateUserHandler))).Methods("POST")
+	router.Handle("/api/v1/users", m.JSON(m.Auth(m.Admin(h.CreateUserHandler)))).Methods("POST")
 
 	// swagger:operation GET /api/v1/users Users list
 	//
-	// List all users
+	// List all users (admin only)
 	// ---
 	// consumes:
 	// - application/json
@@ -163,12 +268,17 @@ func InitRoutes(service string, router *mux.Router) {
 	//       items:
 	//         "$ref": "#/definitions/SanitizedUser"
 	//     type: json
+	//   '403':
+	//     description: authenticated user is not an admin
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "missing required role 'admin'" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
 	//       application/json: { "message": "<ERROR_DETAILS>" }
 	//     type: json
-	router.Handle("/api/v1/users", m.JSON(m.Auth(h.GetUsersHandler))).Methods("GET")
+	router.Handle("/api/v1/users", m.JSON(m.Auth(m.Admin(h.GetUsersHandler)))).Methods("GET")
 
 	// swagger:operation GET /api/v1/users/{id} Users get
 	//
@@ -194,6 +304,11 @@ func InitRoutes(service string, router *mux.Router) {
 	//       type: json
 	//       items:
 	//         "$ref": "#/definitions/SanitizedUser"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
@@ -203,7 +318,7 @@ func InitRoutes(service string, router *mux.Router) {
 
 	// swagger:operation DELETE /api/v1/users/{id} Users delete
 	//
-	// Delete a single user
+	// Delete a single user (admin only)
 	// ---
 	// consumes:
 	// - application/json
@@ -224,12 +339,88 @@ func InitRoutes(service string, router *mux.Router) {
 	//     examples:
 	//       application/json: { "message": "deleted user '<USER_ID:>'" }
 	//     type: json
+	//   '403':
+	//     description: authenticated user is not an admin
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "missing required role 'admin'" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
 	//       application/json: { "message": "could not delete user", "details": "<ERROR_DETAILS>" }
 	//     type: json
-	router.Handle("/api/v1/users/{id}", m.JSON(m.Auth(h.DeleteUserHandler))).Methods("DELETE")
+	router.Handle("/api/v1/users/{id}", m.JSON(m.Auth(m.Admin(h.DeleteUserHandler)))).Methods("DELETE")
+
+	// swagger:operation DELETE /api/v1/users/{id}/sessions Users revoke
+	//
+	// Revokes every token issued to a single user until now
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: user id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: revoked sessions
+	//     examples:
+	//       application/json: { "message": "revoked all sessions from user '<USER_ID>'" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not revoke sessions", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/users/{id}/sessions", m.JSON(m.Auth(h.RevokeUserSessionsHandler))).Methods("DELETE")
+
+	// swagger:operation DELETE /api/v1/users/{id}/lock Users unlock
+	//
+	// Unlocks a single user locked after repeated failed logins (admin only)
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: user id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: unlocked user
+	//     examples:
+	//       application/json: { "message": "unlocked user '<USER_ID>'" }
+	//     type: json
+	//   '403':
+	//     description: missing admin role
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "missing required role 'admin'" }
+	//     type: json
+	//   '404':
+	//     description: user not found
+	//     examples:
+	//       application/json: { "message": "could not unlock user", "details": "could not find user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not unlock user", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/users/{id}/lock", m.JSON(m.Auth(m.Admin(h.UnlockUserHandler)))).Methods("DELETE")
 
 	// swagger:operation POST /api/v1/cards Cards create
 	//
@@ -256,11 +447,21 @@ func InitRoutes(service string, router *mux.Router) {
 	//     examples:
 	//       application/json: { "message": "created card '<CARD_ALIAS>'", "id": "<CARD_ID>" }
 	//     type: json
+	//   '400':
+	//     description: invalid billing cycle or credit limit
+	//     examples:
+	//       application/json: { "message": "could not create card", "details": "invalid card: closing day must be between 1 and 31" }
+	//     type: json
 	//   '409':
 	//     description: card already exists
 	//     examples:
 	//       application/json: { "message": "could not create card", "details": "card already exists" }
 	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
@@ -284,7 +485,7 @@ func InitRoutes(service string, router *mux.Router) {
 
 	// swagger:operation GET /api/v1/cards Cards list
 	//
-	// List all cards from platform
+	// List all cards from platform (admin only)
 	// ---
 	// produces:
 	// - application/json
@@ -300,16 +501,21 @@ func InitRoutes(service string, router *mux.Router) {
 	//       type: array
 	//       items:
 	//         "$ref": "#/definitions/CreditCard"
+	//   '403':
+	//     description: authenticated user is not an admin
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "missing required role 'admin'" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
 	//       application/json: {"message": "<ERROR_DETAILS>"}
 	//     type: json
-	router.Handle("/api/v1/cards", m.JSON(m.Auth(h.GetAllCardsHandler))).Methods("GET")
+	router.Handle("/api/v1/cards", m.JSON(m.Auth(m.Admin(h.GetAllCardsHandler)))).Methods("GET")
 
 	// swagger:operation DELETE /api/v1/cards/{id} Cards delete
 	//
-	// Deletes a single card
+	// Deletes a single card from the authenticated user, or from any user for admins (deprecated by DELETE /api/v1/cards/{owner_id}/{id})
 	// ---
 	// consumes:
 	// - application/json
@@ -330,6 +536,21 @@ func InitRoutes(service string, router *mux.Router) {
 	//     examples:
 	//       application/json: { "message": "deleted card '<CARD_ID>'" }
 	//     type: json
+	//   '403':
+	//     description: card does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: card not found
+	//     examples:
+	//       application/json: { "message": "could not delete card", "details": "could not find card" }
+	//     type: json
+	//   '409':
+	//     description: card is used by spends or recurrences
+	//     examples:
+	//       application/json: { "message": "could not delete card", "details": "card is used by spends or recurrences and can only be archived" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
@@ -347,7 +568,7 @@ func InitRoutes(service string, router *mux.Router) {
 
 	// swagger:operation GET /api/v1/cards/{owner_id} Cards list
 	//
-	// List all cards from a given owner
+	// List all cards from a given owner, archived ones only when asked to
 	// ---
 	// produces:
 	// - application/json
@@ -360,6 +581,10 @@ func InitRoutes(service string, router *mux.Router) {
 	//   in: owner_id
 	//   description: owner id
 	//   required: true
+	// - name: archived
+	//   in: query
+	//   description: also lists archived cards when "true"
+	//   required: false
 	// responses:
 	//   '200':
 	//     description: card response
@@ -367,6 +592,11 @@ func InitRoutes(service string, router *mux.Router) {
 	//       type: array
 	//       items:
 	//         "$ref": "#/definitions/CreditCard"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
@@ -374,12 +604,19 @@ func InitRoutes(service string, router *mux.Router) {
 	//     type: json
 	router.Handle("/api/v1/cards/{owner_id}", m.JSON(m.Auth(h.GetCardsHandler))).Methods("GET")
 
-	// swagger:operation POST /api/v1/balance Balance create
+	// swagger:operation OPTIONS /api/v1/cards/{owner_id}/{id} Cards get
 	//
-	// Creates a single balance for a given owner
+	// OPTIONS
+	// ---
+	// responses:
+	//   '200':
+	//     description: returned options
+	router.Handle("/api/v1/cards/{owner_id}/{id}", h.OptionsCardsHandler).Methods("OPTIONS")
+
+	// swagger:operation GET /api/v1/cards/{owner_id}/{id} Cards get
+	//
+	// Returns a single card from a given owner, even when archived
 	// ---
-	// consumes:
-	// - application/json
 	// produces:
 	// - application/json
 	// parameters:
@@ -389,35 +626,41 @@ func InitRoutes(service string, router *mux.Router) {
 	//   required: true
 	// - name: owner_id
 	//   in: owner_id
-	//   description: balance owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: card id
 	//   required: true
 	// responses:
-	//   '201':
-	//     description: deleted user
-	//     examples:
-	//       application/json: { "message": "created balance", "id": "<BALANCE_ID>" }
-	//     type: json
-	//   '400':
-	//     description: bad request
+	//   '200':
+	//     description: card response
+	//     schema:
+	//       "$ref": "#/definitions/CreditCard"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
 	//     examples:
-	//       application/json: {"message": "could not create balance", "details": "balances must have an 'owner_id'"}
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
 	//     type: json
-	//   '409':
-	//     description: balance already exists
+	//   '404':
+	//     description: card not found
 	//     examples:
-	//       application/json: { "message": "could not create balance", "details": "balance already exists" }
+	//       application/json: { "message": "could not find card", "id": "<CARD_ID>" }
 	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
-	//       application/json: { "message": "could not delete balance", "details": "<ERROR_DETAILS>" }
+	//       application/json: {"message": "<ERROR_DETAILS>"}
 	//     type: json
-	router.Handle("/api/v1/balance", m.JSON(m.Auth(h.CreateBalanceHandler))).Methods("POST")
+	router.Handle("/api/v1/cards/{owner_id}/{id}", m.JSON(m.Auth(h.GetCardHandler))).Methods("GET")
 
-	// swagger:operation GET /api/v1/balance/{owner_id} Balance list
+	// swagger:operation PATCH /api/v1/cards/{owner_id}/{id} Cards patch
 	//
-	// List all balances from a given owner or a single one given a month and year as query params
+	// Partially updates a card, whose last digits can not be changed. Cards are archived with "archived": true,
+	// which keeps the spends made with them but refuses new ones
 	// ---
+	// consumes:
+	// - application/json
 	// produces:
 	// - application/json
 	// parameters:
@@ -425,37 +668,56 @@ func InitRoutes(service string, router *mux.Router) {
 	//   in: headers
 	//   description: application/json
 	//   required: true
-	// - name: month
-	//   in: query
-	//   description: month
-	// - name: year
-	//   in: query
-	//   description: year
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: card id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: card attributes to be changed
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CreditCard"
 	// responses:
 	//   '200':
-	//     description: balance response
+	//     description: updated card
 	//     schema:
-	//       type: array
-	//       items:
-	//         "$ref": "#/definitions/Balance"
+	//       "$ref": "#/definitions/CreditCard"
+	//   '400':
+	//     description: invalid network, billing cycle or credit limit
+	//     examples:
+	//       application/json: { "message": "could not update card", "details": "invalid card: due day must be between 1 and 31" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
 	//   '404':
-	//     description: balance not found
+	//     description: card not found
 	//     examples:
-	//       application/json: []
+	//       application/json: { "message": "could not update card", "details": "could not find card" }
+	//     type: json
+	//   '409':
+	//     description: card is used by active or paused recurrences
+	//     examples:
+	//       application/json: { "message": "could not update card", "details": "card is used by recurrences, which must be cancelled before archiving it" }
 	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
-	//       application/json: {"message": "<ERROR_DETAILS>"}
+	//       application/json: { "message": "could not update card", "details": "<ERROR_DETAILS>" }
 	//     type: json
-	router.Handle("/api/v1/balance/{owner_id}", m.JSON(m.Auth(h.GetBalanceHandler))).Methods("GET")
+	router.Handle("/api/v1/cards/{owner_id}/{id}", m.JSON(m.Auth(h.PatchCardHandler))).Methods("PATCH")
 
-	// swagger:operation POST /api/v1/spends Spends create
+	// swagger:operation DELETE /api/v1/cards/{owner_id}/{id} Cards delete
 	//
-	// Creates a single spend for a given owner
+	// Deletes a single card from a given owner. Cards used by spends or recurrences must be archived instead
 	// ---
-	// consumes:
-	// - application/json
 	// produces:
 	// - application/json
 	// parameters:
@@ -465,29 +727,44 @@ func InitRoutes(service string, router *mux.Router) {
 	//   required: true
 	// - name: owner_id
 	//   in: owner_id
-	//   description: spend owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: card id
 	//   required: true
 	// responses:
-	//   '201':
-	//     description: deleted user
+	//   '200':
+	//     description: deleted card
 	//     examples:
-	//       application/json: { "message": "created spend to user '<OWNER_ID>'", "id": "<SPEND_ID>"}
+	//       application/json: { "message": "deleted card '<CARD_ID>'" }
 	//     type: json
-	//   '400':
-	//     description: bad request
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: card not found
 	//     examples:
-	//       application/json: {"message": "could not create spend", "details": "missing owner ID"}
+	//       application/json: { "message": "could not delete card", "details": "could not find card" }
+	//     type: json
+	//   '409':
+	//     description: card is used by spends or recurrences
+	//     examples:
+	//       application/json: { "message": "could not delete card", "details": "card is used by spends or recurrences and can only be archived" }
 	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
-	//       application/json: { "message": "could not create spend", "details": "<ERROR_DETAILS>" }
+	//       application/json: { "message": "could not delete card", "details": "<ERROR_DETAILS>" }
 	//     type: json
-	router.Handle("/api/v1/spends", m.JSON(m.Auth(h.CreateSpendHandler))).Methods("POST")
+	router.Handle("/api/v1/cards/{owner_id}/{id}", m.JSON(m.Auth(h.DeleteCardHandler))).Methods("DELETE")
 
-	// swagger:operation GET /api/v1/spends/{owner_id} Spends list
+	// swagger:operation GET /api/v1/cards/{owner_id}/{id}/statements/{period} Cards statement
 	//
-	// Get all spends for a given owner id
+	// Returns the credit spends of a card billed in the statement closing at a given month, along with its
+	// total, due date, payment and available credit limit
 	// ---
 	// produces:
 	// - application/json
@@ -499,17 +776,1912 @@ func InitRoutes(service string, router *mux.Router) {
 	// - name: owner_id
 	//   in: owner_id
 	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: card id
+	//   required: true
+	// - name: period
+	//   in: period
+	//   description: month the statement closes at, as YYYY-MM
+	//   required: true
 	// responses:
 	//   '200':
-	//     description: spends response
+	//     description: statement response
 	//     schema:
-	//       type: array
-	//       items:
-	//         "$ref": "#/definitions/Spend"
+	//       "$ref": "#/definitions/Statement"
+	//   '400':
+	//     description: invalid period
+	//     examples:
+	//       application/json: { "message": "could not get statement", "details": "period must be given as YYYY-MM" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: card not found
+	//     examples:
+	//       application/json: { "message": "could not get statement", "details": "could not find card" }
+	//     type: json
+	//   '409':
+	//     description: card has no closing and due days
+	//     examples:
+	//       application/json: { "message": "could not get statement", "details": "card has no billing cycle, its closing and due days must be set" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not get statement", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/cards/{owner_id}/{id}/statements/{period}", m.JSON(m.Auth(h.GetStatementHandler))).Methods("GET")
+
+	// swagger:operation POST /api/v1/cards/{owner_id}/{id}/statements/{period}/payment Cards pay statement
+	//
+	// Marks a closed statement as paid, posting its total as a payment spend to the current month balance.
+	// Payments are not accounted as outcome, since the statement spends already were
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: card id
+	//   required: true
+	// - name: period
+	//   in: period
+	//   description: month the statement closes at, as YYYY-MM
+	//   required: true
+	// responses:
+	//   '201':
+	//     description: statement payment
+	//     schema:
+	//       "$ref": "#/definitions/StatementPayment"
+	//   '400':
+	//     description: invalid period
+	//     examples:
+	//       application/json: { "message": "could not pay statement", "details": "period must be given as YYYY-MM" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: card not found
+	//     examples:
+	//       application/json: { "message": "could not pay statement", "details": "could not find card" }
+	//     type: json
+	//   '409':
+	//     description: statement is still open, already paid or has nothing to be paid
+	//     examples:
+	//       application/json: { "message": "could not pay statement", "details": "statement already paid" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not pay statement", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/cards/{owner_id}/{id}/statements/{period}/payment", m.JSON(m.Auth(h.PayStatementHandler))).Methods("POST")
+
+	// swagger:operation DELETE /api/v1/cards/{owner_id}/{id}/statements/{period}/payment Cards revert statement payment
+	//
+	// Marks a statement as unpaid again, deleting its payment spend from the balance
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: card id
+	//   required: true
+	// - name: period
+	//   in: period
+	//   description: month the statement closes at, as YYYY-MM
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: reverted statement payment
+	//     examples:
+	//       application/json: { "message": "reverted payment of statement '<PERIOD>'" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: statement was not paid
+	//     examples:
+	//       application/json: { "message": "could not revert statement payment", "details": "could not find statement payment" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not revert statement payment", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/cards/{owner_id}/{id}/statements/{period}/payment", m.JSON(m.Auth(h.RevertStatementPaymentHandler))).Methods("DELETE")
+
+	// swagger:operation POST /api/v1/categories Categories create
+	//
+	// Creates a single category, optionally nested under a parent one. Names are unique per owner regardless of case
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: category payload
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/Category"
+	// responses:
+	//   '201':
+	//     description: created category
+	//     examples:
+	//       application/json: { "message": "created category '<CATEGORY_NAME>'", "id": "<CATEGORY_ID>" }
+	//     type: json
+	//   '400':
+	//     description: invalid name, color, icon or parent
+	//     examples:
+	//       application/json: { "message": "could not create category", "details": "invalid category: color must be given as #RRGGBB" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '409':
+	//     description: category already exists
+	//     examples:
+	//       application/json: { "message": "could not create category", "details": "category already exists" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not create category", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/categories", m.JSON(m.Auth(h.CreateCategoryHandler))).Methods("POST")
+
+	// swagger:operation GET /api/v1/categories/{owner_id} Categories list
+	//
+	// List all categories from a given owner, sorted by name
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: category response
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/Category"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
 	//   '500':
 	//     description: internal server error
 	//     examples:
 	//       application/json: {"message": "<ERROR_DETAILS>"}
 	//     type: json
-	router.Handle("/api/v1/spends/{owner_id}", m.JSON(m.Auth(h.GetSpendsHandler))).Methods("GET")
+	router.Handle("/api/v1/categories/{owner_id}", m.JSON(m.Auth(h.GetCategoriesHandler))).Methods("GET")
+
+	// swagger:operation GET /api/v1/categories/{owner_id}/{id} Categories get
+	//
+	// Returns a single category from a given owner
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: category id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: category response
+	//     schema:
+	//       "$ref": "#/definitions/Category"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: category not found
+	//     examples:
+	//       application/json: { "message": "could not get category", "details": "could not find category" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not get category", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/categories/{owner_id}/{id}", m.JSON(m.Auth(h.GetCategoryHandler))).Methods("GET")
+
+	// swagger:operation PATCH /api/v1/categories/{owner_id}/{id} Categories patch
+	//
+	// Partially updates a category. Renaming it rewrites every spend, recurrence, budget and rule using the
+	// former name, and "parent_id": "" moves it to the top level
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: category id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: category attributes to be changed
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/Category"
+	// responses:
+	//   '200':
+	//     description: updated category
+	//     schema:
+	//       "$ref": "#/definitions/Category"
+	//   '400':
+	//     description: invalid name, color, icon or parent
+	//     examples:
+	//       application/json: { "message": "could not update category", "details": "invalid category: it can not be nested under itself or its subcategories" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: category not found
+	//     examples:
+	//       application/json: { "message": "could not update category", "details": "could not find category" }
+	//     type: json
+	//   '409':
+	//     description: another category already has the given name
+	//     examples:
+	//       application/json: { "message": "could not update category", "details": "category already exists" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not update category", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/categories/{owner_id}/{id}", m.JSON(m.Auth(h.PatchCategoryHandler))).Methods("PATCH")
+
+	// swagger:operation POST /api/v1/categories/{owner_id}/{id}/merge Categories merge
+	//
+	// Merges a category into another one: its spends, recurrences, budgets, rules and subcategories are moved
+	// to the target category and it is deleted
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: id of the category to be merged
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: id of the category to merge into, as { "into": "<CATEGORY_ID>" }
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: category merged into
+	//     schema:
+	//       "$ref": "#/definitions/Category"
+	//   '400':
+	//     description: missing or invalid target category
+	//     examples:
+	//       application/json: { "message": "could not merge category", "details": "invalid category: it can not be merged into itself" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: category not found
+	//     examples:
+	//       application/json: { "message": "could not merge category", "details": "could not find category" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not merge category", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/categories/{owner_id}/{id}/merge", m.JSON(m.Auth(h.MergeCategoryHandler))).Methods("POST")
+
+	// swagger:operation DELETE /api/v1/categories/{owner_id}/{id} Categories delete
+	//
+	// Deletes a single category from a given owner. Categories in use must be merged instead, and the ones with
+	// subcategories must have them moved or deleted first
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: category id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: deleted category
+	//     examples:
+	//       application/json: { "message": "deleted category '<CATEGORY_ID>'" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: category not found
+	//     examples:
+	//       application/json: { "message": "could not delete category", "details": "could not find category" }
+	//     type: json
+	//   '409':
+	//     description: category is in use or has subcategories
+	//     examples:
+	//       application/json: { "message": "could not delete category", "details": "category is used by spends, recurrences, budgets or rules and can only be merged" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not delete category", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/categories/{owner_id}/{id}", m.JSON(m.Auth(h.DeleteCategoryHandler))).Methods("DELETE")
+
+	// swagger:operation POST /api/v1/rules Rules create
+	//
+	// Creates a single category rule. Spends created without categories are given the categories of the first
+	// rule, by ascending priority, whose every condition they meet, along with its type when given none
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: category rule payload
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CategoryRule"
+	// responses:
+	//   '201':
+	//     description: created category rule
+	//     examples:
+	//       application/json: { "message": "created category rule", "id": "<RULE_ID>" }
+	//     type: json
+	//   '400':
+	//     description: invalid conditions, categories or type
+	//     examples:
+	//       application/json: { "message": "could not create category rule", "details": "invalid category rule: at least one condition is required" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not create category rule", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/rules", m.JSON(m.Auth(h.CreateCategoryRuleHandler))).Methods("POST")
+
+	// swagger:operation GET /api/v1/rules/{owner_id} Rules list
+	//
+	// List all category rules from a given owner, in the order they are evaluated
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: category rule response
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/CategoryRule"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: {"message": "<ERROR_DETAILS>"}
+	//     type: json
+	router.Handle("/api/v1/rules/{owner_id}", m.JSON(m.Auth(h.GetCategoryRulesHandler))).Methods("GET")
+
+	// swagger:operation POST /api/v1/rules/{owner_id}/dry-run Rules dry-run
+	//
+	// Returns which of the latest spends from a given owner would be matched by its category rules, along with
+	// the categories and type they would be given, without changing them. A rule given at the payload is
+	// evaluated alone instead, without being stored
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: last
+	//   in: query
+	//   description: how many of the latest spends are evaluated, from 1 to 200 (defaults to 50)
+	//   required: false
+	// - name: body
+	//   in: body
+	//   description: category rule to be evaluated instead of the stored ones
+	//   required: false
+	//   schema:
+	//     "$ref": "#/definitions/CategoryRule"
+	// responses:
+	//   '200':
+	//     description: dry-run response
+	//     schema:
+	//       "$ref": "#/definitions/CategoryRuleDryRun"
+	//   '400':
+	//     description: invalid number of spends or rule
+	//     examples:
+	//       application/json: { "message": "could not dry-run category rules", "details": "last must be between 1 and 200" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not dry-run category rules", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/rules/{owner_id}/dry-run", m.JSON(m.Auth(h.DryRunCategoryRulesHandler))).Methods("POST")
+
+	// swagger:operation GET /api/v1/rules/{owner_id}/{id} Rules get
+	//
+	// Returns a single category rule from a given owner
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: category rule id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: category rule response
+	//     schema:
+	//       "$ref": "#/definitions/CategoryRule"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: category rule not found
+	//     examples:
+	//       application/json: { "message": "could not get category rule", "details": "could not find category rule" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not get category rule", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/rules/{owner_id}/{id}", m.JSON(m.Auth(h.GetCategoryRuleHandler))).Methods("GET")
+
+	// swagger:operation PATCH /api/v1/rules/{owner_id}/{id} Rules patch
+	//
+	// Partially updates a category rule. Optional conditions are removed with null, or "" for card_id
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: category rule id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: category rule attributes to be changed
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/CategoryRule"
+	// responses:
+	//   '200':
+	//     description: updated category rule
+	//     schema:
+	//       "$ref": "#/definitions/CategoryRule"
+	//   '400':
+	//     description: invalid conditions, categories or type
+	//     examples:
+	//       application/json: { "message": "could not update category rule", "details": "invalid category rule: pattern is not a valid regular expression" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: category rule not found
+	//     examples:
+	//       application/json: { "message": "could not update category rule", "details": "could not find category rule" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not update category rule", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/rules/{owner_id}/{id}", m.JSON(m.Auth(h.PatchCategoryRuleHandler))).Methods("PATCH")
+
+	// swagger:operation DELETE /api/v1/rules/{owner_id}/{id} Rules delete
+	//
+	// Deletes a single category rule from a given owner
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: category rule id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: deleted category rule
+	//     examples:
+	//       application/json: { "message": "deleted category rule '<RULE_ID>'" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: category rule not found
+	//     examples:
+	//       application/json: { "message": "could not delete category rule", "details": "could not find category rule" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not delete category rule", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/rules/{owner_id}/{id}", m.JSON(m.Auth(h.DeleteCategoryRuleHandler))).Methods("DELETE")
+
+	// swagger:operation POST /api/v1/balance Balance create
+	//
+	// Creates a single balance for a given owner
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: balance owner_id
+	//   required: true
+	// responses:
+	//   '201':
+	//     description: deleted user
+	//     examples:
+	//       application/json: { "message": "created balance", "id": "<BALANCE_ID>" }
+	//     type: json
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: {"message": "could not create balance", "details": "balances must have an 'owner_id'"}
+	//     type: json
+	//   '409':
+	//     description: balance already exists
+	//     examples:
+	//       application/json: { "message": "could not create balance", "details": "balance already exists" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not delete balance", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/balance", m.JSON(m.Auth(h.CreateBalanceHandler))).Methods("POST")
+
+	// swagger:operation GET /api/v1/balance/{owner_id} Balance list
+	//
+	// List balances from a given owner sorted chronologically, or a single one given a month and year as query params
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: month
+	//   in: query
+	//   description: month (requires year)
+	// - name: year
+	//   in: query
+	//   description: year (without month it lists all balances from that year)
+	// - name: from
+	//   in: query
+	//   description: inclusive start period as YYYY-MM
+	// - name: to
+	//   in: query
+	//   description: inclusive end period as YYYY-MM
+	// responses:
+	//   '200':
+	//     description: balance response
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/Balance"
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: {"message": "could not list balances", "details": "'from' must be a period as YYYY-MM"}
+	//     type: json
+	//   '404':
+	//     description: balance not found
+	//     examples:
+	//       application/json: []
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: {"message": "<ERROR_DETAILS>"}
+	//     type: json
+	router.Handle("/api/v1/balance/{owner_id}", m.JSON(m.Auth(h.GetBalanceHandler))).Methods("GET")
+
+	// swagger:operation PUT /api/v1/balance/{owner_id}/{id} Balance update
+	//
+	// Replaces the income, currency and category budgets of a balance, recomputing its spendable amount
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: balance id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: balance income, currency and budgets
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/Balance"
+	// responses:
+	//   '200':
+	//     description: updated balance
+	//     schema:
+	//       "$ref": "#/definitions/Balance"
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: {"message": "could not update balance", "details": "net income can not be greater than gross income"}
+	//     type: json
+	//   '404':
+	//     description: balance not found
+	//     examples:
+	//       application/json: {"message": "could not update balance", "details": "could not find balance"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not update balance", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/balance/{owner_id}/{id}", m.JSON(m.Auth(h.UpdateBalanceHandler))).Methods("PUT")
+
+	// swagger:operation PATCH /api/v1/balance/{owner_id}/{id} Balance patch
+	//
+	// Partially updates the income, currency and category budgets of a balance, recomputing its spendable amount
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: balance id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: balance income, currency and budgets
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/Balance"
+	// responses:
+	//   '200':
+	//     description: updated balance
+	//     schema:
+	//       "$ref": "#/definitions/Balance"
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: {"message": "could not update balance", "details": "net income can not be greater than gross income"}
+	//     type: json
+	//   '404':
+	//     description: balance not found
+	//     examples:
+	//       application/json: {"message": "could not update balance", "details": "could not find balance"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not update balance", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/balance/{owner_id}/{id}", m.JSON(m.Auth(h.PatchBalanceHandler))).Methods("PATCH")
+
+	// swagger:operation DELETE /api/v1/balance/{owner_id}/{id} Balance delete
+	//
+	// Deletes a single balance from a given owner
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: balance id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: deleted balance
+	//     examples:
+	//       application/json: { "message": "deleted balance '<BALANCE_ID>'" }
+	//     type: json
+	//   '404':
+	//     description: balance not found
+	//     examples:
+	//       application/json: {"message": "could not delete balance", "details": "could not find balance"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not delete balance", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/balance/{owner_id}/{id}", m.JSON(m.Auth(h.DeleteBalanceHandler))).Methods("DELETE")
+
+	// swagger:operation POST /api/v1/balance/{owner_id}/{id}/reconcile Balance reconcile
+	//
+	// Reconciles a single balance from a given owner with the spends stored for its month, fixing what a write
+	// interrupted between the spend and the balance left behind
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: balance id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: reconciled balance
+	//     schema:
+	//       "$ref": "#/definitions/Balance"
+	//   '404':
+	//     description: balance not found
+	//     examples:
+	//       application/json: {"message": "could not reconcile balance", "details": "could not find balance"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not reconcile balance", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/balance/{owner_id}/{id}/reconcile", m.JSON(m.Auth(h.ReconcileBalanceHandler))).Methods("POST")
+
+	// swagger:operation GET /api/v1/balance/{owner_id}/commitments Balance commitments
+	//
+	// Get the outcome each month is already committed to, by installments and by active recurrences still to be materialized.
+	// Defaults to the next 12 months
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: from
+	//   in: query
+	//   description: first month, as YYYY-MM
+	//   required: false
+	// - name: to
+	//   in: query
+	//   description: last month, as YYYY-MM
+	//   required: false
+	// - name: year
+	//   in: query
+	//   description: every month of a year
+	//   required: false
+	// responses:
+	//   '200':
+	//     description: commitments per month
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/Commitment"
+	//   '400':
+	//     description: invalid period
+	//     examples:
+	//       application/json: { "message": "could not list commitments", "details": "'from' must be a period as YYYY-MM" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/balance/{owner_id}/commitments", m.JSON(m.Auth(h.GetCommitmentsHandler))).Methods("GET")
+
+	// swagger:operation GET /api/v1/balance/{owner_id}/budgets/{period} Balance budgets
+	//
+	// Get how much was spent, and how much remains, on each budgeted category of a month balance, along with the
+	// 80% and 100% thresholds its spends reached
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: period
+	//   in: period
+	//   description: balance month, as YYYY-MM
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: budgets report
+	//     schema:
+	//       "$ref": "#/definitions/BudgetReport"
+	//   '400':
+	//     description: invalid period
+	//     examples:
+	//       application/json: { "message": "could not get budgets", "details": "period must be given as YYYY-MM" }
+	//     type: json
+	//   '404':
+	//     description: balance not found
+	//     examples:
+	//       application/json: { "message": "could not get budgets", "details": "could not find balance" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not get budgets", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/balance/{owner_id}/budgets/{period}", m.JSON(m.Auth(h.GetBudgetsHandler))).Methods("GET")
+
+	// swagger:operation GET /api/v1/reports/{owner_id}/monthly/{period} Reports monthly
+	//
+	// Get how much an owner spent within a month, in total and by category, payment method, card and type, each one
+	// compared against the previous month. Statement payments are not accounted, and spends with multiple categories
+	// count towards each of them
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: period
+	//   in: period
+	//   description: report month, as YYYY-MM
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: spending report
+	//     schema:
+	//       "$ref": "#/definitions/SpendReport"
+	//   '400':
+	//     description: invalid period
+	//     examples:
+	//       application/json: { "message": "could not get report", "details": "period must be given as YYYY-MM" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not get report", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/reports/{owner_id}/monthly/{period}", m.JSON(m.Auth(h.GetMonthlyReportHandler))).Methods("GET")
+
+	// swagger:operation GET /api/v1/reports/{owner_id}/yearly/{year} Reports yearly
+	//
+	// Get how much an owner spent within a year, in total and by category, payment method, card and type, each one
+	// compared against the previous year, along with the totals of every month compared against the month before it
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: year
+	//   in: year
+	//   description: report year, as YYYY
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: spending report
+	//     schema:
+	//       "$ref": "#/definitions/SpendReport"
+	//   '400':
+	//     description: invalid year
+	//     examples:
+	//       application/json: { "message": "could not get report", "details": "year must be given as YYYY" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not get report", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/reports/{owner_id}/yearly/{year}", m.JSON(m.Auth(h.GetYearlyReportHandler))).Methods("GET")
+
+	// swagger:operation POST /api/v1/imports/{owner_id}/preview Imports preview
+	//
+	// Reads the transactions of a CSV or OFX bank statement as spends of a given owner, categorized by its rules, without
+	// creating them. Transactions whose date, cost and description match an existing spend are reported as duplicates,
+	// and the ones which are not spends, such as incomes, as skipped. The token returned must be given to commit them
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: bank statement
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/SpendImport"
+	// responses:
+	//   '200':
+	//     description: every transaction of the statement
+	//     schema:
+	//       "$ref": "#/definitions/SpendImportResult"
+	//   '400':
+	//     description: invalid statement, mapping or payment method
+	//     examples:
+	//       application/json: { "message": "could not import spends", "details": "invalid import: CSV statement has no column 'Amount'" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not import spends", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/imports/{owner_id}/preview", m.JSON(m.Auth(h.PreviewImportHandler))).Methods("POST")
+
+	// swagger:operation POST /api/v1/imports/{owner_id}/commit Imports commit
+	//
+	// Creates the transactions of a CSV or OFX bank statement which are not duplicates of existing spends as spends of a
+	// given owner, reporting every transaction as its preview does. Commits require the token of their preview and are
+	// refused when the spends imported changed since then. Committing the same statement again creates nothing
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: bank statement
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/SpendImport"
+	// responses:
+	//   '200':
+	//     description: every transaction of the statement, along with the spends created
+	//     schema:
+	//       "$ref": "#/definitions/SpendImportResult"
+	//   '400':
+	//     description: invalid statement, mapping, payment method or token
+	//     examples:
+	//       application/json: { "message": "could not import spends", "details": "invalid import: the spends imported changed since its preview, which must be made again" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not import spends", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/imports/{owner_id}/commit", m.JSON(m.Auth(h.CommitImportHandler))).Methods("POST")
+
+	// swagger:operation POST /api/v1/spends Spends create
+	//
+	// Creates a single spend for a given owner. Credit card purchases with 'installments' create one spend per month instead,
+	// and spends without categories are categorized by the category rules of their owner
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: spend owner_id
+	//   required: true
+	// responses:
+	//   '201':
+	//     description: deleted user
+	//     examples:
+	//       application/json: { "message": "created spend to user '<OWNER_ID>'", "id": "<SPEND_ID>"}
+	//     type: json
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: {"message": "could not create spend", "details": "installments require a credit card payment method"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not create spend", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/spends", m.JSON(m.Auth(h.CreateSpendHandler))).Methods("POST")
+
+	// swagger:operation GET /api/v1/spends/{owner_id} Spends list
+	//
+	// Get a page of spends for a given owner id, sorted by date. The next page cursor is returned at the 'X-Next-Cursor' header
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	// - name: from
+	//   in: query
+	//   description: inclusive start date (YYYY-MM-DD or RFC3339)
+	// - name: to
+	//   in: query
+	//   description: inclusive end date (YYYY-MM-DD or RFC3339)
+	// - name: type
+	//   in: query
+	//   description: fixed or dynamic
+	// - name: category
+	//   in: query
+	//   description: spend category
+	// - name: payment_method
+	//   in: query
+	//   description: debit, credit or payment_slip
+	// - name: card_id
+	//   in: query
+	//   description: credit card id
+	// - name: min_cost
+	//   in: query
+	//   description: minimum cost
+	// - name: max_cost
+	//   in: query
+	//   description: maximum cost
+	// - name: q
+	//   in: query
+	//   description: text contained in the description
+	// - name: limit
+	//   in: query
+	//   description: page size (default 50, max 200)
+	// - name: cursor
+	//   in: query
+	//   description: cursor returned by the previous page
+	// - name: sort
+	//   in: query
+	//   description: asc or desc (default)
+	// responses:
+	//   '200':
+	//     description: spends response
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/Spend"
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: {"message": "could not list spends", "details": "sort must be either 'asc' or 'desc'"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: {"message": "<ERROR_DETAILS>"}
+	//     type: json
+	router.Handle("/api/v1/spends/{owner_id}", m.JSON(m.Auth(h.GetSpendsHandler))).Methods("GET")
+
+	// swagger:operation GET /api/v1/spends/{owner_id}/{id} Spends get
+	//
+	// Get a single spend for a given owner id
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: spend id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: spend response
+	//     schema:
+	//       "$ref": "#/definitions/Spend"
+	//   '404':
+	//     description: spend not found
+	//     examples:
+	//       application/json: { "message": "could not find spend", "id": "<SPEND_ID>" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: {"message": "<ERROR_DETAILS>"}
+	//     type: json
+	router.Handle("/api/v1/spends/{owner_id}/{id}", m.JSON(m.Auth(h.GetSpendHandler))).Methods("GET")
+
+	// swagger:operation PUT /api/v1/spends/{owner_id}/{id} Spends update
+	//
+	// Replaces a single spend for a given owner id, reflecting it at the month balance
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: spend id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: spend payload
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/Spend"
+	// responses:
+	//   '200':
+	//     description: updated spend
+	//     schema:
+	//       "$ref": "#/definitions/Spend"
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: {"message": "could not update spend", "details": "malformed payload"}
+	//     type: json
+	//   '404':
+	//     description: spend not found
+	//     examples:
+	//       application/json: {"message": "could not update spend", "details": "could not find spend"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not update spend", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/spends/{owner_id}/{id}", m.JSON(m.Auth(h.UpdateSpendHandler))).Methods("PUT")
+
+	// swagger:operation PATCH /api/v1/spends/{owner_id}/{id} Spends patch
+	//
+	// Partially updates a single spend for a given owner id, reflecting it at the month balance
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: spend id
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: spend attributes to be changed
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/Spend"
+	// responses:
+	//   '200':
+	//     description: updated spend
+	//     schema:
+	//       "$ref": "#/definitions/Spend"
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: {"message": "could not update spend", "details": "malformed payload"}
+	//     type: json
+	//   '404':
+	//     description: spend not found
+	//     examples:
+	//       application/json: {"message": "could not update spend", "details": "could not find spend"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not update spend", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/spends/{owner_id}/{id}", m.JSON(m.Auth(h.PatchSpendHandler))).Methods("PATCH")
+
+	// swagger:operation DELETE /api/v1/spends/{owner_id}/{id} Spends delete
+	//
+	// Deletes a single spend for a given owner id, removing it from the month balance
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: spend id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: deleted spend
+	//     examples:
+	//       application/json: { "message": "deleted spend '<SPEND_ID>'" }
+	//     type: json
+	//   '404':
+	//     description: spend not found
+	//     examples:
+	//       application/json: {"message": "could not delete spend", "details": "could not find spend"}
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not delete spend", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/spends/{owner_id}/{id}", m.JSON(m.Auth(h.DeleteSpendHandler))).Methods("DELETE")
+
+	// swagger:operation POST /api/v1/recurrences Recurrences create
+	//
+	// Creates a recurring spend for a given owner, materialized as a spend into the month balance on every run
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: recurrence
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/Recurrence"
+	// responses:
+	//   '201':
+	//     description: created recurrence
+	//     examples:
+	//       application/json: { "message": "created recurrence", "owner_id": "<OWNER_ID>", "id": "<RECURRENCE_ID>", "created": 12, "pending": 3 }
+	//     type: json
+	//   '400':
+	//     description: bad request
+	//     examples:
+	//       application/json: { "message": "could not create recurrence", "details": "invalid recurrence: cadence must be one of 'weekly', 'monthly' or 'yearly'" }
+	//     type: json
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not create recurrence", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/recurrences", m.JSON(m.Auth(h.CreateRecurrenceHandler))).Methods("POST")
+
+	// swagger:operation GET /api/v1/recurrences/{owner_id} Recurrences list
+	//
+	// Get every recurring spend for a given owner id, sorted by their next run
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: recurrences
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/Recurrence"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/recurrences/{owner_id}", m.JSON(m.Auth(h.GetRecurrencesHandler))).Methods("GET")
+
+	// swagger:operation PUT /api/v1/recurrences/{owner_id}/{id}/pause Recurrences pause
+	//
+	// Pauses an active recurrence, so no spends are materialized until it's resumed
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: recurrence id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: updated recurrence
+	//     schema:
+	//       "$ref": "#/definitions/Recurrence"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: recurrence not found
+	//     examples:
+	//       application/json: { "message": "could not pause recurrence", "details": "could not find recurrence" }
+	//     type: json
+	//   '409':
+	//     description: recurrence status does not allow it
+	//     examples:
+	//       application/json: { "message": "could not pause recurrence", "details": "recurrence is paused and can not be paused" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not pause recurrence", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/recurrences/{owner_id}/{id}/pause", m.JSON(m.Auth(h.PauseRecurrenceHandler))).Methods("PUT")
+
+	// swagger:operation DELETE /api/v1/recurrences/{owner_id}/{id}/pause Recurrences resume
+	//
+	// Resumes a paused recurrence, skipping the runs missed while paused
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: recurrence id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: updated recurrence
+	//     schema:
+	//       "$ref": "#/definitions/Recurrence"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: recurrence not found
+	//     examples:
+	//       application/json: { "message": "could not resume recurrence", "details": "could not find recurrence" }
+	//     type: json
+	//   '409':
+	//     description: recurrence status does not allow it
+	//     examples:
+	//       application/json: { "message": "could not resume recurrence", "details": "recurrence is active and can not be active" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not resume recurrence", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/recurrences/{owner_id}/{id}/pause", m.JSON(m.Auth(h.ResumeRecurrenceHandler))).Methods("DELETE")
+
+	// swagger:operation DELETE /api/v1/recurrences/{owner_id}/{id} Recurrences cancel
+	//
+	// Cancels a recurrence for good, keeping the spends it already materialized
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: owner_id
+	//   in: owner_id
+	//   description: owner id
+	//   required: true
+	// - name: id
+	//   in: id
+	//   description: recurrence id
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: updated recurrence
+	//     schema:
+	//       "$ref": "#/definitions/Recurrence"
+	//   '403':
+	//     description: resource does not belong to the authenticated user
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "resource does not belong to the authenticated user" }
+	//     type: json
+	//   '404':
+	//     description: recurrence not found
+	//     examples:
+	//       application/json: { "message": "could not cancel recurrence", "details": "could not find recurrence" }
+	//     type: json
+	//   '409':
+	//     description: recurrence status does not allow it
+	//     examples:
+	//       application/json: { "message": "could not cancel recurrence", "details": "recurrence is cancelled and can not be cancelled" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not cancel recurrence", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/recurrences/{owner_id}/{id}", m.JSON(m.Auth(h.CancelRecurrenceHandler))).Methods("DELETE")
+
+	// swagger:operation PUT /api/v1/exchange-rates ExchangeRates upsert
+	//
+	// Creates or replaces daily exchange rates, used to convert spends into their balance currency (admin only)
+	// ---
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: list of exchange rates, one per currency pair and day
+	//   required: true
+	//   schema:
+	//     type: array
+	//     items:
+	//       "$ref": "#/definitions/ExchangeRate"
+	// responses:
+	//   '200':
+	//     description: stored exchange rates
+	//     examples:
+	//       application/json: { "message": "stored exchange rates", "count": 1 }
+	//     type: json
+	//   '400':
+	//     description: malformed payload or invalid exchange rate
+	//     examples:
+	//       application/json: { "message": "could not store exchange rates", "details": "exchange rate 1: unknown ISO-4217 currency 'XXX'" }
+	//     type: json
+	//   '403':
+	//     description: missing admin role
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "missing required role 'admin'" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not store exchange rates", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/exchange-rates", m.JSON(m.Auth(m.Admin(h.UpsertExchangeRatesHandler)))).Methods("PUT")
+
+	// swagger:operation POST /api/v1/exchange-rates/import ExchangeRates import
+	//
+	// Creates or replaces daily exchange rates from CSV lines formatted as 'date,base,quote,rate' (admin only)
+	// ---
+	// consumes:
+	// - text/csv
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   description: "CSV with an optional header, e.g. 2021-06-01,USD,BRL,5.0321"
+	//   required: true
+	// responses:
+	//   '200':
+	//     description: stored exchange rates
+	//     examples:
+	//       application/json: { "message": "stored exchange rates", "count": 30 }
+	//     type: json
+	//   '400':
+	//     description: malformed CSV or invalid exchange rate
+	//     examples:
+	//       application/json: { "message": "malformed payload", "details": "line 2: rate must be a decimal number" }
+	//     type: json
+	//   '403':
+	//     description: missing admin role
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "missing required role 'admin'" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "could not store exchange rates", "details": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/exchange-rates/import", m.JSON(m.Auth(m.Admin(h.ImportExchangeRatesHandler)))).Methods("POST")
+
+	// swagger:operation GET /api/v1/exchange-rates ExchangeRates list
+	//
+	// Returns the daily exchange rates sorted by date (admin only)
+	// ---
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: content-type
+	//   in: headers
+	//   description: application/json
+	//   required: true
+	// - name: base
+	//   in: query
+	//   description: ISO-4217 currency converted from
+	//   required: false
+	// - name: quote
+	//   in: query
+	//   description: ISO-4217 currency converted into
+	//   required: false
+	// - name: from
+	//   in: query
+	//   description: first day, as YYYY-MM-DD or RFC3339
+	//   required: false
+	// - name: to
+	//   in: query
+	//   description: last day, as YYYY-MM-DD or RFC3339
+	//   required: false
+	// responses:
+	//   '200':
+	//     description: exchange rates
+	//     schema:
+	//       type: array
+	//       items:
+	//         "$ref": "#/definitions/ExchangeRate"
+	//   '400':
+	//     description: invalid query params
+	//     examples:
+	//       application/json: { "message": "could not list exchange rates", "details": "unknown ISO-4217 currency 'XXX'" }
+	//     type: json
+	//   '403':
+	//     description: missing admin role
+	//     examples:
+	//       application/json: { "message": "could not authorize", "details": "missing required role 'admin'" }
+	//     type: json
+	//   '500':
+	//     description: internal server error
+	//     examples:
+	//       application/json: { "message": "<ERROR_DETAILS>" }
+	//     type: json
+	router.Handle("/api/v1/exchange-rates", m.JSON(m.Auth(m.Admin(h.GetExchangeRatesHandler)))).Methods("GET")
 }
@@ -2,12 +2,36 @@
 package routes
 
 import (
+	"budget-tracker-api/auth"
+	"budget-tracker-api/controllers"
 	"budget-tracker-api/handlers"
+	"budget-tracker-api/models"
+	"budget-tracker-api/observability"
+	"budget-tracker-api/repository"
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// TestMain will run every test against in-memory repositories, so no database is needed
+func TestMain(m *testing.M) {
+	models.UseRepositories(repository.NewMemoryRepositories())
+	observability.InitMetrics()
+
+	os.Exit(m.Run())
+}
+
 func TestHealthCheckHandler(t *testing.T) {
 	t.Log("here")
 	// m := handlers.GetMiddlewares()
@@ -41,3 +65,2242 @@ func TestHealthCheckHandler(t *testing.T) {
 			rr.Body.String(), expected)
 	}
 }
+
+func TestAuthHandlerRejectsUnauthenticatedRequests(t *testing.T) {
+	m := handlers.GetMiddlewares()
+	h := handlers.GetHandlers()
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"missing header", "", http.StatusBadRequest},
+		{"mistyped bearer", "Token abc", http.StatusUnauthorized},
+		{"invalid token", "Bearer abc.def.ghi", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/v1/cards/5143afc66d44e1ceb372121e", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rr := httptest.NewRecorder()
+			m.Auth(h.GetCardsHandler).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestOwnerScopedHandlersForbidOtherOwners(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	principal := auth.Principal{Subject: "5143afc66d44e1ceb372121e", Login: "admin"}
+	otherOwner := "60a1b2c3d4e5f60718293a4b"
+	spendID := "60a1b2c3d4e5f60718293a4c"
+
+	tests := []struct {
+		name    string
+		method  string
+		handler http.Handler
+		vars    map[string]string
+		body    string
+	}{
+		{"get user", "GET", h.GetUserHandler, map[string]string{"id": otherOwner}, ""},
+		{"delete user", "DELETE", h.DeleteUserHandler, map[string]string{"id": otherOwner}, ""},
+		{"revoke user sessions", "DELETE", h.RevokeUserSessionsHandler, map[string]string{"id": otherOwner}, ""},
+		{"create card", "POST", h.CreateCardHandler, nil, `{"owner_id": "` + otherOwner + `", "network": "visa"}`},
+		{"get cards", "GET", h.GetCardsHandler, map[string]string{"owner_id": otherOwner}, ""},
+		{"get card", "GET", h.GetCardHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+		{"patch card", "PATCH", h.PatchCardHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, `{}`},
+		{"delete card", "DELETE", h.DeleteCardHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+		{"create balance", "POST", h.CreateBalanceHandler, nil, `{"owner_id": "` + otherOwner + `"}`},
+		{"get balance", "GET", h.GetBalanceHandler, map[string]string{"owner_id": otherOwner}, ""},
+		{"update balance", "PUT", h.UpdateBalanceHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, `{}`},
+		{"patch balance", "PATCH", h.PatchBalanceHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, `{}`},
+		{"delete balance", "DELETE", h.DeleteBalanceHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+		{"get budgets", "GET", h.GetBudgetsHandler, map[string]string{"owner_id": otherOwner, "period": "2021-05"}, ""},
+		{"create category", "POST", h.CreateCategoryHandler, nil, `{"owner_id": "` + otherOwner + `", "name": "food"}`},
+		{"get categories", "GET", h.GetCategoriesHandler, map[string]string{"owner_id": otherOwner}, ""},
+		{"get category", "GET", h.GetCategoryHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+		{"patch category", "PATCH", h.PatchCategoryHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, `{}`},
+		{"merge category", "POST", h.MergeCategoryHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, `{"into": "` + spendID + `"}`},
+		{"delete category", "DELETE", h.DeleteCategoryHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+		{"create category rule", "POST", h.CreateCategoryRuleHandler, nil, `{"owner_id": "` + otherOwner + `", "description": "uber"}`},
+		{"get category rules", "GET", h.GetCategoryRulesHandler, map[string]string{"owner_id": otherOwner}, ""},
+		{"get category rule", "GET", h.GetCategoryRuleHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+		{"patch category rule", "PATCH", h.PatchCategoryRuleHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, `{}`},
+		{"delete category rule", "DELETE", h.DeleteCategoryRuleHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+		{"dry-run category rules", "POST", h.DryRunCategoryRulesHandler, map[string]string{"owner_id": otherOwner}, ""},
+		{"get monthly report", "GET", h.GetMonthlyReportHandler, map[string]string{"owner_id": otherOwner, "period": "2021-05"}, ""},
+		{"get yearly report", "GET", h.GetYearlyReportHandler, map[string]string{"owner_id": otherOwner, "year": "2021"}, ""},
+		{"preview import", "POST", h.PreviewImportHandler, map[string]string{"owner_id": otherOwner}, `{}`},
+		{"commit import", "POST", h.CommitImportHandler, map[string]string{"owner_id": otherOwner}, `{}`},
+		{"create spend", "POST", h.CreateSpendHandler, nil, `{"owner_id": "` + otherOwner + `", "cost": 10}`},
+		{"get spends", "GET", h.GetSpendsHandler, map[string]string{"owner_id": otherOwner}, ""},
+		{"get spend", "GET", h.GetSpendHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+		{"update spend", "PUT", h.UpdateSpendHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, `{}`},
+		{"patch spend", "PATCH", h.PatchSpendHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, `{}`},
+		{"delete spend", "DELETE", h.DeleteSpendHandler, map[string]string{"owner_id": otherOwner, "id": spendID}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req = mux.SetURLVars(req, tt.vars)
+			req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusForbidden {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestOwnerScopedHandlersRequirePrincipal(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = mux.SetURLVars(req, map[string]string{"owner_id": "5143afc66d44e1ceb372121e"})
+
+	rr := httptest.NewRecorder()
+	h.GetSpendsHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
+	}
+}
+
+func TestAdminHandlerRequiresAdminRole(t *testing.T) {
+	m := handlers.GetMiddlewares()
+
+	next := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		status    int
+	}{
+		{"unauthenticated", nil, http.StatusUnauthorized},
+		{"regular user", &auth.Principal{Subject: "60a1b2c3d4e5f60718293a4b"}, http.StatusForbidden},
+		{"admin", &auth.Principal{Subject: "5143afc66d44e1ceb372121e", Roles: []string{auth.RoleAdmin}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/v1/users", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), *tt.principal))
+			}
+
+			rr := httptest.NewRecorder()
+			m.Admin(next).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestAuthHandlerRejectsRefreshTokens(t *testing.T) {
+	m := handlers.GetMiddlewares()
+	h := handlers.GetHandlers()
+
+	refreshToken, err := controllers.GenerateJWTRefreshToken("5143afc66d44e1ceb372121e", "60a1b2c3d4e5f60718293a4c", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/cards/5143afc66d44e1ceb372121e", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+refreshToken)
+
+	rr := httptest.NewRecorder()
+	m.Auth(h.GetCardsHandler).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
+	}
+}
+
+func TestSpendLifecycleUpdatesBalance(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	ownerID := "60b1c2d3e4f5a60718293a4b"
+	principal := auth.Principal{Subject: ownerID}
+
+	req, err := http.NewRequest("POST", "/api/v1/spends", strings.NewReader(
+		`{"owner_id": "`+ownerID+`", "type": "fixed", "description": "guitar lessons", "cost": 12.9, "date": "2021-05-10T00:00:00Z"}`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	rr := httptest.NewRecorder()
+	h.CreateSpendHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusCreated, rr.Body.String())
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := models.GetBalance(context.Background(), ownerID, 5, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(balance.Historic) != 1 || balance.Outcome.FixedOutcome != repository.Money(12900) || balance.SpendableAmount != repository.Money(-12900) {
+		t.Errorf("spend was not accounted in balance: %+v", balance)
+	}
+
+	req, err = http.NewRequest("DELETE", "/api/v1/spends/"+ownerID+"/"+created.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "id": created.ID})
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	rr = httptest.NewRecorder()
+	h.DeleteSpendHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	balance, err = models.GetBalance(context.Background(), ownerID, 5, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(balance.Historic) != 0 || balance.Outcome.FixedOutcome != 0 || balance.SpendableAmount != 0 {
+		t.Errorf("spend was not removed from balance: %+v", balance)
+	}
+}
+
+func TestLoginIsLockedAfterRepeatedFailures(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	_, err := models.CreateUser(context.Background(), repository.User{Login: "lockout", SaltedPassword: "right-password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(login string, password string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/api/v1/jwt/issue", strings.NewReader(`{"login": "`+login+`", "password": "`+password+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		h.CreateJWTTokenHandler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// unknown logins and wrong passwords must not be told apart
+	unknown := login("unknown", "wrong-password")
+	wrong := login("lockout", "wrong-password")
+	if unknown.Code != http.StatusUnauthorized || wrong.Code != http.StatusUnauthorized || unknown.Body.String() != wrong.Body.String() {
+		t.Errorf("failed logins are not uniform: got %v %s and %v %s", unknown.Code, unknown.Body.String(), wrong.Code, wrong.Body.String())
+	}
+
+	for i := 0; i < 4; i++ {
+		login("lockout", "wrong-password")
+	}
+
+	rr := login("lockout", "right-password")
+	if status := rr.Code; status != http.StatusTooManyRequests {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusTooManyRequests)
+	}
+
+	if rr.Header().Get("Retry-After") == "" {
+		t.Errorf("locked login did not return a 'Retry-After' header")
+	}
+}
+
+func TestRefreshTokensAreRotatedAndReuseRevokesTheirFamily(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	_, err := models.CreateUser(context.Background(), repository.User{Login: "rotation", SaltedPassword: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(handler http.Handler, path string, body string) (int, repository.JWTResponse) {
+		t.Helper()
+
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var tokens repository.JWTResponse
+		if rr.Code == http.StatusCreated {
+			if err := json.Unmarshal(rr.Body.Bytes(), &tokens); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, tokens
+	}
+
+	login := func() repository.JWTResponse {
+		t.Helper()
+
+		code, tokens := issue(h.CreateJWTTokenHandler, "/api/v1/jwt/issue", `{"login": "rotation", "password": "password"}`)
+		if code != http.StatusCreated || tokens.RefreshToken == "" {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusCreated)
+		}
+		return tokens
+	}
+
+	refresh := func(token string) (int, repository.JWTResponse) {
+		t.Helper()
+		return issue(h.RefreshJWTTokenHandler, "/api/v1/jwt/refresh", `{"refresh": "`+token+`"}`)
+	}
+
+	a := login().RefreshToken
+	other := login().RefreshToken
+
+	code, b := refresh(a)
+	if code != http.StatusCreated || b.RefreshToken == "" || b.RefreshToken == a || b.AccessToken == "" {
+		t.Fatalf("refresh token was not rotated: got %v %+v", code, b)
+	}
+
+	// reusing a rotated token means it was stolen
+	if code, _ := refresh(a); code != http.StatusUnauthorized {
+		t.Errorf("reused refresh token returned wrong status code: got %v want %v", code, http.StatusUnauthorized)
+	}
+
+	if code, _ := refresh(b.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("refresh token from a revoked family returned wrong status code: got %v want %v", code, http.StatusUnauthorized)
+	}
+
+	// sessions from other logins are not affected
+	if code, _ := refresh(other); code != http.StatusCreated {
+		t.Errorf("refresh token from another family returned wrong status code: got %v want %v", code, http.StatusCreated)
+	}
+}
+
+func TestSpendCurrencyMustMatchBalance(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	ownerID := "60b1c2d3e4f5a60718293a4d"
+	principal := auth.Principal{Subject: ownerID}
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.CreateBalance(context.Background(), repository.Balance{
+		OwnerID:  oid,
+		Month:    6,
+		Year:     2021,
+		Currency: "brl",
+		Income:   repository.Income{GrossIncome: 1000000, NetIncome: 800000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"foreign currency", `{"owner_id": "` + ownerID + `", "cost": 10, "currency": "USD", "date": "2021-06-10T00:00:00Z"}`, http.StatusBadRequest},
+		{"unknown currency", `{"owner_id": "` + ownerID + `", "cost": 10, "currency": "XYZ", "date": "2021-06-10T00:00:00Z"}`, http.StatusBadRequest},
+		{"too many decimal places", `{"owner_id": "` + ownerID + `", "cost": 10.001, "date": "2021-06-10T00:00:00Z"}`, http.StatusBadRequest},
+		{"balance currency", `{"owner_id": "` + ownerID + `", "cost": 12.90, "date": "2021-06-10T00:00:00Z"}`, http.StatusCreated},
+		{"same currency", `{"owner_id": "` + ownerID + `", "cost": 0.10, "currency": "BRL", "date": "2021-06-11T00:00:00Z"}`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/api/v1/spends", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+			rr := httptest.NewRecorder()
+			h.CreateSpendHandler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", status, tt.status, rr.Body.String())
+			}
+		})
+	}
+
+	balance, err := models.GetBalance(context.Background(), ownerID, 6, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance.Currency != "BRL" || balance.Outcome.DynamicOutcome.String() != "13" || balance.SpendableAmount.String() != "787" {
+		t.Errorf("unexpected balance: %+v", balance)
+	}
+}
+
+func TestForeignSpendIsConvertedAtSpendDateRate(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	ownerID := "60b1c2d3e4f5a60718293a4e"
+	principal := auth.Principal{Subject: ownerID}
+
+	req, err := http.NewRequest("POST", "/api/v1/exchange-rates/import", strings.NewReader(
+		"date,base,quote,rate\n2021-06-20,USD,BRL,4.9\n2021-07-02,usd,brl,5.0321\n2021-07-02,BRL,EUR,0.2\n",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ImportExchangeRatesHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.CreateBalance(context.Background(), repository.Balance{OwnerID: oid, Month: 7, Year: 2021, Currency: "BRL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"direct rate", `{"owner_id": "` + ownerID + `", "cost": 10, "currency": "USD", "date": "2021-07-05T15:00:00Z"}`, http.StatusCreated},
+		{"inverse rate", `{"owner_id": "` + ownerID + `", "cost": 3, "currency": "EUR", "date": "2021-07-03T00:00:00Z"}`, http.StatusCreated},
+		{"stale rate", `{"owner_id": "` + ownerID + `", "cost": 10, "currency": "USD", "date": "2021-07-20T00:00:00Z"}`, http.StatusBadRequest},
+		{"unknown pair", `{"owner_id": "` + ownerID + `", "cost": 10, "currency": "GBP", "date": "2021-07-05T00:00:00Z"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/api/v1/spends", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+			rr := httptest.NewRecorder()
+			h.CreateSpendHandler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", status, tt.status, rr.Body.String())
+			}
+		})
+	}
+
+	page, err := models.GetSpends(context.Background(), repository.SpendFilter{OwnerID: ownerID, Ascending: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Spends) != 2 {
+		t.Fatalf("unexpected spends: %+v", page.Spends)
+	}
+
+	eur, usd := page.Spends[0], page.Spends[1]
+	if eur.Cost.String() != "15" || eur.Currency != "BRL" || eur.OriginalCost.String() != "3" || eur.OriginalCurrency != "EUR" {
+		t.Errorf("unexpected spend converted by the inverse rate: %+v", eur)
+	}
+
+	if usd.Cost.String() != "50.32" || usd.OriginalCost.String() != "10" || usd.OriginalCurrency != "USD" || usd.ExchangeRate != 5.0321 {
+		t.Errorf("unexpected spend converted by the direct rate: %+v", usd)
+	}
+
+	balance, err := models.GetBalance(context.Background(), ownerID, 7, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance.Outcome.DynamicOutcome.String() != "65.32" {
+		t.Errorf("unexpected balance: %+v", balance)
+	}
+}
+
+func TestRecurrenceCanBePausedResumedAndCancelled(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	ownerID := "60b1c2d3e4f5a60718293a4f"
+	principal := auth.Principal{Subject: ownerID}
+	start := time.Now().UTC().AddDate(0, 1, 0).Format("2006-01-02")
+
+	req, err := http.NewRequest("POST", "/api/v1/recurrences", strings.NewReader(
+		`{"owner_id": "`+ownerID+`", "description": "streaming", "cost": 39.9, "cadence": "monthly", "start": "`+start+`T00:00:00Z"}`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	rr := httptest.NewRecorder()
+	h.CreateRecurrenceHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusCreated, rr.Body.String())
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		method  string
+		handler http.Handler
+		status  int
+		state   string
+	}{
+		{"pause", "PUT", h.PauseRecurrenceHandler, http.StatusOK, repository.RecurrenceStatusPaused},
+		{"pause twice", "PUT", h.PauseRecurrenceHandler, http.StatusConflict, ""},
+		{"resume", "DELETE", h.ResumeRecurrenceHandler, http.StatusOK, repository.RecurrenceStatusActive},
+		{"cancel", "DELETE", h.CancelRecurrenceHandler, http.StatusOK, repository.RecurrenceStatusCancelled},
+		{"resume cancelled", "DELETE", h.ResumeRecurrenceHandler, http.StatusConflict, ""},
+	}
+
+	for _, step := range steps {
+		req, err := http.NewRequest(step.method, "/api/v1/recurrences/"+ownerID+"/"+created.ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "id": created.ID})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		step.handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != step.status {
+			t.Fatalf("%s: handler returned wrong status code: got %v want %v: %s", step.name, status, step.status, rr.Body.String())
+		}
+
+		if step.state == "" {
+			continue
+		}
+
+		var recurrence repository.Recurrence
+		if err := json.Unmarshal(rr.Body.Bytes(), &recurrence); err != nil {
+			t.Fatal(err)
+		}
+
+		if recurrence.Status != step.state {
+			t.Errorf("%s: unexpected status %q", step.name, recurrence.Status)
+		}
+	}
+
+	recurrences, err := models.GetRecurrences(context.Background(), ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recurrences) != 1 || recurrences[0].Status != repository.RecurrenceStatusCancelled || recurrences[0].Cost.String() != "39.9" {
+		t.Errorf("unexpected recurrences: %+v", recurrences)
+	}
+}
+
+func TestInstallmentPurchaseCommitsFutureMonths(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	ownerID := "60b1c2d3e4f5a60718293a50"
+	principal := auth.Principal{Subject: ownerID}
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cardID, err := models.CreateCard(context.Background(), repository.CreditCard{OwnerID: oid, Alias: "platinum", LastDigits: 4321})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"without card", `{"owner_id": "` + ownerID + `", "cost": 1000, "installments": 10, "payment_method": {"debit": true}}`, http.StatusBadRequest},
+		{"too many installments", `{"owner_id": "` + ownerID + `", "cost": 1000, "installments": 100, "payment_method": {"credit": {"id": "` + cardID + `"}}}`, http.StatusBadRequest},
+		{"split purchase", `{"owner_id": "` + ownerID + `", "description": "tv", "cost": 1000, "currency": "BRL", "installments": 3, "date": "2031-01-31T12:00:00Z", "payment_method": {"credit": {"id": "` + cardID + `"}}}`, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/api/v1/spends", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+			rr := httptest.NewRecorder()
+			h.CreateSpendHandler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s", status, tt.status, rr.Body.String())
+			}
+		})
+	}
+
+	page, err := models.GetSpends(context.Background(), repository.SpendFilter{OwnerID: ownerID, Ascending: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Spends) != 3 {
+		t.Fatalf("unexpected installments: %+v", page.Spends)
+	}
+
+	for i, s := range page.Spends {
+		if s.Installment == nil || s.Installment.String() != "installment "+strconv.Itoa(i+1)+" of 3" || s.PaymentMethod.Credit.LastDigits != 4321 {
+			t.Errorf("unexpected installment: %+v", s)
+		}
+	}
+
+	if page.Spends[0].Cost.String() != "333.34" || page.Spends[1].Date.Time().Day() != 28 {
+		t.Errorf("unexpected first installments: %+v", page.Spends[:2])
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/balance/"+ownerID+"/commitments?from=2031-01&to=2031-04", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID})
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	rr := httptest.NewRecorder()
+	h.GetCommitmentsHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	var commitments []repository.Commitment
+	if err := json.Unmarshal(rr.Body.Bytes(), &commitments); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"333.34", "333.33", "333.33", "0"}
+	if len(commitments) != len(expected) {
+		t.Fatalf("unexpected commitments: %+v", commitments)
+	}
+
+	for i, c := range commitments {
+		if c.Total.String() != expected[i] || c.Month != int64(i+1) || c.Year != 2031 {
+			t.Errorf("unexpected commitment: %+v", c)
+		}
+	}
+}
+
+func TestForeignInstallmentPurchaseKeepsItsConversion(t *testing.T) {
+	h := handlers.GetHandlers()
+
+	ownerID := "60b1c2d3e4f5a60718293a5b"
+	principal := auth.Principal{Subject: ownerID}
+
+	req, err := http.NewRequest("POST", "/api/v1/exchange-rates/import", strings.NewReader("2031-03-05,USD,BRL,5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ImportExchangeRatesHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.CreateBalance(context.Background(), repository.Balance{OwnerID: oid, Month: 3, Year: 2031, Currency: "BRL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cardID, err := models.CreateCard(context.Background(), repository.CreditCard{OwnerID: oid, Alias: "travel", LastDigits: 8765})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = http.NewRequest("POST", "/api/v1/spends", strings.NewReader(
+		`{"owner_id": "`+ownerID+`", "description": "flight", "cost": 100, "currency": "USD", "installments": 3, "date": "2031-03-10T12:00:00Z", "payment_method": {"credit": {"id": "`+cardID+`"}}}`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	rr = httptest.NewRecorder()
+	h.CreateSpendHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusCreated, rr.Body.String())
+	}
+
+	page, err := models.GetSpends(context.Background(), repository.SpendFilter{OwnerID: ownerID, Ascending: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Spends) != 3 {
+		t.Fatalf("unexpected installments: %+v", page.Spends)
+	}
+
+	expected := []struct {
+		cost     string
+		original string
+	}{
+		{"166.68", "33.34"},
+		{"166.66", "33.33"},
+		{"166.66", "33.33"},
+	}
+
+	for i, s := range page.Spends {
+		if s.Cost.String() != expected[i].cost || s.Currency != "BRL" ||
+			s.OriginalCost.String() != expected[i].original || s.OriginalCurrency != "USD" || s.ExchangeRate != 5 {
+			t.Errorf("unexpected installment %d: %+v", i+1, s)
+		}
+
+		if s.Installment == nil || s.Installment.PurchaseCost.String() != "500" {
+			t.Errorf("unexpected purchase of installment %d: %+v", i+1, s.Installment)
+		}
+	}
+}
+
+func TestStatementGroupsSpendsByBillingCycleAndIsPaidOnce(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a51"
+	principal := auth.Principal{Subject: ownerID}
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = models.CreateCard(ctx, repository.CreditCard{OwnerID: oid, Alias: "gold", LastDigits: 1111, ClosingDay: 40, DueDay: 5})
+	if err == nil || !strings.Contains(err.Error(), "invalid card") {
+		t.Fatalf("card with an invalid closing day was created: %v", err)
+	}
+
+	cardID, err := models.CreateCard(ctx, repository.CreditCard{
+		OwnerID: oid, Alias: "gold", LastDigits: 1111, ClosingDay: 25, DueDay: 5, CreditLimit: repository.NewMoney(5000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card := `{"credit": {"id": "` + cardID + `"}}`
+	for _, body := range []string{
+		`{"owner_id": "` + ownerID + `", "cost": 10, "date": "2021-04-25T12:00:00Z", "payment_method": ` + card + `}`,
+		`{"owner_id": "` + ownerID + `", "cost": 20, "date": "2021-04-26T00:00:00Z", "payment_method": ` + card + `}`,
+		`{"owner_id": "` + ownerID + `", "cost": 30, "date": "2021-05-25T23:00:00Z", "payment_method": ` + card + `}`,
+		`{"owner_id": "` + ownerID + `", "cost": 300, "installments": 3, "date": "2021-05-10T12:00:00Z", "payment_method": ` + card + `}`,
+		`{"owner_id": "` + ownerID + `", "cost": 40, "date": "2021-05-26T00:00:00Z", "payment_method": ` + card + `}`,
+		`{"owner_id": "` + ownerID + `", "cost": 50, "date": "2021-05-01T00:00:00Z", "payment_method": {"debit": true}}`,
+	} {
+		req, err := http.NewRequest("POST", "/api/v1/spends", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		h.CreateSpendHandler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusCreated, rr.Body.String())
+		}
+	}
+
+	statement := func(method string, path string, handler http.Handler, period string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/api/v1/cards/"+ownerID+"/"+cardID+"/statements/"+period+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "id": cardID, "period": period})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := statement("GET", "", h.GetStatementHandler, "2021-05")
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	var s repository.Statement
+	if err := json.Unmarshal(rr.Body.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Spends) != 3 || s.Total.String() != "150" || s.Paid {
+		t.Errorf("unexpected statement: %+v", s)
+	}
+
+	if s.Due.Time().Format("2006-01-02") != "2021-06-05" || s.AvailableLimit.String() != "4600" {
+		t.Errorf("unexpected statement due date or available limit: %v, %s", s.Due.Time(), s.AvailableLimit)
+	}
+
+	future := time.Now().AddDate(0, 1, 0).Format("2006-01")
+	if status := statement("POST", "/payment", h.PayStatementHandler, future).Code; status != http.StatusConflict {
+		t.Errorf("open statement was paid: got %v want %v", status, http.StatusConflict)
+	}
+
+	if status := statement("POST", "/payment", h.PayStatementHandler, "2021-05").Code; status != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+
+	if status := statement("POST", "/payment", h.PayStatementHandler, "2021-05").Code; status != http.StatusConflict {
+		t.Errorf("statement was paid twice: got %v want %v", status, http.StatusConflict)
+	}
+
+	rr = statement("GET", "", h.GetStatementHandler, "2021-05")
+	if err := json.Unmarshal(rr.Body.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.Paid || s.Payment == nil || s.Payment.Amount.String() != "150" || s.AvailableLimit.String() != "4750" {
+		t.Fatalf("unexpected paid statement: %+v", s)
+	}
+
+	// the payment is kept in the balance without being accounted as outcome again
+	now := time.Now().UTC()
+	balance, err := models.GetBalance(ctx, ownerID, int64(now.Month()), int64(now.Year()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(balance.Historic) != 1 || balance.Historic[0].Type != repository.SpendTypePayment || balance.Outcome.DynamicOutcome != 0 || balance.SpendableAmount != 0 {
+		t.Errorf("unexpected balance after payment: %+v", balance)
+	}
+
+	err = models.DeleteSpend(ctx, ownerID, s.Payment.SpendID.Hex())
+	if err == nil || !strings.Contains(err.Error(), "payment spends") {
+		t.Errorf("payment spend was deleted on its own: %v", err)
+	}
+
+	if status := statement("DELETE", "/payment", h.RevertStatementPaymentHandler, "2021-05").Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	balance, err = models.GetBalance(ctx, ownerID, int64(now.Month()), int64(now.Year()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(balance.Historic) != 0 {
+		t.Errorf("payment spend was kept after reverting it: %+v", balance.Historic)
+	}
+}
+
+func TestCardWithSpendsIsArchivedInsteadOfDeleted(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a52"
+	principal := auth.Principal{Subject: ownerID}
+
+	oid, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cardID, err := models.CreateCard(ctx, repository.CreditCard{OwnerID: oid, Alias: "black", Network: "visa", LastDigits: 2222})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cid, err := primitive.ObjectIDFromHex(cardID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paidWithCard := repository.PaymentMethod{Credit: repository.CreditCard{ID: cid}}
+
+	spendID, err := models.CreateSpend(ctx, repository.Spend{OwnerID: oid, Cost: repository.NewMoney(80), PaymentMethod: paidWithCard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card := func(method string, body string, handler http.Handler) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/api/v1/cards/"+ownerID+"/"+cardID, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "id": cardID})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if status := card("DELETE", "", h.DeleteCardHandler).Code; status != http.StatusConflict {
+		t.Errorf("card with spends was deleted: got %v want %v", status, http.StatusConflict)
+	}
+
+	if status := card("PATCH", `{"network": "amex"}`, h.PatchCardHandler).Code; status != http.StatusBadRequest {
+		t.Errorf("card was updated with an invalid network: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	rr := card("PATCH", `{"alias": "old black", "last_digits": 9999, "archived": true}`, h.PatchCardHandler)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	rr = card("GET", "", h.GetCardHandler)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	var archived repository.CreditCard
+	if err := json.Unmarshal(rr.Body.Bytes(), &archived); err != nil {
+		t.Fatal(err)
+	}
+
+	if archived.Alias != "old black" || archived.LastDigits != 2222 || !archived.Archived || archived.ArchivedAt == 0 {
+		t.Errorf("unexpected archived card: %+v", archived)
+	}
+
+	cards, err := models.GetCards(ctx, ownerID, false)
+	if err != nil || len(cards) != 0 {
+		t.Errorf("archived card was listed: %+v, %v", cards, err)
+	}
+
+	_, err = models.CreateSpend(ctx, repository.Spend{OwnerID: oid, Cost: repository.NewMoney(10), PaymentMethod: paidWithCard})
+	if err == nil || !strings.Contains(err.Error(), "card is archived") {
+		t.Errorf("spend was created with an archived card: %v", err)
+	}
+
+	// historic spends keep the card they were made with
+	spend, err := models.GetSpend(ctx, ownerID, spendID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := models.UpdateSpend(ctx, ownerID, spendID, *spend)
+	if err != nil || updated.PaymentMethod.Credit.Alias != "black" {
+		t.Errorf("unexpected spend made with an archived card: %+v, %v", updated, err)
+	}
+}
+
+func TestBudgetAlertsAreRecordedOncePerThreshold(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a53"
+	principal := auth.Principal{Subject: ownerID}
+
+	post := func(path string, handler http.Handler, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	balance := `{"owner_id": "` + ownerID + `", "month": 5, "year": 2021, "currency": "BRL", "budgets": [%s]}`
+	for _, invalid := range []string{
+		`{"category": "restaurants", "limit": 0}`,
+		`{"category": " ", "limit": 100}`,
+		`{"category": "restaurants", "limit": 100}, {"category": "Restaurants", "limit": 50}`,
+	} {
+		if rr := post("/api/v1/balance", h.CreateBalanceHandler, fmt.Sprintf(balance, invalid)); rr.Code != http.StatusBadRequest {
+			t.Errorf("balance with invalid budgets %s: got %v want %v", invalid, rr.Code, http.StatusBadRequest)
+		}
+	}
+
+	rr := post("/api/v1/balance", h.CreateBalanceHandler, fmt.Sprintf(balance, `{"category": "restaurants", "limit": 100}`))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	spend := `{"owner_id": "` + ownerID + `", "cost": %s, "categories": ["%s"], "date": "2021-05-10T00:00:00Z"}`
+	ids := []string{}
+	for _, s := range [][2]string{{"50", "restaurants"}, {"35", "Restaurants"}, {"40", "groceries"}, {"20", "restaurants"}} {
+		rr := post("/api/v1/spends", h.CreateSpendHandler, fmt.Sprintf(spend, s[0], s[1]))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+
+		var created struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, created.ID)
+	}
+
+	// reaching a threshold again within the month does not alert it twice
+	if err := models.DeleteSpend(ctx, ownerID, ids[3]); err != nil {
+		t.Fatal(err)
+	}
+	if rr := post("/api/v1/spends", h.CreateSpendHandler, fmt.Sprintf(spend, "30", "restaurants")); rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/balance/"+ownerID+"/budgets/2021-05", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "period": "2021-05"})
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	rr = httptest.NewRecorder()
+	h.GetBudgetsHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	var report repository.BudgetReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Budgets) != 1 {
+		t.Fatalf("unexpected budgets: %+v", report.Budgets)
+	}
+
+	b := report.Budgets[0]
+	if b.Spent.String() != "115" || b.Remaining.String() != "-15" || b.Percentage != 115 {
+		t.Errorf("unexpected budget status: %+v", b)
+	}
+
+	if len(report.Alerts) != 2 || report.Alerts[0].Threshold != 80 || report.Alerts[1].Threshold != 100 {
+		t.Fatalf("unexpected budget alerts: %+v", report.Alerts)
+	}
+
+	if report.Alerts[0].SpendID.Hex() != ids[1] || report.Alerts[0].Spent.String() != "85" {
+		t.Errorf("unexpected 80%% alert: %+v", report.Alerts[0])
+	}
+}
+
+func TestCategoriesAreValidatedRenamedAndMerged(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a54"
+	principal := auth.Principal{Subject: ownerID}
+
+	do := func(method string, path string, vars map[string]string, handler http.Handler, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vars != nil {
+			req = mux.SetURLVars(req, vars)
+		}
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	created := func(rr *httptest.ResponseRecorder) string {
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+
+		var result struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result.ID
+	}
+
+	category := `{"owner_id": "` + ownerID + `", "name": "%s", "color": "%s", "parent_id": "%s"}`
+	coffee := created(do("POST", "/api/v1/categories", nil, h.CreateCategoryHandler, fmt.Sprintf(category, "coffee", "#6d4c41", "")))
+	espresso := created(do("POST", "/api/v1/categories", nil, h.CreateCategoryHandler, fmt.Sprintf(category, "espresso", "", coffee)))
+
+	for _, invalid := range []struct {
+		body string
+		code int
+	}{
+		{fmt.Sprintf(category, " ", "", ""), http.StatusBadRequest},
+		{fmt.Sprintf(category, "tea", "brown", ""), http.StatusBadRequest},
+		{fmt.Sprintf(category, "tea", "", "60b1c2d3e4f5a60718293aff"), http.StatusBadRequest},
+		{fmt.Sprintf(category, "Coffee", "", ""), http.StatusConflict},
+	} {
+		if rr := do("POST", "/api/v1/categories", nil, h.CreateCategoryHandler, invalid.body); rr.Code != invalid.code {
+			t.Errorf("category %s: got %v want %v", invalid.body, rr.Code, invalid.code)
+		}
+	}
+
+	// categories can not be nested under their own subcategories
+	vars := map[string]string{"owner_id": ownerID, "id": coffee}
+	if rr := do("PATCH", "/api/v1/categories/"+ownerID+"/"+coffee, vars, h.PatchCategoryHandler, `{"parent_id": "`+espresso+`"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("category nested under its subcategory: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	balance := `{"owner_id": "` + ownerID + `", "month": 6, "year": 2021, "currency": "BRL", "budgets": [{"category": "%s", "limit": 100}]}`
+	if rr := do("POST", "/api/v1/balance", nil, h.CreateBalanceHandler, fmt.Sprintf(balance, "tea")); rr.Code != http.StatusBadRequest {
+		t.Errorf("budget of unknown category: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	created(do("POST", "/api/v1/balance", nil, h.CreateBalanceHandler, fmt.Sprintf(balance, "COFFEE")))
+
+	spend := `{"owner_id": "` + ownerID + `", "cost": 12, "categories": [%s], "date": "2021-06-10T00:00:00Z"}`
+	if rr := do("POST", "/api/v1/spends", nil, h.CreateSpendHandler, fmt.Sprintf(spend, `"tea"`)); rr.Code != http.StatusBadRequest {
+		t.Errorf("spend of unknown category: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	spendID := created(do("POST", "/api/v1/spends", nil, h.CreateSpendHandler, fmt.Sprintf(spend, `"Coffee", "coffee", "espresso"`)))
+
+	assertCategory := func(want string) {
+		t.Helper()
+
+		s, err := models.GetSpend(ctx, ownerID, spendID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s.Categories) != 2 || s.Categories[0] != want || s.Categories[1] != "espresso" {
+			t.Errorf("unexpected spend categories: %v", s.Categories)
+		}
+
+		b, err := models.GetBalance(ctx, ownerID, 6, 2021)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b.Historic) != 1 || b.Historic[0].Categories[0] != want {
+			t.Errorf("unexpected balance historic: %+v", b.Historic)
+		}
+		if len(b.Budgets) != 1 || b.Budgets[0].Category != want {
+			t.Errorf("unexpected balance budgets: %+v", b.Budgets)
+		}
+	}
+	assertCategory("coffee")
+
+	// renaming a category rewrites the spends, balances and budgets using it
+	rr := do("PATCH", "/api/v1/categories/"+ownerID+"/"+coffee, vars, h.PatchCategoryHandler, `{"name": "cafe"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	assertCategory("cafe")
+
+	if rr := do("DELETE", "/api/v1/categories/"+ownerID+"/"+coffee, vars, h.DeleteCategoryHandler, ""); rr.Code != http.StatusConflict {
+		t.Errorf("deleting a category in use: got %v want %v", rr.Code, http.StatusConflict)
+	}
+
+	drinks := created(do("POST", "/api/v1/categories", nil, h.CreateCategoryHandler, fmt.Sprintf(category, "drinks", "", "")))
+
+	if rr := do("POST", "/api/v1/categories/"+ownerID+"/"+coffee+"/merge", vars, h.MergeCategoryHandler, `{"into": "`+coffee+`"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("merging a category into itself: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr = do("POST", "/api/v1/categories/"+ownerID+"/"+coffee+"/merge", vars, h.MergeCategoryHandler, `{"into": "`+drinks+`"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	assertCategory("drinks")
+
+	if _, err := models.GetCategory(ctx, ownerID, coffee); err == nil {
+		t.Error("merged category was not deleted")
+	}
+
+	sub, err := models.GetCategory(ctx, ownerID, espresso)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.ParentID.Hex() != drinks {
+		t.Errorf("subcategory was not moved into the merged category: %+v", sub)
+	}
+
+	userID, err := models.CreateUser(ctx, repository.User{Login: "categories", SaltedPassword: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seeded, err := models.GetCategories(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seeded) == 0 {
+		t.Error("default categories were not created for the new user")
+	}
+}
+
+func TestSpendsAreCategorizedByRules(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a55"
+	principal := auth.Principal{Subject: ownerID}
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	do := func(method string, path string, vars map[string]string, handler http.Handler, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vars != nil {
+			req = mux.SetURLVars(req, vars)
+		}
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	created := func(rr *httptest.ResponseRecorder) string {
+		t.Helper()
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+
+		var result struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result.ID
+	}
+
+	for _, name := range []string{"transport", "streaming", "food"} {
+		if _, err := models.CreateCategory(ctx, repository.Category{OwnerID: owner, Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rule := `{"owner_id": "` + ownerID + `", "priority": %d, %s}`
+	for _, invalid := range []string{
+		`"categories": ["transport"]`,
+		`"pattern": "(uber", "categories": ["transport"]`,
+		`"description": "uber", "categories": ["taxi"]`,
+		`"description": "uber", "categories": []`,
+		`"min_cost": 50, "max_cost": 10, "categories": ["transport"]`,
+		`"payment_method": "cash", "categories": ["transport"]`,
+		`"description": "uber", "type": "payment", "categories": ["transport"]`,
+		`"card_id": "60b1c2d3e4f5a60718293aff", "categories": ["transport"]`,
+	} {
+		if rr := do("POST", "/api/v1/rules", nil, h.CreateCategoryRuleHandler, fmt.Sprintf(rule, 1, invalid)); rr.Code != http.StatusBadRequest {
+			t.Errorf("rule %s: got %v want %v: %s", invalid, rr.Code, http.StatusBadRequest, rr.Body.String())
+		}
+	}
+
+	uber := created(do("POST", "/api/v1/rules", nil, h.CreateCategoryRuleHandler, fmt.Sprintf(rule, 10, `"pattern": "(?i)^uber", "max_cost": 100, "categories": ["Transport"]`)))
+	created(do("POST", "/api/v1/rules", nil, h.CreateCategoryRuleHandler, fmt.Sprintf(rule, 5, `"description": "netflix", "categories": ["streaming"], "type": "fixed"`)))
+
+	req, err := http.NewRequest("GET", "/api/v1/rules/"+ownerID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID})
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	rr := httptest.NewRecorder()
+	h.GetCategoryRulesHandler.ServeHTTP(rr, req)
+
+	var rules []repository.CategoryRule
+	if err := json.Unmarshal(rr.Body.Bytes(), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Priority != 5 || rules[1].ID.Hex() != uber || rules[1].Categories[0] != "transport" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	created(do("POST", "/api/v1/balance", nil, h.CreateBalanceHandler, `{"owner_id": "`+ownerID+`", "month": 7, "year": 2021, "currency": "BRL"}`))
+
+	spend := `{"owner_id": "` + ownerID + `", "description": "%s", "cost": %s, %s"date": "2021-07-10T00:00:00Z"}`
+	tests := []struct {
+		description string
+		cost        string
+		extra       string
+		categories  []string
+		kind        string
+	}{
+		{"Uber to the airport", "45", "", []string{"transport"}, repository.SpendTypeDynamic},
+		// the rule with the lowest priority is applied first
+		{"Uber with Netflix", "30", "", []string{"streaming"}, repository.SpendTypeFixed},
+		{"uber black", "150", "", nil, repository.SpendTypeDynamic},
+		{"Uber eats", "60", `"categories": ["food"], `, []string{"food"}, repository.SpendTypeDynamic},
+		{"Netflix gift", "50", `"type": "dynamic", `, []string{"streaming"}, repository.SpendTypeDynamic},
+	}
+
+	for _, test := range tests {
+		id := created(do("POST", "/api/v1/spends", nil, h.CreateSpendHandler, fmt.Sprintf(spend, test.description, test.cost, test.extra)))
+
+		s, err := models.GetSpend(ctx, ownerID, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if fmt.Sprint(s.Categories) != fmt.Sprint(test.categories) || s.Type != test.kind {
+			t.Errorf("spend %s: got categories %v and type %s, want %v and %s", test.description, s.Categories, s.Type, test.categories, test.kind)
+		}
+	}
+
+	dryRun := func(last string, body string) (int, repository.CategoryRuleDryRun) {
+		t.Helper()
+
+		rr := do("POST", "/api/v1/rules/"+ownerID+"/dry-run?last="+last, map[string]string{"owner_id": ownerID}, h.DryRunCategoryRulesHandler, body)
+
+		var result repository.CategoryRuleDryRun
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, result
+	}
+
+	if code, _ := dryRun("0", ""); code != http.StatusBadRequest {
+		t.Errorf("dry-run of no spends: got %v want %v", code, http.StatusBadRequest)
+	}
+
+	code, result := dryRun("10", "")
+	if code != http.StatusOK || result.Evaluated != 5 || result.Matched != 4 {
+		t.Fatalf("unexpected dry-run of stored rules: %v %+v", code, result)
+	}
+
+	// a rule given at the payload is evaluated alone and not stored
+	code, result = dryRun("4", `{"description": "UBER", "categories": ["food"]}`)
+	if code != http.StatusOK || result.Evaluated != 4 || result.Matched != 3 {
+		t.Fatalf("unexpected dry-run of a given rule: %v %+v", code, result)
+	}
+	for _, m := range result.Matches {
+		if fmt.Sprint(m.AssignedCategories) != "[food]" || !m.RuleID.IsZero() {
+			t.Errorf("unexpected dry-run match: %+v", m)
+		}
+	}
+
+	rules, err = models.GetCategoryRules(ctx, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Errorf("dry-run stored its rule: %+v", rules)
+	}
+
+	// optional conditions are removed with null
+	vars := map[string]string{"owner_id": ownerID, "id": uber}
+	rr = do("PATCH", "/api/v1/rules/"+ownerID+"/"+uber, vars, h.PatchCategoryRuleHandler, `{"max_cost": null}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	r, err := models.GetCategoryRule(ctx, ownerID, uber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.MaxCost != nil || r.Pattern != "(?i)^uber" {
+		t.Errorf("unexpected patched rule: %+v", r)
+	}
+
+	// renaming a category rewrites the rules using it, which keep it in use
+	categories, err := models.GetCategories(ctx, ownerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range categories {
+		if c.Name != "transport" {
+			continue
+		}
+
+		c.Name = "mobility"
+		if _, err := models.UpdateCategory(ctx, ownerID, c.ID.Hex(), c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err = models.GetCategoryRule(ctx, ownerID, uber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(r.Categories) != "[mobility]" {
+		t.Errorf("rule categories were not renamed: %v", r.Categories)
+	}
+
+	if rr := do("DELETE", "/api/v1/rules/"+ownerID+"/"+uber, vars, h.DeleteCategoryRuleHandler, ""); rr.Code != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if rr := do("GET", "/api/v1/rules/"+ownerID+"/"+uber, vars, h.GetCategoryRuleHandler, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("deleted rule: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestReportsCompareSpendsAgainstThePreviousPeriod(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a56"
+	principal := auth.Principal{Subject: ownerID}
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	get := func(vars map[string]string, handler http.Handler) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/api/v1/reports/"+ownerID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, vars)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, name := range []string{"food", "transport"} {
+		if _, err := models.CreateCategory(ctx, repository.Category{OwnerID: owner, Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, month := range []int64{6, 7} {
+		if _, err := models.CreateBalance(ctx, repository.Balance{OwnerID: owner, Month: month, Year: 2021, Currency: "BRL"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cardID, err := models.CreateCard(ctx, repository.CreditCard{OwnerID: owner, Alias: "black", Network: "visa", LastDigits: 3333})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cid, _ := primitive.ObjectIDFromHex(cardID)
+
+	june := primitive.NewDateTimeFromTime(time.Date(2021, time.June, 10, 0, 0, 0, 0, time.UTC))
+	july := primitive.NewDateTimeFromTime(time.Date(2021, time.July, 10, 0, 0, 0, 0, time.UTC))
+	spends := []repository.Spend{
+		{Description: "market", Cost: repository.NewMoney(100), Categories: []string{"food"}, Date: june, PaymentMethod: repository.PaymentMethod{Debit: true}},
+		{Description: "market", Cost: repository.NewMoney(50), Categories: []string{"food"}, Date: july, PaymentMethod: repository.PaymentMethod{Debit: true}},
+		{Description: "uber eats", Cost: repository.NewMoney(30), Categories: []string{"food", "transport"}, Date: july, PaymentMethod: repository.PaymentMethod{Credit: repository.CreditCard{ID: cid}}},
+		{Description: "rent", Type: repository.SpendTypeFixed, Cost: repository.NewMoney(200), Date: july, PaymentMethod: repository.PaymentMethod{PaymentSlip: true}},
+	}
+	for _, spend := range spends {
+		spend.OwnerID = owner
+		if _, err := models.CreateSpend(ctx, spend); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rr := get(map[string]string{"owner_id": ownerID, "period": "2021-13"}, h.GetMonthlyReportHandler); rr.Code != http.StatusBadRequest {
+		t.Errorf("report of an invalid period: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	rr := get(map[string]string{"owner_id": ownerID, "period": "2021-07"}, h.GetMonthlyReportHandler)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var monthly repository.SpendReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &monthly); err != nil {
+		t.Fatal(err)
+	}
+
+	summary := func(totals []repository.SpendTotal) string {
+		var s []string
+		for _, total := range totals {
+			s = append(s, fmt.Sprintf("%s%s:%v/%v/%v", total.Key, total.Label, total.Total, total.Previous, total.Delta))
+		}
+		return strings.Join(s, " ")
+	}
+
+	if monthly.Period != "2021-07" || monthly.PreviousPeriod != "2021-06" {
+		t.Errorf("unexpected periods: %s and %s", monthly.Period, monthly.PreviousPeriod)
+	}
+	if got := summary(monthly.Totals); got != ":280/100/180" {
+		t.Errorf("unexpected totals: %s", got)
+	}
+	// spends count towards each of their categories, and categories missing this month are kept
+	if got := summary(monthly.ByCategory); got != ":200/0/200 food:80/100/-20 transport:30/0/30" {
+		t.Errorf("unexpected totals by category: %s", got)
+	}
+	if got := summary(monthly.ByPaymentMethod); got != "payment_slip:200/0/200 debit:50/100/-50 credit:30/0/30" {
+		t.Errorf("unexpected totals by payment method: %s", got)
+	}
+	if got := summary(monthly.ByCard); got != cardID+"black:30/0/30" {
+		t.Errorf("unexpected totals by card: %s", got)
+	}
+	if got := summary(monthly.ByType); got != "fixed:200/0/200 dynamic:80/100/-20" {
+		t.Errorf("unexpected totals by type: %s", got)
+	}
+	if monthly.ByMonth != nil {
+		t.Errorf("monthly report has totals by month: %+v", monthly.ByMonth)
+	}
+
+	rr = get(map[string]string{"owner_id": ownerID, "year": "2021"}, h.GetYearlyReportHandler)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var yearly repository.SpendReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &yearly); err != nil {
+		t.Fatal(err)
+	}
+
+	if yearly.Period != "2021" || yearly.PreviousPeriod != "2020" {
+		t.Errorf("unexpected periods: %s and %s", yearly.Period, yearly.PreviousPeriod)
+	}
+	if got := summary(yearly.Totals); got != ":380/0/380" {
+		t.Errorf("unexpected totals: %s", got)
+	}
+	if got := summary(yearly.ByMonth); got != "2021-06:100/0/100 2021-07:280/100/180 2021-08:0/280/-280" {
+		t.Errorf("unexpected totals by month: %s", got)
+	}
+}
+
+func TestYearlyReportKeepsMonthsWithoutSpends(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a5c"
+	principal := auth.Principal{Subject: ownerID}
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	for _, month := range []time.Month{time.December, time.February, time.April} {
+		year := 2022
+		if month == time.December {
+			year = 2021
+		}
+
+		spend := repository.Spend{
+			OwnerID:       owner,
+			Description:   "market",
+			Cost:          repository.NewMoney(float64(month)),
+			Currency:      "BRL",
+			Date:          primitive.NewDateTimeFromTime(time.Date(year, month, 10, 0, 0, 0, 0, time.UTC)),
+			PaymentMethod: repository.PaymentMethod{Debit: true},
+		}
+		if _, err := models.CreateSpend(ctx, spend); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest("GET", "/api/v1/reports/"+ownerID+"/yearly/2022", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "year": "2022"})
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	rr := httptest.NewRecorder()
+	h.GetYearlyReportHandler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusOK, rr.Body.String())
+	}
+
+	var yearly repository.SpendReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &yearly); err != nil {
+		t.Fatal(err)
+	}
+
+	var months []string
+	for _, total := range yearly.ByMonth {
+		months = append(months, fmt.Sprintf("%s:%v/%v/%v", total.Key, total.Total, total.Previous, total.Delta))
+	}
+
+	// january and march have no spends, but follow months with them
+	expected := "2022-01:0/12/-12 2022-02:2/0/2 2022-03:0/2/-2 2022-04:4/0/4 2022-05:0/4/-4"
+	if got := strings.Join(months, " "); got != expected {
+		t.Errorf("unexpected totals by month: got %s want %s", got, expected)
+	}
+}
+
+func TestImportedSpendsAreDeduplicated(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a57"
+	principal := auth.Principal{Subject: ownerID}
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	// rules created before their owner had categories keep free-form ones
+	if _, err := models.CreateCategoryRule(ctx, repository.CategoryRule{OwnerID: owner, Description: "uber", Categories: []string{"Transport"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.CreateCategory(ctx, repository.Category{OwnerID: owner, Name: "transport"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.CreateBalance(ctx, repository.Balance{OwnerID: owner, Month: 7, Year: 2021, Currency: "BRL"}); err != nil {
+		t.Fatal(err)
+	}
+
+	date := primitive.NewDateTimeFromTime(time.Date(2021, time.July, 9, 15, 0, 0, 0, time.UTC))
+	if _, err := models.CreateSpend(ctx, repository.Spend{OwnerID: owner, Description: "Market", Cost: repository.NewMoney(100), Date: date}); err != nil {
+		t.Fatal(err)
+	}
+
+	statement := strings.Join([]string{
+		"Date,Description,Amount,Currency",
+		"2021-07-09,MARKET ,-100.00,BRL",
+		"2021-07-10,Uber trip,-45.90,BRL",
+		// identical transactions are kept apart
+		"2021-07-10,Uber trip,-45.90,BRL",
+		"2021-07-11,Salary,5000,BRL",
+		"2021-07-32,Bakery,-12,BRL",
+		// there is no exchange rate to convert it
+		"2021-07-12,Coffee,-5,JPY",
+	}, `\n`)
+	payload := `{"format": "csv", "content": "` + statement + `", "mapping": {"date": "date", "description": "description", "amount": "amount", "currency": "currency"}, "payment_method": {"debit": true}}`
+	approved := func(payload string, token string) string {
+		return strings.TrimSuffix(payload, "}") + `, "token": "` + token + `"}`
+	}
+
+	post := func(handler http.Handler, body string) (int, repository.SpendImportResult) {
+		t.Helper()
+
+		req, err := http.NewRequest("POST", "/api/v1/imports/"+ownerID, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var result repository.SpendImportResult
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+		} else if !json.Valid(rr.Body.Bytes()) {
+			t.Errorf("import failed with malformed JSON: %s", rr.Body.String())
+		}
+		return rr.Code, result
+	}
+
+	statuses := func(result repository.SpendImportResult) string {
+		var s []string
+		for _, row := range result.Rows {
+			s = append(s, fmt.Sprintf("%d:%s", row.Line, row.Status))
+		}
+		return strings.Join(s, " ")
+	}
+
+	for _, invalid := range []string{
+		`{"format": "qif", "content": "` + statement + `"}`,
+		`{"format": "csv", "content": "` + statement + `", "mapping": {"date": "date", "description": "description", "amount": "value"}}`,
+		`{"format": "csv", "content": "` + statement + `", "mapping": {"date": "date", "description": "description", "amount": "\"value\""}}`,
+		`{"format": "ofx", "content": "` + statement + `"}`,
+		`{"format": "csv", "content": "` + statement + `", "mapping": {"date": "date", "description": "description", "amount": "amount"}, "payment_method": {"credit": {"id": "60b1c2d3e4f5a60718293aff"}}}`,
+	} {
+		if code, _ := post(h.PreviewImportHandler, invalid); code != http.StatusBadRequest {
+			t.Errorf("import %s: got %v want %v", invalid, code, http.StatusBadRequest)
+		}
+	}
+
+	code, preview := post(h.PreviewImportHandler, payload)
+	if code != http.StatusOK || preview.Committed || preview.New != 3 || preview.Duplicates != 1 || preview.Skipped != 1 || preview.Invalid != 1 {
+		t.Fatalf("unexpected preview: %v %+v", code, preview)
+	}
+	if got := statuses(preview); got != "2:duplicate 3:new 4:new 5:skipped 6:invalid 7:new" {
+		t.Errorf("unexpected preview statuses: %s", got)
+	}
+	if spend := preview.Rows[1].Spend; fmt.Sprint(spend.Categories) != "[transport]" || spend.Cost != repository.NewMoney(45.9) || !spend.PaymentMethod.Debit {
+		t.Errorf("unexpected previewed spend: %+v", spend)
+	}
+
+	// commits are refused unless they create exactly the spends of the preview they approve
+	for _, unapproved := range []string{
+		payload,
+		approved(payload, "0"+preview.Token[1:]),
+		approved(strings.Replace(payload, "-45.90", "-54.90", 1), preview.Token),
+		approved(strings.Replace(payload, `"debit": true`, `"payment_slip": true`, 1), preview.Token),
+	} {
+		if code, _ := post(h.CommitImportHandler, unapproved); code != http.StatusBadRequest {
+			t.Errorf("commit %s: got %v want %v", unapproved, code, http.StatusBadRequest)
+		}
+	}
+
+	page, err := models.GetSpends(ctx, repository.SpendFilter{OwnerID: ownerID})
+	if err != nil || len(page.Spends) != 1 {
+		t.Fatalf("preview created spends: %+v, %v", page.Spends, err)
+	}
+
+	code, commit := post(h.CommitImportHandler, approved(payload, preview.Token))
+	if code != http.StatusOK || !commit.Committed || commit.Created != 2 || commit.Failed != 1 || commit.Duplicates != 1 {
+		t.Fatalf("unexpected commit: %v %+v", code, commit)
+	}
+	if got := statuses(commit); got != "2:duplicate 3:created 4:created 5:skipped 6:invalid 7:failed" {
+		t.Errorf("unexpected commit statuses: %s", got)
+	}
+
+	created, err := models.GetSpend(ctx, ownerID, commit.Rows[1].SpendID)
+	if err != nil || fmt.Sprint(created.Categories) != "[transport]" {
+		t.Errorf("unexpected imported spend: %+v, %v", created, err)
+	}
+
+	// the same preview can not be committed twice, while committing the statement again creates nothing
+	if code, _ := post(h.CommitImportHandler, approved(payload, preview.Token)); code != http.StatusBadRequest {
+		t.Errorf("preview committed twice: got %v want %v", code, http.StatusBadRequest)
+	}
+
+	_, preview = post(h.PreviewImportHandler, payload)
+	code, commit = post(h.CommitImportHandler, approved(payload, preview.Token))
+	if code != http.StatusOK || commit.Created != 0 || commit.Duplicates != 3 {
+		t.Fatalf("unexpected second commit: %v %+v", code, commit)
+	}
+}
+
+func TestLegacyCardDeletionAuthorizesTheCardOwner(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a58"
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	cardID, err := models.CreateCard(ctx, repository.CreditCard{OwnerID: owner, Alias: "gold", Network: "visa", LastDigits: 4444})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remove := func(principal auth.Principal) int {
+		req, err := http.NewRequest("DELETE", "/api/v1/cards/"+cardID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": cardID})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		h.DeleteCardHandler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if status := remove(auth.Principal{Subject: "60b1c2d3e4f5a60718293aff"}); status != http.StatusForbidden {
+		t.Errorf("card was deleted by another user: got %v want %v", status, http.StatusForbidden)
+	}
+
+	admin := auth.Principal{Subject: "5143afc66d44e1ceb372121e", Roles: []string{auth.RoleAdmin}}
+	if status := remove(admin); status != http.StatusOK {
+		t.Errorf("card was not deleted by an admin: got %v want %v", status, http.StatusOK)
+	}
+
+	if status := remove(admin); status != http.StatusNotFound {
+		t.Errorf("deleted card was found: got %v want %v", status, http.StatusNotFound)
+	}
+}
+
+func TestRevokingSessionsRejectsTokensIssuedInTheSameSecond(t *testing.T) {
+	m := handlers.GetMiddlewares()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a59"
+
+	authenticate := func(token string) int {
+		t.Helper()
+
+		req, err := http.NewRequest("GET", "/api/v1/spends", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		rr := httptest.NewRecorder()
+		m.Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})).ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// waits for a new second, so the token and the revocation share the same 'iat'
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+	before, err := controllers.GenerateJWTAccessToken(ownerID, "revoked", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	if err := models.RevokeUserSessions(ctx, ownerID, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	after, err := controllers.GenerateJWTAccessToken(ownerID, "revoked", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := authenticate(before); code != http.StatusUnauthorized {
+		t.Errorf("token issued before revoking sessions in the same second was accepted: %d", code)
+	}
+
+	if code := authenticate(after); code != http.StatusOK {
+		t.Errorf("token issued right after revoking sessions was rejected: %d", code)
+	}
+}
+
+func TestPatchedForeignSpendKeepsItsOriginalAmount(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a5a"
+	principal := auth.Principal{Subject: ownerID}
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	req, err := http.NewRequest("POST", "/api/v1/exchange-rates/import", strings.NewReader("date,base,quote,rate\n2021-09-01,GBP,BRL,7\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	h.ImportExchangeRatesHandler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	if _, err := models.CreateBalance(ctx, repository.Balance{OwnerID: owner, Month: 9, Year: 2021, Currency: "BRL"}); err != nil {
+		t.Fatal(err)
+	}
+
+	date := primitive.NewDateTimeFromTime(time.Date(2021, time.September, 2, 0, 0, 0, 0, time.UTC))
+	id, err := models.CreateSpend(ctx, repository.Spend{OwnerID: owner, Description: "tea", Cost: repository.NewMoney(10), Currency: "GBP", Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := func(body string) repository.Spend {
+		t.Helper()
+
+		req, err := http.NewRequest("PATCH", "/api/v1/spends/"+ownerID+"/"+id, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "id": id})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		h.PatchSpendHandler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+
+		spend, err := models.GetSpend(ctx, ownerID, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *spend
+	}
+
+	spend := patch(`{"description": "afternoon tea"}`)
+	if spend.Description != "afternoon tea" || spend.Cost.String() != "70" || spend.Currency != "BRL" ||
+		spend.OriginalCost.String() != "10" || spend.OriginalCurrency != "GBP" || spend.ExchangeRate != 7 {
+		t.Errorf("patched spend lost its original amount: %+v", spend)
+	}
+
+	// changing the cost replaces the converted amount
+	spend = patch(`{"cost": 65}`)
+	if spend.Cost.String() != "65" || spend.Currency != "BRL" || spend.OriginalCurrency != "" || spend.OriginalCost != 0 {
+		t.Errorf("unexpected spend after changing its cost: %+v", spend)
+	}
+
+	balance, err := models.GetBalance(ctx, ownerID, 9, 2021)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Outcome.DynamicOutcome.String() != "65" {
+		t.Errorf("unexpected balance: %+v", balance.Outcome)
+	}
+}
+
+func TestPatchedPaymentMethodReplacesTheCurrentOne(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a5b"
+	principal := auth.Principal{Subject: ownerID}
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	cardID, err := models.CreateCard(ctx, repository.CreditCard{OwnerID: owner, Alias: "black", LastDigits: 2468})
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, _ := primitive.ObjectIDFromHex(cardID)
+
+	date := primitive.NewDateTimeFromTime(time.Date(2021, time.October, 2, 0, 0, 0, 0, time.UTC))
+	id, err := models.CreateSpend(ctx, repository.Spend{
+		OwnerID:       owner,
+		Description:   "groceries",
+		Cost:          repository.NewMoney(30),
+		PaymentMethod: repository.PaymentMethod{Credit: repository.CreditCard{ID: card}},
+		Date:          date,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req, err := http.NewRequest("PATCH", "/api/v1/spends/"+ownerID+"/"+id, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "id": id})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		h.PatchSpendHandler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// spends paid with more than one payment method are rejected
+	rr := patch(`{"payment_method": {"debit": true, "credit": {"id": "` + cardID + `"}}}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+
+	rr = patch(`{"payment_method": {"debit": true}}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	spend, err := models.GetSpend(ctx, ownerID, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !spend.PaymentMethod.Debit || !spend.PaymentMethod.Credit.ID.IsZero() {
+		t.Errorf("payment method was merged into the current one: %+v", spend.PaymentMethod)
+	}
+}
+
+func TestBalancesAreListedByOwnerAndPeriod(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a60"
+	otherID := "60b1c2d3e4f5a60718293a61"
+	principal := auth.Principal{Subject: ownerID}
+
+	seed := []struct {
+		owner string
+		month int64
+		year  int64
+	}{
+		{ownerID, 3, 2021},
+		{otherID, 2, 2021},
+		{ownerID, 12, 2020},
+		{ownerID, 2, 2022},
+		{ownerID, 1, 2021},
+	}
+
+	for _, b := range seed {
+		oid, _ := primitive.ObjectIDFromHex(b.owner)
+		if _, err := models.CreateBalance(ctx, repository.Balance{OwnerID: oid, Month: b.month, Year: b.year}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		periods []string
+	}{
+		{"all", "", http.StatusOK, []string{"2020-12", "2021-01", "2021-03", "2022-02"}},
+		{"year", "?year=2021", http.StatusOK, []string{"2021-01", "2021-03"}},
+		{"range", "?from=2020-12&to=2021-02", http.StatusOK, []string{"2020-12", "2021-01"}},
+		{"open range", "?from=2021-02", http.StatusOK, []string{"2021-03", "2022-02"}},
+		{"empty range", "?from=2023-01&to=2023-12", http.StatusNotFound, nil},
+		{"inverted range", "?from=2021-03&to=2021-01", http.StatusBadRequest, nil},
+		{"invalid period", "?from=2021-13", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", "/api/v1/balance/"+ownerID+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		h.GetBalanceHandler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.status {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v: %s", tt.name, rr.Code, tt.status, rr.Body.String())
+			continue
+		}
+
+		if tt.status != http.StatusOK {
+			continue
+		}
+
+		var balances []repository.Balance
+		if err := json.Unmarshal(rr.Body.Bytes(), &balances); err != nil {
+			t.Fatal(err)
+		}
+
+		periods := []string{}
+		for _, b := range balances {
+			if b.OwnerID.Hex() != ownerID {
+				t.Errorf("%s: balance from another owner was listed: %+v", tt.name, b)
+			}
+			periods = append(periods, fmt.Sprintf("%d-%02d", b.Year, b.Month))
+		}
+
+		if strings.Join(periods, ",") != strings.Join(tt.periods, ",") {
+			t.Errorf("%s: unexpected balances: got %v want %v", tt.name, periods, tt.periods)
+		}
+	}
+}
+
+func TestBalanceUpdatesRecomputeSpendableAmount(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a62"
+	principal := auth.Principal{Subject: ownerID}
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	id, err := models.CreateBalance(ctx, repository.Balance{
+		OwnerID:  owner,
+		Month:    10,
+		Year:     2021,
+		Currency: "BRL",
+		Income:   repository.Income{GrossIncome: repository.NewMoney(1200), NetIncome: repository.NewMoney(1000)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := primitive.NewDateTimeFromTime(time.Date(2021, time.October, 5, 0, 0, 0, 0, time.UTC))
+	_, err = models.CreateSpend(ctx, repository.Spend{OwnerID: owner, Description: "rent", Cost: repository.NewMoney(100), Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := models.GetBalanceByID(ctx, ownerID, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(method string, handler http.Handler, body string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req, err := http.NewRequest(method, "/api/v1/balance/"+ownerID+"/"+id, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID, "id": id})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	rr := send("PATCH", h.PatchBalanceHandler, `{"income": {"gross": 2000, "net": 1500}}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var updated repository.Balance
+	if err := json.Unmarshal(rr.Body.Bytes(), &updated); err != nil {
+		t.Fatal(err)
+	}
+
+	// the spendable amount is recomputed from the spends the balance already has
+	if updated.SpendableAmount.String() != "1400" || len(updated.Historic) != 1 || updated.Currency != "BRL" {
+		t.Errorf("unexpected balance after changing its income: %+v", updated)
+	}
+
+	if updated.UpdatedAt <= created.UpdatedAt {
+		t.Errorf("balance update time was not bumped: got %v after %v", updated.UpdatedAt, created.UpdatedAt)
+	}
+
+	// spends are already converted into the balance currency, so it can't change under them
+	rr = send("PUT", h.UpdateBalanceHandler, `{"currency": "USD", "income": {"gross": 2000, "net": 1500}}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+
+	rr = send("PUT", h.UpdateBalanceHandler, `{"currency": "BRL", "income": {"gross": 100, "net": 200}}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+
+	balance, err := models.GetBalanceByID(ctx, ownerID, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if balance.Currency != "BRL" || balance.SpendableAmount.String() != "1400" {
+		t.Errorf("refused updates changed the balance: %+v", balance)
+	}
+
+	rr = send("DELETE", h.DeleteBalanceHandler, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	rr = send("PATCH", h.PatchBalanceHandler, `{"income": {"gross": 2000, "net": 1500}}`)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusNotFound, rr.Body.String())
+	}
+}
+
+func TestSpendsAreFilteredAndPaginated(t *testing.T) {
+	h := handlers.GetHandlers()
+	ctx := context.Background()
+
+	ownerID := "60b1c2d3e4f5a60718293a63"
+	principal := auth.Principal{Subject: ownerID}
+	owner, _ := primitive.ObjectIDFromHex(ownerID)
+
+	create := func(spendType string, category string, date time.Time) string {
+		t.Helper()
+
+		id, err := models.CreateSpend(ctx, repository.Spend{
+			OwnerID:     owner,
+			Type:        spendType,
+			Description: category,
+			Cost:        repository.NewMoney(10),
+			Categories:  []string{category},
+			Date:        primitive.NewDateTimeFromTime(date),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	tied := time.Date(2021, time.November, 10, 12, 0, 0, 0, time.UTC)
+
+	// spends sharing the same date are only told apart by their IDs
+	expected := map[string]bool{}
+	for i := 0; i < 7; i++ {
+		expected[create(repository.SpendTypeDynamic, "groceries", tied)] = true
+	}
+
+	create(repository.SpendTypeFixed, "groceries", tied)
+	create(repository.SpendTypeDynamic, "housing", tied)
+	create(repository.SpendTypeDynamic, "groceries", time.Date(2021, time.October, 20, 0, 0, 0, 0, time.UTC))
+
+	list := func(query string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req, err := http.NewRequest("GET", "/api/v1/spends/"+ownerID+"?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"owner_id": ownerID})
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+		rr := httptest.NewRecorder()
+		h.GetSpendsHandler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, sort := range []string{"asc", "desc"} {
+		filter := "type=dynamic&category=groceries&from=2021-11-01&to=2021-11-30&limit=3&sort=" + sort
+
+		seen := map[string]bool{}
+		pages, cursor := 0, ""
+		for {
+			query := filter
+			if cursor != "" {
+				query += "&cursor=" + url.QueryEscape(cursor)
+			}
+
+			rr := list(query)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("%s: handler returned wrong status code: got %v want %v: %s", sort, rr.Code, http.StatusOK, rr.Body.String())
+			}
+
+			var spends []repository.Spend
+			if err := json.Unmarshal(rr.Body.Bytes(), &spends); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range spends {
+				if seen[s.ID.Hex()] {
+					t.Errorf("%s: spend was listed twice: %s", sort, s.ID.Hex())
+				}
+				seen[s.ID.Hex()] = true
+			}
+
+			pages++
+			cursor = rr.Header().Get("X-Next-Cursor")
+			if cursor == "" || pages > len(expected) {
+				break
+			}
+		}
+
+		if pages != 3 || len(seen) != len(expected) {
+			t.Errorf("%s: unexpected pages: got %d pages with %d spends want 3 pages with %d spends", sort, pages, len(seen), len(expected))
+		}
+
+		for id := range expected {
+			if !seen[id] {
+				t.Errorf("%s: spend was skipped: %s", sort, id)
+			}
+		}
+	}
+
+	for _, query := range []string{"limit=0", "limit=ten", "sort=up", "cursor=not-a-cursor", "type=monthly", "from=yesterday"} {
+		if rr := list(query); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", query, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -22,16 +22,20 @@ type HTTPConfig struct {
 	TLSConfig *tls.Config
 	CertFile  string
 	KeyFile   string
+	// ReadTimeout, WriteTimeout and ShutdownTimeout default to 1s, 1s and 15s when not defined
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
 }
 
 // WaitGracefulShutdown is blocking code to wait a SIGNAL to graceful shutdown the server
-func WaitGracefulShutdown(srv *http.Server, timeoutSeconds time.Duration) (err error) {
+func WaitGracefulShutdown(srv *http.Server, timeout time.Duration) (err error) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	os := <-quit
 
 	log.Printf("Waiting server to shutdown due to signal '%+v'", os)
-	ctx, cancel := context.WithTimeout(context.Background(), timeoutSeconds*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
 		return err
@@ -43,8 +47,8 @@ func WaitGracefulShutdown(srv *http.Server, timeoutSeconds time.Duration) (err e
 // InitHTTPServer will init a HTTP/1 (h2) server with optional TLS enforcement
 func (c HTTPConfig) InitHTTPServer(serveTLS bool) (err error) {
 	srv := &http.Server{
-		WriteTimeout: 1 * time.Second,
-		ReadTimeout:  1 * time.Second,
+		WriteTimeout: durationOrDefault(c.WriteTimeout, 1*time.Second),
+		ReadTimeout:  durationOrDefault(c.ReadTimeout, 1*time.Second),
 		Addr:         c.Port,
 		Handler:      c.Router,
 		TLSConfig:    c.TLSConfig,
@@ -68,10 +72,19 @@ func (c HTTPConfig) InitHTTPServer(serveTLS bool) (err error) {
 		}()
 	}
 
-	if err := WaitGracefulShutdown(srv, 15); err != nil {
+	if err := WaitGracefulShutdown(srv, durationOrDefault(c.ShutdownTimeout, 15*time.Second)); err != nil {
 		return err
 	}
 
 	log.Infoln("Server finished")
 	return nil
 }
+
+// durationOrDefault will return a duration, or a default one when it's not defined
+func durationOrDefault(d time.Duration, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+
+	return d
+}
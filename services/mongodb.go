@@ -1,6 +1,7 @@
 package services
 
 import (
+	"budget-tracker-api/config"
 	"budget-tracker-api/observability"
 	"context"
 	"time"
@@ -15,7 +16,7 @@ import (
 	"go.mongodb.org/mongo-driver/x/bsonx"
 )
 
-const (
+var (
 	// MongodbURI will define a URI to be used across packages
 	MongodbURI = "mongodb://localhost:27017/"
 	// MongodbDatabase will define a database to be used across packages
@@ -28,13 +29,51 @@ const (
 	MongodbBalanceCollection = "balance"
 	// MongodbSpendsCollection will define a Spend collection
 	MongodbSpendsCollection = "spends"
-)
+	// MongodbRefreshTokensCollection will define a refresh tokens collection
+	MongodbRefreshTokensCollection = "refresh_tokens"
+	// MongodbRevocationsCollection will define a revoked tokens collection
+	MongodbRevocationsCollection = "revocations"
+	// MongodbLoginAttemptsCollection will define a failed login attempts collection
+	MongodbLoginAttemptsCollection = "login_attempts"
+	// MongodbExchangeRatesCollection will define a daily exchange rates collection
+	MongodbExchangeRatesCollection = "exchange_rates"
+	// MongodbRecurrencesCollection will define a recurring spends collection
+	MongodbRecurrencesCollection = "recurrences"
+	// MongodbStatementPaymentsCollection will define a credit card statement payments collection
+	MongodbStatementPaymentsCollection = "statement_payments"
+	// MongodbBudgetAlertsCollection will define a category budget alerts collection
+	MongodbBudgetAlertsCollection = "budget_alerts"
+	// MongodbCategoriesCollection will define a spend categories collection
+	MongodbCategoriesCollection = "categories"
+	// MongodbCategoryRulesCollection will define a spend auto-categorization rules collection
+	MongodbCategoryRulesCollection = "category_rules"
+	// MongodbTimeout will define the timeout of every mongoDB operation
+	MongodbTimeout = 5 * time.Second
 
-var (
 	// MongoClient will define a mongoDB client to be initialized and used across pkgs
 	MongoClient *mongo.Client
 )
 
+// ConfigureMongoDB will define the URI, database, collections and timeout used across packages
+func ConfigureMongoDB(c config.MongoDBConfig) {
+	MongodbURI = c.URI
+	MongodbDatabase = c.Database
+	MongodbTimeout = c.Timeout
+	MongodbUserCollection = c.Collections.Users
+	MongodbCardsCollection = c.Collections.Cards
+	MongodbBalanceCollection = c.Collections.Balances
+	MongodbSpendsCollection = c.Collections.Spends
+	MongodbRefreshTokensCollection = c.Collections.RefreshTokens
+	MongodbRevocationsCollection = c.Collections.Revocations
+	MongodbLoginAttemptsCollection = c.Collections.LoginAttempts
+	MongodbExchangeRatesCollection = c.Collections.ExchangeRates
+	MongodbRecurrencesCollection = c.Collections.Recurrences
+	MongodbStatementPaymentsCollection = c.Collections.StatementPayments
+	MongodbBudgetAlertsCollection = c.Collections.BudgetAlerts
+	MongodbCategoriesCollection = c.Collections.Categories
+	MongodbCategoryRulesCollection = c.Collections.CategoryRules
+}
+
 // MongoCfg satisfies DataManager and Monger Interfaces
 type MongoCfg struct {
 	// Database URI for mongodb. Example: "mongodb://localhost:2701"
@@ -90,6 +129,253 @@ func setIndexes(ctx context.Context, c *mongo.Client) error {
 		return err
 	}
 
+	// spends are always listed by owner and paginated by date, optionally narrowed by other attributes
+	spendsIndexes := []bsonx.Doc{
+		{
+			{Key: "owner_id", Value: bsonx.Int32(1)},
+			{Key: "date", Value: bsonx.Int32(-1)},
+			{Key: "_id", Value: bsonx.Int32(-1)},
+		},
+		{
+			{Key: "owner_id", Value: bsonx.Int32(1)},
+			{Key: "type", Value: bsonx.Int32(1)},
+			{Key: "date", Value: bsonx.Int32(-1)},
+		},
+		{
+			{Key: "owner_id", Value: bsonx.Int32(1)},
+			{Key: "categories", Value: bsonx.Int32(1)},
+			{Key: "date", Value: bsonx.Int32(-1)},
+		},
+		{
+			{Key: "owner_id", Value: bsonx.Int32(1)},
+			{Key: "payment_method.credit._id", Value: bsonx.Int32(1)},
+			{Key: "date", Value: bsonx.Int32(-1)},
+		},
+	}
+
+	for _, keys := range spendsIndexes {
+		_, err = setIndex(ctx, c, MongodbDatabase, MongodbSpendsCollection, keys, options.Index())
+		if err != nil {
+			return err
+		}
+	}
+
+	// expired refresh tokens are automatically removed by mongoDB
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbRefreshTokensCollection,
+		bsonx.Doc{{Key: "expires_at", Value: bsonx.Int32(1)}},
+		options.Index().SetExpireAfterSeconds(0),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbRefreshTokensCollection,
+		bsonx.Doc{{Key: "family", Value: bsonx.Int32(1)}},
+		options.Index(),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbRefreshTokensCollection,
+		bsonx.Doc{{Key: "owner_id", Value: bsonx.Int32(1)}},
+		options.Index(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// revocations are only kept while the revoked tokens would still be valid
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbRevocationsCollection,
+		bsonx.Doc{{Key: "expires_at", Value: bsonx.Int32(1)}},
+		options.Index().SetExpireAfterSeconds(0),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbRevocationsCollection,
+		bsonx.Doc{{Key: "jti", Value: bsonx.Int32(1)}},
+		options.Index().SetSparse(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbRevocationsCollection,
+		bsonx.Doc{
+			{Key: "owner_id", Value: bsonx.Int32(1)},
+			{Key: "not_before", Value: bsonx.Int32(-1)},
+		},
+		options.Index().SetSparse(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	// failed login attempts are forgotten after a while without new failures
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbLoginAttemptsCollection,
+		bsonx.Doc{{Key: "expires_at", Value: bsonx.Int32(1)}},
+		options.Index().SetExpireAfterSeconds(0),
+	)
+	if err != nil {
+		return err
+	}
+
+	// a currency pair has a single rate per day
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbExchangeRatesCollection,
+		bsonx.Doc{
+			{Key: "base", Value: bsonx.Int32(1)},
+			{Key: "quote", Value: bsonx.Int32(1)},
+			{Key: "date", Value: bsonx.Int32(1)},
+		},
+		options.Index().SetUnique(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	// installments of the same purchase are looked up together
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbSpendsCollection,
+		bsonx.Doc{{Key: "installment.purchase_id", Value: bsonx.Int32(1)}},
+		options.Index().SetSparse(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	// the scheduler looks up active recurrences by their next run
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbRecurrencesCollection,
+		bsonx.Doc{
+			{Key: "status", Value: bsonx.Int32(1)},
+			{Key: "next_run", Value: bsonx.Int32(1)},
+		},
+		options.Index(),
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbRecurrencesCollection,
+		bsonx.Doc{{Key: "owner_id", Value: bsonx.Int32(1)}},
+		options.Index(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// a card statement is paid a single time
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbStatementPaymentsCollection,
+		bsonx.Doc{
+			{Key: "card_id", Value: bsonx.Int32(1)},
+			{Key: "year", Value: bsonx.Int32(1)},
+			{Key: "month", Value: bsonx.Int32(1)},
+		},
+		options.Index().SetUnique(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	// a budget threshold is alerted a single time per category and month
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbBudgetAlertsCollection,
+		bsonx.Doc{
+			{Key: "owner_id", Value: bsonx.Int32(1)},
+			{Key: "year", Value: bsonx.Int32(1)},
+			{Key: "month", Value: bsonx.Int32(1)},
+			{Key: "category", Value: bsonx.Int32(1)},
+			{Key: "threshold", Value: bsonx.Int32(1)},
+		},
+		options.Index().SetUnique(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	// category names are unique per owner regardless of case
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbCategoriesCollection,
+		bsonx.Doc{
+			{Key: "owner_id", Value: bsonx.Int32(1)},
+			{Key: "name", Value: bsonx.Int32(1)},
+		},
+		options.Index().SetUnique(true).SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+	)
+	if err != nil {
+		return err
+	}
+
+	// rules are always evaluated in priority order
+	_, err = setIndex(
+		ctx,
+		c,
+		MongodbDatabase,
+		MongodbCategoryRulesCollection,
+		bsonx.Doc{
+			{Key: "owner_id", Value: bsonx.Int32(1)},
+			{Key: "priority", Value: bsonx.Int32(1)},
+		},
+		options.Index(),
+	)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -103,7 +389,7 @@ func setIndex(ctx context.Context, c *mongo.Client, database string, collection
 	defer span.End()
 
 	ind := c.Database(database).Collection(collection).Indexes()
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
 
 	i, err := ind.CreateOne(
 		ctx,
@@ -123,7 +409,7 @@ func setIndex(ctx context.Context, c *mongo.Client, database string, collection
 
 // InitDatabaseWithURI will return a database client for usage
 func InitDatabaseWithURI(uri string) (*mongo.Client, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), MongodbTimeout)
 
 	clientOptions := options.Client().ApplyURI(uri)
 	clientOptions.Monitor = otelmongo.NewMonitor("mongodb")
@@ -160,7 +446,7 @@ func (m MongoCfg) Health() (err error) {
 // Get will perform a mongoDB FindOne operation
 func (m MongoCfg) Get(ctx context.Context, filter interface{}) (r *mongo.SingleResult, err error) {
 	col := MongoClient.Database(m.Database).Collection(m.Colletion)
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
 
 	r = col.FindOne(ctx, filter)
 	if r.Err() != nil {
@@ -173,11 +459,11 @@ func (m MongoCfg) Get(ctx context.Context, filter interface{}) (r *mongo.SingleR
 }
 
 // GetAll will perform a mongoDB Find operation
-func (m MongoCfg) GetAll(ctx context.Context, filter interface{}) (r *mongo.Cursor, err error) {
+func (m MongoCfg) GetAll(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (r *mongo.Cursor, err error) {
 	col := MongoClient.Database(m.Database).Collection(m.Colletion)
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
 
-	r, err = col.Find(ctx, filter)
+	r, err = col.Find(ctx, filter, opts...)
 	if err != nil {
 		cancel()
 		return r, err
@@ -191,7 +477,7 @@ func (m MongoCfg) GetAll(ctx context.Context, filter interface{}) (r *mongo.Curs
 // Create will perform a mongoDB InsertOne operation
 func (m MongoCfg) Create(ctx context.Context, filter interface{}) (r *mongo.InsertOneResult, err error) {
 	col := MongoClient.Database(m.Database).Collection(m.Colletion)
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
 
 	r, err = col.InsertOne(ctx, filter)
 	if err != nil {
@@ -207,7 +493,7 @@ func (m MongoCfg) Create(ctx context.Context, filter interface{}) (r *mongo.Inse
 func (m MongoCfg) Delete(ctx context.Context, filter interface{}) (r *mongo.DeleteResult, err error) {
 
 	col := MongoClient.Database(m.Database).Collection(m.Colletion)
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
 
 	r, err = col.DeleteOne(ctx, filter)
 	if err != nil {
@@ -218,3 +504,79 @@ func (m MongoCfg) Delete(ctx context.Context, filter interface{}) (r *mongo.Dele
 
 	return r, nil
 }
+
+// Update will perform a mongoDB UpdateOne operation
+func (m MongoCfg) Update(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (r *mongo.UpdateResult, err error) {
+	col := MongoClient.Database(m.Database).Collection(m.Colletion)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
+
+	r, err = col.UpdateOne(ctx, filter, update, opts...)
+	if err != nil {
+		cancel()
+		return r, err
+	}
+	defer cancel()
+
+	return r, nil
+}
+
+// Replace will perform a mongoDB ReplaceOne operation
+func (m MongoCfg) Replace(ctx context.Context, filter interface{}, replacement interface{}) (r *mongo.UpdateResult, err error) {
+	col := MongoClient.Database(m.Database).Collection(m.Colletion)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
+
+	r, err = col.ReplaceOne(ctx, filter, replacement)
+	if err != nil {
+		cancel()
+		return r, err
+	}
+	defer cancel()
+
+	return r, nil
+}
+
+// FindOneAndUpdate will perform a mongoDB FindOneAndUpdate operation
+func (m MongoCfg) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) (r *mongo.SingleResult, err error) {
+	col := MongoClient.Database(m.Database).Collection(m.Colletion)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
+
+	r = col.FindOneAndUpdate(ctx, filter, update, opts...)
+	if r.Err() != nil {
+		cancel()
+		return &mongo.SingleResult{}, r.Err()
+	}
+
+	defer cancel()
+	return r, nil
+}
+
+// UpdateMany will perform a mongoDB UpdateMany operation
+func (m MongoCfg) UpdateMany(ctx context.Context, filter interface{}, update interface{}) (r *mongo.UpdateResult, err error) {
+	col := MongoClient.Database(m.Database).Collection(m.Colletion)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
+
+	r, err = col.UpdateMany(ctx, filter, update)
+	if err != nil {
+		cancel()
+		return r, err
+	}
+	defer cancel()
+
+	return r, nil
+}
+
+// Aggregate will perform a mongoDB Aggregate operation
+func (m MongoCfg) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (r *mongo.Cursor, err error) {
+	col := MongoClient.Database(m.Database).Collection(m.Colletion)
+	ctx, cancel := context.WithTimeout(ctx, MongodbTimeout)
+
+	r, err = col.Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		cancel()
+		return r, err
+	}
+
+	defer cancel()
+
+	return r, nil
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	// SQL drivers registered as "postgres" and "sqlite3"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+)
+
+// SQLClient will define a SQL database client to be initialized and used across pkgs
+var SQLClient *sql.DB
+
+// InitSQLDatabase will return a SQL client for a given driver ("postgres" or "sqlite3") and data source
+func InitSQLDatabase(driver string, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite allows a single writer, so sharing one connection avoids 'database is locked' errors
+	if driver == "sqlite3" {
+		db.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), MongodbTimeout)
+	defer cancel()
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Infoln("connected to", driver, "database")
+	return db, nil
+}